@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asabya/x-go/internal/handlers"
+	"github.com/asabya/x-go/internal/tasks"
+	"github.com/asabya/x-go/pkg/getmoni"
+	"github.com/asabya/x-go/pkg/twitter"
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix starts the name of every environment variable overriding a config field
+const envPrefix = "XGO_"
+
+type Config struct {
+	Usernames       []string `yaml:"usernames"`
+	PostgresURL     string   `yaml:"postgres_url"`
+	GetMoniAPIKey   string   `yaml:"getmoni_api_key"`
+	GetMoniAPIKeys  []string `yaml:"getmoni_api_keys"` // Rotated through along with GetMoniAPIKey
+	AdminAPIKey     string   `yaml:"admin_api_key"`
+	DryRun          bool     `yaml:"dry_run"`
+	UserAgent       string   `yaml:"user_agent"`
+	LogLevel        string   `yaml:"log_level"`
+	MaxResults      int      `yaml:"max_results"`
+	TweetFetchLimit int      `yaml:"tweet_fetch_limit"`
+	// CookieSaveInterval is how often rotated session cookies are saved, 0 to never
+	CookieSaveInterval time.Duration `yaml:"cookie_save_interval"`
+	// EndpointConcurrency caps the calls to an endpoint each account runs at once
+	EndpointConcurrency map[string]int `yaml:"endpoint_concurrency"`
+	Retry               struct {
+		MaxAttempts int           `yaml:"max_attempts"`
+		BaseDelay   time.Duration `yaml:"base_delay"`
+		MaxDelay    time.Duration `yaml:"max_delay"`
+	} `yaml:"retry"`
+	CircuitBreaker struct {
+		FailureThreshold int           `yaml:"failure_threshold"`
+		Cooldown         time.Duration `yaml:"cooldown"`
+	} `yaml:"circuit_breaker"`
+	LoginThrottle struct {
+		Delay  time.Duration `yaml:"delay"`
+		Jitter time.Duration `yaml:"jitter"`
+	} `yaml:"login_throttle"`
+	GetMoniRetry struct {
+		MaxRetries  int           `yaml:"max_retries"`
+		BaseBackoff time.Duration `yaml:"base_backoff"`
+		MaxBackoff  time.Duration `yaml:"max_backoff"`
+	} `yaml:"getmoni_retry"`
+	ProfileUpdates struct {
+		StaleAfter time.Duration `yaml:"stale_after"`
+		Interval   time.Duration `yaml:"interval"`
+	} `yaml:"profile_updates"`
+	Retention struct {
+		Enabled    bool          `yaml:"enabled"`
+		MaxAgeDays int           `yaml:"max_age_days"`
+		Interval   time.Duration `yaml:"interval"`
+		BatchSize  int           `yaml:"batch_size"`
+	} `yaml:"retention"`
+	Orphans struct {
+		Enabled  bool          `yaml:"enabled"`
+		Interval time.Duration `yaml:"interval"`
+		Limit    int           `yaml:"limit"`
+		Repair   bool          `yaml:"repair"`
+	} `yaml:"orphans"`
+	Language struct {
+		Allowlist []string `yaml:"allowlist"`
+	} `yaml:"language"`
+	Alerts struct {
+		WebhookURL        string `yaml:"webhook_url"`
+		LikesThreshold    int    `yaml:"likes_threshold"`
+		RetweetsThreshold int    `yaml:"retweets_threshold"`
+	} `yaml:"alerts"`
+	Webhooks struct {
+		QueueSize   int           `yaml:"queue_size"`
+		Workers     int           `yaml:"workers"`
+		MaxAttempts int           `yaml:"max_attempts"`
+		RetryDelay  time.Duration `yaml:"retry_delay"`
+		Timeout     time.Duration `yaml:"timeout"`
+	} `yaml:"webhooks"`
+	Idempotency struct {
+		TTL        time.Duration `yaml:"ttl"`
+		MaxEntries int           `yaml:"max_entries"`
+	} `yaml:"idempotency"`
+	Server struct {
+		ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+		ReadTimeout       time.Duration `yaml:"read_timeout"`
+		WriteTimeout      time.Duration `yaml:"write_timeout"`
+		IdleTimeout       time.Duration `yaml:"idle_timeout"`
+		RequestTimeout    time.Duration `yaml:"request_timeout"`
+		MaxBodyBytes      int           `yaml:"max_body_bytes"`
+	} `yaml:"server"`
+}
+
+// defaultConfig returns the settings used when neither config.yaml nor the
+// environment sets them. The request timeout stays below the write timeout
+// so slow scraper-backed handlers are cancelled and can still write an error
+// response before the connection is closed.
+func defaultConfig() Config {
+	var config Config
+	config.LogLevel = "info"
+	config.MaxResults = twitter.DefaultMaxResults
+	config.TweetFetchLimit = tasks.DefaultTweetFetchLimit
+	config.CookieSaveInterval = twitter.DefaultCookieSaveInterval
+	config.Retry.MaxAttempts = twitter.DefaultRetryConfig.MaxAttempts
+	config.Retry.BaseDelay = twitter.DefaultRetryConfig.BaseDelay
+	config.Retry.MaxDelay = twitter.DefaultRetryConfig.MaxDelay
+	config.CircuitBreaker.FailureThreshold = twitter.DefaultCircuitBreakerConfig.FailureThreshold
+	config.CircuitBreaker.Cooldown = twitter.DefaultCircuitBreakerConfig.Cooldown
+	config.LoginThrottle.Delay = twitter.DefaultLoginThrottle.Delay
+	config.LoginThrottle.Jitter = twitter.DefaultLoginThrottle.Jitter
+	config.GetMoniRetry.MaxRetries = getmoni.DefaultMaxRetries
+	config.GetMoniRetry.BaseBackoff = getmoni.DefaultBaseBackoff
+	config.GetMoniRetry.MaxBackoff = getmoni.DefaultMaxBackoff
+	config.ProfileUpdates.StaleAfter = tasks.DefaultProfileStaleAfter
+	config.ProfileUpdates.Interval = tasks.DefaultProfileCheckInterval
+	config.Retention.Interval = tasks.DefaultRetentionInterval
+	config.Retention.BatchSize = tasks.DefaultRetentionBatchSize
+	config.Orphans.Interval = tasks.DefaultOrphanCheckInterval
+	config.Orphans.Limit = tasks.DefaultOrphanReportLimit
+	config.Webhooks.QueueSize = tasks.DefaultWebhookConfig.QueueSize
+	config.Webhooks.Workers = tasks.DefaultWebhookConfig.Workers
+	config.Webhooks.MaxAttempts = tasks.DefaultWebhookConfig.MaxAttempts
+	config.Webhooks.RetryDelay = tasks.DefaultWebhookConfig.RetryDelay
+	config.Webhooks.Timeout = tasks.DefaultWebhookConfig.Timeout
+	config.Idempotency.TTL = handlers.DefaultIdempotencyTTL
+	config.Idempotency.MaxEntries = handlers.DefaultIdempotencyMaxEntries
+	config.Server.ReadHeaderTimeout = 10 * time.Second
+	config.Server.ReadTimeout = 30 * time.Second
+	config.Server.WriteTimeout = 150 * time.Second
+	config.Server.IdleTimeout = 120 * time.Second
+	config.Server.RequestTimeout = 120 * time.Second
+	config.Server.MaxBodyBytes = handlers.DefaultMaxBodyBytes
+	return config
+}
+
+// loadConfig layers the defaults, the config file at path and the XGO_*
+// environment variables, each overriding the one before. A missing config
+// file is skipped, so the server can be configured from the environment alone.
+func loadConfig(path string) (Config, bool, error) {
+	config := defaultConfig()
+
+	fileFound := true
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		fileFound = false
+	case err != nil:
+		return config, false, fmt.Errorf("error reading config file at %s: %v", path, err)
+	default:
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return config, true, fmt.Errorf("error parsing config file: %v", err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(&config).Elem(), envPrefix, os.LookupEnv); err != nil {
+		return config, fileFound, err
+	}
+	return config, fileFound, nil
+}
+
+// applyEnv overrides the fields of the struct v from environment variables
+// named after their yaml keys: the prefix followed by the upper-cased key
+// path joined with underscores, e.g. XGO_POSTGRES_URL or XGO_RETRY_MAX_ATTEMPTS.
+// Lists are comma separated, maps are comma separated key=value pairs and
+// durations use Go syntax such as 90s.
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		key := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name+"_", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// setField parses value into field according to the field's type
+func setField(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if field.Type() != reflect.TypeOf(map[string]int(nil)) {
+			return fmt.Errorf("unsupported map type %s", field.Type())
+		}
+		items := make(map[string]int)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, number, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got %q", item)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(number))
+			if err != nil {
+				return err
+			}
+			items[strings.TrimSpace(key)] = n
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
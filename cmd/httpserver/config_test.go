@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+postgres_url: "postgresql://file"
+getmoni_api_key: "file-key"
+max_results: 500
+retry:
+  max_attempts: 5
+`), 0o600)
+	assert.NoError(t, err)
+
+	t.Setenv("XGO_GETMONI_API_KEY", "env-key")
+	t.Setenv("XGO_RETRY_BASE_DELAY", "2s")
+	t.Setenv("XGO_USERNAMES", "alice, bob")
+	t.Setenv("XGO_DRY_RUN", "true")
+	t.Setenv("XGO_ENDPOINT_CONCURRENCY", "search_tweets=2, get_followers=1")
+
+	config, found, err := loadConfig(path)
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// Environment over file
+	assert.Equal(t, "env-key", config.GetMoniAPIKey)
+	assert.Equal(t, 2*time.Second, config.Retry.BaseDelay)
+	assert.Equal(t, []string{"alice", "bob"}, config.Usernames)
+	assert.True(t, config.DryRun)
+	assert.Equal(t, map[string]int{"search_tweets": 2, "get_followers": 1}, config.EndpointConcurrency)
+	// File over defaults
+	assert.Equal(t, "postgresql://file", config.PostgresURL)
+	assert.Equal(t, 500, config.MaxResults)
+	assert.Equal(t, 5, config.Retry.MaxAttempts)
+	// Defaults
+	assert.Equal(t, defaultConfig().Retry.MaxDelay, config.Retry.MaxDelay)
+	assert.Equal(t, defaultConfig().Server.WriteTimeout, config.Server.WriteTimeout)
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	t.Setenv("XGO_POSTGRES_URL", "postgresql://env")
+
+	config, found, err := loadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, "postgresql://env", config.PostgresURL)
+	assert.Equal(t, "info", config.LogLevel)
+}
+
+func TestLoadConfigInvalidEnv(t *testing.T) {
+	t.Setenv("XGO_SERVER_WRITE_TIMEOUT", "soon")
+
+	_, _, err := loadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	assert.EqualError(t, err, `invalid XGO_SERVER_WRITE_TIMEOUT: time: invalid duration "soon"`)
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -13,138 +13,300 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/asabya/x-go/internal/db"
 	"github.com/asabya/x-go/internal/handlers"
 	"github.com/asabya/x-go/internal/tasks"
 	"github.com/asabya/x-go/pkg/getmoni"
+	"github.com/asabya/x-go/pkg/logging"
 	"github.com/asabya/x-go/pkg/twitter"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq" // postgres driver
-	"gopkg.in/yaml.v2"
 )
 
-type Config struct {
-	Usernames     []string `yaml:"usernames"`
-	PostgresURL   string   `yaml:"postgres_url"`
-	GetMoniAPIKey string   `yaml:"getmoni_api_key"`
-}
-
 func main() {
-	// Set up logging
-	logger := log.New(os.Stdout, "[twitter-http] ", log.LstdFlags|log.Lshortfile)
+	// Set up logging, at info level until the config file is read
+	logger := logging.New(os.Stdout, "[twitter-http] ", log.LstdFlags|log.Lshortfile, logging.LevelInfo)
 
 	// Get XGO path from environment variable or use default
 	xgoPath := os.Getenv("XGO_PATH")
 	if xgoPath == "" {
-		logger.Fatalf("XGO_PATH is not set")
+		logger.Fatal("XGO_PATH is not set")
 	}
 
-	// Read config file from XGO_PATH
+	// Layer the defaults, config.yaml from XGO_PATH and XGO_* environment variables
 	configPath := filepath.Join(xgoPath, "config.yaml")
-	configData, err := os.ReadFile(configPath)
+	config, configFound, err := loadConfig(configPath)
 	if err != nil {
-		logger.Fatalf("Error reading config file at %s: %v", configPath, err)
+		logger.Fatal("Error loading config: %v", err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(configData, &config); err != nil {
-		logger.Fatalf("Error parsing config file: %v", err)
+	logLevel, err := logging.ParseLevel(config.LogLevel)
+	if err != nil {
+		logger.Fatal("Error loading config: %v", err)
 	}
-	postgresURL := config.PostgresURL
-	if postgresURL[len(postgresURL)-1] != '?' {
-		postgresURL += "?"
+	logger = logging.New(os.Stdout, "[twitter-http] ", log.LstdFlags|log.Lshortfile, logLevel)
+	handlers.SetLogger(logger)
+	if !configFound {
+		logger.Info("No config file at %s, using defaults and environment variables", configPath)
 	}
-	if !strings.Contains(postgresURL, "sslmode=") {
-		if postgresURL[len(postgresURL)-1] != '?' {
-			postgresURL += "&"
-		}
-		postgresURL += "sslmode=disable"
+
+	postgresURL, err := db.NormalizePostgresURL(config.PostgresURL)
+	if errors.Is(err, db.ErrEmptyPostgresURL) {
+		logger.Fatal("postgres_url is not set in config.yaml or %sPOSTGRES_URL", envPrefix)
+	}
+	if err != nil {
+		logger.Fatal("Error loading config: %v", err)
 	}
 
 	// Connect to database
 	database, err := sql.Open("postgres", postgresURL)
 	if err != nil {
-		logger.Fatalf("Failed to connect to database: %v", err)
+		logger.Fatal("Failed to connect to database: %v", err)
 	}
 	defer database.Close()
 
 	// Test the connection
 	if err := database.Ping(); err != nil {
-		logger.Fatalf("Failed to ping database: %v", err)
+		logger.Fatal("Failed to ping database: %v", err)
+	}
+
+	retryConfig := twitter.RetryConfig{
+		MaxAttempts: config.Retry.MaxAttempts,
+		BaseDelay:   config.Retry.BaseDelay,
+		MaxDelay:    config.Retry.MaxDelay,
+	}
+	if config.CircuitBreaker.FailureThreshold > 0 && config.CircuitBreaker.Cooldown <= 0 {
+		logger.Fatal("circuit_breaker.cooldown must be positive")
+	}
+
+	// A request timeout at or above the write timeout would let the
+	// connection close before the handler gets to report the timeout
+	requestTimeout := config.Server.RequestTimeout
+	writeTimeout := config.Server.WriteTimeout
+	if writeTimeout > 0 && (requestTimeout <= 0 || requestTimeout >= writeTimeout) {
+		logger.Warning("server.request_timeout (%s) should be below server.write_timeout (%s)", requestTimeout, writeTimeout)
+	}
+
+	// Cap the limit of searches and timelines
+	maxResults := config.MaxResults
+	handlers.SetMaxResults(maxResults)
+	if config.Server.MaxBodyBytes <= 0 {
+		logger.Fatal("server.max_body_bytes must be positive")
+	}
+	handlers.SetMaxBodyBytes(int64(config.Server.MaxBodyBytes))
+
+	// Idempotency-Key support is disabled with max_entries set to 0
+	var idempotency *handlers.IdempotencyCache
+	if config.Idempotency.MaxEntries > 0 {
+		if config.Idempotency.TTL <= 0 {
+			logger.Fatal("idempotency.ttl must be positive")
+		}
+		idempotency = handlers.NewIdempotencyCache(config.Idempotency.TTL, config.Idempotency.MaxEntries)
 	}
 
 	// Create agent manager with account management
-	agentManager, err := twitter.NewAgentManager(xgoPath)
+	agentManager, err := twitter.NewAgentManager(xgoPath,
+		twitter.WithDryRun(config.DryRun),
+		twitter.WithUserAgent(config.UserAgent),
+		twitter.WithRetryConfig(retryConfig),
+		twitter.WithCircuitBreaker(twitter.CircuitBreakerConfig{
+			FailureThreshold: config.CircuitBreaker.FailureThreshold,
+			Cooldown:         config.CircuitBreaker.Cooldown,
+		}),
+		twitter.WithLoginThrottle(twitter.LoginThrottle{
+			Delay:  config.LoginThrottle.Delay,
+			Jitter: config.LoginThrottle.Jitter,
+		}),
+		twitter.WithLogger(logger),
+		twitter.WithMaxResults(maxResults),
+		twitter.WithEndpointConcurrency(config.EndpointConcurrency),
+		twitter.WithTweetStore(handlers.NewTweetStore(database)),
+	)
 	if err != nil {
-		logger.Fatalf("Failed to create agent manager: %v", err)
+		logger.Fatal("Failed to create agent manager: %v", err)
 	}
 
 	// Check if at least one agent is logged in
-	hasLoggedInAgent := false
-	for i := 0; i < agentManager.GetAgentCount(); i++ {
-		if agent, err := agentManager.GetAgent(i); err == nil && agent.IsLoggedIn() {
-			hasLoggedInAgent = true
-			break
-		}
-	}
-	fmt.Println("hasLoggedInAgent", hasLoggedInAgent)
+	logger.Info("Has logged in agent: %v", agentManager.HasLoggedInAgent())
 
 	// Initialize GetMoni client
-	getmoniClient := getmoni.NewGetMoni(config.GetMoniAPIKey)
+	getmoniClient := getmoni.NewGetMoniWithOptions(config.GetMoniAPIKey,
+		getmoni.WithAPIKeys(config.GetMoniAPIKeys...),
+		getmoni.WithMaxRetries(config.GetMoniRetry.MaxRetries),
+		getmoni.WithBaseBackoff(config.GetMoniRetry.BaseBackoff),
+		getmoni.WithMaxBackoff(config.GetMoniRetry.MaxBackoff),
+	)
+	getmoniClient.SetLogger(logger)
 
-	// Create buffered channel for smart users (buffer size of 1000 to handle bursts)
-	smartUsersChan := make(chan string, 1000)
+	// Create buffered queue for smart users (buffer size of 1000 to handle bursts),
+	// kept in the database so users queued before a restart are still processed
+	smartUsers := tasks.NewPersistentUserQueue(database, 1000, logger)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Report whether GetMoni is reachable; the server starts either way
+	if status, err := getmoniClient.CheckStatus(ctx); err != nil {
+		logger.Error("Failed to check GetMoni server status: %v", err)
+	} else {
+		logger.Info("GetMoni server status: %v", status)
+	}
+
 	// Start background tasks
-	tasks.StartProfileUpdates(database, agentManager, logger)
-	tasks.StartTweetUpdates(database, agentManager, logger)
-	tasks.StartSmartTweetUpdates(ctx, database, agentManager, logger, smartUsersChan)
+	if config.CookieSaveInterval > 0 {
+		agentManager.StartCookieSaving(ctx, config.CookieSaveInterval)
+	}
+	tasks.StartProfileUpdates(database, agentManager, logger, tasks.ProfileUpdateConfig{
+		StaleAfter: config.ProfileUpdates.StaleAfter,
+		Interval:   config.ProfileUpdates.Interval,
+	})
+	// Events are delivered to the webhooks registered through /api/admin/webhooks
+	webhooks := tasks.NewWebhooks(database, tasks.WebhookConfig{
+		QueueSize:   config.Webhooks.QueueSize,
+		Workers:     config.Webhooks.Workers,
+		MaxAttempts: config.Webhooks.MaxAttempts,
+		RetryDelay:  config.Webhooks.RetryDelay,
+		Timeout:     config.Webhooks.Timeout,
+	}, logger)
+	webhooks.Start(ctx)
+	// Engagement alerts stay disabled unless a threshold is configured
+	alerter := tasks.NewAlerter(tasks.AlertConfig{
+		WebhookURL:        config.Alerts.WebhookURL,
+		LikesThreshold:    config.Alerts.LikesThreshold,
+		RetweetsThreshold: config.Alerts.RetweetsThreshold,
+	}, webhooks, logger)
+	languages := tasks.LanguageFilter{Allowlist: config.Language.Allowlist}
+	if len(languages.Allowlist) > 0 {
+		logger.Info("Only storing ingested tweets in languages: %s", strings.Join(languages.Allowlist, ", "))
+	}
+	if config.TweetFetchLimit <= 0 {
+		logger.Fatal("tweet_fetch_limit must be positive")
+	}
+	tasks.StartTweetUpdates(database, agentManager, logger, alerter, webhooks, languages, config.TweetFetchLimit)
+	tasks.StartSmartTweetUpdates(ctx, database, agentManager, logger, smartUsers, languages)
+	go func() {
+		restored, err := smartUsers.Restore(ctx)
+		// Stopping early on shutdown is expected, the rest stay pending
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Error restoring pending smart users: %v", err)
+		}
+		if restored > 0 {
+			logger.Info("Queued %d smart users left pending before the restart", restored)
+		}
+	}()
+
+	// Tweet retention is opt-in so no stored data is deleted unexpectedly
+	if config.Retention.Enabled {
+		if config.Retention.MaxAgeDays <= 0 {
+			logger.Fatal("retention.max_age_days must be set when retention is enabled")
+		}
+		tasks.StartTweetRetention(ctx, database, tasks.RetentionConfig{
+			MaxAge:    time.Duration(config.Retention.MaxAgeDays) * 24 * time.Hour,
+			Interval:  config.Retention.Interval,
+			BatchSize: config.Retention.BatchSize,
+		}, logger)
+	}
+
+	// The orphaned tweet check is opt-in, and only reports unless repair is set
+	if config.Orphans.Enabled {
+		tasks.StartOrphanCheck(ctx, database, agentManager, tasks.OrphanCheckConfig{
+			Interval: config.Orphans.Interval,
+			Limit:    config.Orphans.Limit,
+			Repair:   config.Orphans.Repair,
+		}, logger)
+	}
 
 	r := mux.NewRouter()
 
 	// Basic endpoints that don't require login
+	r.HandleFunc("/api/whoami", handlers.HandleWhoamiWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/agents", handlers.HandleGetAgentsWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/ratelimits/{endpoint}", handlers.HandleGetEndpointRateLimitsWithManager(agentManager)).Methods("GET")
 	r.HandleFunc("/api/user/{username}/tweets", handlers.HandleGetUserTweetsWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/user/{username}/media", handlers.HandleGetMediaTweetsWithManager(agentManager)).Methods("GET")
 	r.HandleFunc("/api/user/{username}/profile", handlers.HandleGetProfileWithManager(agentManager)).Methods("GET")
 	r.HandleFunc("/api/tweet/{id}", handlers.HandleGetTweetWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/tweet/{id}/detail", handlers.HandleGetTweetDetail(database, agentManager)).Methods("GET")
+	r.HandleFunc("/api/tweet/{id}", handlers.HandleTweetExistsWithManager(agentManager)).Methods("HEAD")
+	r.HandleFunc("/api/tweets", handlers.HandleGetTweetsWithManager(agentManager)).Methods("POST")
 	r.HandleFunc("/api/tweet/{id}/replies", handlers.HandleGetTweetRepliesWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/tweet/{id}/thread", handlers.HandleGetTweetThreadWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/tweet/{id}/context", handlers.HandleGetConversationContextWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/tweet/{id}/stats", handlers.HandleGetTweetStatsWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/tweet/validate", handlers.HandleValidateTweet()).Methods("POST")
+	r.HandleFunc("/api/conversation/{id}", handlers.HandleGetConversation(database)).Methods("GET")
+	r.HandleFunc("/api/trends", handlers.HandleGetTrendsWithManager(agentManager)).Methods("GET")
+	r.HandleFunc("/api/user/{username}/deleted-tweets", handlers.HandleGetDeletedTweets(database)).Methods("GET")
+	r.HandleFunc("/api/user/{username}/stats-history", handlers.HandleGetStatsHistory(database)).Methods("GET")
+	r.HandleFunc("/api/user/{username}/follower-diff", handlers.HandleGetFollowerDiff(database)).Methods("GET")
 	r.HandleFunc("/api/search/tweets", handlers.HandleSearchTweetsInDB(database)).Methods("GET")
+	r.HandleFunc("/api/search/combined", handlers.HandleSearchCombined(database, agentManager)).Methods("GET")
 	r.HandleFunc("/api/users", handlers.HandleAddUser(database)).Methods("POST")
 
 	// Smart endpoints
-	r.HandleFunc("/api/user/{username}/smart-followers", handlers.HandleSaveSmartFollowers(getmoniClient, database, smartUsersChan)).Methods("GET")
+	r.HandleFunc("/api/user/{username}/smart-followers", handlers.HandleSaveSmartFollowers(getmoniClient, database, smartUsers, webhooks)).Methods("GET")
+	r.HandleFunc("/api/user/{username}/smart-mentions", handlers.HandleGetSmartMentions(getmoniClient, database)).Methods("GET")
 	r.HandleFunc("/api/search/smart-tweets", handlers.HandleSearchSmartTweetsInDB(database)).Methods("GET")
+	r.HandleFunc("/api/search/all-tweets", handlers.HandleSearchAllTweetsInDB(database)).Methods("GET")
 
-	// Endpoints that require login
-	if hasLoggedInAgent {
-		r.HandleFunc("/api/user/{username}/followers", handlers.HandleGetFollowersWithManager(agentManager)).Methods("GET")
-		r.HandleFunc("/api/search", handlers.HandleSearchTweetsWithManager(agentManager)).Methods("GET")
-		r.HandleFunc("/api/follow/{id}", handlers.HandleFollowUserWithManager(agentManager)).Methods("POST")
-		r.HandleFunc("/api/unfollow/{id}", handlers.HandleUnfollowUserWithManager(agentManager)).Methods("POST")
-		r.HandleFunc("/api/tweet", handlers.HandleCreateTweetWithManager(agentManager)).Methods("POST")
-		r.HandleFunc("/api/tweet/{id}/like", handlers.HandleLikeTweetWithManager(agentManager)).Methods("POST")
-		r.HandleFunc("/api/tweet/{id}/unlike", handlers.HandleUnlikeTweetWithManager(agentManager)).Methods("POST")
-		r.HandleFunc("/api/tweet/{id}/retweet", handlers.HandleRetweetWithManager(agentManager)).Methods("POST")
-	}
+	// Endpoints that require login, answering 503 while no agent is logged in
+	loginRoutes := r.NewRoute().Subrouter()
+	loginRoutes.Use(handlers.RequireLoginMiddleware(agentManager))
+	loginRoutes.HandleFunc("/api/user/{username}/followers", handlers.HandleGetFollowersWithManager(agentManager, database)).Methods("GET")
+	loginRoutes.HandleFunc("/api/user/{username}/mutuals", handlers.HandleGetMutualFollowsWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/user/{username}/replies", handlers.HandleGetUserRepliesWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/search", handlers.HandleSearchTweetsWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/search/advanced", handlers.HandleSearchTweetsAdvancedWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/follow/batch", handlers.HandleBatchFollowWithManager(agentManager)).Methods("POST")
+	loginRoutes.HandleFunc("/api/unfollow/batch", handlers.HandleBatchUnfollowWithManager(agentManager)).Methods("POST")
+	loginRoutes.HandleFunc("/api/follow/{id}", handlers.HandleFollowUserWithManager(agentManager)).Methods("POST")
+	loginRoutes.HandleFunc("/api/unfollow/{id}", handlers.HandleUnfollowUserWithManager(agentManager)).Methods("POST")
+	loginRoutes.HandleFunc("/api/relationship", handlers.HandleGetRelationshipWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/notifications", handlers.HandleGetNotificationsWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/tweet/{id}/quotes", handlers.HandleGetQuoteTweetsWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/list/{id}/tweets", handlers.HandleGetListTweetsWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/list/{id}/members", handlers.HandleGetListMembersWithManager(agentManager)).Methods("GET")
+	loginRoutes.HandleFunc("/api/tweet", handlers.HandleCreateTweetWithManager(agentManager, idempotency)).Methods("POST")
+	loginRoutes.HandleFunc("/api/tweet/{id}/reply", handlers.HandleReplyToTweetWithManager(agentManager)).Methods("POST")
+	loginRoutes.HandleFunc("/api/tweet/{id}/like", handlers.HandleLikeTweetWithManager(agentManager)).Methods("POST")
+	loginRoutes.HandleFunc("/api/tweet/{id}/unlike", handlers.HandleUnlikeTweetWithManager(agentManager)).Methods("POST")
+	loginRoutes.HandleFunc("/api/tweet/{id}/retweet", handlers.HandleRetweetWithManager(agentManager)).Methods("POST")
+
+	// Admin endpoints, only available with the admin API key
+	adminRoutes := r.PathPrefix("/api/admin").Subrouter()
+	adminRoutes.Use(handlers.RequireAPIKeyMiddleware(config.AdminAPIKey))
+	adminRoutes.HandleFunc("/agents/reload", handlers.HandleReloadAgentsWithManager(agentManager)).Methods("POST")
+	adminRoutes.HandleFunc("/agents/{username}/relogin", handlers.HandleReloginWithManager(agentManager)).Methods("POST")
+	adminRoutes.HandleFunc("/orphans", handlers.HandleGetOrphans(database)).Methods("GET")
+	// Webhooks make the server POST to the registered URLs, so only admins may manage them
+	adminRoutes.HandleFunc("/webhooks", handlers.HandleCreateWebhook(database)).Methods("POST")
+	adminRoutes.HandleFunc("/webhooks", handlers.HandleListWebhooks(database)).Methods("GET")
+	adminRoutes.HandleFunc("/webhooks/{id}", handlers.HandleDeleteWebhook(database)).Methods("DELETE")
 
 	// Add middleware for logging and recovery
 	r.Use(handlers.LoggingMiddleware(logger))
+	r.Use(handlers.DryRunMiddleware())
+	r.Use(handlers.AgentSelectionMiddleware())
+	r.Use(handlers.TimeoutMiddleware(requestTimeout))
 	r.Use(mux.CORSMethodMiddleware(r))
 
 	// Start the server with graceful shutdown
 	addr := ":8080"
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: r,
+		Addr:              addr,
+		Handler:           r,
+		ReadHeaderTimeout: config.Server.ReadHeaderTimeout,
+		ReadTimeout:       config.Server.ReadTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       config.Server.IdleTimeout,
 	}
 
 	// Channel to listen for errors coming from the server
 	serverErrors := make(chan error, 1)
 
 	go func() {
-		logger.Printf("Starting server on %s", addr)
+		logger.Info("Starting server on %s", addr)
 		serverErrors <- srv.ListenAndServe()
 	}()
 
@@ -155,20 +317,30 @@ func main() {
 	// Blocking select waiting for either a signal or an error
 	select {
 	case err := <-serverErrors:
-		logger.Printf("Server error: %v", err)
+		logger.Error("Server error: %v", err)
 	case sig := <-shutdown:
-		logger.Printf("Received signal: %v", sig)
+		logger.Info("Received signal: %v", sig)
 	}
 
 	// Create shutdown context with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
-	// Attempt graceful shutdown
+	// Stop accepting requests, then stop the background tasks, then close
+	// the smart users queue. Handlers still running after a shutdown
+	// timeout can't panic on the closed queue: their usernames are dropped.
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		logger.Printf("Error during server shutdown: %v", err)
+		logger.Error("Error during server shutdown: %v", err)
 	}
+	cancel()
 
-	// Close the smart users channel
-	close(smartUsersChan)
+	// Calls to Twitter of handlers that outlived the shutdown timeout and of
+	// the background tasks run to their end, so no session is cut off
+	// mid-request; the cancelled tasks don't start new ones
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer drainCancel()
+	if err := twitter.WaitForScraperCalls(drainCtx); err != nil {
+		logger.Error("Exiting with calls to Twitter in progress: %v", err)
+	}
+	smartUsers.Close()
 }
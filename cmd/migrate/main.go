@@ -1,22 +1,45 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/asabya/x-go/internal/db"
+	"github.com/asabya/x-go/internal/tasks"
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	Usernames   []string `yaml:"usernames"`
-	PostgresURL string   `yaml:"postgres_url"`
+	Usernames        []string `yaml:"usernames"`
+	PostgresURL      string   `yaml:"postgres_url"`
+	TextSearchConfig string   `yaml:"text_search_config"`
+	UserAgent        string   `yaml:"user_agent"`
+	TweetFetchLimit  int      `yaml:"tweet_fetch_limit"`
+	Language         struct {
+		Allowlist []string `yaml:"allowlist"`
+	} `yaml:"language"`
 }
 
 func main() {
 	logger := log.New(os.Stdout, "[migrate] ", log.LstdFlags|log.Lshortfile)
 
+	// "migrate backfill [--limit N]" also seeds the configured users after migrating
+	backfill := len(os.Args) > 1 && os.Args[1] == "backfill"
+	backfillFlags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	limit := backfillFlags.Int("limit", 0, "tweets to fetch per user (default: tweet_fetch_limit from config.yaml)")
+	if backfill {
+		backfillFlags.Parse(os.Args[2:])
+	} else if len(os.Args) > 1 {
+		logger.Fatalf("Unknown command %q, expected no command or backfill", os.Args[1])
+	}
+
 	// Read config file
 	configData, err := os.ReadFile("config.yaml")
 	if err != nil {
@@ -37,11 +60,50 @@ func main() {
 	}
 
 	// Initialize database
-	database, err := db.InitDB(config.PostgresURL, config.Usernames)
+	database, err := db.InitDB(config.PostgresURL, config.Usernames, config.TextSearchConfig)
 	if err != nil {
 		logger.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
 
 	fmt.Println("Database migration completed successfully!")
+
+	if !backfill {
+		return
+	}
+
+	fetchLimit := *limit
+	if fetchLimit == 0 {
+		fetchLimit = config.TweetFetchLimit
+	}
+	if fetchLimit == 0 {
+		fetchLimit = tasks.DefaultTweetFetchLimit
+	}
+	if fetchLimit < 0 {
+		logger.Fatal("--limit must be positive")
+	}
+
+	// Accounts are read from XGO_PATH like the HTTP server does
+	xgoPath := os.Getenv("XGO_PATH")
+	if xgoPath == "" {
+		xgoPath = "."
+	}
+	backfillLogger := logging.New(os.Stdout, "[migrate] ", log.LstdFlags|log.Lshortfile, logging.LevelInfo)
+	agentManager, err := twitter.NewAgentManager(xgoPath,
+		twitter.WithUserAgent(config.UserAgent),
+		twitter.WithLogger(backfillLogger),
+	)
+	if err != nil {
+		logger.Fatalf("Failed to create agent manager: %v", err)
+	}
+
+	// Stop between users on Ctrl-C, keeping what was stored so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	languages := tasks.LanguageFilter{Allowlist: config.Language.Allowlist}
+	if err := tasks.Backfill(ctx, database, agentManager, backfillLogger, config.Usernames, languages, fetchLimit); err != nil {
+		logger.Fatalf("Backfill failed: %v", err)
+	}
+	fmt.Println("Backfill completed successfully!")
 }
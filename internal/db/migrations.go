@@ -3,7 +3,6 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"strings"
 
 	_ "github.com/lib/pq"
 )
@@ -73,8 +72,7 @@ const (
 			quoted_status_id TEXT,
 			in_reply_to_status_id TEXT,
 			place TEXT,
-			FOREIGN KEY (user_id) REFERENCES users(id),
-			FOREIGN KEY (username) REFERENCES users(username)
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		);`
 
 	createSmartUsersTable = `
@@ -118,22 +116,178 @@ const (
 			quoted_status_id TEXT,
 			in_reply_to_status_id TEXT,
 			place TEXT,
-			FOREIGN KEY (user_id) REFERENCES smart_users(id),
-			FOREIGN KEY (username) REFERENCES smart_users(username)
+			FOREIGN KEY (user_id) REFERENCES smart_users(id) ON DELETE CASCADE
 		);`
+
+	// One row per successful profile update, to chart growth over time
+	createProfileStatsHistoryTable = `
+		CREATE TABLE IF NOT EXISTS profile_stats_history (
+			id SERIAL PRIMARY KEY,
+			username VARCHAR(50) NOT NULL,
+			captured_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			followers_count INT,
+			following_count INT,
+			tweets_count INT
+		);`
+
+	// A user's follower list at one point in time, stored when the whole list
+	// is fetched with snapshot=true, to tell who followed and unfollowed since.
+	// truncated is set when the list was cut short by the result cap.
+	createFollowerSnapshotsTable = `
+		CREATE TABLE IF NOT EXISTS follower_snapshots (
+			id SERIAL PRIMARY KEY,
+			username VARCHAR(50) NOT NULL,
+			captured_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			truncated BOOLEAN NOT NULL DEFAULT false,
+			follower_ids TEXT[] NOT NULL,
+			follower_usernames TEXT[] NOT NULL
+		);`
+
+	// Overrides the number of tweets fetched for the user in each update cycle
+	addUsersTweetFetchLimitColumn = `
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS tweet_fetch_limit INT CHECK (tweet_fetch_limit > 0);`
+
+	// When the user's profile was last fetched, to refresh stale profiles, and
+	// when fetching it last failed, to back off from profiles that keep failing
+	addUsersProfileUpdatedAtColumn = `
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS profile_updated_at TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS profile_update_failed_at TIMESTAMP;`
+
+	// Tweets missing from several consecutive update cycles are marked deleted
+	// instead of being removed, so earlier results can still be explained
+	addTweetsDeletionColumns = `
+		ALTER TABLE tweets
+			ADD COLUMN IF NOT EXISTS missed_cycles INT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;`
+
+	// Set once an engagement alert has been sent for the tweet
+	addTweetsAlertColumn = `
+		ALTER TABLE tweets
+			ADD COLUMN IF NOT EXISTS alerted_at TIMESTAMP;`
+
+	// When the orphan repair last failed to find the user of an orphaned
+	// tweet, so the tweet isn't retried in every check
+	addTweetsOrphanColumn = `
+		ALTER TABLE tweets
+			ADD COLUMN IF NOT EXISTS orphan_unresolved_at TIMESTAMP;`
+
+	// The language detected in a tweet's text, as an ISO 639-1 code
+	addTweetsLanguageColumns = `
+		ALTER TABLE tweets ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+		ALTER TABLE smart_tweets ADD COLUMN IF NOT EXISTS language VARCHAR(8);`
+
+	// The ID of the tweet starting each tweet's conversation, shared by all
+	// tweets of a thread. Tweets stored before it was kept get it from raw_json.
+	addTweetsConversationColumns = `
+		ALTER TABLE tweets ADD COLUMN IF NOT EXISTS conversation_id TEXT;
+		ALTER TABLE smart_tweets ADD COLUMN IF NOT EXISTS conversation_id TEXT;
+		UPDATE tweets SET conversation_id = raw_json->>'ConversationID'
+			WHERE conversation_id IS NULL AND raw_json->>'ConversationID' <> '';
+		UPDATE smart_tweets SET conversation_id = raw_json->>'ConversationID'
+			WHERE conversation_id IS NULL AND raw_json->>'ConversationID' <> '';
+		CREATE INDEX IF NOT EXISTS idx_tweets_conversation_id ON tweets (conversation_id, time_parsed);
+		CREATE INDEX IF NOT EXISTS idx_smart_tweets_conversation_id ON smart_tweets (conversation_id, time_parsed);`
+
+	// Every stored tweet once: a user can be both tracked and a smart user,
+	// in which case their tweets are in both tables and the tweets row wins
+	createAllTweetsView = `
+		CREATE OR REPLACE VIEW all_tweets AS
+		SELECT DISTINCT ON (id) * FROM (
+			SELECT t.id, t.username, t.text, t.likes, t.replies, t.retweets, t.views,
+				t.timestamp, t.deleted_at IS NOT NULL AS deleted, 'tracked' AS source,
+				u.followers_count AS user_followers_count, u.tweets_count AS user_tweets_count
+			FROM tweets t
+			LEFT JOIN users u ON t.user_id = u.id
+			UNION ALL
+			SELECT t.id, t.username, t.text, t.likes, t.replies, t.retweets, t.views,
+				t.timestamp, false, 'smart',
+				u.followers_count, u.tweets_count
+			FROM smart_tweets t
+			LEFT JOIN smart_users u ON t.user_id = u.id
+		) stored
+		ORDER BY id, source = 'tracked' DESC;`
+
+	// Each smart mentions response fetched from GetMoni, kept as returned
+	// since the feed's shape isn't flattened into columns
+	createSmartMentionsTable = `
+		CREATE TABLE IF NOT EXISTS smart_mentions (
+			id SERIAL PRIMARY KEY,
+			username VARCHAR(50) NOT NULL,
+			fetched_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			from_date TIMESTAMP,
+			to_date TIMESTAMP,
+			response JSONB NOT NULL
+		);`
+
+	// Usernames are stored in the lower case form of twitter.NormalizeUsername.
+	// Rows stored before that are lowercased unless that would clash with
+	// another row of the same user, which is left for manual cleanup.
+	lowercaseUsernames = `
+		UPDATE users u SET username = LOWER(u.username)
+		WHERE u.username <> LOWER(u.username)
+			AND u.id = (SELECT MIN(id) FROM users o WHERE LOWER(o.username) = LOWER(u.username))
+			AND NOT EXISTS (SELECT 1 FROM users o WHERE o.username = LOWER(u.username));
+		UPDATE smart_users u SET username = LOWER(u.username)
+		WHERE u.username <> LOWER(u.username)
+			AND u.id = (SELECT MIN(id) FROM smart_users o WHERE LOWER(o.username) = LOWER(u.username))
+			AND NOT EXISTS (SELECT 1 FROM smart_users o WHERE o.username = LOWER(u.username));`
+
+	// Smart users waiting for their tweets to be fetched, so the queue
+	// survives a restart. Rows are removed once the user has been processed.
+	createPendingSmartUsersTable = `
+		CREATE TABLE IF NOT EXISTS pending_smart_users (
+			username VARCHAR(50) PRIMARY KEY,
+			enqueued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			attempts INT NOT NULL DEFAULT 0
+		);`
+
+	// URLs registered to receive tweet events, signed with their secret
+	createWebhooksTable = `
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id SERIAL PRIMARY KEY,
+			url TEXT NOT NULL,
+			events TEXT[] NOT NULL,
+			secret TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);`
+
+	// The full tweet as returned by the scraper, keeping the fields that
+	// aren't flattened into columns such as media, entities and quoted tweets
+	addTweetsRawJSONColumns = `
+		ALTER TABLE tweets ADD COLUMN IF NOT EXISTS raw_json JSONB;
+		ALTER TABLE smart_tweets ADD COLUMN IF NOT EXISTS raw_json JSONB;`
+
+	// Older databases also have a foreign key on the tweets' username, which
+	// made deleting or renaming a user fail. It is dropped, keeping username as
+	// denormalized data, and the user_id key is recreated with ON DELETE CASCADE
+	// so a user's tweets are removed with the user.
+	fixTweetsForeignKeys = `
+		ALTER TABLE tweets DROP CONSTRAINT IF EXISTS tweets_username_fkey;
+		ALTER TABLE smart_tweets DROP CONSTRAINT IF EXISTS smart_tweets_username_fkey;
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tweets_user_id_fkey' AND confdeltype = 'c') THEN
+				ALTER TABLE tweets DROP CONSTRAINT IF EXISTS tweets_user_id_fkey;
+				ALTER TABLE tweets ADD CONSTRAINT tweets_user_id_fkey
+					FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'smart_tweets_user_id_fkey' AND confdeltype = 'c') THEN
+				ALTER TABLE smart_tweets DROP CONSTRAINT IF EXISTS smart_tweets_user_id_fkey;
+				ALTER TABLE smart_tweets ADD CONSTRAINT smart_tweets_user_id_fkey
+					FOREIGN KEY (user_id) REFERENCES smart_users(id) ON DELETE CASCADE;
+			END IF;
+		END $$;`
 )
 
-// InitDB initializes the database connection and creates tables
-func InitDB(postgresURL string, usernames []string) (*sql.DB, error) {
-	// Add sslmode=disable to the connection string if not present
-	if postgresURL[len(postgresURL)-1] != '?' {
-		postgresURL += "?"
-	}
-	if !strings.Contains(postgresURL, "sslmode=") {
-		if postgresURL[len(postgresURL)-1] != '?' {
-			postgresURL += "&"
-		}
-		postgresURL += "sslmode=disable"
+// InitDB initializes the database connection and creates tables. The tweet
+// text indexes use the PostgreSQL text search configuration textSearchConfig,
+// DefaultTextSearchConfig when it is empty.
+func InitDB(postgresURL string, usernames []string, textSearchConfig string) (*sql.DB, error) {
+	postgresURL, err := NormalizePostgresURL(postgresURL)
+	if err != nil {
+		return nil, err
 	}
 
 	db, err := sql.Open("postgres", postgresURL)
@@ -146,8 +300,12 @@ func InitDB(postgresURL string, usernames []string) (*sql.DB, error) {
 		return nil, fmt.Errorf("error connecting to the database: %v", err)
 	}
 
+	if textSearchConfig == "" {
+		textSearchConfig = DefaultTextSearchConfig
+	}
+
 	// Create tables
-	if err := createTables(db); err != nil {
+	if err := createTables(db, textSearchConfig); err != nil {
 		return nil, fmt.Errorf("error creating tables: %v", err)
 	}
 
@@ -159,17 +317,60 @@ func InitDB(postgresURL string, usernames []string) (*sql.DB, error) {
 	return db, nil
 }
 
-func createTables(db *sql.DB) error {
+func createTables(db *sql.DB, textSearchConfig string) error {
 	// Create users table
 	if _, err := db.Exec(createUsersTable); err != nil {
 		return fmt.Errorf("error creating users table: %v", err)
 	}
 
+	// Add tweet_fetch_limit column to users table
+	if _, err := db.Exec(addUsersTweetFetchLimitColumn); err != nil {
+		return fmt.Errorf("error adding tweet_fetch_limit column to users table: %v", err)
+	}
+
+	// Add profile update columns to users table
+	if _, err := db.Exec(addUsersProfileUpdatedAtColumn); err != nil {
+		return fmt.Errorf("error adding profile update columns to users table: %v", err)
+	}
+
 	// Create tweets table
 	if _, err := db.Exec(createTweetsTable); err != nil {
 		return fmt.Errorf("error creating tweets table: %v", err)
 	}
 
+	// Add deletion tracking columns to tweets table
+	if _, err := db.Exec(addTweetsDeletionColumns); err != nil {
+		return fmt.Errorf("error adding deletion columns to tweets table: %v", err)
+	}
+
+	// Add engagement alert column to tweets table
+	if _, err := db.Exec(addTweetsAlertColumn); err != nil {
+		return fmt.Errorf("error adding alert column to tweets table: %v", err)
+	}
+
+	// Add orphan repair column to tweets table
+	if _, err := db.Exec(addTweetsOrphanColumn); err != nil {
+		return fmt.Errorf("error adding orphan column to tweets table: %v", err)
+	}
+
+	// Create profile_stats_history table
+	if _, err := db.Exec(createProfileStatsHistoryTable); err != nil {
+		return fmt.Errorf("error creating profile_stats_history table: %v", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_profile_stats_history_username ON profile_stats_history (username, captured_at)"); err != nil {
+		return fmt.Errorf("error creating index for profile_stats_history table: %v", err)
+	}
+
+	// Create follower_snapshots table
+	if _, err := db.Exec(createFollowerSnapshotsTable); err != nil {
+		return fmt.Errorf("error creating follower_snapshots table: %v", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_follower_snapshots_username ON follower_snapshots (username, captured_at)"); err != nil {
+		return fmt.Errorf("error creating index for follower_snapshots table: %v", err)
+	}
+
 	// Create smart_users table
 	if _, err := db.Exec(createSmartUsersTable); err != nil {
 		return fmt.Errorf("error creating smart_users table: %v", err)
@@ -180,14 +381,57 @@ func createTables(db *sql.DB) error {
 		return fmt.Errorf("error creating smart_tweets table: %v", err)
 	}
 
-	// Create text indexes for tweets table
-	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tweets_text ON tweets USING gin(to_tsvector('english', text))"); err != nil {
-		return fmt.Errorf("error creating text index for tweets table: %v", err)
+	// Add raw tweet columns to tweets and smart_tweets tables
+	if _, err := db.Exec(addTweetsRawJSONColumns); err != nil {
+		return fmt.Errorf("error adding raw_json columns to tweet tables: %v", err)
+	}
+
+	if _, err := db.Exec(fixTweetsForeignKeys); err != nil {
+		return fmt.Errorf("error fixing foreign keys of tweet tables: %v", err)
+	}
+
+	// Add language columns to tweets and smart_tweets tables
+	if _, err := db.Exec(addTweetsLanguageColumns); err != nil {
+		return fmt.Errorf("error adding language columns to tweet tables: %v", err)
+	}
+
+	// Add and index conversation columns of tweets and smart_tweets tables
+	if _, err := db.Exec(addTweetsConversationColumns); err != nil {
+		return fmt.Errorf("error adding conversation_id columns to tweet tables: %v", err)
+	}
+
+	// Lowercase usernames stored before they were normalized
+	if _, err := db.Exec(lowercaseUsernames); err != nil {
+		return fmt.Errorf("error lowercasing usernames: %v", err)
+	}
+
+	// Create all_tweets view over tweets and smart_tweets
+	if _, err := db.Exec(createAllTweetsView); err != nil {
+		return fmt.Errorf("error creating all_tweets view: %v", err)
+	}
+
+	// Create smart_mentions table
+	if _, err := db.Exec(createSmartMentionsTable); err != nil {
+		return fmt.Errorf("error creating smart_mentions table: %v", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_smart_mentions_username ON smart_mentions (username, fetched_at)"); err != nil {
+		return fmt.Errorf("error creating index for smart_mentions table: %v", err)
+	}
+
+	// Create pending_smart_users table
+	if _, err := db.Exec(createPendingSmartUsersTable); err != nil {
+		return fmt.Errorf("error creating pending_smart_users table: %v", err)
+	}
+
+	// Create webhooks table
+	if _, err := db.Exec(createWebhooksTable); err != nil {
+		return fmt.Errorf("error creating webhooks table: %v", err)
 	}
 
-	// Create text indexes for smart_tweets table
-	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_smart_tweets_text ON smart_tweets USING gin(to_tsvector('english', text))"); err != nil {
-		return fmt.Errorf("error creating text index for smart_tweets table: %v", err)
+	// Create text indexes for tweets and smart_tweets tables
+	if err := createTextSearchIndexes(db, textSearchConfig); err != nil {
+		return err
 	}
 
 	return nil
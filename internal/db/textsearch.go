@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultTextSearchConfig is the PostgreSQL text search configuration used
+// for the tweet text indexes when none is configured
+const DefaultTextSearchConfig = "english"
+
+// textSearchConfigPattern matches names of text search configurations. The
+// name is written into the index definitions, so nothing else is accepted.
+var textSearchConfigPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// textSearchIndexes are the full text indexes on the text column of the tweet tables
+var textSearchIndexes = []struct {
+	name  string
+	table string
+}{
+	{"idx_tweets_text", "tweets"},
+	{"idx_smart_tweets_text", "smart_tweets"},
+}
+
+// textSearchIndexSQL returns the statement creating the full text index
+// name on the text column of table with the text search configuration config
+func textSearchIndexSQL(name, table, config string) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING gin(to_tsvector('%s', text))", name, table, config)
+}
+
+// createTextSearchIndexes creates the full text indexes of the tweet tables
+// with the text search configuration config. Indexes built with another
+// configuration are dropped and rebuilt, which can take a while on large tables.
+func createTextSearchIndexes(db *sql.DB, config string) error {
+	if !textSearchConfigPattern.MatchString(config) {
+		return fmt.Errorf("invalid text search configuration %q", config)
+	}
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pg_ts_config WHERE cfgname = $1)", config).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking text search configuration: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("unknown text search configuration %q", config)
+	}
+
+	for _, index := range textSearchIndexes {
+		var definition string
+		err := db.QueryRow("SELECT indexdef FROM pg_indexes WHERE indexname = $1", index.name).Scan(&definition)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return fmt.Errorf("error reading text index of %s table: %v", index.table, err)
+		case !strings.Contains(definition, "'"+config+"'::regconfig"):
+			if _, err := db.Exec("DROP INDEX " + index.name); err != nil {
+				return fmt.Errorf("error dropping text index of %s table: %v", index.table, err)
+			}
+		}
+
+		if _, err := db.Exec(textSearchIndexSQL(index.name, index.table, config)); err != nil {
+			return fmt.Errorf("error creating text index for %s table: %v", index.table, err)
+		}
+	}
+	return nil
+}
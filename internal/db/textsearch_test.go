@@ -0,0 +1,19 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextSearchIndexSQL(t *testing.T) {
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS idx_tweets_text ON tweets USING gin(to_tsvector('german', text))",
+		textSearchIndexSQL("idx_tweets_text", "tweets", "german"))
+
+	for _, config := range []string{"english", "simple", "pg_catalog_x", "spanish2"} {
+		assert.True(t, textSearchConfigPattern.MatchString(config), config)
+	}
+	for _, config := range []string{"", "English", "english'); DROP TABLE tweets; --", "pg_catalog.english"} {
+		assert.False(t, textSearchConfigPattern.MatchString(config), config)
+	}
+}
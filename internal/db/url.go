@@ -0,0 +1,42 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrEmptyPostgresURL is returned for a blank database URL
+var ErrEmptyPostgresURL = errors.New("postgres URL is empty")
+
+// NormalizePostgresURL validates a postgres:// or postgresql:// connection
+// URL and adds sslmode=disable when no sslmode is set. Other query
+// parameters are kept.
+func NormalizePostgresURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", ErrEmptyPostgresURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// A *url.Error quotes the whole URL, password included, so only the
+		// reason is returned
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", fmt.Errorf("invalid postgres URL: %v", err)
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return "", fmt.Errorf("invalid postgres URL: scheme must be postgres or postgresql, got %q", u.Scheme)
+	}
+
+	query := u.Query()
+	if query.Get("sslmode") == "" {
+		query.Set("sslmode", "disable")
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
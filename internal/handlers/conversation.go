@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// ConversationResponse lists the stored tweets of a conversation, oldest first
+type ConversationResponse struct {
+	ConversationID string        `json:"conversation_id"`
+	Tweets         []TweetDetail `json:"tweets"`
+}
+
+// HandleGetConversation handles listing the stored tweets of a conversation,
+// the thread started by the tweet with the given ID, from the tweets and
+// smart_tweets tables. Only stored tweets are returned, nothing is fetched.
+func HandleGetConversation(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conversationID := mux.Vars(r)["id"]
+
+		// A tweet stored in both tables is listed once, from tweets
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT `+storedTweetColumns+`, deleted_at IS NOT NULL FROM tweets WHERE conversation_id = $1
+			UNION ALL
+			SELECT `+storedTweetColumns+`, false FROM smart_tweets s
+			WHERE conversation_id = $1 AND NOT EXISTS (SELECT 1 FROM tweets t WHERE t.id = s.id)
+			ORDER BY time_parsed, id`, conversationID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error executing query: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		response := ConversationResponse{
+			ConversationID: conversationID,
+			Tweets:         make([]TweetDetail, 0),
+		}
+
+		for rows.Next() {
+			tweet, err := scanStoredTweet(rows.Scan)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error scanning tweet: %v", err), http.StatusInternalServerError)
+				return
+			}
+			tweet.HTML = ""
+			tweet.Raw = nil
+			response.Tweets = append(response.Tweets, *tweet)
+		}
+
+		if err := rows.Err(); err != nil {
+			http.Error(w, fmt.Sprintf("Error reading tweets: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if len(response.Tweets) == 0 {
+			http.Error(w, fmt.Sprintf("No stored tweets in conversation %s", conversationID), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func getConversation(handler http.HandlerFunc) *httptest.ResponseRecorder {
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/conversation/100", nil), map[string]string{"id": "100"})
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestGetConversation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	root := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rows := storedTweetRows()
+	rows.AddRow("100", "42", "alice", "Alice", "root", "<p>root</p>", "", 3, 1, 0, 100, root, false, false, false, "", "", "", "100", `{"ID": "100"}`, false)
+	rows.AddRow("101", "43", "bob", "Bob", "reply", "", "", 0, 0, 0, 10, root.Add(time.Minute), true, false, false, "100", "", "", "100", `{}`, true)
+	rows.AddRow("102", "44", "carol", "Carol", "smart reply", "", "", 0, 0, 0, 5, root.Add(time.Hour), true, false, false, "101", "", "", "100", `{}`, false)
+
+	// Smart tweets also stored in tweets are left out, and both tables are
+	// ordered together by time
+	mock.ExpectQuery(`FROM tweets WHERE conversation_id = \$1 UNION ALL SELECT .*, false FROM smart_tweets s WHERE conversation_id = \$1 AND NOT EXISTS \(SELECT 1 FROM tweets t WHERE t.id = s.id\) ORDER BY time_parsed, id`).
+		WithArgs("100").WillReturnRows(rows)
+
+	w := getConversation(HandleGetConversation(db))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response ConversationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "100", response.ConversationID)
+	if assert.Len(t, response.Tweets, 3) {
+		assert.Equal(t, "100", response.Tweets[0].ID)
+		assert.Equal(t, root, response.Tweets[0].CreatedAt.UTC())
+		// HTML and the raw tweet are never included
+		assert.Empty(t, response.Tweets[0].HTML)
+		assert.Empty(t, response.Tweets[0].Raw)
+		assert.Equal(t, "101", response.Tweets[1].ID)
+		assert.True(t, response.Tweets[1].Deleted)
+		assert.Equal(t, "102", response.Tweets[2].ID)
+		assert.Equal(t, "101", response.Tweets[2].InReplyToStatusID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// A conversation without stored tweets isn't found
+	mock.ExpectQuery(`FROM tweets`).WithArgs("100").WillReturnRows(storedTweetRows())
+	w = getConversation(HandleGetConversation(db))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mock.ExpectQuery(`FROM tweets`).WillReturnError(errors.New("connection lost"))
+	w = getConversation(HandleGetConversation(db))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DeletedTweet is a stored tweet that is no longer returned by Twitter
+type DeletedTweet struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	Likes     int       `json:"likes"`
+	Replies   int       `json:"replies"`
+	Retweets  int       `json:"retweets"`
+	Views     int       `json:"views"`
+	CreatedAt time.Time `json:"created_at"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// DeletedTweetsResponse lists the deleted tweets of a user, most recently deleted first
+type DeletedTweetsResponse struct {
+	Username string         `json:"username"`
+	Tweets   []DeletedTweet `json:"tweets"`
+}
+
+// HandleGetDeletedTweets handles listing the stored tweets of a user that have been marked deleted
+func HandleGetDeletedTweets(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT id, text, likes, replies, retweets, views, time_parsed, deleted_at
+			FROM tweets
+			WHERE LOWER(username) = $1 AND deleted_at IS NOT NULL
+			ORDER BY deleted_at DESC`, username)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error executing query: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		response := DeletedTweetsResponse{
+			Username: username,
+			Tweets:   make([]DeletedTweet, 0),
+		}
+
+		for rows.Next() {
+			var tweet DeletedTweet
+			var createdAt sql.NullTime
+			if err := rows.Scan(
+				&tweet.ID, &tweet.Text, &tweet.Likes, &tweet.Replies, &tweet.Retweets, &tweet.Views,
+				&createdAt, &tweet.DeletedAt,
+			); err != nil {
+				http.Error(w, fmt.Sprintf("Error scanning tweet: %v", err), http.StatusInternalServerError)
+				return
+			}
+			tweet.CreatedAt = createdAt.Time
+			response.Tweets = append(response.Tweets, tweet)
+		}
+
+		if err := rows.Err(); err != nil {
+			http.Error(w, fmt.Sprintf("Error reading tweets: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func getDeletedTweets(handler http.HandlerFunc, username string) *httptest.ResponseRecorder {
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/user/x/deleted-tweets", nil), map[string]string{"username": username})
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestGetDeletedTweets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deleted := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "text", "likes", "replies", "retweets", "views", "time_parsed", "deleted_at"}
+
+	// The username is normalized and the tweets come most recently deleted first
+	mock.ExpectQuery(`FROM tweets WHERE LOWER\(username\) = \$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC`).
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("2", "second", 1, 0, 0, 10, created, deleted).
+			AddRow("1", "first", 3, 1, 2, 100, nil, deleted.Add(-time.Hour)))
+
+	w := getDeletedTweets(HandleGetDeletedTweets(db), "@Alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response DeletedTweetsResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "alice", response.Username)
+	if assert.Len(t, response.Tweets, 2) {
+		assert.Equal(t, "2", response.Tweets[0].ID)
+		assert.Equal(t, created, response.Tweets[0].CreatedAt.UTC())
+		assert.Equal(t, deleted, response.Tweets[0].DeletedAt.UTC())
+		assert.Equal(t, "1", response.Tweets[1].ID)
+		assert.Equal(t, 3, response.Tweets[1].Likes)
+		// A tweet without a stored time has none
+		assert.True(t, response.Tweets[1].CreatedAt.IsZero())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// No deleted tweets is an empty list
+	mock.ExpectQuery(`FROM tweets`).WithArgs("bob").WillReturnRows(sqlmock.NewRows(columns))
+	w = getDeletedTweets(HandleGetDeletedTweets(db), "bob")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"username": "bob", "tweets": []}`, w.Body.String())
+
+	mock.ExpectQuery(`FROM tweets`).WillReturnError(errors.New("connection lost"))
+	w = getDeletedTweets(HandleGetDeletedTweets(db), "bob")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// An invalid username is rejected before the query
+	w = getDeletedTweets(HandleGetDeletedTweets(db), "not a user!")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/asabya/x-go/pkg/twitter"
+)
+
+// rateLimitRetryAfter is the wait suggested in the Retry-After header of
+// rate limited responses. Twitter's rate limit windows are 15 minutes long,
+// but the agent rotation usually frees up an account well before that.
+const rateLimitRetryAfter = 60
+
+// Classifications of AgentManager errors reported in error responses
+const (
+	errorKindInvalidRequest = "invalid_request"
+	errorKindRateLimited    = "rate_limited"
+	errorKindNotLoggedIn    = "not_logged_in"
+	errorKindNotFound       = "not_found"
+	errorKindUpstream       = "upstream"
+	errorKindInternal       = "internal"
+)
+
+// ErrorResponse is the JSON body of a failed AgentManager request
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Kind  string `json:"kind"`
+	// Fields lists every request field at fault when a tweet is invalid
+	Fields []twitter.TweetIssue `json:"fields,omitempty"`
+}
+
+// statusForError maps an AgentManager error to an HTTP status code,
+// treating an unknown agent_username as a client error
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, twitter.ErrInvalidAgentIndex), errors.Is(err, twitter.ErrUnknownSourceAccount),
+		errors.Is(err, twitter.ErrInvalidTweetRequest), errors.Is(err, twitter.ErrInvalidFollowBatch),
+		errors.Is(err, twitter.ErrInvalidSearch), errors.Is(err, twitter.ErrInvalidUsername),
+		errors.Is(err, twitter.ErrInvalidList), errors.Is(err, twitter.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, twitter.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, twitter.ErrNotLoggedIn):
+		return http.StatusUnauthorized
+	case errors.Is(err, twitter.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, twitter.ErrUpstream):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// errorKind returns the classification reported for an error answered with status
+func errorKind(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return errorKindInvalidRequest
+	case http.StatusTooManyRequests:
+		return errorKindRateLimited
+	case http.StatusUnauthorized:
+		return errorKindNotLoggedIn
+	case http.StatusNotFound:
+		return errorKindNotFound
+	case http.StatusBadGateway:
+		return errorKindUpstream
+	}
+	return errorKindInternal
+}
+
+// writeAgentError responds to a failed AgentManager call with the status
+// code for err and its message and classification as JSON. Rate limited
+// responses suggest when to retry in a Retry-After header.
+func writeAgentError(w http.ResponseWriter, err error) {
+	status := statusForError(err)
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", strconv.Itoa(rateLimitRetryAfter))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Kind: errorKind(status)})
+}
+
+// writeInvalidTweet responds to a tweet that can't be posted with 400 and
+// every problem found, the first one being the error message
+func writeInvalidTweet(w http.ResponseWriter, issues []twitter.TweetIssue) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:  fmt.Sprintf("%v: %s", twitter.ErrInvalidTweetRequest, issues[0].Message),
+		Kind:   errorKindInvalidRequest,
+		Fields: issues,
+	})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAgentError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		kind       string
+		retryAfter string
+	}{
+		{"unknown agent", twitter.ErrInvalidAgentIndex, http.StatusBadRequest, "invalid_request", ""},
+		{"invalid tweet", fmt.Errorf("%w: reply_to and quote_of can't both be set", twitter.ErrInvalidTweetRequest), http.StatusBadRequest, "invalid_request", ""},
+		{"rate limited", &twitter.AgentError{Kind: twitter.ErrRateLimited, Message: "rate limit error"}, http.StatusTooManyRequests, "rate_limited", "60"},
+		{"not logged in", &twitter.AgentError{Kind: twitter.ErrNotLoggedIn, Message: "This tool requires login."}, http.StatusUnauthorized, "not_logged_in", ""},
+		{"not found", &twitter.AgentError{Kind: twitter.ErrNotFound, Message: "error getting profile: user not found"}, http.StatusNotFound, "not_found", ""},
+		{"upstream", &twitter.AgentError{Kind: twitter.ErrUpstream, Message: "error getting trends: response status 503"}, http.StatusBadGateway, "upstream", ""},
+		{"invalid argument", &twitter.AgentError{Kind: twitter.ErrInvalidArgument, Message: "username parameter is required"}, http.StatusBadRequest, "invalid_request", ""},
+		{"unclassified", &twitter.AgentError{Message: "error marshaling results: unsupported value"}, http.StatusInternalServerError, "internal", ""},
+		{"other", errors.New("boom"), http.StatusInternalServerError, "internal", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeAgentError(w, tt.err)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.retryAfter, w.Header().Get("Retry-After"))
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var body ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, ErrorResponse{Error: tt.err.Error(), Kind: tt.kind}, body)
+		})
+	}
+}
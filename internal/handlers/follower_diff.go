@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Follower is a user in a follower snapshot
+type Follower struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// FollowerDiffResponse is who followed and unfollowed a user between the two
+// most recent follower snapshots
+type FollowerDiffResponse struct {
+	Username string     `json:"username"`
+	From     time.Time  `json:"from"` // When the older snapshot was captured
+	To       time.Time  `json:"to"`   // When the newer snapshot was captured
+	Gained   []Follower `json:"gained"`
+	Lost     []Follower `json:"lost"`
+	// Truncated is set when either snapshot was cut short by the result cap,
+	// in which case followers past the cap show up as gained or lost
+	Truncated bool `json:"truncated"`
+}
+
+// followerSnapshot is a follower list as stored in follower_snapshots
+type followerSnapshot struct {
+	capturedAt time.Time
+	truncated  bool
+	followers  []Follower
+}
+
+// followersListing is the part of a followers listing that is snapshotted
+type followersListing struct {
+	Followers []struct {
+		UserID   string
+		Username string
+	}
+	NextCursor string `json:"next_cursor"`
+}
+
+// saveFollowerSnapshot stores the followers of a listing fetched with all=true
+// as the current follower snapshot of username
+func saveFollowerSnapshot(ctx context.Context, db *sql.DB, username string, result interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("error marshaling followers: %v", err)
+	}
+	var listing followersListing
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return fmt.Errorf("error reading followers: %v", err)
+	}
+
+	ids := make([]string, 0, len(listing.Followers))
+	usernames := make([]string, 0, len(listing.Followers))
+	for _, follower := range listing.Followers {
+		if follower.UserID == "" {
+			continue
+		}
+		ids = append(ids, follower.UserID)
+		usernames = append(usernames, follower.Username)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO follower_snapshots (username, captured_at, truncated, follower_ids, follower_usernames)
+		VALUES ($1, NOW(), $2, $3, $4)`,
+		username, listing.NextCursor != "", pq.Array(ids), pq.Array(usernames))
+	if err != nil {
+		return fmt.Errorf("error inserting follower snapshot: %v", err)
+	}
+	return nil
+}
+
+// diffFollowers returns the followers in current but not in previous, and
+// those in previous but not in current, matched by user ID so renamed
+// followers aren't reported
+func diffFollowers(previous, current []Follower) (gained, lost []Follower) {
+	gained = make([]Follower, 0)
+	lost = make([]Follower, 0)
+
+	before := make(map[string]bool, len(previous))
+	for _, follower := range previous {
+		before[follower.UserID] = true
+	}
+	after := make(map[string]bool, len(current))
+	for _, follower := range current {
+		after[follower.UserID] = true
+		if !before[follower.UserID] {
+			gained = append(gained, follower)
+		}
+	}
+	for _, follower := range previous {
+		if !after[follower.UserID] {
+			lost = append(lost, follower)
+		}
+	}
+	return gained, lost
+}
+
+// HandleGetFollowerDiff handles returning who followed and unfollowed a user
+// between the two most recent follower snapshots, which are stored by
+// fetching the followers with all=true&snapshot=true
+func HandleGetFollowerDiff(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT captured_at, truncated, follower_ids, follower_usernames
+			FROM follower_snapshots
+			WHERE LOWER(username) = $1
+			ORDER BY captured_at DESC
+			LIMIT 2`, username)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error executing query: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		// Newest first
+		var snapshots []followerSnapshot
+		for rows.Next() {
+			var snapshot followerSnapshot
+			var ids, usernames []string
+			if err := rows.Scan(&snapshot.capturedAt, &snapshot.truncated, pq.Array(&ids), pq.Array(&usernames)); err != nil {
+				http.Error(w, fmt.Sprintf("Error scanning follower snapshot: %v", err), http.StatusInternalServerError)
+				return
+			}
+			for i, id := range ids {
+				follower := Follower{UserID: id}
+				if i < len(usernames) {
+					follower.Username = usernames[i]
+				}
+				snapshot.followers = append(snapshot.followers, follower)
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+
+		if err := rows.Err(); err != nil {
+			http.Error(w, fmt.Sprintf("Error reading follower snapshots: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if len(snapshots) < 2 {
+			http.Error(w, fmt.Sprintf("Not enough follower snapshots of %s, found %d of the 2 needed. Store one by fetching its followers with all=true&snapshot=true", username, len(snapshots)), http.StatusNotFound)
+			return
+		}
+
+		current, previous := snapshots[0], snapshots[1]
+		response := FollowerDiffResponse{
+			Username:  username,
+			From:      previous.capturedAt,
+			To:        current.capturedAt,
+			Truncated: previous.truncated || current.truncated,
+		}
+		response.Gained, response.Lost = diffFollowers(previous.followers, current.followers)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
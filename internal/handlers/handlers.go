@@ -1,23 +1,122 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/asabya/x-go/internal/tasks"
 	"github.com/asabya/x-go/pkg/getmoni"
+	"github.com/asabya/x-go/pkg/logging"
 	"github.com/asabya/x-go/pkg/twitter"
 	"github.com/gorilla/mux"
 )
 
-func LoggingMiddleware(logger *log.Logger) mux.MiddlewareFunc {
+// logger is used by handlers for messages that aren't tied to a response
+var logger logging.Logger = logging.Default()
+
+// SetLogger sets the logger used by the handlers
+func SetLogger(l logging.Logger) {
+	logger = l
+}
+
+// requestIDPattern matches client supplied request IDs that are safe to reuse
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// maxResults caps the limit of tweet searches and timelines
+var maxResults = twitter.DefaultMaxResults
+
+// SetMaxResults sets the largest limit accepted by the search and timeline
+// handlers; larger limits are capped with a warning. 0 disables the cap.
+func SetMaxResults(n int) {
+	maxResults = n
+}
+
+// DefaultMaxBodyBytes is the default size limit of JSON request bodies
+const DefaultMaxBodyBytes = 1 << 20
+
+// maxBodyBytes caps the size of the JSON bodies of POST requests
+var maxBodyBytes int64 = DefaultMaxBodyBytes
+
+// SetMaxBodyBytes sets the largest JSON request body the handlers read;
+// larger bodies are answered with 413 Request Entity Too Large
+func SetMaxBodyBytes(n int64) {
+	maxBodyBytes = n
+}
+
+// decodeJSONBody decodes the JSON body of r into v, reading at most
+// maxBodyBytes of it. On failure it writes the error response, 413 for an
+// oversized body and 400 otherwise, and returns false.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	return decodeBody(w, r, v, false)
+}
+
+// decodeStrictJSONBody is decodeJSONBody also rejecting fields v doesn't
+// have, so a misspelled field is reported instead of silently ignored
+func decodeStrictJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	return decodeBody(w, r, v, true)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}, strict bool) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	decoder := json.NewDecoder(r.Body)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Request body too large. Must be at most %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+	return false
+}
+
+// clampLimit caps limit at maxResults, reporting a cap in the
+// X-Limit-Warning header of responses that are plain JSON arrays
+func clampLimit(w http.ResponseWriter, limit int) int {
+	limit, warning := twitter.ClampLimit(limit, maxResults)
+	if warning != "" {
+		w.Header().Set("X-Limit-Warning", warning)
+	}
+	return limit
+}
+
+// setPartialResults sets the X-Partial-Results header to the reasons partial
+// gives for results being incomplete, when there are any
+func setPartialResults(w http.ResponseWriter, partial []string) {
+	if len(partial) > 0 {
+		w.Header().Set("X-Partial-Results", strings.Join(partial, "; "))
+	}
+}
+
+// LoggingMiddleware logs the method, path and status of every request. Each
+// request gets an ID, taken from a well-formed X-Request-ID header or newly
+// generated, which is stored in the request context for the agent manager's
+// log lines and echoed back in the X-Request-ID response header.
+func LoggingMiddleware(logger logging.Logger) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get("X-Request-ID")
+			if !requestIDPattern.MatchString(requestID) {
+				requestID = twitter.NewRequestID()
+			}
+			w.Header().Set("X-Request-ID", requestID)
+			r = r.WithContext(twitter.ContextWithRequestID(r.Context(), requestID))
+
 			// Create a response wrapper to capture the status code and headers
 			rw := &responseWriter{
 				ResponseWriter: w,
@@ -25,99 +124,867 @@ func LoggingMiddleware(logger *log.Logger) mux.MiddlewareFunc {
 				headers:        make(http.Header),
 			}
 
-			// Call the next handler
-			next.ServeHTTP(rw, r)
-			logger.Printf("%s %s status: %d", r.Method, r.URL.Path, rw.status)
-		})
+			// Call the next handler
+			next.ServeHTTP(rw, r)
+			logger.Info("[request_id=%s] %s %s status: %d", requestID, r.Method, r.URL.Path, rw.status)
+		})
+	}
+}
+
+// DryRunMiddleware marks the request context as a dry run when the client sends
+// an "X-Dry-Run: true" header, so write operations are logged but not executed
+func DryRunMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if dryRun, _ := strconv.ParseBool(r.Header.Get("X-Dry-Run")); dryRun {
+				w.Header().Set("X-Dry-Run", "true")
+				r = r.WithContext(twitter.ContextWithDryRun(r.Context(), true))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TimeoutMiddleware bounds each request with a context deadline, so scraper
+// calls are cancelled and the handler can still write an error response
+// before the server's write timeout closes the connection. A zero timeout
+// disables the deadline.
+func TimeoutMiddleware(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AgentSelectionMiddleware reports how the agent manager chose the agents
+// serving a request in X-Agent-Selection response headers, one per selection,
+// e.g. "strategy=round_robin; agent=alice; index=2/3". Requests that don't use
+// an agent get no header.
+func AgentSelectionMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &twitter.SelectionRecorder{}
+			r = r.WithContext(twitter.ContextWithSelectionRecorder(r.Context(), recorder))
+			next.ServeHTTP(&selectionWriter{ResponseWriter: w, recorder: recorder}, r)
+		})
+	}
+}
+
+// selectionWriter adds the recorded agent selections to the response headers
+// just before they are written
+type selectionWriter struct {
+	http.ResponseWriter
+	recorder    *twitter.SelectionRecorder
+	wroteHeader bool
+}
+
+func (sw *selectionWriter) addSelectionHeaders() {
+	if sw.wroteHeader {
+		return
+	}
+	sw.wroteHeader = true
+	for _, selection := range sw.recorder.Selections() {
+		sw.Header().Add("X-Agent-Selection", selection.String())
+	}
+}
+
+func (sw *selectionWriter) WriteHeader(code int) {
+	sw.addSelectionHeaders()
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *selectionWriter) Write(b []byte) (int, error) {
+	sw.addSelectionHeaders()
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client, so streamed responses such as
+// NDJSON exports aren't held back by the wrapper
+func (sw *selectionWriter) Flush() {
+	sw.addSelectionHeaders()
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (sw *selectionWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// responseWriter is a wrapper for http.ResponseWriter that captures the status code and headers
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	headers http.Header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.ResponseWriter.Header()
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func HandleGetUserTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+		limit := 50
+
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+
+		limit = clampLimit(w, limit)
+
+		sortByOldest := false
+		if sortStr := r.URL.Query().Get("sort_by_oldest"); sortStr == "true" {
+			sortByOldest = true
+		}
+
+		var result interface{}
+		var partial []string
+		var agentUsername string
+		var err error
+		if sinceID := r.URL.Query().Get("since_id"); sinceID != "" {
+			if r.URL.Query().Get("since") != "" {
+				http.Error(w, "The since and since_id parameters can't be combined", http.StatusBadRequest)
+				return
+			}
+			if _, parseErr := strconv.ParseUint(sinceID, 10, 64); parseErr != nil {
+				http.Error(w, "Invalid since_id parameter. Must be a tweet ID", http.StatusBadRequest)
+				return
+			}
+			var newestID string
+			result, newestID, partial, agentUsername, err = manager.GetNewUserTweets(r.Context(), username, sinceID)
+			if err == nil {
+				w.Header().Set("X-Newest-Tweet-ID", newestID)
+			}
+		} else if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+			since, parseErr := parseSince(sinceStr)
+			if parseErr != nil {
+				http.Error(w, "Invalid since parameter. Must be a date (YYYY-MM-DD) or an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			result, partial, agentUsername, err = manager.GetUserTweetsSince(r.Context(), username, since)
+		} else {
+			result, partial, agentUsername, err = manager.GetUserTweets(r.Context(), username, limit, sortByOldest)
+		}
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+		setPartialResults(w, partial)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// usernameParam returns the username path variable in its normalized form.
+// On an invalid username it writes a 400 response and returns false.
+func usernameParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	username, err := twitter.NormalizeUsername(mux.Vars(r)["username"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return username, true
+}
+
+// parseSince parses a since parameter given as a date, meaning midnight UTC,
+// or as an RFC 3339 time
+func parseSince(value string) (time.Time, error) {
+	if since, err := time.Parse("2006-01-02", value); err == nil {
+		return since, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// HandleGetMediaTweetsWithManager handles getting the tweets of a user that
+// have photos, videos or GIFs, with their media URLs
+func HandleGetMediaTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+		limit := 50
+
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+
+		limit = clampLimit(w, limit)
+
+		result, agentUsername, err := manager.GetMediaTweets(r.Context(), username, limit)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func HandleGetProfileWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+
+		includePinned := false
+		if includeStr := r.URL.Query().Get("include_pinned"); includeStr != "" {
+			var err error
+			includePinned, err = strconv.ParseBool(includeStr)
+			if err != nil {
+				http.Error(w, "Invalid include_pinned parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, agentUsername, err := manager.GetProfile(r.Context(), username, includePinned)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func HandleGetTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		tweetID := vars["id"]
+
+		result, agentUsername, err := manager.GetTweet(r.Context(), tweetID)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleTweetExistsWithManager answers HEAD requests for a tweet with 200
+// when it exists and is public, 403 when its author is protected and 404
+// when it's gone, without fetching the tweet itself
+func HandleTweetExistsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exists, protected, err := manager.TweetExists(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		switch {
+		case !exists:
+			w.WriteHeader(http.StatusNotFound)
+		case protected:
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+type GetTweetsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// HandleGetTweetsWithManager handles getting several tweets by ID. With
+// use_cache=true, tweets already stored in the database aren't fetched.
+func HandleGetTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req GetTweetsRequest
+		if !decodeJSONBody(w, r, &req) {
+			return
+		}
+
+		useCache := false
+		if useCacheStr := r.URL.Query().Get("use_cache"); useCacheStr != "" {
+			var err error
+			useCache, err = strconv.ParseBool(useCacheStr)
+			if err != nil {
+				http.Error(w, "Invalid use_cache parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Only invalid IDs fail the whole request; per-tweet errors are in the result
+		result, agentUsername, err := manager.GetTweets(r.Context(), req.IDs, useCache)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// RequireLoginMiddleware answers 503 with a JSON error when no agent is
+// currently logged in, instead of calling endpoints that need an account
+func RequireLoginMiddleware(manager *twitter.AgentManager) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !manager.HasLoggedInAgent() {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": "no authenticated Twitter account is available",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAPIKeyMiddleware only lets through requests carrying apiKey in the
+// X-API-Key header, answering 401 otherwise. With no apiKey configured every
+// request is refused with 403, so admin routes are disabled by default.
+func RequireAPIKeyMiddleware(apiKey string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status, message := 0, ""
+			switch {
+			case apiKey == "":
+				status, message = http.StatusForbidden, "admin API is disabled, set admin_api_key to enable it"
+			case subtle.ConstantTimeCompare([]byte(r.Header.Get("X-API-Key")), []byte(apiKey)) != 1:
+				status, message = http.StatusUnauthorized, "missing or invalid X-API-Key header"
+			}
+			if status != 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(map[string]string{"error": message})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HandleReloginWithManager logs an account in again with its stored
+// credentials and responds with its new login status
+func HandleReloginWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+
+		status := twitter.AgentStartupStatus{Username: username, Status: twitter.AgentStatusActive}
+		code := http.StatusOK
+		if err := manager.Relogin(username); err != nil {
+			var loginErr *twitter.LoginError
+			if !errors.As(err, &loginErr) {
+				writeAgentError(w, err)
+				return
+			}
+			status = twitter.AgentStartupStatus{Username: loginErr.Username, Status: loginErr.Status, Error: loginErr.Err.Error()}
+			code = http.StatusBadGateway
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+// HandleReloadAgentsWithManager reloads the configured accounts, logging in
+// new ones and dropping removed ones, and responds with the outcome and the
+// login status of every account
+func HandleReloadAgentsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := manager.ReloadAccounts(r.Context())
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func HandleWhoamiWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := manager.AccountStatuses(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+	}
+}
+
+func HandleGetAgentsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := manager.StartupStatuses()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+	}
+}
+
+// HandleGetEndpointRateLimitsWithManager handles returning every agent's call
+// window for one rate limited endpoint, along with its global call spacing
+func HandleGetEndpointRateLimitsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limits, err := manager.EndpointRateLimits(mux.Vars(r)["endpoint"])
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(limits)
+	}
+}
+
+func HandleGetTrendsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, agentUsername, err := manager.GetTrends(r.Context())
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func HandleSearchTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		limit := 50
+
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+
+		limit = clampLimit(w, limit)
+
+		opts := twitter.SearchOptions{
+			Since: r.URL.Query().Get("since"),
+			Until: r.URL.Query().Get("until"),
+		}
+		if err := opts.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if includeStr := r.URL.Query().Get("include_html"); includeStr != "" {
+			var err error
+			opts.IncludeHTML, err = strconv.ParseBool(includeStr)
+			if err != nil {
+				http.Error(w, "Invalid include_html parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, partial, agentUsername, err := manager.SearchTweets(r.Context(), query, limit, opts)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+		setPartialResults(w, partial)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleSearchTweetsAdvancedWithManager handles searching tweets by fields,
+// which are turned into a Twitter search query. mentions and hashtags are
+// comma-separated.
+func HandleSearchTweetsAdvancedWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		search := twitter.AdvancedSearch{
+			Keywords: params.Get("keywords"),
+			From:     params.Get("from"),
+			To:       params.Get("to"),
+			Lang:     params.Get("lang"),
+			Since:    params.Get("since"),
+			Until:    params.Get("until"),
+		}
+		for _, list := range []struct {
+			name   string
+			values *[]string
+		}{{"mentions", &search.Mentions}, {"hashtags", &search.Hashtags}} {
+			for _, value := range strings.Split(params.Get(list.name), ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					*list.values = append(*list.values, value)
+				}
+			}
+		}
+		for _, count := range []struct {
+			name  string
+			value *int
+		}{{"min_likes", &search.MinLikes}, {"min_retweets", &search.MinRetweets}} {
+			if countStr := params.Get(count.name); countStr != "" {
+				n, err := strconv.Atoi(countStr)
+				if err != nil || n < 0 {
+					http.Error(w, fmt.Sprintf("Invalid %s parameter. Must be a non-negative integer", count.name), http.StatusBadRequest)
+					return
+				}
+				*count.value = n
+			}
+		}
+
+		limit := 50
+		if limitStr := params.Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+		limit = clampLimit(w, limit)
+
+		includeHTML := false
+		if includeStr := params.Get("include_html"); includeStr != "" {
+			var err error
+			includeHTML, err = strconv.ParseBool(includeStr)
+			if err != nil {
+				http.Error(w, "Invalid include_html parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, partial, agentUsername, err := manager.SearchTweetsAdvanced(r.Context(), search, limit, includeHTML)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+		setPartialResults(w, partial)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+type CreateTweetRequest struct {
+	Text          string                    `json:"text"`
+	ReplyTo       string                    `json:"reply_to,omitempty"`
+	QuoteOf       string                    `json:"quote_of,omitempty"`
+	Media         []twitter.MediaAttachment `json:"media,omitempty"`
+	ScheduleTime  string                    `json:"schedule_time,omitempty"`
+	AutoThread    bool                      `json:"auto_thread,omitempty"`
+	Poll          *twitter.Poll             `json:"poll,omitempty"`
+	AgentUsername string                    `json:"agent_username,omitempty"`
+}
+
+// postTweetRequest converts req to the request PostTweet takes
+func (req CreateTweetRequest) postTweetRequest() twitter.PostTweetRequest {
+	return twitter.PostTweetRequest{
+		Text:    req.Text,
+		ReplyTo: req.ReplyTo,
+		QuoteOf: req.QuoteOf,
+		Media:   req.Media,
+		CreateTweetOptions: twitter.CreateTweetOptions{
+			ScheduleTime: req.ScheduleTime,
+			AutoThread:   req.AutoThread,
+			Poll:         req.Poll,
+		},
+		AgentUsername: req.AgentUsername,
+	}
+}
+
+// HandleValidateTweet checks a tweet as POST /api/tweet would before posting
+// it and reports every problem found, without posting anything. An invalid
+// tweet is still a 200 OK; the report says whether it's valid.
+func HandleValidateTweet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateTweetRequest
+		if !decodeStrictJSONBody(w, r, &req) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(twitter.ValidateTweet(req.postTweetRequest()))
+	}
+}
+
+// HandleCreateTweetWithManager handles posting a tweet. A request carrying
+// an Idempotency-Key header already answered by idempotency is answered with
+// the original response instead of posting again; a nil idempotency cache
+// ignores the header.
+func HandleCreateTweetWithManager(manager *twitter.AgentManager, idempotency *IdempotencyCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateTweetRequest
+		if !decodeStrictJSONBody(w, r, &req) {
+			return
+		}
+		// Checked here too, before a key is claimed, to report every field at fault
+		if validation := twitter.ValidateTweet(req.postTweetRequest()); !validation.Valid {
+			writeInvalidTweet(w, validation.Errors)
+			return
+		}
+
+		key := r.Header.Get(idempotencyKeyHeader)
+		if idempotency == nil {
+			key = ""
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, fmt.Sprintf("Invalid Idempotency-Key header. Must be at most %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+			return
+		}
+		if key != "" {
+			cached, ok := idempotency.begin(w, key, requestFingerprint(req))
+			if !ok {
+				return
+			}
+			if cached != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Agent-Username", cached.agentUsername)
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.Write(cached.body)
+				return
+			}
+		}
+
+		result, agentUsername, err := manager.PostTweet(r.Context(), req.postTweetRequest())
+		if err != nil {
+			if key != "" {
+				idempotency.release(key)
+			}
+			writeAgentError(w, err)
+			return
+		}
+
+		body, err := json.Marshal(result)
+		if err != nil {
+			// The tweet was posted, so the key is completed rather than
+			// released to prevent a repost, and retries get the error
+			message := fmt.Sprintf("Tweet posted, but its response could not be encoded: %v", err)
+			if key != "" {
+				errorBody, _ := json.Marshal(map[string]string{"error": message})
+				idempotency.complete(key, append(errorBody, '\n'), agentUsername)
+			}
+			http.Error(w, message, http.StatusInternalServerError)
+			return
+		}
+		body = append(body, '\n')
+		if key != "" {
+			idempotency.complete(key, body, agentUsername)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		w.Write(body)
+	}
+}
+
+type ReplyTweetRequest struct {
+	Text          string `json:"text"`
+	AgentUsername string `json:"agent_username,omitempty"`
+}
+
+func HandleReplyToTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		tweetID := vars["id"]
+
+		var req ReplyTweetRequest
+		if !decodeJSONBody(w, r, &req) {
+			return
+		}
+		if strings.TrimSpace(req.Text) == "" {
+			http.Error(w, "text is required", http.StatusBadRequest)
+			return
+		}
+
+		result, agentUsername, err := manager.Reply(r.Context(), tweetID, req.Text, req.AgentUsername)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleFollowUserWithManager handles following a user given by user ID or username
+func HandleFollowUserWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := manager.ResolveUserID(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		agentUsername, err := manager.Follow(r.Context(), userID, r.URL.Query().Get("agent_username"))
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "user_id": userID})
 	}
 }
 
-// responseWriter is a wrapper for http.ResponseWriter that captures the status code and headers
-type responseWriter struct {
-	http.ResponseWriter
-	status  int
-	headers http.Header
+// HandleUnfollowUserWithManager handles unfollowing a user given by user ID or username
+func HandleUnfollowUserWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := manager.ResolveUserID(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		agentUsername, err := manager.Unfollow(r.Context(), userID, r.URL.Query().Get("agent_username"))
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "user_id": userID})
+	}
 }
 
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.status = code
-	rw.ResponseWriter.WriteHeader(code)
+// FollowBatchRequest is the body of a batch follow or unfollow
+type FollowBatchRequest struct {
+	UserIDs []string `json:"user_ids"`
 }
 
-func (rw *responseWriter) Header() http.Header {
-	return rw.ResponseWriter.Header()
+// HandleBatchFollowWithManager handles following several users. The response
+// reports the outcome for each user.
+func HandleBatchFollowWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return handleFollowBatch(manager.BatchFollow)
 }
 
-func (rw *responseWriter) Write(b []byte) (int, error) {
-	return rw.ResponseWriter.Write(b)
+// HandleBatchUnfollowWithManager handles unfollowing several users
+func HandleBatchUnfollowWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return handleFollowBatch(manager.BatchUnfollow)
 }
 
-func HandleGetUserTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+func handleFollowBatch(batch func(ctx context.Context, userIDs []string) (*twitter.FollowBatchResult, error)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		username := vars["username"]
-		limit := 50
-
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			if l, err := strconv.Atoi(limitStr); err == nil {
-				limit = l
-			}
+		var req FollowBatchRequest
+		if !decodeJSONBody(w, r, &req) {
+			return
 		}
 
-		sortByOldest := false
-		if sortStr := r.URL.Query().Get("sort_by_oldest"); sortStr == "true" {
-			sortByOldest = true
+		result, err := batch(r.Context(), req.UserIDs)
+		if err != nil {
+			writeAgentError(w, err)
+			return
 		}
 
-		result, agentUsername, err := manager.GetUserTweets(r.Context(), username, limit, sortByOldest)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func HandleLikeTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		tweetID := vars["id"]
+
+		agentUsername, err := manager.LikeTweet(r.Context(), tweetID, r.URL.Query().Get("agent_username"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 	}
 }
 
-func HandleGetProfileWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+func HandleUnlikeTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
-		username := vars["username"]
+		tweetID := vars["id"]
 
-		result, agentUsername, err := manager.GetProfile(r.Context(), username)
+		agentUsername, err := manager.UnlikeTweet(r.Context(), tweetID, r.URL.Query().Get("agent_username"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 	}
 }
 
-func HandleGetTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+func HandleRetweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		tweetID := vars["id"]
 
-		result, agentUsername, err := manager.GetTweet(r.Context(), tweetID)
+		agentUsername, err := manager.Retweet(r.Context(), tweetID, r.URL.Query().Get("agent_username"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(result)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 	}
 }
 
-func HandleSearchTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+// HandleGetFollowersWithManager handles listing the followers of a user, a
+// page at a time or all of them with all=true. With snapshot=true the whole
+// list is also stored for HandleGetFollowerDiff.
+func HandleGetFollowersWithManager(manager *twitter.AgentManager, db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query().Get("q")
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
 		limit := 50
 
 		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
@@ -126,34 +993,108 @@ func HandleSearchTweetsWithManager(manager *twitter.AgentManager) http.HandlerFu
 			}
 		}
 
-		result, agentUsername, err := manager.SearchTweets(r.Context(), query, limit)
+		cursor := r.URL.Query().Get("cursor")
+		all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+
+		snapshot := false
+		if snapshotStr := r.URL.Query().Get("snapshot"); snapshotStr != "" {
+			var err error
+			snapshot, err = strconv.ParseBool(snapshotStr)
+			if err != nil {
+				http.Error(w, "Invalid snapshot parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+			// A page of followers can't be told apart from unfollows
+			if snapshot && !all {
+				http.Error(w, "Invalid snapshot parameter. Requires all=true", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var result interface{}
+		var agentUsername string
+		var err error
+		if all {
+			max := maxResults
+			if maxStr := r.URL.Query().Get("max"); maxStr != "" {
+				max, err = strconv.Atoi(maxStr)
+				if err != nil || max <= 0 {
+					http.Error(w, "Invalid max parameter. Must be a positive integer", http.StatusBadRequest)
+					return
+				}
+			}
+			max = clampLimit(w, max)
+			result, agentUsername, err = manager.GetAllFollowers(r.Context(), username, max)
+		} else {
+			result, agentUsername, err = manager.GetFollowers(r.Context(), username, limit, cursor)
+		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
+		if snapshot {
+			if err := saveFollowerSnapshot(r.Context(), db, username, result); err != nil {
+				http.Error(w, fmt.Sprintf("Error saving follower snapshot: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
 		json.NewEncoder(w).Encode(result)
 	}
 }
 
-type CreateTweetRequest struct {
-	Text         string `json:"text"`
-	ScheduleTime string `json:"schedule_time,omitempty"`
+// HandleGetMutualFollowsWithManager handles listing the users followed by
+// both a user and one of the logged-in accounts
+func HandleGetMutualFollowsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+
+		result, agentUsername, err := manager.MutualFollows(r.Context(), username)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
 }
 
-func HandleCreateTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+func HandleGetTweetRepliesWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req CreateTweetRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		vars := mux.Vars(r)
+		tweetID := vars["id"]
+		cursor := r.URL.Query().Get("cursor")
+
+		result, agentUsername, err := manager.GetTweetReplies(r.Context(), tweetID, cursor)
+		if err != nil {
+			writeAgentError(w, err)
 			return
 		}
 
-		result, agentUsername, err := manager.CreateTweet(r.Context(), req.Text, req.ScheduleTime)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleGetQuoteTweetsWithManager handles getting a page of the tweets
+// quoting a tweet
+func HandleGetQuoteTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tweetID := mux.Vars(r)["id"]
+		cursor := r.URL.Query().Get("cursor")
+
+		result, agentUsername, err := manager.GetQuoteTweets(r.Context(), tweetID, cursor)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
@@ -163,108 +1104,155 @@ func HandleCreateTweetWithManager(manager *twitter.AgentManager) http.HandlerFun
 	}
 }
 
-func HandleFollowUserWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+// HandleGetListTweetsWithManager handles getting the latest tweets of a list
+func HandleGetListTweetsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		userID := vars["id"]
+		listID := mux.Vars(r)["id"]
+		limit := 20
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+		limit = clampLimit(w, limit)
 
-		agentUsername, err := manager.Follow(r.Context(), userID)
+		result, agentUsername, err := manager.GetListTweets(r.Context(), listID, limit)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		json.NewEncoder(w).Encode(result)
 	}
 }
 
-func HandleUnfollowUserWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+// HandleGetListMembersWithManager handles getting a page of the members of a
+// list, continuing from the cursor query parameter
+func HandleGetListMembersWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		userID := vars["id"]
+		listID := mux.Vars(r)["id"]
+		cursor := r.URL.Query().Get("cursor")
 
-		agentUsername, err := manager.Unfollow(r.Context(), userID)
+		result, agentUsername, err := manager.GetListMembers(r.Context(), listID, cursor)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		json.NewEncoder(w).Encode(result)
 	}
 }
 
-func HandleLikeTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+// HandleGetUserRepliesWithManager handles getting a page of the replies a
+// user posted, continuing from the cursor query parameter
+func HandleGetUserRepliesWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		tweetID := vars["id"]
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+		cursor := r.URL.Query().Get("cursor")
 
-		agentUsername, err := manager.LikeTweet(r.Context(), tweetID)
+		result, agentUsername, err := manager.GetUserReplies(r.Context(), username, cursor)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		json.NewEncoder(w).Encode(result)
 	}
 }
 
-func HandleUnlikeTweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+func HandleGetTweetThreadWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		tweetID := vars["id"]
+		maxDepth := 0
+
+		if depthStr := r.URL.Query().Get("max_depth"); depthStr != "" {
+			var err error
+			maxDepth, err = strconv.Atoi(depthStr)
+			if err != nil || maxDepth <= 0 {
+				http.Error(w, "Invalid max_depth parameter. Must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
 
-		agentUsername, err := manager.UnlikeTweet(r.Context(), tweetID)
+		result, agentUsername, err := manager.GetTweetThread(r.Context(), tweetID, maxDepth)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		json.NewEncoder(w).Encode(result)
 	}
 }
 
-func HandleRetweetWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+// HandleGetConversationContextWithManager handles getting the chain of
+// parent tweets of a tweet, ordered from the root of the conversation
+func HandleGetConversationContextWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		tweetID := vars["id"]
 
-		agentUsername, err := manager.Retweet(r.Context(), tweetID)
+		result, agentUsername, err := manager.GetConversationContext(r.Context(), tweetID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Agent-Username", agentUsername)
-		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		json.NewEncoder(w).Encode(result)
 	}
 }
 
-func HandleGetFollowersWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+// HandleGetTweetStatsWithManager handles getting only the current engagement
+// counts of a tweet, for polling many tweets without their full payload
+func HandleGetTweetStatsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		username := vars["username"]
-		limit := 50
+		tweetID := mux.Vars(r)["id"]
 
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			if l, err := strconv.Atoi(limitStr); err == nil {
-				limit = l
-			}
+		likes, retweets, replies, views, err := manager.GetTweetStats(r.Context(), tweetID)
+		if err != nil {
+			writeAgentError(w, err)
+			return
 		}
 
-		cursor := r.URL.Query().Get("cursor")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(twitter.TweetStats{
+			TweetID:  tweetID,
+			Likes:    likes,
+			Retweets: retweets,
+			Replies:  replies,
+			Views:    views,
+		})
+	}
+}
 
-		result, agentUsername, err := manager.GetFollowers(r.Context(), username, limit, cursor)
+// HandleGetRelationshipWithManager handles getting whether a source account
+// and a target user follow each other. The source defaults to the account of
+// the next available agent.
+func HandleGetRelationshipWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		source := r.URL.Query().Get("source")
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		result, agentUsername, err := manager.GetRelationship(r.Context(), source, target)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
@@ -274,15 +1262,20 @@ func HandleGetFollowersWithManager(manager *twitter.AgentManager) http.HandlerFu
 	}
 }
 
-func HandleGetTweetRepliesWithManager(manager *twitter.AgentManager) http.HandlerFunc {
+// HandleGetNotificationsWithManager handles getting a page of the
+// notifications of the account named by the agent parameter, which is
+// required since notifications belong to one account
+func HandleGetNotificationsWithManager(manager *twitter.AgentManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		tweetID := vars["id"]
-		cursor := r.URL.Query().Get("cursor")
+		agent := r.URL.Query().Get("agent")
+		if agent == "" {
+			http.Error(w, "agent parameter is required", http.StatusBadRequest)
+			return
+		}
 
-		result, agentUsername, err := manager.GetTweetReplies(r.Context(), tweetID, cursor)
+		result, agentUsername, err := manager.GetNotifications(r.Context(), agent, r.URL.Query().Get("cursor"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeAgentError(w, err)
 			return
 		}
 
@@ -295,8 +1288,7 @@ func HandleGetTweetRepliesWithManager(manager *twitter.AgentManager) http.Handle
 func HandleAddUser(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req tasks.Profile
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		if !decodeJSONBody(w, r, &req) {
 			return
 		}
 
@@ -304,9 +1296,15 @@ func HandleAddUser(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Username is required", http.StatusBadRequest)
 			return
 		}
+		username, err := twitter.NormalizeUsername(req.Username)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Username = username
 
 		// Insert the user into the database with all fields
-		_, err := db.Exec(`
+		_, err = db.ExecContext(r.Context(), `
 			INSERT INTO users (
 				user_id, username, name, biography, avatar, banner,
 				birthday, location, url, website, joined,
@@ -347,25 +1345,215 @@ func HandleAddUser(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// HandleSaveSmartFollowers handles the request to get and save smart followers
-func HandleSaveSmartFollowers(getmoni *getmoni.GetMoni, db *sql.DB, newUsers chan string) http.HandlerFunc {
+// normalizeSmartFollowers normalizes the usernames of items as returned by
+// GetMoni, dropping invalid usernames and duplicates that only differ in case
+// so the bulk insert doesn't update the same row twice
+func normalizeSmartFollowers(items []getmoni.SmartFollowerItem) []getmoni.SmartFollowerItem {
+	normalized := make([]getmoni.SmartFollowerItem, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		username, err := twitter.NormalizeUsername(item.Meta.Username)
+		if err != nil {
+			logger.Warning("Skipping smart follower: %v", err)
+			continue
+		}
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		item.Meta.Username = username
+		normalized = append(normalized, item)
+	}
+	return normalized
+}
+
+// insertSmartFollowers runs the bulk insert query of smart followers,
+// returning the usernames of the inserted rows as opposed to updated ones
+func insertSmartFollowers(ctx context.Context, db *sql.DB, query string, args []interface{}) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	added := make(map[string]bool)
+	for rows.Next() {
+		var username string
+		var inserted bool
+		if err := rows.Scan(&username, &inserted); err != nil {
+			return nil, err
+		}
+		if inserted {
+			added[username] = true
+		}
+	}
+	return added, rows.Err()
+}
+
+// smartFollowersParams selects which smart followers are fetched from GetMoni
+type smartFollowersParams struct {
+	limit            int
+	offset           int
+	orderBy          string
+	orderByDirection string
+}
+
+// parseSmartFollowersParams reads the limit, offset, order_by and
+// order_by_direction query parameters, falling back to GetMoni's defaults
+func parseSmartFollowersParams(r *http.Request) (smartFollowersParams, error) {
+	params := smartFollowersParams{
+		limit:            getmoni.DefaultSmartFollowersLimit,
+		orderBy:          getmoni.DefaultSmartFollowersOrderBy,
+		orderByDirection: getmoni.DefaultSmartFollowersOrderDirection,
+	}
+	query := r.URL.Query()
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > getmoni.MaxSmartFollowersLimit {
+			return params, fmt.Errorf("Invalid limit parameter. Must be an integer from 1 to %d", getmoni.MaxSmartFollowersLimit)
+		}
+		params.limit = limit
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return params, fmt.Errorf("Invalid offset parameter. Must be a non-negative integer")
+		}
+		params.offset = offset
+	}
+
+	if orderBy := query.Get("order_by"); orderBy != "" {
+		params.orderBy = strings.ToUpper(orderBy)
+	}
+	if direction := query.Get("order_by_direction"); direction != "" {
+		params.orderByDirection = strings.ToUpper(direction)
+	}
+	if !getmoni.ValidSmartFollowersOrder(params.orderBy, params.orderByDirection) {
+		return params, fmt.Errorf("Invalid order_by or order_by_direction parameter. order_by must be one of: %s; order_by_direction must be ASC or DESC",
+			strings.Join(getmoni.SmartFollowersOrderFields, ", "))
+	}
+
+	return params, nil
+}
+
+// maxSmartFollowerPatternLength caps the name and bio patterns of
+// smartFollowerFilter
+const maxSmartFollowerPatternLength = 200
+
+// smartFollowerFilter screens the smart followers fetched from GetMoni
+// before they are saved. Zero values don't filter.
+type smartFollowerFilter struct {
+	minFollowers int
+	minTweets    int
+	name         *regexp.Regexp
+	bio          *regexp.Regexp
+}
+
+// parseSmartFollowerFilter reads the min_followers, min_tweets, name and bio
+// query parameters. name and bio are case-insensitive regular expressions,
+// so a plain word matches as a substring.
+func parseSmartFollowerFilter(r *http.Request) (smartFollowerFilter, error) {
+	var filter smartFollowerFilter
+	query := r.URL.Query()
+
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"min_followers", &filter.minFollowers},
+		{"min_tweets", &filter.minTweets},
+	} {
+		if str := query.Get(param.name); str != "" {
+			n, err := strconv.Atoi(str)
+			if err != nil || n < 0 {
+				return filter, fmt.Errorf("Invalid %s parameter. Must be a non-negative integer", param.name)
+			}
+			*param.value = n
+		}
+	}
+
+	for _, param := range []struct {
+		name  string
+		value **regexp.Regexp
+	}{
+		{"name", &filter.name},
+		{"bio", &filter.bio},
+	} {
+		if pattern := query.Get(param.name); pattern != "" {
+			if len(pattern) > maxSmartFollowerPatternLength {
+				return filter, fmt.Errorf("Invalid %s parameter. Must be at most %d characters", param.name, maxSmartFollowerPatternLength)
+			}
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid %s parameter. Must be a regular expression: %v", param.name, err)
+			}
+			*param.value = re
+		}
+	}
+
+	return filter, nil
+}
+
+// matches reports whether meta passes every criterion of f
+func (f smartFollowerFilter) matches(meta getmoni.UserMeta) bool {
+	return meta.FollowersCount >= f.minFollowers &&
+		meta.TweetCount >= f.minTweets &&
+		(f.name == nil || f.name.MatchString(meta.Name)) &&
+		(f.bio == nil || f.bio.MatchString(meta.Description))
+}
+
+// apply returns the items matching f
+func (f smartFollowerFilter) apply(items []getmoni.SmartFollowerItem) []getmoni.SmartFollowerItem {
+	kept := make([]getmoni.SmartFollowerItem, 0, len(items))
+	for _, item := range items {
+		if f.matches(item.Meta) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// HandleSaveSmartFollowers handles the request to get and save smart followers.
+// Only followers matching the optional filter are saved and queued for tweet
+// ingestion. Followers saved for the first time are published to webhooks.
+func HandleSaveSmartFollowers(getmoni *getmoni.GetMoni, db *sql.DB, newUsers *tasks.UserQueue, webhooks *tasks.Webhooks) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		username := vars["username"]
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+
+		params, err := parseSmartFollowersParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter, err := parseSmartFollowerFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		// Get smart followers from GetMoni with default parameters
-		result, err := getmoni.GetSmartFollowers(username, 100, 0, "FOLLOWERS_COUNT", "DESC")
+		result, err := getmoni.GetSmartFollowers(username, params.limit, params.offset, params.orderBy, params.orderByDirection)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		result.Items = normalizeSmartFollowers(result.Items)
+		fetched := len(result.Items)
+		result.Items = filter.apply(result.Items)
+		filtered := fetched - len(result.Items)
 
 		if len(result.Items) == 0 {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status":  "success",
-				"message": "No followers to save",
-				"data":    result,
+				"status":   "success",
+				"message":  "No followers to save",
+				"saved":    0,
+				"filtered": filtered,
+				"data":     result,
 			})
 			return
 		}
@@ -413,32 +1601,45 @@ func HandleSaveSmartFollowers(getmoni *getmoni.GetMoni, db *sql.DB, newUsers cha
 				joined = EXCLUDED.joined,
 				tweets_count = EXCLUDED.tweets_count,
 				followers_count = EXCLUDED.followers_count
+			RETURNING username, xmax = 0
 		`
 
-		// Execute the bulk insert
-		_, err = db.Exec(query, args...)
+		// Execute the bulk insert, noting the followers that weren't saved before
+		added, err := insertSmartFollowers(r.Context(), db, query, args)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error inserting followers: %v", err), http.StatusInternalServerError)
 			return
 		}
+		for _, item := range result.Items {
+			if added[item.Meta.Username] {
+				webhooks.Publish(tasks.EventSmartUserAdded, tasks.SmartUserAddedEvent{
+					Username:       item.Meta.Username,
+					UserID:         strconv.FormatInt(item.Meta.TwitterUserID, 10),
+					Name:           item.Meta.Name,
+					FollowersCount: item.Meta.FollowersCount,
+					FollowerOf:     username,
+				})
+			}
+		}
 
 		// Send each new user to the channel for immediate tweet processing
 		for _, item := range result.Items {
-			log.Printf("Attempting to send user %s to processing channel", item.Meta.Username)
-			select {
-			case newUsers <- item.Meta.Username:
-				log.Printf("Successfully sent user %s to processing channel", item.Meta.Username)
-			default:
-				// Channel is full or closed, log error but continue
-				log.Printf("Warning: Could not send user %s to processing channel", item.Meta.Username)
+			logger.Debug("Attempting to send user %s to processing channel", item.Meta.Username)
+			if newUsers.Enqueue(item.Meta.Username) {
+				logger.Debug("Successfully sent user %s to processing channel", item.Meta.Username)
+			} else {
+				// Queue is full or closed for shutdown, log error but continue
+				logger.Warning("Could not send user %s to processing channel", item.Meta.Username)
 			}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "success",
-			"message": fmt.Sprintf("Successfully saved %d smart followers", len(result.Items)),
-			"data":    result,
+			"status":   "success",
+			"message":  fmt.Sprintf("Successfully saved %d smart followers", len(result.Items)),
+			"saved":    len(result.Items),
+			"filtered": filtered,
+			"data":     result,
 		})
 	}
 }
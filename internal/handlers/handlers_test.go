@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/asabya/x-go/pkg/getmoni"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAPIKeyMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		apiKey string
+		header string
+		status int
+	}{
+		{"disabled", "", "", http.StatusForbidden},
+		{"disabled ignores header", "", "anything", http.StatusForbidden},
+		{"missing key", "secret", "", http.StatusUnauthorized},
+		{"wrong key", "secret", "guess", http.StatusUnauthorized},
+		{"valid key", "secret", "secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/agents/alice/relogin", nil)
+			if tt.header != "" {
+				r.Header.Set("X-API-Key", tt.header)
+			}
+			w := httptest.NewRecorder()
+			RequireAPIKeyMiddleware(tt.apiKey)(ok).ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestDecodeJSONBodyLimit(t *testing.T) {
+	defer SetMaxBodyBytes(DefaultMaxBodyBytes)
+	SetMaxBodyBytes(64)
+
+	// The body is rejected before the database is used
+	handler := HandleAddUser(nil)
+
+	body := `{"username": "` + strings.Repeat("a", 100) + `"}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "at most 64 bytes")
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"username":`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Username is required\n", w.Body.String())
+}
+
+// Invalid tweets are rejected before the manager is used
+func TestCreateTweetRequestValidation(t *testing.T) {
+	handler := HandleCreateTweetWithManager(nil, nil)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tweet", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"text": "hello", "scheduled_time": "2030-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown field "scheduled_time"`)
+
+	w = post(`{"text": " ", "schedule_time": "tomorrow"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "invalid tweet request: text is required unless media is attached", response.Error)
+	assert.Equal(t, "invalid_request", response.Kind)
+	if assert.Len(t, response.Fields, 2) {
+		assert.Equal(t, "text", response.Fields[0].Field)
+		assert.Equal(t, "schedule_time", response.Fields[1].Field)
+	}
+
+	w = post(`{"quote_of": "1", "media": ["2", {"id": "3", "alt_text": "` + strings.Repeat("a", 1001) + `"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "alt text of media 2 exceeds 1000 characters (counted 1001)")
+}
+
+// An invalid max_depth is rejected before the manager is used
+func TestTweetThreadMaxDepth(t *testing.T) {
+	handler := HandleGetTweetThreadWithManager(nil)
+	for _, depth := range []string{"abc", "-1", "0"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tweet/1/thread?max_depth="+depth, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, depth)
+		assert.Equal(t, "Invalid max_depth parameter. Must be a positive integer\n", w.Body.String())
+	}
+}
+
+func TestUsernameParam(t *testing.T) {
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"username": "@Alice"})
+	username, ok := usernameParam(httptest.NewRecorder(), r)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", username)
+
+	// Invalid usernames are rejected before the database is queried
+	w := httptest.NewRecorder()
+	r = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"username": "not a user"})
+	HandleGetStatsHistory(nil)(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"Username": "way_too_long_username"}`))
+	HandleAddUser(nil)(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestNormalizeSmartFollowers(t *testing.T) {
+	item := func(username string) getmoni.SmartFollowerItem {
+		return getmoni.SmartFollowerItem{Meta: getmoni.UserMeta{Username: username}}
+	}
+	items := normalizeSmartFollowers([]getmoni.SmartFollowerItem{item("@Bob"), item("carol"), item("bob"), item("bad name")})
+
+	var usernames []string
+	for _, item := range items {
+		usernames = append(usernames, item.Meta.Username)
+	}
+	assert.Equal(t, []string{"bob", "carol"}, usernames)
+}
+
+func TestSmartFollowerFilter(t *testing.T) {
+	item := func(username, bio string, followers, tweets int) getmoni.SmartFollowerItem {
+		return getmoni.SmartFollowerItem{Meta: getmoni.UserMeta{
+			Username: username, Name: strings.ToUpper(username), Description: bio, FollowersCount: followers, TweetCount: tweets,
+		}}
+	}
+	items := []getmoni.SmartFollowerItem{
+		item("alice", "Founder of Acme", 50000, 900),
+		item("bob", "co-founder, investor", 8000, 20000),
+		item("carol", "engineer", 120000, 5000),
+	}
+
+	for _, tt := range []struct {
+		query    string
+		expected []string
+		wantErr  string
+	}{
+		{query: "", expected: []string{"alice", "bob", "carol"}},
+		{query: "min_followers=10000", expected: []string{"alice", "carol"}},
+		{query: "min_tweets=1000", expected: []string{"bob", "carol"}},
+		{query: "bio=founder", expected: []string{"alice", "bob"}},
+		{query: "bio=^founder&min_followers=10000", expected: []string{"alice"}},
+		{query: "name=^car", expected: []string{"carol"}},
+		{query: "min_followers=-1", wantErr: "Invalid min_followers parameter. Must be a non-negative integer"},
+		{query: "min_tweets=many", wantErr: "Invalid min_tweets parameter. Must be a non-negative integer"},
+		{query: "bio=(founder", wantErr: "Invalid bio parameter. Must be a regular expression"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			filter, err := parseSmartFollowerFilter(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			var usernames []string
+			for _, item := range filter.apply(items) {
+				usernames = append(usernames, item.Meta.Username)
+			}
+			assert.Equal(t, tt.expected, usernames)
+		})
+	}
+}
+
+func TestDiffFollowers(t *testing.T) {
+	previous := []Follower{
+		{UserID: "1", Username: "alice"},
+		{UserID: "2", Username: "bob"},
+		{UserID: "3", Username: "carol"},
+	}
+	current := []Follower{
+		{UserID: "1", Username: "alice"},
+		{UserID: "3", Username: "carol_renamed"},
+		{UserID: "4", Username: "dave"},
+	}
+
+	gained, lost := diffFollowers(previous, current)
+	assert.Equal(t, []Follower{{UserID: "4", Username: "dave"}}, gained)
+	assert.Equal(t, []Follower{{UserID: "2", Username: "bob"}}, lost)
+
+	gained, lost = diffFollowers(current, current)
+	assert.Empty(t, gained)
+	assert.NotNil(t, gained)
+	assert.Empty(t, lost)
+	assert.NotNil(t, lost)
+}
+
+func TestSaveFollowerSnapshot(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// A listing cut short by max keeps its next_cursor
+	listing := map[string]interface{}{
+		"followers": []map[string]interface{}{
+			{"UserID": "1", "Username": "alice"},
+			{"UserID": "", "Username": "unknown"},
+			{"UserID": "2", "Username": "bob"},
+		},
+		"next_cursor": "page2",
+	}
+	mock.ExpectExec(`INSERT INTO follower_snapshots`).
+		WithArgs("carol", true, pq.Array([]string{"1", "2"}), pq.Array([]string{"alice", "bob"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, saveFollowerSnapshot(context.Background(), db, "carol", listing))
+
+	// A complete listing isn't truncated
+	listing["next_cursor"] = ""
+	mock.ExpectExec(`INSERT INTO follower_snapshots`).
+		WithArgs("carol", false, pq.Array([]string{"1", "2"}), pq.Array([]string{"alice", "bob"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, saveFollowerSnapshot(context.Background(), db, "carol", listing))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Database calls end with the request context, which TimeoutMiddleware bounds
+func TestDBHandlersUseRequestContext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM tweets`).WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	handler := TimeoutMiddleware(10 * time.Millisecond)(HandleGetDeletedTweets(db))
+
+	r := mux.SetURLVars(httptest.NewRequest("GET", "/api/user/alice/deleted-tweets", nil), map[string]string{"username": "alice"})
+	w := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(w, r)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
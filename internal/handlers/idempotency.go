@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIdempotencyTTL is how long a completed request is remembered by default
+	DefaultIdempotencyTTL = time.Hour
+	// DefaultIdempotencyMaxEntries is the default number of remembered requests
+	DefaultIdempotencyMaxEntries = 10000
+
+	// idempotencyKeyHeader names the header clients send their idempotency key in
+	idempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength caps the length of accepted idempotency keys
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyCache remembers the responses of recent requests by their
+// Idempotency-Key header, so a client retrying a request whose response it
+// never received gets the original response instead of a second tweet.
+// Completed entries expire after the TTL and the oldest are evicted once the
+// cache holds maxEntries. Entries in progress are never evicted, so their
+// requests can't be repeated; new keys are refused while they fill the cache.
+type IdempotencyCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // of *idempotencyEntry, oldest first
+	now        func() time.Time
+}
+
+// idempotencyEntry is a request seen with a key. Until it's done, repeats of
+// the key are refused.
+type idempotencyEntry struct {
+	key           string
+	fingerprint   [sha256.Size]byte
+	expiresAt     time.Time
+	done          bool
+	body          []byte
+	agentUsername string
+}
+
+// NewIdempotencyCache returns a cache remembering up to maxEntries requests for ttl
+func NewIdempotencyCache(ttl time.Duration, maxEntries int) *IdempotencyCache {
+	return &IdempotencyCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// begin claims key for a request with the given body fingerprint. It returns
+// the remembered entry if key was already completed with the same body;
+// otherwise, if key can't be used, it writes the error response and returns
+// ok false.
+func (c *IdempotencyCache) begin(w http.ResponseWriter, key string, fingerprint [sha256.Size]byte) (cached *idempotencyEntry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.evictExpired(now)
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*idempotencyEntry)
+		switch {
+		case entry.fingerprint != fingerprint:
+			http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+			return nil, false
+		case !entry.done:
+			http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+			return nil, false
+		}
+		return entry, true
+	}
+
+	for c.order.Len() >= c.maxEntries {
+		oldest := c.oldestDone()
+		if oldest == nil {
+			http.Error(w, "Too many requests with an Idempotency-Key are in progress", http.StatusServiceUnavailable)
+			return nil, false
+		}
+		c.remove(oldest)
+	}
+	c.entries[key] = c.order.PushBack(&idempotencyEntry{
+		key:         key,
+		fingerprint: fingerprint,
+		expiresAt:   now.Add(c.ttl),
+	})
+	return nil, true
+}
+
+// complete remembers the response body of the request that claimed key
+func (c *IdempotencyCache) complete(key string, body []byte, agentUsername string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*idempotencyEntry)
+		entry.done = true
+		entry.body = body
+		entry.agentUsername = agentUsername
+	}
+}
+
+// release forgets key after its request failed, so the client can retry it
+func (c *IdempotencyCache) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, found := c.entries[key]; found {
+		c.remove(element)
+	}
+}
+
+// evictExpired removes the completed entries past their expiry. Entries
+// share one TTL, so they expire in insertion order.
+func (c *IdempotencyCache) evictExpired(now time.Time) {
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*idempotencyEntry)
+		if !now.After(entry.expiresAt) {
+			return
+		}
+		if entry.done {
+			c.remove(element)
+		}
+		element = next
+	}
+}
+
+// oldestDone returns the oldest completed entry, or nil if every entry is in progress
+func (c *IdempotencyCache) oldestDone() *list.Element {
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		if element.Value.(*idempotencyEntry).done {
+			return element
+		}
+	}
+	return nil
+}
+
+func (c *IdempotencyCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*idempotencyEntry).key)
+}
+
+// requestFingerprint identifies the parameters of a request, to tell a retry
+// from a different request reusing its key
+func requestFingerprint(req interface{}) [sha256.Size]byte {
+	data, _ := json.Marshal(req)
+	return sha256.Sum256(data)
+}
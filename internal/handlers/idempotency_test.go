@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Now()
+	cache := NewIdempotencyCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+	first := sha256.Sum256([]byte("first"))
+	second := sha256.Sum256([]byte("second"))
+
+	begin := func(key string, fingerprint [sha256.Size]byte) (*idempotencyEntry, bool, int) {
+		w := httptest.NewRecorder()
+		cached, ok := cache.begin(w, key, fingerprint)
+		return cached, ok, w.Code
+	}
+
+	cached, ok, _ := begin("a", first)
+	assert.True(t, ok)
+	assert.Nil(t, cached)
+
+	_, ok, status := begin("a", first)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusConflict, status)
+
+	cache.complete("a", []byte(`{"id":"1"}`), "alice")
+	cached, ok, _ = begin("a", first)
+	assert.True(t, ok)
+	assert.Equal(t, `{"id":"1"}`, string(cached.body))
+	assert.Equal(t, "alice", cached.agentUsername)
+
+	_, ok, status = begin("a", second)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+	// A failed request can be retried with its key
+	begin("b", first)
+	cache.release("b")
+	cached, ok, _ = begin("b", first)
+	assert.True(t, ok)
+	assert.Nil(t, cached)
+	cache.complete("b", []byte(`{"id":"2"}`), "alice")
+
+	// The oldest key is evicted when the cache is full
+	begin("c", first)
+	assert.NotContains(t, cache.entries, "a")
+	assert.Contains(t, cache.entries, "b")
+
+	// Completed keys expire after the TTL
+	now = now.Add(2 * time.Minute)
+	cached, ok, _ = begin("b", first)
+	assert.True(t, ok)
+	assert.Nil(t, cached)
+	_, ok, status = begin("c", first)
+	assert.False(t, ok, "requests in progress don't expire")
+	assert.Equal(t, http.StatusConflict, status)
+}
+
+func TestIdempotencyCacheKeepsRequestsInProgress(t *testing.T) {
+	cache := NewIdempotencyCache(time.Minute, 2)
+	fingerprint := sha256.Sum256([]byte("tweet"))
+	begin := func(key string) (bool, int) {
+		w := httptest.NewRecorder()
+		_, ok := cache.begin(w, key, fingerprint)
+		return ok, w.Code
+	}
+
+	begin("a")
+	begin("b")
+	cache.complete("b", []byte(`{"id":"2"}`), "alice")
+
+	// The completed key is evicted rather than the older one in progress
+	ok, _ := begin("c")
+	assert.True(t, ok)
+	assert.Contains(t, cache.entries, "a")
+	assert.NotContains(t, cache.entries, "b")
+
+	// Once every key is in progress, new keys are refused
+	ok, status := begin("d")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	ok, status = begin("a")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusConflict, status, "a repeat of a request in progress is still refused")
+
+	cache.release("a")
+	ok, _ = begin("d")
+	assert.True(t, ok)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/asabya/x-go/internal/tasks"
+)
+
+// maxOrphanReportLimit caps the number of orphaned tweets listed per request
+const maxOrphanReportLimit = 1000
+
+// HandleGetOrphans handles reporting the stored tweets whose user_id or
+// username no longer resolves to a user. It never modifies them; repairs
+// are done by the orphan check task when its repair flag is set.
+func HandleGetOrphans(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := tasks.DefaultOrphanReportLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			l, err := strconv.Atoi(limitStr)
+			if err != nil || l <= 0 || l > maxOrphanReportLimit {
+				http.Error(w, fmt.Sprintf("Invalid limit parameter. Must be an integer from 1 to %d", maxOrphanReportLimit), http.StatusBadRequest)
+				return
+			}
+			limit = l
+		}
+
+		report, err := tasks.FindOrphanedTweets(r.Context(), db, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
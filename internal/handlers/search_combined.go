@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/asabya/x-go/pkg/twitter"
+)
+
+const (
+	// SourceDB marks tweets served from the database
+	SourceDB = "db"
+	// SourceLive marks tweets fetched from Twitter
+	SourceLive = "live"
+)
+
+// CombinedTweet is a tweet from either the database or a live search
+type CombinedTweet struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	Likes    int    `json:"likes"`
+	Replies  int    `json:"replies"`
+	Retweets int    `json:"retweets"`
+	Views    int    `json:"views,omitempty"`
+	Deleted  bool   `json:"deleted,omitempty"`
+	Source   string `json:"source"`
+}
+
+// CombinedSearchResponse lists database matches first, followed by any new live matches
+type CombinedSearchResponse struct {
+	Tweets    []CombinedTweet `json:"tweets"`
+	LiveError string          `json:"live_error,omitempty"`
+	Warning   string          `json:"warning,omitempty"`
+}
+
+// liveSearchTweet mirrors the tweet shape returned by AgentManager.SearchTweets
+type liveSearchTweet struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Likes    int    `json:"likes"`
+	Retweets int    `json:"retweets"`
+	Replies  int    `json:"replies"`
+	Author   struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// HandleSearchCombined searches the database and, when live=true, augments
+// the results with a live Twitter search. Tweets are deduplicated by ID and
+// a failed live search is reported in live_error instead of failing the request.
+func HandleSearchCombined(db *sql.DB, manager *twitter.AgentManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, pattern, sortBy, limit, err := parseDBSearchParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, warning := twitter.ClampLimit(limit, maxResults)
+
+		live := false
+		if liveStr := r.URL.Query().Get("live"); liveStr != "" {
+			live, err = strconv.ParseBool(liveStr)
+			if err != nil {
+				http.Error(w, "Invalid live parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		response := CombinedSearchResponse{
+			Tweets:  make([]CombinedTweet, 0),
+			Warning: warning,
+		}
+		seen := make(map[string]bool)
+
+		// Rows are kept in the order of the query, which sorts them by sort_by
+		err = scanTweetsInDB(r.Context(), db, pattern, sortBy, limit, func(userID int64, user User, tweet Tweet) error {
+			if seen[tweet.ID] {
+				return nil
+			}
+			seen[tweet.ID] = true
+			response.Tweets = append(response.Tweets, CombinedTweet{
+				ID:       tweet.ID,
+				Username: user.Username,
+				Text:     tweet.Text,
+				Likes:    tweet.Likes,
+				Replies:  tweet.Replies,
+				Retweets: tweet.Retweets,
+				Views:    tweet.Views,
+				Deleted:  tweet.Deleted,
+				Source:   SourceDB,
+			})
+			return nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if live {
+			result, partial, agentUsername, err := manager.SearchTweets(r.Context(), query, limit, twitter.SearchOptions{})
+			if err != nil {
+				response.LiveError = err.Error()
+			} else {
+				w.Header().Set("X-Agent-Username", agentUsername)
+				setPartialResults(w, partial)
+
+				var liveTweets []liveSearchTweet
+				data, _ := json.Marshal(result)
+				if err := json.Unmarshal(data, &liveTweets); err != nil {
+					response.LiveError = err.Error()
+				}
+
+				for _, tweet := range liveTweets {
+					if seen[tweet.ID] {
+						continue
+					}
+					seen[tweet.ID] = true
+					response.Tweets = append(response.Tweets, CombinedTweet{
+						ID:       tweet.ID,
+						Username: tweet.Author.Username,
+						Text:     tweet.Text,
+						Likes:    tweet.Likes,
+						Replies:  tweet.Replies,
+						Retweets: tweet.Retweets,
+						Source:   SourceLive,
+					})
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
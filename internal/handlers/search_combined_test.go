@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCombinedKeepsOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// The query returns tweets by likes, alternating between two authors
+	rows := sqlmock.NewRows([]string{
+		"user_id", "id", "text", "likes", "replies", "retweets", "views", "deleted",
+		"is_verified", "is_private", "is_blue_verified", "following_count", "followers_count",
+		"likes_count", "tweets_count", "username",
+	})
+	for _, row := range []struct {
+		userID   int64
+		id       string
+		likes    int
+		username string
+	}{
+		{1, "30", 30, "alice"},
+		{2, "20", 20, "bob"},
+		{1, "10", 10, "alice"},
+		{2, "5", 5, "bob"},
+	} {
+		rows.AddRow(row.userID, row.id, "go", row.likes, 0, 0, 0, false, false, false, false, 0, 0, 0, 0, row.username)
+	}
+	mock.ExpectQuery(`ORDER BY t.likes DESC NULLS LAST`).WithArgs("%go%", 50).WillReturnRows(rows)
+
+	w := httptest.NewRecorder()
+	HandleSearchCombined(db, nil)(w, httptest.NewRequest("GET", "/api/search/combined?q=go&sort_by=likes", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response CombinedSearchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	var ids []string
+	for _, tweet := range response.Tweets {
+		ids = append(ids, tweet.ID)
+		assert.Equal(t, SourceDB, tweet.Source)
+	}
+	assert.Equal(t, []string{"30", "20", "10", "5"}, ids)
+	assert.Equal(t, "bob", response.Tweets[1].Username)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+)
+
+// csvHeader lists the columns written by writeTweetsCSV
+var csvHeader = []string{"username", "text", "likes", "replies", "retweets", "views"}
+
+// writeTweetsCSV streams the tweet search results to w as CSV, one row per
+// tweet as it is read from the database. Errors before the first row are
+// reported as a 500; later errors can only end the download early.
+func writeTweetsCSV(w http.ResponseWriter, r *http.Request, db *sql.DB, pattern, sortBy string, limit int) {
+	cw := csv.NewWriter(w)
+	wroteHeader := false
+
+	writeHeader := func() error {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="tweets.csv"`)
+		wroteHeader = true
+		return cw.Write(csvHeader)
+	}
+
+	err := scanTweetsInDB(r.Context(), db, pattern, sortBy, limit, func(userID int64, user User, tweet Tweet) error {
+		if !wroteHeader {
+			if err := writeHeader(); err != nil {
+				return err
+			}
+		}
+
+		return cw.Write([]string{
+			user.Username,
+			tweet.Text,
+			strconv.Itoa(tweet.Likes),
+			strconv.Itoa(tweet.Replies),
+			strconv.Itoa(tweet.Retweets),
+			strconv.Itoa(tweet.Views),
+		})
+	})
+	if err != nil {
+		if !wroteHeader {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logger.Error("Error streaming CSV results: %v", err)
+		cw.Flush()
+		return
+	}
+
+	// No matches still produces a CSV with just the header row
+	if !wroteHeader {
+		if err := writeHeader(); err != nil {
+			logger.Error("Error writing CSV header: %v", err)
+			return
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		logger.Error("Error flushing CSV results: %v", err)
+	}
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/lib/pq"
 )
 
 type SearchResponse struct {
-	Users []User `json:"users"`
+	Users   []User `json:"users"`
+	Warning string `json:"warning,omitempty"`
 }
 
 type User struct {
@@ -28,126 +34,303 @@ type User struct {
 
 // Tweet represents the simplified tweet structure for the API response
 type Tweet struct {
+	ID       string `json:"id"`
 	Text     string `json:"text"`
 	Likes    int    `json:"likes"`
 	Replies  int    `json:"replies"`
 	Retweets int    `json:"retweets"`
 	Views    int    `json:"views"`
+	Deleted  bool   `json:"deleted,omitempty"`
+	// Source is the table a tweet was stored in, "tracked" or "smart", set by the all-tweets search
+	Source string `json:"source,omitempty"`
 }
 
 // HandleSearchTweetsInDB handles searching tweets in the database
 func HandleSearchTweetsInDB(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query().Get("q")
-		if query == "" {
-			http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		_, pattern, sortBy, limit, err := parseDBSearchParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Get sorting parameters
-		sortBy := r.URL.Query().Get("sort_by")
-		if sortBy == "" {
-			sortBy = "timestamp" // default sort by timestamp
-		}
-
-		// Validate sort_by parameter
-		validSortFields := map[string]bool{
-			"timestamp": true,
-			"likes":     true,
-			"views":     true,
-		}
-		if !validSortFields[sortBy] {
-			http.Error(w, "Invalid sort_by parameter. Must be one of: timestamp, likes, views", http.StatusBadRequest)
+		format, err := searchFormat(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Get limit parameter
-		limit := 50 // default limit
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			parsedLimit, err := strconv.Atoi(limitStr)
-			if err != nil || parsedLimit <= 0 {
-				http.Error(w, "Invalid limit parameter. Must be a positive integer", http.StatusBadRequest)
-				return
+		limit, warning := twitter.ClampLimit(limit, maxResults)
+
+		switch format {
+		case "csv":
+			if warning != "" {
+				w.Header().Set("X-Limit-Warning", warning)
 			}
-			limit = parsedLimit
+			writeTweetsCSV(w, r, db, pattern, sortBy, limit)
+			return
+		case "ndjson":
+			if warning != "" {
+				w.Header().Set("X-Limit-Warning", warning)
+			}
+			writeTweetsNDJSON(w, r, db, pattern, sortBy, limit)
+			return
 		}
 
-		// Build the query with user join - only select needed fields
-		sqlQuery := `
-			SELECT 
-				t.user_id,
-				t.text, t.likes, t.replies, t.retweets, t.views,
-				u.is_verified, u.is_private, u.is_blue_verified,
-				u.following_count, u.followers_count,
-				u.likes_count, u.tweets_count, u.username
-			FROM tweets t
-			LEFT JOIN users u ON t.user_id = u.id
-			WHERE t.text ILIKE $1
-			ORDER BY t.` + sortBy + ` DESC
-			LIMIT $2`
-
-		rows, err := db.Query(sqlQuery, "%"+query+"%", limit)
+		users, err := searchTweetsInDB(r.Context(), db, pattern, sortBy, limit)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error executing query: %v", err), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
 
-		// Map to store users and their tweets
-		userMap := make(map[int64]*User)
+		response := SearchResponse{
+			Users:   users,
+			Warning: warning,
+		}
 
-		for rows.Next() {
-			var userID int64
-			var tweet Tweet
-			// Temporary variables for handling NULL values
-			var userIsVerified, userIsPrivate, userIsBlueVerified sql.NullBool
-			var userFollowingCount, userFollowersCount, userLikesCount, userTweetsCount sql.NullInt64
-			var userUsername sql.NullString
-			err := rows.Scan(
-				&userID,
-				&tweet.Text, &tweet.Likes, &tweet.Replies, &tweet.Retweets, &tweet.Views,
-				&userIsVerified, &userIsPrivate, &userIsBlueVerified,
-				&userFollowingCount, &userFollowersCount,
-				&userLikesCount, &userTweetsCount, &userUsername,
-			)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Error scanning tweet: %v", err), http.StatusInternalServerError)
-				return
-			}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
 
-			// Get or create user
-			user, exists := userMap[userID]
-			if !exists {
-				user = &User{
-					UserIsVerified:     userIsVerified.Valid && userIsVerified.Bool,
-					UserIsPrivate:      userIsPrivate.Valid && userIsPrivate.Bool,
-					UserIsBlueVerified: userIsBlueVerified.Valid && userIsBlueVerified.Bool,
-					UserFollowingCount: int(userFollowingCount.Int64),
-					UserFollowersCount: int(userFollowersCount.Int64),
-					UserLikesCount:     int(userLikesCount.Int64),
-					UserTweetsCount:    int(userTweetsCount.Int64),
-					Username:           userUsername.String,
-					Tweets:             make([]Tweet, 0),
-				}
-				userMap[userID] = user
-			}
+// searchFormat returns the output format of a tweet search: the format query
+// parameter, else ndjson when the Accept header asks for it, else json
+func searchFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "json", "csv", "ndjson":
+		return format, nil
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), ndjsonContentType) {
+			return "ndjson", nil
+		}
+		return "json", nil
+	}
+	return "", fmt.Errorf("Invalid format parameter. Must be one of: json, csv, ndjson")
+}
 
-			user.Tweets = append(user.Tweets, tweet)
+// Match modes of the q parameter of database searches
+const (
+	// matchLiteral matches q as typed, so % and _ match themselves
+	matchLiteral = "literal"
+	// matchWildcard passes q to ILIKE as is, so % matches any text and _ any character
+	matchWildcard = "wildcard"
+)
+
+// likeEscaper escapes the ILIKE metacharacters, using the default escape character \
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern returns the ILIKE pattern matching text containing query.
+// In literal mode its metacharacters are escaped; in wildcard mode they
+// keep their meaning.
+func likePattern(query, mode string) string {
+	if mode != matchWildcard {
+		query = likeEscaper.Replace(query)
+	}
+	return "%" + query + "%"
+}
+
+// parseMatchMode reads and validates the mode query parameter
+func parseMatchMode(r *http.Request) (string, error) {
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", matchLiteral:
+		return matchLiteral, nil
+	case matchWildcard:
+		return matchWildcard, nil
+	}
+	return "", fmt.Errorf("Invalid mode parameter. Must be one of: literal, wildcard")
+}
+
+// validSortFields are the columns DB searches can sort by. sort_by is
+// interpolated into the ORDER BY, so only these names may reach the SQL.
+// The counts can be NULL, so searches sort DESC NULLS LAST to keep tweets
+// without them below the rest rather than at the top.
+var validSortFields = map[string]bool{
+	"timestamp": true,
+	"likes":     true,
+	"views":     true,
+	"retweets":  true,
+	"replies":   true,
+}
+
+// invalidSortByMessage is the error for a sort_by not in validSortFields
+const invalidSortByMessage = "Invalid sort_by parameter. Must be one of: timestamp, likes, views, retweets, replies"
+
+// parseDBSearchParams reads and validates the q, mode, sort_by and limit
+// query parameters. pattern is the ILIKE pattern matching q in its mode.
+func parseDBSearchParams(r *http.Request) (query, pattern, sortBy string, limit int, err error) {
+	query = r.URL.Query().Get("q")
+	if query == "" {
+		return "", "", "", 0, fmt.Errorf("Query parameter 'q' is required")
+	}
+	mode, err := parseMatchMode(r)
+	if err != nil {
+		return "", "", "", 0, err
+	}
+	pattern = likePattern(query, mode)
+
+	// Get sorting parameters
+	sortBy = r.URL.Query().Get("sort_by")
+	if sortBy == "" {
+		sortBy = "timestamp" // default sort by timestamp
+	}
+
+	// Validate sort_by parameter
+	if !validSortFields[sortBy] {
+		return "", "", "", 0, errors.New(invalidSortByMessage)
+	}
+
+	// Get limit parameter
+	limit = 50 // default limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 {
+			return "", "", "", 0, fmt.Errorf("Invalid limit parameter. Must be a positive integer")
 		}
+		limit = parsedLimit
+	}
 
-		// Convert map to slice
-		users := make([]User, 0, len(userMap))
-		for _, user := range userMap {
-			users = append(users, *user)
+	return query, pattern, sortBy, limit, nil
+}
+
+// searchTweetsInDB finds stored tweets whose text matches the ILIKE pattern,
+// grouped by user. sortBy must already be validated against the allowed sort fields.
+func searchTweetsInDB(ctx context.Context, db *sql.DB, pattern, sortBy string, limit int) ([]User, error) {
+	// Map to store users and their tweets
+	userMap := make(map[int64]*User)
+
+	err := scanTweetsInDB(ctx, db, pattern, sortBy, limit, func(userID int64, user User, tweet Tweet) error {
+		// Get or create user
+		existing, exists := userMap[userID]
+		if !exists {
+			user.Tweets = make([]Tweet, 0)
+			existing = &user
+			userMap[userID] = existing
 		}
 
-		response := SearchResponse{
-			Users: users,
+		existing.Tweets = append(existing.Tweets, tweet)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert map to slice
+	users := make([]User, 0, len(userMap))
+	for _, user := range userMap {
+		users = append(users, *user)
+	}
+
+	return users, nil
+}
+
+// scanTweetsInDB runs the tweet search for the ILIKE pattern and calls fn for
+// every matching row as it is read, so callers can stream results without
+// buffering them. sortBy must already be validated against the allowed sort fields.
+func scanTweetsInDB(ctx context.Context, db *sql.DB, pattern, sortBy string, limit int, fn func(userID int64, user User, tweet Tweet) error) error {
+	// Build the query with user join - only select needed fields
+	sqlQuery := `
+		SELECT 
+			t.user_id, t.id,
+			t.text, t.likes, t.replies, t.retweets, t.views,
+			t.deleted_at IS NOT NULL,
+			u.is_verified, u.is_private, u.is_blue_verified,
+			u.following_count, u.followers_count,
+			u.likes_count, u.tweets_count, u.username
+		FROM tweets t
+		LEFT JOIN users u ON t.user_id = u.id
+		WHERE t.text ILIKE $1 ESCAPE '\'
+		ORDER BY t.` + sortBy + ` DESC NULLS LAST
+		LIMIT $2`
+
+	rows, err := db.QueryContext(ctx, sqlQuery, pattern, limit)
+	if err != nil {
+		return fmt.Errorf("Error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		var tweet Tweet
+		// Temporary variables for handling NULL values
+		var userIsVerified, userIsPrivate, userIsBlueVerified sql.NullBool
+		var userFollowingCount, userFollowersCount, userLikesCount, userTweetsCount sql.NullInt64
+		var userUsername sql.NullString
+		err := rows.Scan(
+			&userID, &tweet.ID,
+			&tweet.Text, &tweet.Likes, &tweet.Replies, &tweet.Retweets, &tweet.Views,
+			&tweet.Deleted,
+			&userIsVerified, &userIsPrivate, &userIsBlueVerified,
+			&userFollowingCount, &userFollowersCount,
+			&userLikesCount, &userTweetsCount, &userUsername,
+		)
+		if err != nil {
+			return fmt.Errorf("Error scanning tweet: %v", err)
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		user := User{
+			UserIsVerified:     userIsVerified.Valid && userIsVerified.Bool,
+			UserIsPrivate:      userIsPrivate.Valid && userIsPrivate.Bool,
+			UserIsBlueVerified: userIsBlueVerified.Valid && userIsBlueVerified.Bool,
+			UserFollowingCount: int(userFollowingCount.Int64),
+			UserFollowersCount: int(userFollowersCount.Int64),
+			UserLikesCount:     int(userLikesCount.Int64),
+			UserTweetsCount:    int(userTweetsCount.Int64),
+			Username:           userUsername.String,
+		}
+
+		if err := fn(userID, user, tweet); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error reading tweets: %v", err)
+	}
+
+	return nil
+}
+
+// smartTweetsQuery builds the smart tweets search: tweets containing any of
+// queries, matched in mode, by any of usernames (lowercased) and by users with at least
+// minFollowers followers. Empty filters are left out, and the placeholders
+// are numbered in the order their arguments are added.
+func smartTweetsQuery(queries []string, mode string, usernames []string, minFollowers int, sortBy string, limit int) (string, []interface{}) {
+	// Build the query with user join - only select needed fields
+	sqlQuery := `
+		SELECT 
+			t.user_id, t.id,
+			t.text, t.likes, t.replies, t.retweets, t.views,
+			u.followers_count, u.tweets_count, u.username
+		FROM smart_tweets t
+		LEFT JOIN smart_users u ON t.user_id = u.id`
+
+	var args []interface{}
+	placeholder := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var conditions []string
+	if len(queries) > 0 {
+		// Build the text condition with multiple ILIKE alternatives
+		textClauses := make([]string, len(queries))
+		for i, query := range queries {
+			textClauses[i] = "t.text ILIKE " + placeholder(likePattern(query, mode)) + ` ESCAPE '\'`
+		}
+		conditions = append(conditions, "("+strings.Join(textClauses, " OR ")+")")
 	}
+	if len(usernames) > 0 {
+		conditions = append(conditions, "LOWER(u.username) = ANY("+placeholder(pq.Array(usernames))+")")
+	}
+	if minFollowers > 0 {
+		conditions = append(conditions, "u.followers_count >= "+placeholder(minFollowers))
+	}
+	if len(conditions) > 0 {
+		sqlQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	sqlQuery += fmt.Sprintf(" ORDER BY t.%s DESC NULLS LAST LIMIT %s", sortBy, placeholder(limit))
+	return sqlQuery, args
 }
 
 // HandleSearchSmartTweetsInDB handles searching smart tweets in the database
@@ -164,13 +347,8 @@ func HandleSearchSmartTweetsInDB(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Validate sort_by parameter
-		validSortFields := map[string]bool{
-			"timestamp": true,
-			"likes":     true,
-			"views":     true,
-		}
 		if !validSortFields[sortBy] {
-			http.Error(w, "Invalid sort_by parameter. Must be one of: timestamp, likes, views", http.StatusBadRequest)
+			http.Error(w, invalidSortByMessage, http.StatusBadRequest)
 			return
 		}
 
@@ -184,35 +362,41 @@ func HandleSearchSmartTweetsInDB(db *sql.DB) http.HandlerFunc {
 			}
 			limit = parsedLimit
 		}
-
-		// Build the query with user join - only select needed fields
-		sqlQuery := `
-			SELECT 
-				t.user_id,
-				t.text, t.likes, t.replies, t.retweets, t.views,
-				u.followers_count, u.tweets_count, u.username
-			FROM smart_tweets t
-			LEFT JOIN smart_users u ON t.user_id = u.id`
-
-		var args []interface{}
-
-		// Add WHERE clause only if there are query parameters
-		if len(queries) > 0 {
-			sqlQuery += " WHERE "
-			// Build the WHERE clause with multiple ILIKE conditions
-			whereClauses := make([]string, len(queries))
-			args = make([]interface{}, len(queries))
-			for i, query := range queries {
-				whereClauses[i] = fmt.Sprintf("t.text ILIKE $%d", i+1)
-				args[i] = "%" + query + "%"
+		limit, warning := twitter.ClampLimit(limit, maxResults)
+
+		// Restrict to smart users by username and follower count
+		var usernames []string
+		for _, value := range queryParams["username"] {
+			for _, username := range strings.Split(value, ",") {
+				if strings.TrimSpace(username) == "" {
+					continue
+				}
+				username, err := twitter.NormalizeUsername(username)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				usernames = append(usernames, username)
+			}
+		}
+		minFollowers := 0
+		if minFollowersStr := queryParams.Get("min_followers"); minFollowersStr != "" {
+			parsed, err := strconv.Atoi(minFollowersStr)
+			if err != nil || parsed < 0 {
+				http.Error(w, "Invalid min_followers parameter. Must be a non-negative integer", http.StatusBadRequest)
+				return
 			}
-			sqlQuery += strings.Join(whereClauses, " OR ")
+			minFollowers = parsed
 		}
 
-		sqlQuery += fmt.Sprintf(" ORDER BY t.%s DESC LIMIT $%d", sortBy, len(args)+1)
-		args = append(args, limit)
+		mode, err := parseMatchMode(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		rows, err := db.Query(sqlQuery, args...)
+		sqlQuery, args := smartTweetsQuery(queries, mode, usernames, minFollowers, sortBy, limit)
+		rows, err := db.QueryContext(r.Context(), sqlQuery, args...)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error executing query: %v", err), http.StatusInternalServerError)
 			return
@@ -229,7 +413,7 @@ func HandleSearchSmartTweetsInDB(db *sql.DB) http.HandlerFunc {
 			var userFollowersCount, userTweetsCount sql.NullInt64
 			var userUsername sql.NullString
 			err := rows.Scan(
-				&userID,
+				&userID, &tweet.ID,
 				&tweet.Text, &tweet.Likes, &tweet.Replies, &tweet.Retweets, &tweet.Views,
 				&userFollowersCount, &userTweetsCount, &userUsername,
 			)
@@ -260,10 +444,86 @@ func HandleSearchSmartTweetsInDB(db *sql.DB) http.HandlerFunc {
 		}
 
 		response := SearchResponse{
-			Users: users,
+			Users:   users,
+			Warning: warning,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// HandleSearchAllTweetsInDB handles searching the tweets of tracked and smart
+// users at once. Tweets stored in both tables are returned once, so the
+// results don't overlap the way /api/search/tweets and /api/search/smart-tweets do.
+func HandleSearchAllTweetsInDB(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, pattern, sortBy, limit, err := parseDBSearchParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, warning := twitter.ClampLimit(limit, maxResults)
+
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT id, COALESCE(username, ''), text,
+				COALESCE(likes, 0), COALESCE(replies, 0), COALESCE(retweets, 0), COALESCE(views, 0),
+				deleted, source, user_followers_count, user_tweets_count
+			FROM all_tweets
+			WHERE text ILIKE $1 ESCAPE '\'
+			ORDER BY `+sortBy+` DESC NULLS LAST
+			LIMIT $2`, pattern, limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error executing query: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		// User IDs differ between users and smart_users, so group by username
+		userMap := make(map[string]*User)
+		var order []string
+
+		for rows.Next() {
+			var tweet Tweet
+			var username string
+			var userFollowersCount, userTweetsCount sql.NullInt64
+			if err := rows.Scan(
+				&tweet.ID, &username, &tweet.Text,
+				&tweet.Likes, &tweet.Replies, &tweet.Retweets, &tweet.Views,
+				&tweet.Deleted, &tweet.Source, &userFollowersCount, &userTweetsCount,
+			); err != nil {
+				http.Error(w, fmt.Sprintf("Error scanning tweet: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			key := strings.ToLower(username)
+			user, exists := userMap[key]
+			if !exists {
+				user = &User{
+					Username:           username,
+					UserFollowersCount: int(userFollowersCount.Int64),
+					UserTweetsCount:    int(userTweetsCount.Int64),
+					Tweets:             make([]Tweet, 0),
+				}
+				userMap[key] = user
+				order = append(order, key)
+			}
+			user.Tweets = append(user.Tweets, tweet)
+		}
+		if err := rows.Err(); err != nil {
+			http.Error(w, fmt.Sprintf("Error reading tweets: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		users := make([]User, 0, len(order))
+		for _, key := range order {
+			users = append(users, *userMap[key])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SearchResponse{
+			Users:   users,
+			Warning: warning,
+		})
+	}
+}
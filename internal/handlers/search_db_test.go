@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmartTweetsQuery(t *testing.T) {
+	query, args := smartTweetsQuery([]string{"go", "rust"}, matchLiteral, []string{"alice", "bob"}, 1000, "likes", 20)
+	assert.Contains(t, query, ` WHERE (t.text ILIKE $1 ESCAPE '\' OR t.text ILIKE $2 ESCAPE '\') AND LOWER(u.username) = ANY($3) AND u.followers_count >= $4`)
+	assert.Contains(t, query, " ORDER BY t.likes DESC NULLS LAST LIMIT $5")
+	assert.Equal(t, []interface{}{"%go%", "%rust%", pq.Array([]string{"alice", "bob"}), 1000, 20}, args)
+
+	// Placeholders stay consecutive when filters are left out
+	query, args = smartTweetsQuery(nil, matchLiteral, []string{"alice"}, 0, "timestamp", 50)
+	assert.Contains(t, query, " WHERE LOWER(u.username) = ANY($1) ORDER BY t.timestamp DESC NULLS LAST LIMIT $2")
+	assert.Len(t, args, 2)
+
+	query, args = smartTweetsQuery(nil, matchLiteral, nil, 0, "timestamp", 50)
+	assert.NotContains(t, query, "WHERE")
+	assert.Contains(t, query, " LIMIT $1")
+	assert.Equal(t, []interface{}{50}, args)
+}
+
+func TestDBSearchSortBy(t *testing.T) {
+	for _, sortBy := range []string{"retweets", "replies"} {
+		r := httptest.NewRequest("GET", "/api/search/tweets?q=go&sort_by="+sortBy, nil)
+		_, _, parsed, _, err := parseDBSearchParams(r)
+		assert.NoError(t, err)
+		assert.Equal(t, sortBy, parsed)
+
+		query, _ := smartTweetsQuery(nil, matchLiteral, nil, 0, sortBy, 50)
+		assert.Contains(t, query, " ORDER BY t."+sortBy+" DESC NULLS LAST LIMIT $1")
+	}
+
+	r := httptest.NewRequest("GET", "/api/search/tweets?q=go", nil)
+	_, _, sortBy, _, err := parseDBSearchParams(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "timestamp", sortBy)
+
+	// Anything else is rejected before reaching the SQL
+	r = httptest.NewRequest("GET", "/api/search/tweets?q=go&sort_by=likes%3BDROP+TABLE+tweets", nil)
+	_, _, _, _, err = parseDBSearchParams(r)
+	assert.EqualError(t, err, invalidSortByMessage)
+
+	w := httptest.NewRecorder()
+	HandleSearchSmartTweetsInDB(nil)(w, httptest.NewRequest("GET", "/api/search/smart-tweets?sort_by=quotes", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSearchTweetsInDBClampsLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// The query runs with the capped LIMIT, not the one requested
+	mock.ExpectQuery(`FROM tweets t`).WithArgs("%go%", maxResults).WillReturnRows(sqlmock.NewRows([]string{
+		"user_id", "id", "text", "likes", "replies", "retweets", "views", "deleted",
+		"is_verified", "is_private", "is_blue_verified", "following_count", "followers_count",
+		"likes_count", "tweets_count", "username",
+	}).AddRow(1, "10", "go", 0, 0, 0, 0, false, false, false, false, 0, 0, 0, 0, "alice"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/search/tweets?q=go&limit="+strconv.Itoa(maxResults+50), nil)
+	HandleSearchTweetsInDB(db)(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	var response SearchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Users, 1)
+	_, warning := twitter.ClampLimit(maxResults+50, maxResults)
+	assert.Equal(t, warning, response.Warning)
+	assert.Contains(t, response.Warning, "exceeds the maximum of "+strconv.Itoa(maxResults))
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		query   string
+		mode    string
+		pattern string
+	}{
+		{"golang", matchLiteral, "%golang%"},
+		{"100%", matchLiteral, `%100\%%`},
+		{"snake_case", matchLiteral, `%snake\_case%`},
+		{`C:\dir`, matchLiteral, `%C:\\dir%`},
+		{"100%", matchWildcard, "%100%%"},
+		{"snake_case", matchWildcard, "%snake_case%"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.pattern, likePattern(tt.query, tt.mode), tt.query+" "+tt.mode)
+	}
+}
+
+func TestDBSearchMatchMode(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/search/tweets?q=50%25_off", nil)
+	query, pattern, _, _, err := parseDBSearchParams(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "50%_off", query)
+	assert.Equal(t, `%50\%\_off%`, pattern)
+
+	r = httptest.NewRequest("GET", "/api/search/tweets?q=50%25_off&mode=wildcard", nil)
+	_, pattern, _, _, err = parseDBSearchParams(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "%50%_off%", pattern)
+
+	r = httptest.NewRequest("GET", "/api/search/tweets?q=go&mode=regex", nil)
+	_, _, _, _, err = parseDBSearchParams(r)
+	assert.EqualError(t, err, "Invalid mode parameter. Must be one of: literal, wildcard")
+}
+
+func TestSearchFormat(t *testing.T) {
+	tests := []struct {
+		url    string
+		accept string
+		format string
+	}{
+		{"/api/search/tweets?q=go", "", "json"},
+		{"/api/search/tweets?q=go", "application/x-ndjson", "ndjson"},
+		{"/api/search/tweets?q=go&format=csv", "application/x-ndjson", "csv"},
+		{"/api/search/tweets?q=go&format=ndjson", "", "ndjson"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.url, nil)
+		r.Header.Set("Accept", tt.accept)
+		format, err := searchFormat(r)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.format, format, tt.url)
+	}
+
+	_, err := searchFormat(httptest.NewRequest("GET", "/api/search/tweets?q=go&format=xml", nil))
+	assert.EqualError(t, err, "Invalid format parameter. Must be one of: json, csv, ndjson")
+}
+
+func TestSearchNDJSONFlushesThroughMiddleware(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{
+		"user_id", "id", "text", "likes", "replies", "retweets", "views", "deleted",
+		"is_verified", "is_private", "is_blue_verified", "following_count", "followers_count",
+		"likes_count", "tweets_count", "username",
+	})
+	for i := 0; i < ndjsonFlushRows+1; i++ {
+		rows.AddRow(1, strconv.Itoa(i), "go", 0, 0, 0, 0, false, false, false, false, 0, 0, 0, 0, "alice")
+	}
+	mock.ExpectQuery(`FROM tweets t`).WillReturnRows(rows)
+
+	// The same middleware chain as the server wraps the response writer
+	router := mux.NewRouter()
+	router.HandleFunc("/api/search/tweets", HandleSearchTweetsInDB(db))
+	router.Use(LoggingMiddleware(logger))
+	router.Use(DryRunMiddleware())
+	router.Use(AgentSelectionMiddleware())
+	router.Use(TimeoutMiddleware(time.Minute))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/search/tweets?q=go&format=ndjson&limit=200", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ndjsonContentType, w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed, "rows are flushed while streaming")
+	assert.Equal(t, ndjsonFlushRows+1, strings.Count(w.Body.String(), "\n"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+)
+
+// ndjsonFlushRows is how many rows writeTweetsNDJSON writes between flushes
+const ndjsonFlushRows = 100
+
+// ndjsonContentType is the media type of newline delimited JSON
+const ndjsonContentType = "application/x-ndjson"
+
+// TweetRow is one line of an NDJSON search export: a tweet along with its
+// author, flattened so each line stands on its own
+type TweetRow struct {
+	Tweet
+	Username           string `json:"username"`
+	UserIsVerified     bool   `json:"user_is_verified,omitempty"`
+	UserIsPrivate      bool   `json:"user_is_private,omitempty"`
+	UserIsBlueVerified bool   `json:"user_is_blue_verified,omitempty"`
+	UserFollowingCount int    `json:"user_following_count,omitempty"`
+	UserFollowersCount int    `json:"user_followers_count,omitempty"`
+	UserLikesCount     int    `json:"user_likes_count,omitempty"`
+	UserTweetsCount    int    `json:"user_tweets_count,omitempty"`
+}
+
+// writeTweetsNDJSON streams the tweet search results to w as newline
+// delimited JSON, one TweetRow per tweet as it is read from the database,
+// flushing every ndjsonFlushRows rows. Errors before the first row are
+// reported as a 500; later errors can only end the download early.
+func writeTweetsNDJSON(w http.ResponseWriter, r *http.Request, db *sql.DB, pattern, sortBy string, limit int) {
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	rows := 0
+
+	err := scanTweetsInDB(r.Context(), db, pattern, sortBy, limit, func(userID int64, user User, tweet Tweet) error {
+		if rows == 0 {
+			w.Header().Set("Content-Type", ndjsonContentType)
+		}
+		if err := encoder.Encode(TweetRow{
+			Tweet:              tweet,
+			Username:           user.Username,
+			UserIsVerified:     user.UserIsVerified,
+			UserIsPrivate:      user.UserIsPrivate,
+			UserIsBlueVerified: user.UserIsBlueVerified,
+			UserFollowingCount: user.UserFollowingCount,
+			UserFollowersCount: user.UserFollowersCount,
+			UserLikesCount:     user.UserLikesCount,
+			UserTweetsCount:    user.UserTweetsCount,
+		}); err != nil {
+			return err
+		}
+		rows++
+		if flusher != nil && rows%ndjsonFlushRows == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if rows == 0 {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logger.Error("Error streaming NDJSON results: %v", err)
+		return
+	}
+
+	// No matches produces an empty body
+	if rows == 0 {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/asabya/x-go/pkg/getmoni"
+)
+
+// HandleGetSmartMentions handles fetching the smart mentions of a user from
+// GetMoni, optionally limited to a from/to date range. Every response is also
+// saved to the smart_mentions table so mentions can be tracked over time.
+func HandleGetSmartMentions(client *getmoni.GetMoni, db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+
+		from, err := parseTimeParam(r, "from")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := parseTimeParam(r, "to")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit := getmoni.DefaultSmartMentionsLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 || limit > getmoni.MaxSmartMentionsLimit {
+				http.Error(w, fmt.Sprintf("Invalid limit parameter. Must be an integer from 1 to %d", getmoni.MaxSmartMentionsLimit), http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err := client.GetSmartMentions(username, r.URL.Query().Get("from"), r.URL.Query().Get("to"), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// The client reports a missing API key in the result instead of failing
+		if msg, ok := result["error"].(string); ok {
+			http.Error(w, msg, http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := saveSmartMentions(r.Context(), db, username, from, to, result); err != nil {
+			logger.Error("Error saving smart mentions for %s: %v", username, err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// saveSmartMentions records a smart mentions response for username
+func saveSmartMentions(ctx context.Context, db *sql.DB, username string, from, to *time.Time, result map[string]interface{}) error {
+	response, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("error marshaling smart mentions: %v", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO smart_mentions (username, from_date, to_date, response)
+		VALUES ($1, $2, $3, $4::jsonb)`,
+		username, from, to, string(response))
+	if err != nil {
+		return fmt.Errorf("error inserting smart mentions: %v", err)
+	}
+	return nil
+}
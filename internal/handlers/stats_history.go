@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// StatsSnapshot is a user's profile counts at one point in time
+type StatsSnapshot struct {
+	CapturedAt     time.Time `json:"captured_at"`
+	FollowersCount int       `json:"followers_count"`
+	FollowingCount int       `json:"following_count"`
+	TweetsCount    int       `json:"tweets_count"`
+}
+
+// StatsHistoryResponse is the series of snapshots for a user, oldest first
+type StatsHistoryResponse struct {
+	Username string          `json:"username"`
+	History  []StatsSnapshot `json:"history"`
+}
+
+// parseTimeParam parses a date (2006-01-02) or RFC3339 timestamp query parameter
+func parseTimeParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid %s parameter. Must be a date (YYYY-MM-DD) or RFC3339 timestamp", name)
+}
+
+// HandleGetStatsHistory handles returning the follower, following and tweet
+// count history of a user, optionally limited to a from/to date range
+func HandleGetStatsHistory(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := usernameParam(w, r)
+		if !ok {
+			return
+		}
+
+		from, err := parseTimeParam(r, "from")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := parseTimeParam(r, "to")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT captured_at, followers_count, following_count, tweets_count
+			FROM profile_stats_history
+			WHERE LOWER(username) = $1
+				AND ($2::timestamp IS NULL OR captured_at >= $2)
+				AND ($3::timestamp IS NULL OR captured_at <= $3)
+			ORDER BY captured_at ASC`, username, from, to)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error executing query: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		response := StatsHistoryResponse{
+			Username: username,
+			History:  make([]StatsSnapshot, 0),
+		}
+
+		for rows.Next() {
+			var snapshot StatsSnapshot
+			var followers, following, tweets sql.NullInt64
+			if err := rows.Scan(&snapshot.CapturedAt, &followers, &following, &tweets); err != nil {
+				http.Error(w, fmt.Sprintf("Error scanning stats: %v", err), http.StatusInternalServerError)
+				return
+			}
+			snapshot.FollowersCount = int(followers.Int64)
+			snapshot.FollowingCount = int(following.Int64)
+			snapshot.TweetsCount = int(tweets.Int64)
+			response.History = append(response.History, snapshot)
+		}
+
+		if err := rows.Err(); err != nil {
+			http.Error(w, fmt.Sprintf("Error reading stats: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
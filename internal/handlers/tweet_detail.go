@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/asabya/x-go/pkg/langdetect"
+	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/gorilla/mux"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// TweetDetail is a single tweet, served from the database when stored
+type TweetDetail struct {
+	ID                string    `json:"id"`
+	UserID            string    `json:"user_id"`
+	Username          string    `json:"username"`
+	Name              string    `json:"name"`
+	Text              string    `json:"text"`
+	HTML              string    `json:"html,omitempty"`
+	PermanentURL      string    `json:"permanent_url"`
+	Likes             int       `json:"likes"`
+	Replies           int       `json:"replies"`
+	Retweets          int       `json:"retweets"`
+	Views             int       `json:"views"`
+	CreatedAt         time.Time `json:"created_at"`
+	IsReply           bool      `json:"is_reply"`
+	IsQuoted          bool      `json:"is_quoted"`
+	IsRetweet         bool      `json:"is_retweet"`
+	InReplyToStatusID string    `json:"in_reply_to_status_id,omitempty"`
+	QuotedStatusID    string    `json:"quoted_status_id,omitempty"`
+	RetweetedStatusID string    `json:"retweeted_status_id,omitempty"`
+	ConversationID    string    `json:"conversation_id,omitempty"`
+	Deleted           bool      `json:"deleted,omitempty"`
+	Source            string    `json:"source"`
+	// Raw is the full tweet as returned by the scraper, only included with fields=raw
+	Raw json.RawMessage `json:"raw,omitempty"`
+}
+
+// liveTweet mirrors the tweet shape returned by AgentManager.GetTweet
+type liveTweet struct {
+	ID                string
+	UserID            string
+	Username          string
+	Name              string
+	Text              string
+	HTML              string
+	PermanentURL      string
+	Likes             int
+	Replies           int
+	Retweets          int
+	Views             int
+	TimeParsed        time.Time
+	Timestamp         int64
+	IsPin             bool
+	IsReply           bool
+	IsQuoted          bool
+	IsRetweet         bool
+	IsSelfThread      bool
+	SensitiveContent  bool
+	InReplyToStatusID string
+	QuotedStatusID    string
+	RetweetedStatusID string
+	ConversationID    string
+}
+
+// TweetGetter fetches a tweet from Twitter; *twitter.AgentManager implements it
+type TweetGetter interface {
+	GetTweet(ctx context.Context, tweetID string) (interface{}, string, error)
+}
+
+// storedTweetColumns selects a stored tweet in TweetDetail field order
+const storedTweetColumns = `
+	id, COALESCE(tweeter_user_id, ''), COALESCE(username, ''), COALESCE(name, ''),
+	COALESCE(text, ''), COALESCE(html, ''), COALESCE(permanent_url, ''),
+	COALESCE(likes, 0), COALESCE(replies, 0), COALESCE(retweets, 0), COALESCE(views, 0),
+	time_parsed, COALESCE(is_reply, false), COALESCE(is_quoted, false), COALESCE(is_retweet, false),
+	COALESCE(in_reply_to_status_id, ''), COALESCE(quoted_status_id, ''), COALESCE(retweeted_status_id, ''),
+	COALESCE(conversation_id, ''), COALESCE(raw_json::text, '')`
+
+// HandleGetTweetDetail handles getting a tweet by ID as a TweetDetail. Unlike
+// HandleGetTweetWithManager, a tweet stored in the tweets or smart_tweets
+// table is returned without calling Twitter; otherwise, or when refresh=true,
+// it is fetched live and cached if its author is tracked.
+// With fields=raw the full scraper tweet is included as raw; a stored tweet
+// saved before raw tweets were kept is fetched live to get it.
+func HandleGetTweetDetail(db *sql.DB, manager TweetGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		tweetID := vars["id"]
+
+		refresh := false
+		if refreshStr := r.URL.Query().Get("refresh"); refreshStr != "" {
+			var err error
+			refresh, err = strconv.ParseBool(refreshStr)
+			if err != nil {
+				http.Error(w, "Invalid refresh parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		includeHTML := false
+		if includeStr := r.URL.Query().Get("include_html"); includeStr != "" {
+			var err error
+			includeHTML, err = strconv.ParseBool(includeStr)
+			if err != nil {
+				http.Error(w, "Invalid include_html parameter. Must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		includeRaw := false
+		switch r.URL.Query().Get("fields") {
+		case "":
+		case "raw":
+			includeRaw = true
+		default:
+			http.Error(w, "Invalid fields parameter. Must be raw", http.StatusBadRequest)
+			return
+		}
+
+		if !refresh {
+			tweet, err := getStoredTweet(r.Context(), db, tweetID)
+			if err == nil && (!includeRaw || len(tweet.Raw) > 0) {
+				if !includeRaw {
+					tweet.Raw = nil
+				}
+				if !includeHTML {
+					tweet.HTML = ""
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(tweet)
+				return
+			}
+			// A database error shouldn't stop the tweet from being served live
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				logger.Error("Error reading stored tweet %s: %v", tweetID, err)
+			}
+		}
+
+		result, agentUsername, err := manager.GetTweet(r.Context(), tweetID)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+
+		var live liveTweet
+		data, _ := json.Marshal(result)
+		if err := json.Unmarshal(data, &live); err != nil {
+			http.Error(w, fmt.Sprintf("Error decoding tweet: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if err := cacheTweet(r.Context(), db, live, data); err != nil {
+			logger.Error("Error caching tweet %s: %v", tweetID, err)
+		}
+
+		detail := TweetDetail{
+			ID:                live.ID,
+			UserID:            live.UserID,
+			Username:          live.Username,
+			Name:              live.Name,
+			Text:              live.Text,
+			HTML:              live.HTML,
+			PermanentURL:      live.PermanentURL,
+			Likes:             live.Likes,
+			Replies:           live.Replies,
+			Retweets:          live.Retweets,
+			Views:             live.Views,
+			CreatedAt:         live.TimeParsed,
+			IsReply:           live.IsReply,
+			IsQuoted:          live.IsQuoted,
+			IsRetweet:         live.IsRetweet,
+			InReplyToStatusID: live.InReplyToStatusID,
+			QuotedStatusID:    live.QuotedStatusID,
+			RetweetedStatusID: live.RetweetedStatusID,
+			ConversationID:    live.ConversationID,
+			Source:            SourceLive,
+		}
+		if includeRaw {
+			detail.Raw = data
+		}
+		if !includeHTML {
+			detail.HTML = ""
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Agent-Username", agentUsername)
+		json.NewEncoder(w).Encode(detail)
+	}
+}
+
+// getStoredTweet looks a tweet up in the tweets table, then in smart_tweets.
+// It returns sql.ErrNoRows when neither has it.
+func getStoredTweet(ctx context.Context, db *sql.DB, tweetID string) (*TweetDetail, error) {
+	row := db.QueryRowContext(ctx, `
+		SELECT `+storedTweetColumns+`, deleted_at IS NOT NULL FROM tweets WHERE id = $1
+		UNION ALL
+		SELECT `+storedTweetColumns+`, false FROM smart_tweets WHERE id = $1
+		LIMIT 1`, tweetID)
+
+	return scanStoredTweet(row.Scan)
+}
+
+// scanStoredTweet reads a row of storedTweetColumns followed by whether the
+// tweet is deleted
+func scanStoredTweet(scan func(dest ...interface{}) error) (*TweetDetail, error) {
+	tweet := TweetDetail{Source: SourceDB}
+	var createdAt sql.NullTime
+	var raw string
+	if err := scan(
+		&tweet.ID, &tweet.UserID, &tweet.Username, &tweet.Name,
+		&tweet.Text, &tweet.HTML, &tweet.PermanentURL,
+		&tweet.Likes, &tweet.Replies, &tweet.Retweets, &tweet.Views,
+		&createdAt, &tweet.IsReply, &tweet.IsQuoted, &tweet.IsRetweet,
+		&tweet.InReplyToStatusID, &tweet.QuotedStatusID, &tweet.RetweetedStatusID,
+		&tweet.ConversationID, &raw, &tweet.Deleted,
+	); err != nil {
+		return nil, err
+	}
+	tweet.CreatedAt = createdAt.Time
+	if raw != "" {
+		tweet.Raw = json.RawMessage(raw)
+	}
+	return &tweet, nil
+}
+
+// cacheTweet stores a live tweet and its raw JSON in the tweets table, or in
+// smart_tweets, when its author is tracked there. Tweets of untracked authors
+// aren't stored, as both tables reference their author's row.
+func cacheTweet(ctx context.Context, db *sql.DB, tweet liveTweet, raw []byte) error {
+	for _, tables := range [][2]string{{"tweets", "users"}, {"smart_tweets", "smart_users"}} {
+		res, err := db.ExecContext(ctx, `
+			INSERT INTO `+tables[0]+` (
+				id, user_id, tweeter_user_id, username, name, text, html,
+				time_parsed, timestamp, permanent_url, likes, replies,
+				retweets, views, is_pin, is_reply, is_quoted, is_retweet,
+				is_self_thread, sensitive_content, retweeted_status_id,
+				quoted_status_id, in_reply_to_status_id, raw_json, language, conversation_id
+			)
+			SELECT $1, u.id, $2, u.username, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, NULLIF($23, '')::jsonb, NULLIF($24, ''), NULLIF($25, '')
+			FROM `+tables[1]+` u WHERE LOWER(u.username) = LOWER($22)
+			ON CONFLICT (id) DO UPDATE SET
+				likes = EXCLUDED.likes,
+				replies = EXCLUDED.replies,
+				retweets = EXCLUDED.retweets,
+				views = EXCLUDED.views,
+				raw_json = EXCLUDED.raw_json,
+				conversation_id = EXCLUDED.conversation_id`,
+			tweet.ID, tweet.UserID, tweet.Name, tweet.Text, tweet.HTML,
+			tweet.TimeParsed, tweet.Timestamp, tweet.PermanentURL, tweet.Likes, tweet.Replies,
+			tweet.Retweets, tweet.Views, tweet.IsPin, tweet.IsReply, tweet.IsQuoted, tweet.IsRetweet,
+			tweet.IsSelfThread, tweet.SensitiveContent, tweet.RetweetedStatusID,
+			tweet.QuotedStatusID, tweet.InReplyToStatusID, tweet.Username, string(raw), langdetect.Detect(tweet.Text),
+			tweet.ConversationID)
+		if err != nil {
+			return fmt.Errorf("error caching tweet in %s: %v", tables[0], err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// tweetStore reads tweets from the tweets and smart_tweets tables
+type tweetStore struct {
+	db *sql.DB
+}
+
+// NewTweetStore returns a twitter.TweetStore backed by the stored tweets
+func NewTweetStore(db *sql.DB) twitter.TweetStore {
+	return &tweetStore{db: db}
+}
+
+// GetStoredTweet returns the stored tweet with the given ID, or nil when it isn't stored
+func (s *tweetStore) GetStoredTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
+	tweet, err := getStoredTweet(ctx, s.db, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &twitterscraper.Tweet{
+		ID:                tweet.ID,
+		UserID:            tweet.UserID,
+		Username:          tweet.Username,
+		Name:              tweet.Name,
+		Text:              tweet.Text,
+		HTML:              tweet.HTML,
+		PermanentURL:      tweet.PermanentURL,
+		Likes:             tweet.Likes,
+		Replies:           tweet.Replies,
+		Retweets:          tweet.Retweets,
+		Views:             tweet.Views,
+		TimeParsed:        tweet.CreatedAt,
+		Timestamp:         tweet.CreatedAt.Unix(),
+		IsReply:           tweet.IsReply,
+		IsQuoted:          tweet.IsQuoted,
+		IsRetweet:         tweet.IsRetweet,
+		InReplyToStatusID: tweet.InReplyToStatusID,
+		QuotedStatusID:    tweet.QuotedStatusID,
+		RetweetedStatusID: tweet.RetweetedStatusID,
+		ConversationID:    tweet.ConversationID,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTweetGetter serves one live tweet and counts the fetches
+type fakeTweetGetter struct {
+	tweet   map[string]interface{}
+	fetches int
+}
+
+func (g *fakeTweetGetter) GetTweet(ctx context.Context, tweetID string) (interface{}, string, error) {
+	g.fetches++
+	return g.tweet, "agent1", nil
+}
+
+// storedTweetRows returns rows of storedTweetColumns and the deleted flag
+func storedTweetRows(values ...driver.Value) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "tweeter_user_id", "username", "name", "text", "html", "permanent_url",
+		"likes", "replies", "retweets", "views", "time_parsed", "is_reply", "is_quoted", "is_retweet",
+		"in_reply_to_status_id", "quoted_status_id", "retweeted_status_id", "conversation_id", "raw_json", "deleted",
+	})
+	if len(values) > 0 {
+		rows.AddRow(values...)
+	}
+	return rows
+}
+
+func getTweetDetail(t *testing.T, handler http.HandlerFunc, url string) (*httptest.ResponseRecorder, TweetDetail) {
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, url, nil), map[string]string{"id": "123"})
+	w := httptest.NewRecorder()
+	handler(w, r)
+	var detail TweetDetail
+	if w.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	}
+	return w, detail
+}
+
+func TestGetTweetDetailStored(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM tweets WHERE id = \$1`).WithArgs("123").WillReturnRows(storedTweetRows(
+		"123", "42", "alice", "Alice", "hello", "<p>hello</p>", "https://twitter.com/alice/status/123",
+		3, 1, 2, 100, created, false, false, false, "", "", "", "123", `{"ID": "123"}`, false,
+	))
+	getter := &fakeTweetGetter{}
+
+	w, detail := getTweetDetail(t, HandleGetTweetDetail(db, getter), "/api/tweet/123/detail")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, getter.fetches, "stored tweets aren't fetched")
+	assert.Equal(t, SourceDB, detail.Source)
+	assert.Equal(t, "alice", detail.Username)
+	assert.Equal(t, 3, detail.Likes)
+	assert.Equal(t, created, detail.CreatedAt.UTC())
+	// HTML and the raw tweet are left out unless asked for
+	assert.Empty(t, detail.HTML)
+	assert.Empty(t, detail.Raw)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTweetDetailLive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM tweets WHERE id = \$1`).WithArgs("123").WillReturnRows(storedTweetRows())
+	// The author isn't tracked, so neither table stores the tweet
+	mock.ExpectExec(`INSERT INTO tweets`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO smart_tweets`).WillReturnResult(sqlmock.NewResult(0, 0))
+	getter := &fakeTweetGetter{tweet: map[string]interface{}{
+		"ID": "123", "UserID": "42", "Username": "alice", "Text": "hello", "Likes": 5, "ConversationID": "100",
+	}}
+
+	w, detail := getTweetDetail(t, HandleGetTweetDetail(db, getter), "/api/tweet/123/detail")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, getter.fetches)
+	assert.Equal(t, "agent1", w.Header().Get("X-Agent-Username"))
+	assert.Equal(t, SourceLive, detail.Source)
+	assert.Equal(t, "hello", detail.Text)
+	assert.Equal(t, 5, detail.Likes)
+	assert.Equal(t, "100", detail.ConversationID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// refresh=true skips the database lookup
+	mock.ExpectExec(`INSERT INTO tweets`).WillReturnResult(sqlmock.NewResult(0, 1))
+	w, detail = getTweetDetail(t, HandleGetTweetDetail(db, getter), "/api/tweet/123/detail?refresh=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, getter.fetches)
+	assert.Equal(t, SourceLive, detail.Source)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
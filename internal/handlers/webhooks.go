@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/asabya/x-go/internal/tasks"
+	"github.com/gorilla/mux"
+)
+
+// createWebhookRequest is the body of a webhook registration
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs the deliveries, generated when empty
+	Secret string `json:"secret"`
+}
+
+// validate checks the URL and events of req, deduplicating the events
+func (req *createWebhookRequest) validate() error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Invalid url. Must be an absolute http or https URL")
+	}
+	if len(req.Events) == 0 {
+		return fmt.Errorf("At least one event is required, one of: %s", strings.Join(tasks.WebhookEvents, ", "))
+	}
+
+	events := make([]string, 0, len(req.Events))
+	seen := make(map[string]bool)
+	for _, event := range req.Events {
+		if !tasks.IsWebhookEvent(event) {
+			return fmt.Errorf("Invalid event %q. Must be one of: %s", event, strings.Join(tasks.WebhookEvents, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	req.Events = events
+	return nil
+}
+
+// HandleCreateWebhook handles registering a webhook for tweet events. The
+// response includes the secret deliveries are signed with, which isn't
+// returned again.
+func HandleCreateWebhook(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createWebhookRequest
+		if !decodeJSONBody(w, r, &req) {
+			return
+		}
+		if err := req.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Secret == "" {
+			secret, err := tasks.NewWebhookSecret()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			req.Secret = secret
+		}
+
+		hook, err := tasks.CreateWebhook(r.Context(), db, req.URL, req.Events, req.Secret)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+	}
+}
+
+// HandleListWebhooks handles listing the registered webhooks, without their secrets
+func HandleListWebhooks(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hooks, err := tasks.ListWebhooks(r.Context(), db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hooks)
+	}
+}
+
+// HandleDeleteWebhook handles removing a registered webhook
+func HandleDeleteWebhook(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid webhook id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := tasks.DeleteWebhook(r.Context(), db, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
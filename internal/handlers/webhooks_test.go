@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/asabya/x-go/internal/tasks"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWebhookRequestValidate(t *testing.T) {
+	req := createWebhookRequest{
+		URL:    "https://example.com/hook",
+		Events: []string{tasks.EventNewTweet, tasks.EventEngagement, tasks.EventNewTweet},
+	}
+	assert.NoError(t, req.validate())
+	assert.Equal(t, []string{tasks.EventNewTweet, tasks.EventEngagement}, req.Events)
+
+	for _, invalid := range []createWebhookRequest{
+		{URL: "example.com/hook", Events: []string{tasks.EventNewTweet}},
+		{URL: "ftp://example.com/hook", Events: []string{tasks.EventNewTweet}},
+		{URL: "https://example.com/hook"},
+		{URL: "https://example.com/hook", Events: []string{"tweet.deleted"}},
+	} {
+		assert.Error(t, invalid.validate(), "%+v", invalid)
+	}
+}
+
+func TestWebhookHandlersValidation(t *testing.T) {
+	// Invalid requests are rejected before the database is used
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/webhooks", strings.NewReader(`{"url": "https://example.com/hook", "events": ["nope"]}`))
+	HandleCreateWebhook(nil)(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid event")
+
+	w = httptest.NewRecorder()
+	r = mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/api/admin/webhooks/abc", nil), map[string]string{"id": "abc"})
+	HandleDeleteWebhook(nil)(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,138 @@
+package tasks
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/lib/pq"
+)
+
+// AlertConfig configures engagement alerts. A threshold of 0 is ignored.
+type AlertConfig struct {
+	WebhookURL        string // Optional, alerts are also published to registered webhooks
+	LikesThreshold    int
+	RetweetsThreshold int
+}
+
+// EngagementAlert is the JSON body posted to the webhook when a tweet
+// crosses an engagement threshold
+type EngagementAlert struct {
+	TweetID           string    `json:"tweet_id"`
+	Username          string    `json:"username"`
+	Text              string    `json:"text"`
+	PermanentURL      string    `json:"permanent_url"`
+	Likes             int       `json:"likes"`
+	Retweets          int       `json:"retweets"`
+	LikesThreshold    int       `json:"likes_threshold,omitempty"`
+	RetweetsThreshold int       `json:"retweets_threshold,omitempty"`
+	AlertedAt         time.Time `json:"alerted_at"`
+}
+
+// Alerter posts an EngagementAlert to a webhook the first time a stored
+// tweet reaches the configured likes or retweets, and publishes it as an
+// EventEngagement to the registered webhooks
+type Alerter struct {
+	config   AlertConfig
+	webhooks *Webhooks
+	client   *http.Client
+	logger   logging.Logger
+}
+
+// NewAlerter creates an Alerter publishing to webhooks, which may be nil. It
+// returns nil when no threshold is configured or there is neither a webhook
+// URL nor webhooks to alert, which disables alerting.
+func NewAlerter(config AlertConfig, webhooks *Webhooks, logger logging.Logger) *Alerter {
+	if (config.WebhookURL == "" && webhooks == nil) || (config.LikesThreshold <= 0 && config.RetweetsThreshold <= 0) {
+		return nil
+	}
+	return &Alerter{
+		config:   config,
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+	}
+}
+
+// checkTweets alerts for the tweets among ids that have reached a threshold
+// and haven't been alerted yet. Each tweet is marked alerted before its
+// webhook is sent and unmarked when sending fails, so it is retried on the
+// next cycle but never reported twice.
+func (a *Alerter) checkTweets(db *sql.DB, ids []string) error {
+	if a == nil || len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(`
+		UPDATE tweets SET alerted_at = NOW()
+		WHERE id = ANY($1) AND alerted_at IS NULL
+			AND (($2 > 0 AND likes >= $2) OR ($3 > 0 AND retweets >= $3))
+		RETURNING id, COALESCE(username, ''), COALESCE(text, ''), COALESCE(permanent_url, ''),
+			COALESCE(likes, 0), COALESCE(retweets, 0), alerted_at`,
+		pq.Array(ids), a.config.LikesThreshold, a.config.RetweetsThreshold)
+	if err != nil {
+		return fmt.Errorf("error claiming tweets to alert: %v", err)
+	}
+
+	var alerts []EngagementAlert
+	func() {
+		defer rows.Close()
+		for rows.Next() {
+			alert := EngagementAlert{
+				LikesThreshold:    a.config.LikesThreshold,
+				RetweetsThreshold: a.config.RetweetsThreshold,
+			}
+			if err := rows.Scan(&alert.TweetID, &alert.Username, &alert.Text, &alert.PermanentURL,
+				&alert.Likes, &alert.Retweets, &alert.AlertedAt); err != nil {
+				a.logger.Error("Error scanning tweet to alert: %v", err)
+				continue
+			}
+			alerts = append(alerts, alert)
+		}
+	}()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading tweets to alert: %v", err)
+	}
+
+	for _, alert := range alerts {
+		if err := a.send(alert); err != nil {
+			a.logger.Error("Error sending engagement alert for tweet %s: %v", alert.TweetID, err)
+			if _, err := db.Exec("UPDATE tweets SET alerted_at = NULL WHERE id = $1", alert.TweetID); err != nil {
+				a.logger.Error("Error resetting alert state of tweet %s: %v", alert.TweetID, err)
+			}
+			continue
+		}
+		// Published once the alert is sent so a retried alert isn't published twice
+		a.webhooks.Publish(EventEngagement, alert)
+		a.logger.Info("Sent engagement alert for tweet %s by %s (%d likes, %d retweets)",
+			alert.TweetID, alert.Username, alert.Likes, alert.Retweets)
+	}
+	return nil
+}
+
+// send posts alert to the configured webhook URL, if any
+func (a *Alerter) send(alert EngagementAlert) error {
+	if a.config.WebhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("error marshaling alert: %v", err)
+	}
+
+	resp, err := a.client.Post(a.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting alert: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAlerterDisabled(t *testing.T) {
+	assert.Nil(t, NewAlerter(AlertConfig{LikesThreshold: 100}, nil, logging.Default()))
+	assert.Nil(t, NewAlerter(AlertConfig{WebhookURL: "http://localhost/hook"}, nil, logging.Default()))
+	assert.NotNil(t, NewAlerter(AlertConfig{WebhookURL: "http://localhost/hook", RetweetsThreshold: 10}, nil, logging.Default()))
+
+	// Registered webhooks can be alerted without a webhook URL
+	webhooks := NewWebhooks(nil, WebhookConfig{}, logging.Default())
+	assert.Nil(t, NewAlerter(AlertConfig{}, webhooks, logging.Default()))
+	assert.NotNil(t, NewAlerter(AlertConfig{LikesThreshold: 100}, webhooks, logging.Default()))
+
+	// A nil alerter has nothing to check
+	var alerter *Alerter
+	assert.NoError(t, alerter.checkTweets(nil, []string{"1"}))
+}
+
+func TestAlerterSend(t *testing.T) {
+	var received EngagementAlert
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	alerter := NewAlerter(AlertConfig{WebhookURL: server.URL, LikesThreshold: 100}, nil, logging.Default())
+	alert := EngagementAlert{TweetID: "1", Username: "alice", Likes: 150, LikesThreshold: 100}
+
+	assert.NoError(t, alerter.send(alert))
+	assert.Equal(t, "1", received.TweetID)
+	assert.Equal(t, 150, received.Likes)
+
+	status = http.StatusInternalServerError
+	assert.EqualError(t, alerter.send(alert), "webhook responded with status 500")
+}
@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter"
+)
+
+// Backfill fetches and stores the profile and latest fetchLimit tweets of
+// each of usernames once, adding users missing from the users table. It
+// seeds a fresh database the way the profile and tweet updates would on
+// their first cycle, without their long intervals. Engagement alerts aren't
+// checked, since every backfilled tweet would be new. A user that fails
+// doesn't stop the others; the number of failed users is returned as an error.
+func Backfill(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, usernames []string, languages LanguageFilter, fetchLimit int) error {
+	failed := 0
+	for i, username := range usernames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		logger.Info("Backfilling %s (%d/%d)", username, i+1, len(usernames))
+
+		if err := backfillUser(ctx, db, source, logger, username, languages, fetchLimit); err != nil {
+			logger.Error("Error backfilling %s: %v", username, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("backfill of %d of %d users failed", failed, len(usernames))
+	}
+	return nil
+}
+
+func backfillUser(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, username string, languages LanguageFilter, fetchLimit int) error {
+	username, err := twitter.NormalizeUsername(username)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("INSERT INTO users (username) VALUES ($1) ON CONFLICT (username) DO NOTHING", username); err != nil {
+		return fmt.Errorf("error adding user %s: %v", username, err)
+	}
+	if err := updateProfile(ctx, db, source, username); err != nil {
+		return err
+	}
+
+	var userID string
+	if err := db.QueryRow("SELECT id FROM users WHERE username = $1", username).Scan(&userID); err != nil {
+		return fmt.Errorf("error getting user ID for %s: %v", username, err)
+	}
+	return updateUserTweets(ctx, db, source, logger, nil, nil, languages, username, userID, fetchLimit)
+}
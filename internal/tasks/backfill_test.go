@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfill(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	source := &fakeSource{
+		profiles: map[string]twitter.UserProfile{
+			"alice": {UserID: "100", Username: "alice", FollowersCount: 10},
+		},
+		tweets: map[string][]map[string]interface{}{
+			"alice": {
+				{"ID": "10", "UserID": "100", "Username": "alice", "Text": "The market is up today and we are ready for more", "Likes": 3},
+				// Skipped by the language filter
+				{"ID": "11", "UserID": "100", "Username": "alice", "Text": "Je pense que c'est une très bonne nouvelle pour nous"},
+			},
+		},
+	}
+
+	// Alice is added under her normalized username and her tweet is stored
+	// under her users row ID
+	mock.ExpectExec(`INSERT INTO users \(username\) VALUES \(\$1\) ON CONFLICT \(username\) DO NOTHING`).WithArgs("alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO profile_stats_history`).WithArgs("alice", 10, 0, 0).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id FROM users WHERE username = \$1`).WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	args := []driver.Value{"10", "1", "100", "alice"}
+	for len(args) < 27 {
+		args = append(args, sqlmock.AnyArg())
+	}
+	mock.ExpectQuery(`INSERT INTO tweets`).WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+	// The invalid username is skipped without touching the database. Bob is
+	// added, but his profile can't be fetched, so his tweets aren't either.
+	mock.ExpectExec(`INSERT INTO users \(username\)`).WithArgs("bob").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = Backfill(ctx, db, source, logging.Default(), []string{"@Alice", "not a user!", "bob"}, LanguageFilter{Allowlist: []string{"en"}}, 5)
+	assert.EqualError(t, err, "backfill of 2 of 3 users failed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{"alice"}, source.fetched)
+	assert.Equal(t, []int{5}, source.limits)
+
+	// A cancelled backfill stops before the next user
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	source.fetched = nil
+	assert.ErrorIs(t, Backfill(cancelled, db, source, logging.Default(), []string{"alice"}, LanguageFilter{}, 5), context.Canceled)
+	assert.Empty(t, source.fetched)
+}
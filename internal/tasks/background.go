@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/asabya/x-go/pkg/langdetect"
+	"github.com/asabya/x-go/pkg/logging"
 	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/lib/pq"
 )
 
 type Profile struct {
@@ -67,248 +69,588 @@ type Tweet struct {
 	RetweetedStatusID string
 	QuotedStatusID    string
 	InReplyToStatusID string
+	ConversationID    string
 	Place             string
+	// RawJSON is the tweet as returned by the agent, stored in raw_json
+	RawJSON json.RawMessage `json:"-"`
+	// Language is the language detected in Text, empty when unknown
+	Language string `json:"-"`
 }
 
-// StartProfileUpdates starts a goroutine that updates user profiles periodically
-func StartProfileUpdates(db *sql.DB, agentManager *twitter.AgentManager, logger *log.Logger) {
+// tweetSource fetches the profiles and tweets the background tasks store.
+// *twitter.AgentManager implements it; tests substitute a fake.
+type tweetSource interface {
+	GetProfile(ctx context.Context, username string, includePinned bool) (interface{}, string, error)
+	GetUserTweets(ctx context.Context, username string, limit int, sortByOldest bool) (interface{}, []string, string, error)
+}
+
+// userPause is the delay between the users of a profile or smart tweet
+// update cycle, spreading their Twitter calls out
+var userPause = 10 * time.Second
+
+// pause waits for d, returning false when ctx is done first
+func pause(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// decodeTweets converts the tweets returned by AgentManager.GetUserTweets
+// into Tweets, keeping each tweet's full JSON in RawJSON and detecting its language
+func decodeTweets(tweetsData interface{}) ([]Tweet, error) {
+	tweetsBytes, err := json.Marshal(tweetsData)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling tweets data: %v", err)
+	}
+
+	var raws []json.RawMessage
+	if err := json.Unmarshal(tweetsBytes, &raws); err != nil {
+		return nil, fmt.Errorf("error unmarshaling tweets data: %v", err)
+	}
+
+	tweets := make([]Tweet, 0, len(raws))
+	for _, raw := range raws {
+		var tweet Tweet
+		if err := json.Unmarshal(raw, &tweet); err != nil {
+			return nil, fmt.Errorf("error unmarshaling tweets data: %v", err)
+		}
+		tweet.RawJSON = raw
+		tweet.Language = langdetect.Detect(tweet.Text)
+		tweets = append(tweets, tweet)
+	}
+	return tweets, nil
+}
+
+// profileFromUserProfile maps a profile fetched by the agent manager to the
+// columns of the users table
+func profileFromUserProfile(p twitter.UserProfile) Profile {
+	profile := Profile{
+		UserID:               p.UserID,
+		Username:             p.Username,
+		Name:                 p.Name,
+		Biography:            p.Biography,
+		Avatar:               p.AvatarURL,
+		Banner:               p.BannerURL,
+		Birthday:             p.Birthday,
+		Location:             p.Location,
+		URL:                  p.URL,
+		Website:              p.Website,
+		TweetsCount:          p.TweetsCount,
+		LikesCount:           p.LikesCount,
+		MediaCount:           p.MediaCount,
+		FollowersCount:       p.FollowersCount,
+		FollowingCount:       p.FollowingCount,
+		FriendsCount:         p.FriendsCount,
+		NormalFollowersCount: p.NormalFollowersCount,
+		FastFollowersCount:   p.FastFollowersCount,
+		ListedCount:          p.ListedCount,
+		IsVerified:           p.IsVerified,
+		IsPrivate:            p.IsPrivate,
+		IsBlueVerified:       p.IsBlueVerified,
+		CanHighlightTweets:   p.CanHighlightTweets,
+		HasGraduatedAccess:   p.HasGraduatedAccess,
+		FollowedBy:           p.FollowsViewer,
+		Following:            p.ViewerFollows,
+		Sensitive:            p.Sensitive,
+		ProfileImageShape:    p.ProfileImageShape,
+	}
+	if p.Joined != nil {
+		profile.Joined = *p.Joined
+	}
+	return profile
+}
+
+// updateProfile fetches the profile of username, stores it in the users
+// table and records a follower count snapshot
+func updateProfile(ctx context.Context, db *sql.DB, source tweetSource, username string) error {
+	profileData, _, err := source.GetProfile(ctx, username, false)
+	if err != nil {
+		return fmt.Errorf("error getting profile for %s: %v", username, err)
+	}
+
+	// Convert interface{} to Profile struct
+	profileBytes, err := json.Marshal(profileData)
+	if err != nil {
+		return fmt.Errorf("error marshaling profile data: %v", err)
+	}
+
+	var userProfile twitter.UserProfile
+	if err := json.Unmarshal(profileBytes, &userProfile); err != nil {
+		return fmt.Errorf("error unmarshaling profile data: %v", err)
+	}
+	profile := profileFromUserProfile(userProfile)
+
+	// Update user profile in database
+	_, err = db.Exec(`
+		UPDATE users SET 
+			user_id = $1, name = $2, biography = $3, avatar = $4, banner = $5,
+			location = $6, url = $7, website = $8, joined = $9,
+			tweets_count = $10, likes_count = $11, media_count = $12,
+			followers_count = $13, following_count = $14, friends_count = $15,
+			normal_followers_count = $16, fast_followers_count = $17, listed_count = $18,
+			is_verified = $19, is_private = $20, is_blue_verified = $21,
+			can_highlight_tweets = $22, has_graduated_access = $23,
+			followed_by = $24, following = $25, sensitive = $26,
+			profile_image_shape = $27, profile_updated_at = NOW(), profile_update_failed_at = NULL
+		WHERE username = $28`,
+		profile.UserID, profile.Name, profile.Biography, profile.Avatar, profile.Banner,
+		profile.Location, profile.URL, profile.Website, profile.Joined,
+		profile.TweetsCount, profile.LikesCount, profile.MediaCount,
+		profile.FollowersCount, profile.FollowingCount, profile.FriendsCount,
+		profile.NormalFollowersCount, profile.FastFollowersCount, profile.ListedCount,
+		profile.IsVerified, profile.IsPrivate, profile.IsBlueVerified,
+		profile.CanHighlightTweets, profile.HasGraduatedAccess,
+		profile.FollowedBy, profile.Following, profile.Sensitive,
+		profile.ProfileImageShape, username)
+
+	if err != nil {
+		return fmt.Errorf("error updating profile for %s: %v", username, err)
+	}
+
+	// Record a snapshot for the stats history, skipping empty profiles
+	// so a failed fetch doesn't show up as a drop to zero
+	if profile.UserID != "" {
+		_, err = db.Exec(`
+			INSERT INTO profile_stats_history (username, captured_at, followers_count, following_count, tweets_count)
+			VALUES ($1, NOW(), $2, $3, $4)`,
+			username, profile.FollowersCount, profile.FollowingCount, profile.TweetsCount)
+		if err != nil {
+			return fmt.Errorf("error recording stats history for %s: %v", username, err)
+		}
+	}
+	return nil
+}
+
+const (
+	// DefaultProfileStaleAfter is how old a stored profile gets before it is refreshed
+	DefaultProfileStaleAfter = 12 * time.Hour
+	// DefaultProfileCheckInterval is how often stale profiles are looked for
+	DefaultProfileCheckInterval = time.Hour
+)
+
+// ProfileUpdateConfig configures the refreshing of stored profiles
+type ProfileUpdateConfig struct {
+	StaleAfter time.Duration // Profiles updated longer ago than this are refreshed
+	Interval   time.Duration // Time between looking for stale profiles
+}
+
+// staleProfilesQuery selects the users whose profile was never fetched,
+// first so new users are enriched promptly, then those updated longer ago
+// than $1 seconds, least recently updated first. Users whose last update
+// failed within $1 seconds are left out, so a profile that keeps failing is
+// retried once per staleness period instead of first in every cycle.
+const staleProfilesQuery = `
+	SELECT username FROM users
+	WHERE (profile_updated_at IS NULL OR profile_updated_at < NOW() - $1 * INTERVAL '1 second')
+		AND (profile_update_failed_at IS NULL OR profile_update_failed_at < NOW() - $1 * INTERVAL '1 second')
+	ORDER BY profile_updated_at ASC NULLS FIRST, profile_update_failed_at ASC NULLS FIRST`
+
+// runProfileUpdates runs one profile update cycle, refreshing the stored
+// profiles older than staleAfter. Failing users are logged and their failure
+// recorded, so they are skipped until staleAfter has passed; only failing to
+// list them is returned.
+func runProfileUpdates(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, staleAfter time.Duration) error {
+	rows, err := db.QueryContext(ctx, staleProfilesQuery, staleAfter.Seconds())
+	if err != nil {
+		return fmt.Errorf("error querying users: %v", err)
+	}
+
+	// Read the usernames first so the connection isn't held while
+	// profiles are fetched
+	var usernames []string
+	func() {
+		defer rows.Close()
+		for rows.Next() {
+			var username string
+			if err := rows.Scan(&username); err != nil {
+				logger.Error("Error scanning username: %v", err)
+				continue
+			}
+			usernames = append(usernames, username)
+		}
+	}()
+
+	for i, username := range usernames {
+		if i > 0 && !pause(ctx, userPause) {
+			return ctx.Err()
+		}
+		if err := updateProfile(ctx, db, source, username); err != nil {
+			logger.Error("%v", err)
+			if _, err := db.ExecContext(ctx, `UPDATE users SET profile_update_failed_at = NOW() WHERE username = $1`, username); err != nil {
+				logger.Error("Error recording failed profile update for %s: %v", username, err)
+			}
+		}
+	}
+	return nil
+}
+
+// StartProfileUpdates starts a goroutine that refreshes the stored profiles
+// older than config.StaleAfter, checking for them every config.Interval
+func StartProfileUpdates(db *sql.DB, agentManager *twitter.AgentManager, logger logging.Logger, config ProfileUpdateConfig) {
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = DefaultProfileStaleAfter
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultProfileCheckInterval
+	}
+
 	go func() {
 		for {
-			rows, err := db.Query("SELECT username FROM users WHERE user_id IS NULL")
-			if err != nil {
-				logger.Printf("Error querying users: %v", err)
+			if err := runProfileUpdates(context.Background(), db, agentManager, logger, config.StaleAfter); err != nil {
+				logger.Error("%v", err)
 				time.Sleep(10 * time.Second)
 				continue
 			}
+			time.Sleep(config.Interval)
+		}
+	}()
+}
 
-			// Process all rows
-			func() {
-				defer rows.Close()
-				for rows.Next() {
-					var username string
-					if err := rows.Scan(&username); err != nil {
-						logger.Printf("Error scanning username: %v", err)
-						continue
-					}
+// DefaultTweetFetchLimit is the number of tweets fetched per user in each
+// update cycle when neither the config nor the user sets one
+const DefaultTweetFetchLimit = 20
 
-					profileData, _, err := agentManager.GetProfile(context.Background(), username)
-					if err != nil {
-						logger.Printf("Error getting profile for %s: %v", username, err)
-						continue
-					}
+// tweetFetchLimit returns the number of tweets to fetch for a user: the
+// user's own tweet_fetch_limit when it is set and positive, else fetchLimit
+func tweetFetchLimit(userLimit sql.NullInt64, fetchLimit int) int {
+	if userLimit.Valid && userLimit.Int64 > 0 {
+		return int(userLimit.Int64)
+	}
+	return fetchLimit
+}
 
-					// Convert interface{} to Profile struct
-					profileBytes, err := json.Marshal(profileData)
-					if err != nil {
-						logger.Printf("Error marshaling profile data: %v", err)
-						continue
-					}
+// updateUserTweets fetches the latest limit tweets of username and stores
+// them for the user with the users table id userID. Tweets missing from the
+// timeline are counted towards their deletion, and when alerter isn't nil
+// tweets crossing its thresholds are alerted on. Tweets stored for the first
+// time are published to webhooks, which may be nil. Failures to store single
+// tweets are logged without failing the update.
+func updateUserTweets(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, alerter *Alerter, webhooks *Webhooks, languages LanguageFilter, username, userID string, limit int) error {
+	tweetsData, partial, _, err := source.GetUserTweets(ctx, username, limit, false)
+	if err != nil {
+		return fmt.Errorf("error getting tweets for %s: %v", username, err)
+	}
+	if len(partial) > 0 {
+		logger.Warning("Storing incomplete tweets of %s: %s", username, partial[0])
+	}
 
-					var profile Profile
-					if err := json.Unmarshal(profileBytes, &profile); err != nil {
-						logger.Printf("Error unmarshaling profile data: %v", err)
-						continue
-					}
+	tweets, err := decodeTweets(tweetsData)
+	if err != nil {
+		return fmt.Errorf("error decoding tweets for %s: %v", username, err)
+	}
 
-					// Update user profile in database
-					_, err = db.Exec(`
-						UPDATE users SET 
-							user_id = $1, name = $2, biography = $3, avatar = $4, banner = $5,
-							location = $6, url = $7, website = $8, joined = $9,
-							tweets_count = $10, likes_count = $11, media_count = $12,
-							followers_count = $13, following_count = $14, friends_count = $15,
-							normal_followers_count = $16, fast_followers_count = $17, listed_count = $18,
-							is_verified = $19, is_private = $20, is_blue_verified = $21,
-							can_highlight_tweets = $22, has_graduated_access = $23,
-							followed_by = $24, following = $25, sensitive = $26,
-							profile_image_shape = $27
-						WHERE username = $28`,
-						profile.UserID, profile.Name, profile.Biography, profile.Avatar, profile.Banner,
-						profile.Location, profile.URL, profile.Website, profile.Joined,
-						profile.TweetsCount, profile.LikesCount, profile.MediaCount,
-						profile.FollowersCount, profile.FollowingCount, profile.FriendsCount,
-						profile.NormalFollowersCount, profile.FastFollowersCount, profile.ListedCount,
-						profile.IsVerified, profile.IsPrivate, profile.IsBlueVerified,
-						profile.CanHighlightTweets, profile.HasGraduatedAccess,
-						profile.FollowedBy, profile.Following, profile.Sensitive,
-						profile.ProfileImageShape, username)
-
-					if err != nil {
-						logger.Printf("Error updating profile for %s: %v", username, err)
-					}
+	for _, tweet := range tweets {
+		if !languages.Allows(tweet.Language) {
+			logger.Debug("Skipping tweet %s of %s in language %s", tweet.ID, username, tweet.Language)
+			continue
+		}
 
-					time.Sleep(10 * time.Second)
-				}
-			}()
+		// Insert tweet if it doesn't exist. xmax is 0 only for inserted rows.
+		var inserted bool
+		err = db.QueryRow(`
+			INSERT INTO tweets (
+				id, user_id, tweeter_user_id, username, name, text, html,
+				time_parsed, timestamp, permanent_url, likes, replies,
+				retweets, views, is_pin, is_reply, is_quoted, is_retweet,
+				is_self_thread, sensitive_content, retweeted_status_id,
+				quoted_status_id, in_reply_to_status_id, place, raw_json, language, conversation_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, NULLIF($25, '')::jsonb, NULLIF($26, ''), NULLIF($27, ''))
+			ON CONFLICT (id) DO UPDATE SET
+				likes = EXCLUDED.likes,
+				replies = EXCLUDED.replies,
+				retweets = EXCLUDED.retweets,
+				views = EXCLUDED.views,
+				raw_json = EXCLUDED.raw_json,
+				conversation_id = EXCLUDED.conversation_id,
+				missed_cycles = 0,
+				deleted_at = NULL
+			RETURNING xmax = 0`,
+			tweet.ID, userID, tweet.UserID, tweet.Username, tweet.Name, tweet.Text, tweet.HTML,
+			tweet.TimeParsed, tweet.Timestamp, tweet.PermanentURL, tweet.Likes, tweet.Replies,
+			tweet.Retweets, tweet.Views, tweet.IsPin, tweet.IsReply, tweet.IsQuoted, tweet.IsRetweet,
+			tweet.IsSelfThread, tweet.SensitiveContent, tweet.RetweetedStatusID,
+			tweet.QuotedStatusID, tweet.InReplyToStatusID, tweet.Place, string(tweet.RawJSON), tweet.Language, tweet.ConversationID).Scan(&inserted)
 
-			time.Sleep(12 * time.Hour)
+		if err != nil {
+			logger.Error("Error inserting/updating tweet: %v", err)
+			continue
 		}
-	}()
+		if inserted {
+			webhooks.Publish(EventNewTweet, NewTweetEvent{Username: username, Tweet: tweet})
+		}
+	}
+
+	if err := markMissingTweets(db, userID, tweets); err != nil {
+		logger.Error("Error marking missing tweets for %s: %v", username, err)
+	}
+
+	ids := make([]string, 0, len(tweets))
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+	}
+	if err := alerter.checkTweets(db, ids); err != nil {
+		logger.Error("Error checking engagement alerts for %s: %v", username, err)
+	}
+	return nil
+}
+
+// trackedUser is a row of users whose tweets are updated
+type trackedUser struct {
+	username   string
+	id         string
+	fetchLimit sql.NullInt64 // the user's tweet_fetch_limit
+}
+
+// listTrackedUsers returns every user whose tweets are updated. Rows that
+// can't be read are logged and left out.
+func listTrackedUsers(ctx context.Context, db *sql.DB, logger logging.Logger) ([]trackedUser, error) {
+	rows, err := db.QueryContext(ctx, "SELECT username, id, tweet_fetch_limit FROM users")
+	if err != nil {
+		return nil, fmt.Errorf("error querying users: %v", err)
+	}
+	defer rows.Close()
+
+	var users []trackedUser
+	for rows.Next() {
+		var user trackedUser
+		if err := rows.Scan(&user.username, &user.id, &user.fetchLimit); err != nil {
+			logger.Error("Error scanning user data: %v", err)
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
 }
 
-// StartTweetUpdates starts a goroutine that updates user tweets periodically
-func StartTweetUpdates(db *sql.DB, agentManager *twitter.AgentManager, logger *log.Logger) {
+// runTweetUpdates runs one tweet update cycle over all users, fetching
+// fetchLimit tweets per user unless the user's tweet_fetch_limit overrides
+// it. Failing users are logged and skipped; only failing to list them, or ctx
+// being done, is returned.
+func runTweetUpdates(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, alerter *Alerter, webhooks *Webhooks, languages LanguageFilter, fetchLimit int) error {
+	users, err := listTrackedUsers(ctx, db, logger)
+	if err != nil {
+		return err
+	}
+	return updateTrackedUsers(ctx, db, source, logger, alerter, webhooks, languages, users, fetchLimit)
+}
+
+// updateTrackedUsers updates the tweets of users like runTweetUpdates does
+// for every user. Failing users are logged and skipped; only ctx being done
+// is returned.
+func updateTrackedUsers(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, alerter *Alerter, webhooks *Webhooks, languages LanguageFilter, users []trackedUser, fetchLimit int) error {
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if user.fetchLimit.Valid && user.fetchLimit.Int64 <= 0 {
+			logger.Warning("Ignoring invalid tweet_fetch_limit %d of %s", user.fetchLimit.Int64, user.username)
+		}
+
+		limit := tweetFetchLimit(user.fetchLimit, fetchLimit)
+		if err := updateUserTweets(ctx, db, source, logger, alerter, webhooks, languages, user.username, user.id, limit); err != nil {
+			logger.Error("%v", err)
+		}
+	}
+	return nil
+}
+
+// StartTweetUpdates starts a goroutine that updates user tweets periodically,
+// fetching fetchLimit tweets per user unless the user's tweet_fetch_limit
+// overrides it. When alerter isn't nil, fetched tweets crossing its thresholds
+// are alerted on, and new tweets are published to webhooks unless it is nil.
+// Tweets in languages the filter doesn't allow aren't stored.
+func StartTweetUpdates(db *sql.DB, agentManager *twitter.AgentManager, logger logging.Logger, alerter *Alerter, webhooks *Webhooks, languages LanguageFilter, fetchLimit int) {
 	go func() {
 		for {
-			rows, err := db.Query("SELECT username, id FROM users")
-			if err != nil {
-				logger.Printf("Error querying users: %v", err)
+			if err := runTweetUpdates(context.Background(), db, agentManager, logger, alerter, webhooks, languages, fetchLimit); err != nil {
+				logger.Error("%v", err)
 				time.Sleep(time.Hour)
 				continue
 			}
+			time.Sleep(6 * time.Hour)
+		}
+	}()
+}
 
-			// Process all rows
-			func() {
-				defer rows.Close()
-				for rows.Next() {
-					var username string
-					var userID string
-					if err := rows.Scan(&username, &userID); err != nil {
-						logger.Printf("Error scanning user data: %v", err)
-						continue
-					}
-
-					tweetsData, _, err := agentManager.GetUserTweets(context.Background(), username, 20, false)
-					if err != nil {
-						logger.Printf("Error getting tweets for %s: %v", username, err)
-						continue
-					}
+// deletedTweetMissThreshold is the number of consecutive update cycles a
+// stored tweet must be missing from the user's timeline before it is marked deleted
+const deletedTweetMissThreshold = 3
+
+// markMissingTweets counts a missed cycle for every stored tweet of the user
+// that falls inside the window covered by tweets but was not returned, and
+// marks tweets deleted once they reach deletedTweetMissThreshold. Tweets older
+// than the fetched window are left alone since they may just be on a later page.
+func markMissingTweets(db *sql.DB, userID string, tweets []Tweet) error {
+	var ids []string
+	var oldest time.Time
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+		// Pinned tweets can be much older than the rest of the page
+		if tweet.IsPin {
+			continue
+		}
+		if oldest.IsZero() || tweet.TimeParsed.Before(oldest) {
+			oldest = tweet.TimeParsed
+		}
+	}
 
-					// Convert interface{} to []Tweet
-					tweetsBytes, err := json.Marshal(tweetsData)
-					if err != nil {
-						logger.Printf("Error marshaling tweets data: %v", err)
-						continue
-					}
+	// Nothing to compare against, e.g. a private or suspended account
+	if oldest.IsZero() {
+		return nil
+	}
 
-					var tweets []Tweet
-					if err := json.Unmarshal(tweetsBytes, &tweets); err != nil {
-						logger.Printf("Error unmarshaling tweets data: %v", err)
-						continue
-					}
+	_, err := db.Exec(`
+		UPDATE tweets SET missed_cycles = missed_cycles + 1
+		WHERE user_id = $1 AND deleted_at IS NULL
+			AND time_parsed >= $2 AND NOT (id = ANY($3))`,
+		userID, oldest, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("error counting missed cycles: %v", err)
+	}
 
-					for _, tweet := range tweets {
-						// Insert tweet if it doesn't exist
-						_, err = db.Exec(`
-							INSERT INTO tweets (
-								id, user_id, tweeter_user_id, username, name, text, html,
-								time_parsed, timestamp, permanent_url, likes, replies,
-								retweets, views, is_pin, is_reply, is_quoted, is_retweet,
-								is_self_thread, sensitive_content, retweeted_status_id,
-								quoted_status_id, in_reply_to_status_id, place
-							) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
-							ON CONFLICT (id) DO UPDATE SET
-								likes = EXCLUDED.likes,
-								replies = EXCLUDED.replies,
-								retweets = EXCLUDED.retweets,
-								views = EXCLUDED.views`,
-							tweet.ID, userID, tweet.UserID, tweet.Username, tweet.Name, tweet.Text, tweet.HTML,
-							tweet.TimeParsed, tweet.Timestamp, tweet.PermanentURL, tweet.Likes, tweet.Replies,
-							tweet.Retweets, tweet.Views, tweet.IsPin, tweet.IsReply, tweet.IsQuoted, tweet.IsRetweet,
-							tweet.IsSelfThread, tweet.SensitiveContent, tweet.RetweetedStatusID,
-							tweet.QuotedStatusID, tweet.InReplyToStatusID, tweet.Place)
-
-						if err != nil {
-							logger.Printf("Error inserting/updating tweet: %v", err)
-						}
-					}
-				}
-			}()
+	_, err = db.Exec(`
+		UPDATE tweets SET deleted_at = NOW()
+		WHERE user_id = $1 AND deleted_at IS NULL AND missed_cycles >= $2`,
+		userID, deletedTweetMissThreshold)
+	if err != nil {
+		return fmt.Errorf("error marking deleted tweets: %v", err)
+	}
 
-			time.Sleep(6 * time.Hour)
-		}
-	}()
+	return nil
 }
 
 // StartSmartTweetUpdates starts a goroutine that updates smart user tweets periodically
-// and also processes new users received through the newUsers channel
-func StartSmartTweetUpdates(ctx context.Context, db *sql.DB, agentManager *twitter.AgentManager, logger *log.Logger, newUsers chan string) {
-	logger.Printf("Starting smart tweet updates goroutine")
+// and also processes new users received through the newUsers queue, reporting
+// each back with Done. Tweets in languages the filter doesn't allow aren't stored.
+func StartSmartTweetUpdates(ctx context.Context, db *sql.DB, agentManager *twitter.AgentManager, logger logging.Logger, newUsers *UserQueue, languages LanguageFilter) {
+	logger.Info("Starting smart tweet updates goroutine")
 	go func() {
-		logger.Printf("Smart tweet updates goroutine started")
+		logger.Debug("Smart tweet updates goroutine started")
 		ticker := time.NewTicker(6 * time.Hour)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
-				logger.Printf("Stopping smart tweet updates due to context cancellation")
+				logger.Info("Stopping smart tweet updates due to context cancellation")
 				return
-			case username, ok := <-newUsers:
+			case username, ok := <-newUsers.Usernames():
 				if !ok {
-					logger.Printf("Channel closed, stopping goroutine")
+					logger.Info("Channel closed, stopping goroutine")
 					return
 				}
-				logger.Printf("Received new user %s from channel", username)
+				logger.Info("Received new user %s from channel", username)
 				// Process a new user immediately
-				if err := processSmartUserTweets(db, agentManager, logger, username); err != nil {
-					logger.Printf("Error processing new smart user %s: %v", username, err)
+				err := processSmartUserTweets(ctx, db, agentManager, logger, username, languages)
+				if err != nil && ctx.Err() != nil {
+					// Interrupted by shutdown, it stays pending for the next start
+					logger.Info("Stopping smart tweet updates due to context cancellation")
+					return
 				}
-			case <-ticker.C:
-				logger.Printf("Running periodic updates...")
-				// Process all users periodically
-				rows, err := db.Query("SELECT username, id FROM smart_users")
 				if err != nil {
-					logger.Printf("Error querying smart users: %v", err)
-					continue
+					logger.Error("Error processing new smart user %s: %v", username, err)
 				}
-
-				// Process all rows
-				func() {
-					defer rows.Close()
-					for rows.Next() {
-						select {
-						case <-ctx.Done():
-							logger.Printf("Stopping smart tweet updates due to context cancellation")
-							return
-						default:
-							var username string
-							var userID string
-							if err := rows.Scan(&username, &userID); err != nil {
-								logger.Printf("Error scanning smart user data: %v", err)
-								continue
-							}
-
-							if err := processSmartUserTweets(db, agentManager, logger, username); err != nil {
-								logger.Printf("Error processing smart user %s: %v", username, err)
-							}
-
-							// Add a small delay between processing each user to avoid rate limiting
-							time.Sleep(10 * time.Second)
-						}
+				newUsers.Done(username, err)
+			case <-ticker.C:
+				logger.Info("Running periodic updates...")
+				if err := runSmartTweetUpdates(ctx, db, agentManager, logger, languages); err != nil {
+					if ctx.Err() != nil {
+						logger.Info("Stopping smart tweet updates due to context cancellation")
+						return
 					}
-				}()
+					logger.Error("%v", err)
+				}
 			}
 		}
 	}()
 }
 
+// runSmartTweetUpdates runs one update cycle over all smart users. Failing
+// users are logged and skipped; only failing to list them, or ctx being done,
+// is returned.
+func runSmartTweetUpdates(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, languages LanguageFilter) error {
+	usernames, err := listSmartUsers(ctx, db, logger)
+	if err != nil {
+		return err
+	}
+	return updateSmartUsers(ctx, db, source, logger, languages, usernames)
+}
+
+// listSmartUsers returns the usernames of every smart user. Rows that can't
+// be read are logged and left out.
+func listSmartUsers(ctx context.Context, db *sql.DB, logger logging.Logger) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT username FROM smart_users")
+	if err != nil {
+		return nil, fmt.Errorf("error querying smart users: %v", err)
+	}
+	defer rows.Close()
+
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			logger.Error("Error scanning smart user data: %v", err)
+			continue
+		}
+		usernames = append(usernames, username)
+	}
+	return usernames, rows.Err()
+}
+
+// updateSmartUsers updates the tweets of the smart users with usernames,
+// pausing between users. Failing users are logged and skipped; only ctx
+// being done is returned.
+func updateSmartUsers(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, languages LanguageFilter, usernames []string) error {
+	for i, username := range usernames {
+		// Add a small delay between users to avoid rate limiting
+		if i > 0 && !pause(ctx, userPause) {
+			return ctx.Err()
+		}
+
+		if err := processSmartUserTweets(ctx, db, source, logger, username, languages); err != nil {
+			logger.Error("Error processing smart user %s: %v", username, err)
+		}
+	}
+	return nil
+}
+
 // processSmartUserTweets handles the tweet fetching and database updates for a single smart user
-func processSmartUserTweets(db *sql.DB, agentManager *twitter.AgentManager, logger *log.Logger, username string) error {
+func processSmartUserTweets(ctx context.Context, db *sql.DB, source tweetSource, logger logging.Logger, username string, languages LanguageFilter) error {
 	// Get user ID from database
 	var userID string
-	err := db.QueryRow("SELECT id FROM smart_users WHERE username = $1", username).Scan(&userID)
+	err := db.QueryRowContext(ctx, "SELECT id FROM smart_users WHERE username = $1", username).Scan(&userID)
 	if err != nil {
 		return fmt.Errorf("error getting user ID for %s: %v", username, err)
 	}
 
-	tweetsData, _, err := agentManager.GetUserTweets(context.Background(), username, 20, false)
+	tweetsData, partial, _, err := source.GetUserTweets(ctx, username, 20, false)
 	if err != nil {
 		return fmt.Errorf("error getting tweets for smart user %s: %v", username, err)
 	}
-
-	// Convert interface{} to []Tweet
-	tweetsBytes, err := json.Marshal(tweetsData)
-	if err != nil {
-		return fmt.Errorf("error marshaling smart user tweets data: %v", err)
+	if len(partial) > 0 {
+		logger.Warning("Storing incomplete tweets of smart user %s: %s", username, partial[0])
 	}
 
-	var tweets []Tweet
-	if err := json.Unmarshal(tweetsBytes, &tweets); err != nil {
-		return fmt.Errorf("error unmarshaling smart user tweets data: %v", err)
+	tweets, err := decodeTweets(tweetsData)
+	if err != nil {
+		return fmt.Errorf("error decoding smart user tweets: %v", err)
 	}
 
 	for _, tweet := range tweets {
+		if !languages.Allows(tweet.Language) {
+			logger.Debug("Skipping smart tweet %s of %s in language %s", tweet.ID, username, tweet.Language)
+			continue
+		}
+
 		// Insert tweet if it doesn't exist
 		_, err = db.Exec(`
 			INSERT INTO smart_tweets (
@@ -316,18 +658,20 @@ func processSmartUserTweets(db *sql.DB, agentManager *twitter.AgentManager, logg
 				time_parsed, timestamp, permanent_url, likes, replies,
 				retweets, views, is_pin, is_reply, is_quoted, is_retweet,
 				is_self_thread, sensitive_content, retweeted_status_id,
-				quoted_status_id, in_reply_to_status_id, place
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
+				quoted_status_id, in_reply_to_status_id, place, raw_json, language, conversation_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, NULLIF($25, '')::jsonb, NULLIF($26, ''), NULLIF($27, ''))
 			ON CONFLICT (id) DO UPDATE SET
 				likes = EXCLUDED.likes,
 				replies = EXCLUDED.replies,
 				retweets = EXCLUDED.retweets,
-				views = EXCLUDED.views`,
+				views = EXCLUDED.views,
+				raw_json = EXCLUDED.raw_json,
+				conversation_id = EXCLUDED.conversation_id`,
 			tweet.ID, userID, tweet.UserID, tweet.Username, tweet.Name, tweet.Text, tweet.HTML,
 			tweet.TimeParsed, tweet.Timestamp, tweet.PermanentURL, tweet.Likes, tweet.Replies,
 			tweet.Retweets, tweet.Views, tweet.IsPin, tweet.IsReply, tweet.IsQuoted, tweet.IsRetweet,
 			tweet.IsSelfThread, tweet.SensitiveContent, tweet.RetweetedStatusID,
-			tweet.QuotedStatusID, tweet.InReplyToStatusID, tweet.Place)
+			tweet.QuotedStatusID, tweet.InReplyToStatusID, tweet.Place, string(tweet.RawJSON), tweet.Language, tweet.ConversationID)
 
 		if err != nil {
 			return fmt.Errorf("error inserting/updating smart tweet: %v", err)
@@ -0,0 +1,308 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeTweets(t *testing.T) {
+	var tweetsData interface{}
+	err := json.Unmarshal([]byte(`[
+		{"ID": "1", "Text": "first", "Likes": 3, "Photos": [{"URL": "https://pbs.twimg.com/1.jpg"}]},
+		{"ID": "2", "Text": "second", "ConversationID": "1", "QuotedStatus": {"ID": "1"}}
+	]`), &tweetsData)
+	assert.NoError(t, err)
+
+	tweets, err := decodeTweets(tweetsData)
+	assert.NoError(t, err)
+	assert.Len(t, tweets, 2)
+	assert.Equal(t, "first", tweets[0].Text)
+	assert.Equal(t, 3, tweets[0].Likes)
+	assert.Equal(t, "1", tweets[1].ConversationID)
+	// Fields without a column are kept in the raw JSON
+	assert.JSONEq(t, `{"ID": "1", "Text": "first", "Likes": 3, "Photos": [{"URL": "https://pbs.twimg.com/1.jpg"}]}`, string(tweets[0].RawJSON))
+	assert.JSONEq(t, `{"ID": "2", "Text": "second", "ConversationID": "1", "QuotedStatus": {"ID": "1"}}`, string(tweets[1].RawJSON))
+
+	_, err = decodeTweets(map[string]interface{}{"ID": "1"})
+	assert.Error(t, err)
+}
+
+func TestLanguageFilter(t *testing.T) {
+	assert.True(t, LanguageFilter{}.Allows("fr"))
+
+	filter := LanguageFilter{Allowlist: []string{"EN", "de"}}
+	assert.True(t, filter.Allows("en"))
+	assert.True(t, filter.Allows("de"))
+	assert.False(t, filter.Allows("fr"))
+	// Tweets of unknown language are kept
+	assert.True(t, filter.Allows(""))
+}
+
+func TestTweetFetchLimit(t *testing.T) {
+	assert.Equal(t, 20, tweetFetchLimit(sql.NullInt64{}, 20))
+	assert.Equal(t, 100, tweetFetchLimit(sql.NullInt64{Int64: 100, Valid: true}, 20))
+	// Invalid per-user limits fall back to the global one
+	assert.Equal(t, 20, tweetFetchLimit(sql.NullInt64{Int64: 0, Valid: true}, 20))
+	assert.Equal(t, 20, tweetFetchLimit(sql.NullInt64{Int64: -5, Valid: true}, 20))
+}
+
+func TestProfileFromUserProfile(t *testing.T) {
+	var fetched twitter.UserProfile
+	err := json.Unmarshal([]byte(`{
+		"user_id": "42", "username": "alice", "name": "Alice", "bio": "bio",
+		"avatar_url": "https://pbs.twimg.com/a.jpg", "banner_url": "https://pbs.twimg.com/b.jpg",
+		"birthday": "1990-01-01", "location": "Berlin", "url": "https://twitter.com/alice",
+		"website": "https://alice.dev", "joined": "2010-06-01T00:00:00Z",
+		"tweets": 1, "likes": 2, "media": 3, "followers": 4, "following": 5, "friends": 6,
+		"normal_followers": 7, "fast_followers": 8, "listed": 9,
+		"verified": true, "private": true, "blue_verified": true, "can_highlight_tweets": true,
+		"has_graduated_access": true, "sensitive": true, "profile_image_shape": "Circle",
+		"viewer_follows": true, "follows_viewer": true
+	}`), &fetched)
+	assert.NoError(t, err)
+
+	// Every column but the database ID is populated
+	profile := reflect.ValueOf(profileFromUserProfile(fetched))
+	for i := 0; i < profile.NumField(); i++ {
+		name := profile.Type().Field(i).Name
+		if name == "ID" {
+			continue
+		}
+		assert.False(t, profile.Field(i).IsZero(), "%s isn't set", name)
+	}
+}
+
+// fakeSource serves canned tweets and profiles per username
+type fakeSource struct {
+	tweets   map[string][]map[string]interface{}
+	profiles map[string]twitter.UserProfile
+	err      error
+	fetched  []string
+	limits   []int
+}
+
+func (s *fakeSource) GetProfile(ctx context.Context, username string, includePinned bool) (interface{}, string, error) {
+	if profile, ok := s.profiles[username]; ok {
+		return profile, "agent", nil
+	}
+	return nil, "", fmt.Errorf("no profile for %s", username)
+}
+
+func (s *fakeSource) GetUserTweets(ctx context.Context, username string, limit int, sortByOldest bool) (interface{}, []string, string, error) {
+	s.fetched = append(s.fetched, username)
+	s.limits = append(s.limits, limit)
+	if s.err != nil {
+		return nil, nil, "", s.err
+	}
+	tweets := s.tweets[username]
+	if len(tweets) > limit {
+		tweets = tweets[:limit]
+	}
+	return tweets, nil, "agent", nil
+}
+
+func TestPause(t *testing.T) {
+	assert.True(t, pause(context.Background(), 0))
+	assert.True(t, pause(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, pause(ctx, time.Hour))
+	assert.False(t, pause(ctx, 0))
+}
+
+func TestUpdateUserTweetsSourceError(t *testing.T) {
+	// The fetch fails before the database is used
+	source := &fakeSource{err: errors.New("rate limited")}
+	err := updateUserTweets(context.Background(), nil, source, logging.Default(), nil, nil, LanguageFilter{}, "alice", "1", 20)
+	assert.ErrorContains(t, err, "error getting tweets for alice")
+	assert.Equal(t, []string{"alice"}, source.fetched)
+}
+
+func TestListTrackedUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT username, id, tweet_fetch_limit FROM users`).WillReturnRows(
+		sqlmock.NewRows([]string{"username", "id", "tweet_fetch_limit"}).AddRow("alice", "1", nil).AddRow("bob", "2", 5))
+	users, err := listTrackedUsers(context.Background(), db, logging.Default())
+	assert.NoError(t, err)
+	assert.Equal(t, []trackedUser{
+		{username: "alice", id: "1"},
+		{username: "bob", id: "2", fetchLimit: sql.NullInt64{Int64: 5, Valid: true}},
+	}, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTrackedUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	tweet := map[string]interface{}{
+		"ID": "10", "Username": "alice", "Text": "The market is up today and we are ready for more",
+		"Likes": 1, "ConversationID": "10", "TimeParsed": time.Now().UTC(),
+	}
+	source := &fakeSource{tweets: map[string][]map[string]interface{}{
+		// Alice's tweet_fetch_limit leaves out the second tweet
+		"alice": {tweet, {"ID": "9", "Username": "alice", "Text": "older"}},
+	}}
+	users := []trackedUser{
+		{username: "alice", id: "1", fetchLimit: sql.NullInt64{Int64: 1, Valid: true}},
+		// Bob has no tweets, so nothing is stored or marked missing
+		{username: "bob", id: "2"},
+	}
+	webhooks := NewWebhooks(nil, WebhookConfig{}, logging.Default())
+
+	// Inserted
+	mock.ExpectQuery(`INSERT INTO tweets`).WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+	mock.ExpectExec(`UPDATE tweets SET missed_cycles = missed_cycles \+ 1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE tweets SET deleted_at = NOW\(\)`).WithArgs("1", deletedTweetMissThreshold).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, updateTrackedUsers(ctx, db, source, logging.Default(), nil, webhooks, LanguageFilter{}, users, 20))
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{"alice", "bob"}, source.fetched)
+	assert.Equal(t, []int{1, 20}, source.limits)
+	if assert.Len(t, webhooks.queue, 1, "new tweets are published") {
+		event := <-webhooks.queue
+		assert.Equal(t, EventNewTweet, event.Event)
+	}
+
+	// Updated on conflict
+	mock.ExpectQuery(`INSERT INTO tweets`).WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(false))
+	mock.ExpectExec(`UPDATE tweets SET missed_cycles = missed_cycles \+ 1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE tweets SET deleted_at = NOW\(\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, updateTrackedUsers(ctx, db, source, logging.Default(), nil, webhooks, LanguageFilter{}, users[:1], 20))
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Len(t, webhooks.queue, 0, "updated tweets aren't published again")
+
+	// A cancelled cycle stops before the next user
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	source.fetched = nil
+	assert.ErrorIs(t, updateTrackedUsers(cancelled, db, source, logging.Default(), nil, webhooks, LanguageFilter{}, users, 20), context.Canceled)
+	assert.Empty(t, source.fetched)
+}
+
+func TestMarkMissingTweets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now().UTC()
+	tweets := []Tweet{
+		{ID: "3", TimeParsed: now},
+		{ID: "2", TimeParsed: now.Add(-2 * time.Hour)},
+		// Far older than the page, so it doesn't widen the window
+		{ID: "1", TimeParsed: now.AddDate(0, -1, 0), IsPin: true},
+	}
+
+	// Only tweets since the oldest unpinned one count a miss, and the pinned
+	// tweet isn't missing since it was returned
+	mock.ExpectExec(`UPDATE tweets SET missed_cycles = missed_cycles \+ 1 WHERE user_id = \$1 AND deleted_at IS NULL AND time_parsed >= \$2 AND NOT \(id = ANY\(\$3\)\)`).
+		WithArgs("1", now.Add(-2*time.Hour), pq.Array([]string{"3", "2", "1"})).WillReturnResult(sqlmock.NewResult(0, 2))
+	// Tweets are deleted once they miss deletedTweetMissThreshold cycles
+	mock.ExpectExec(`UPDATE tweets SET deleted_at = NOW\(\) WHERE user_id = \$1 AND deleted_at IS NULL AND missed_cycles >= \$2`).
+		WithArgs("1", deletedTweetMissThreshold).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, markMissingTweets(db, "1", tweets))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Without an unpinned tweet there is no window to compare against
+	assert.NoError(t, markMissingTweets(db, "1", nil))
+	assert.NoError(t, markMissingTweets(db, "1", tweets[2:]))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// A tweet returned again starts counting its misses over, so only
+	// consecutive misses delete it
+	source := &fakeSource{tweets: map[string][]map[string]interface{}{
+		"alice": {{"ID": "2", "Username": "alice", "Text": "back again", "TimeParsed": now}},
+	}}
+	mock.ExpectQuery(`INSERT INTO tweets .* ON CONFLICT \(id\) DO UPDATE SET .* missed_cycles = 0, deleted_at = NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(false))
+	mock.ExpectExec(`UPDATE tweets SET missed_cycles = missed_cycles \+ 1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE tweets SET deleted_at = NOW\(\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, updateUserTweets(context.Background(), db, source, logging.Default(), nil, nil, LanguageFilter{}, "alice", "1", 20))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Failures are returned
+	mock.ExpectExec(`UPDATE tweets SET missed_cycles`).WillReturnError(errors.New("connection lost"))
+	assert.EqualError(t, markMissingTweets(db, "1", tweets), "error counting missed cycles: connection lost")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunSmartTweetUpdates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+	defer func(d time.Duration) { userPause = d }(userPause)
+	userPause = 0
+
+	source := &fakeSource{tweets: map[string][]map[string]interface{}{
+		"alice": {
+			{"ID": "10", "Username": "alice", "Text": "The market is up today and we are ready for more", "Likes": 1},
+			// Skipped by the language filter
+			{"ID": "11", "Username": "alice", "Text": "Je pense que c'est une très bonne nouvelle pour nous"},
+		},
+	}}
+
+	mock.ExpectQuery(`SELECT username FROM smart_users`).WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("alice").AddRow("bob"))
+	mock.ExpectQuery(`SELECT id FROM smart_users WHERE username = \$1`).WithArgs("alice").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("7"))
+	mock.ExpectExec(`INSERT INTO smart_tweets`).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Bob is no longer stored, which is logged without stopping the cycle
+	mock.ExpectQuery(`SELECT id FROM smart_users WHERE username = \$1`).WithArgs("bob").WillReturnError(sql.ErrNoRows)
+	assert.NoError(t, runSmartTweetUpdates(ctx, db, source, logging.Default(), LanguageFilter{Allowlist: []string{"en"}}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{"alice"}, source.fetched)
+
+	// A cancelled cycle stops between users
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	source.fetched = nil
+	assert.ErrorIs(t, updateSmartUsers(cancelled, db, source, logging.Default(), LanguageFilter{}, []string{"alice", "bob"}), context.Canceled)
+	assert.Empty(t, source.fetched)
+}
+
+func TestStaleProfilesQuery(t *testing.T) {
+	// Never fetched profiles come first, then the least recently updated;
+	// among those, users that never failed come before those that did
+	assert.Contains(t, staleProfilesQuery, "ORDER BY profile_updated_at ASC NULLS FIRST, profile_update_failed_at ASC NULLS FIRST")
+	// Users that failed within the staleness period are left out
+	assert.Contains(t, staleProfilesQuery, "profile_update_failed_at IS NULL OR profile_update_failed_at < NOW() - $1 * INTERVAL '1 second'")
+}
+
+func TestRunProfileUpdates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	defer func(d time.Duration) { userPause = d }(userPause)
+	userPause = 0
+
+	source := &fakeSource{profiles: map[string]twitter.UserProfile{
+		"alice": {UserID: "1", Username: "alice", FollowersCount: 10},
+	}}
+
+	mock.ExpectQuery(`SELECT username FROM users`).WithArgs(float64(3600)).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("bob").AddRow("alice"))
+	// Bob's profile can't be fetched, so the failure is recorded
+	mock.ExpectExec(`UPDATE users SET profile_update_failed_at = NOW\(\) WHERE username = \$1`).WithArgs("bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Updating Alice clears an earlier failure
+	mock.ExpectExec(`profile_updated_at = NOW\(\), profile_update_failed_at = NULL`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO profile_stats_history`).WithArgs("alice", 10, 0, 0).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, runProfileUpdates(context.Background(), db, source, logging.Default(), time.Hour))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
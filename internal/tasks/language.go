@@ -0,0 +1,26 @@
+package tasks
+
+import "strings"
+
+// LanguageFilter decides which ingested tweets are stored by the language
+// detected in their text. The detected language is always recorded in the
+// language column; with an empty Allowlist every tweet is stored.
+type LanguageFilter struct {
+	// Allowlist holds the ISO 639-1 codes of the languages to store, e.g. "en".
+	// Tweets whose language can't be detected, such as links or emoji only,
+	// are always stored.
+	Allowlist []string
+}
+
+// Allows reports whether a tweet detected as lang should be stored
+func (f LanguageFilter) Allows(lang string) bool {
+	if len(f.Allowlist) == 0 || lang == "" {
+		return true
+	}
+	for _, allowed := range f.Allowlist {
+		if strings.EqualFold(allowed, lang) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/lib/pq"
+)
+
+// Reasons a stored tweet is orphaned
+const (
+	OrphanMissingUser     = "missing_user"     // user_id doesn't match any users.id
+	OrphanUnknownUsername = "unknown_username" // username doesn't match any users.username
+)
+
+const (
+	// DefaultOrphanCheckInterval is how often orphaned tweets are looked for
+	DefaultOrphanCheckInterval = 24 * time.Hour
+	// DefaultOrphanReportLimit is the number of orphaned tweets listed per check
+	DefaultOrphanReportLimit = 100
+
+	// orphanRetryAfter is how long the repair skips orphaned tweets whose
+	// user couldn't be found, so they don't crowd out the others
+	orphanRetryAfter = 7 * 24 * time.Hour
+)
+
+// OrphanCheckConfig configures the search for orphaned tweets
+type OrphanCheckConfig struct {
+	Interval time.Duration // Time between checks
+	Limit    int           // Orphaned tweets handled per check
+	Repair   bool          // Re-link orphaned tweets instead of only logging them
+}
+
+// OrphanedTweet is a stored tweet whose user_id or username no longer
+// resolves to a user
+type OrphanedTweet struct {
+	ID            string `json:"id"`
+	UserID        *int64 `json:"user_id"` // users.id the tweet is linked to, if any
+	TweeterUserID string `json:"tweeter_user_id"`
+	Username      string `json:"username"`
+	Reason        string `json:"reason"` // One of the Orphan reasons
+}
+
+// OrphanReport lists the orphaned tweets, most recent first
+type OrphanReport struct {
+	Total  int             `json:"total"` // All orphaned tweets, which may be more than listed
+	Tweets []OrphanedTweet `json:"tweets"`
+}
+
+// orphanedTweetsCondition matches the tweets t, left joined with their user
+// u, that are orphaned. Usernames are compared case-insensitively since
+// older rows may not be normalized.
+const orphanedTweetsCondition = `
+	u.id IS NULL
+	OR NOT EXISTS (SELECT 1 FROM users n WHERE LOWER(n.username) = LOWER(t.username))`
+
+// unresolvedOrphansCondition leaves out the tweets t whose user the repair
+// failed to find within the last %s seconds, a query parameter
+const unresolvedOrphansCondition = `
+	AND (t.orphan_unresolved_at IS NULL OR t.orphan_unresolved_at < NOW() - %s * INTERVAL '1 second')`
+
+// FindOrphanedTweets reports the stored tweets whose user_id doesn't match
+// a user, e.g. because the user was never linked, or whose username doesn't
+// match one, e.g. because the user changed it. At most limit tweets are
+// listed. It only reads.
+func FindOrphanedTweets(ctx context.Context, db *sql.DB, limit int) (*OrphanReport, error) {
+	return findOrphanedTweets(ctx, db, limit, false)
+}
+
+// findRepairableOrphans reports the orphaned tweets like FindOrphanedTweets,
+// leaving out those whose user the repair recently failed to find
+func findRepairableOrphans(ctx context.Context, db *sql.DB, limit int) (*OrphanReport, error) {
+	return findOrphanedTweets(ctx, db, limit, true)
+}
+
+func findOrphanedTweets(ctx context.Context, db *sql.DB, limit int, skipUnresolved bool) (*OrphanReport, error) {
+	countCondition := orphanedTweetsCondition
+	listCondition := orphanedTweetsCondition
+	var countArgs []interface{}
+	listArgs := []interface{}{limit}
+	if skipUnresolved {
+		countCondition = "(" + orphanedTweetsCondition + ")" + fmt.Sprintf(unresolvedOrphansCondition, "$1")
+		listCondition = "(" + orphanedTweetsCondition + ")" + fmt.Sprintf(unresolvedOrphansCondition, "$2")
+		countArgs = append(countArgs, orphanRetryAfter.Seconds())
+		listArgs = append(listArgs, orphanRetryAfter.Seconds())
+	}
+
+	report := &OrphanReport{Tweets: make([]OrphanedTweet, 0)}
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tweets t
+		LEFT JOIN users u ON u.id = t.user_id
+		WHERE `+countCondition, countArgs...).Scan(&report.Total); err != nil {
+		return nil, fmt.Errorf("error counting orphaned tweets: %v", err)
+	}
+	if report.Total == 0 {
+		return report, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.id, t.user_id, COALESCE(t.tweeter_user_id, ''), COALESCE(t.username, ''), u.id IS NULL
+		FROM tweets t
+		LEFT JOIN users u ON u.id = t.user_id
+		WHERE `+listCondition+`
+		ORDER BY t.time_parsed DESC NULLS LAST
+		LIMIT $1`, listArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying orphaned tweets: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tweet OrphanedTweet
+		var userID sql.NullInt64
+		var missingUser bool
+		if err := rows.Scan(&tweet.ID, &userID, &tweet.TweeterUserID, &tweet.Username, &missingUser); err != nil {
+			return nil, fmt.Errorf("error scanning orphaned tweet: %v", err)
+		}
+		if userID.Valid {
+			tweet.UserID = &userID.Int64
+		}
+		tweet.Reason = OrphanUnknownUsername
+		if missingUser {
+			tweet.Reason = OrphanMissingUser
+		}
+		report.Tweets = append(report.Tweets, tweet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading orphaned tweets: %v", err)
+	}
+	return report, nil
+}
+
+// orphanGroup is the orphaned tweets of one author
+type orphanGroup struct {
+	TweeterUserID string
+	Username      string
+	TweetIDs      []string
+}
+
+// groupOrphans groups orphaned tweets by author, the Twitter user ID or
+// else the username, so each author is resolved once. Groups keep the order
+// in which their authors first appear.
+func groupOrphans(tweets []OrphanedTweet) []*orphanGroup {
+	var groups []*orphanGroup
+	byKey := make(map[string]*orphanGroup)
+	for _, tweet := range tweets {
+		key := "id:" + tweet.TweeterUserID
+		if tweet.TweeterUserID == "" {
+			key = "username:" + strings.ToLower(tweet.Username)
+		}
+		group, ok := byKey[key]
+		if !ok {
+			group = &orphanGroup{TweeterUserID: tweet.TweeterUserID, Username: tweet.Username}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.TweetIDs = append(group.TweetIDs, tweet.ID)
+	}
+	return groups
+}
+
+// RepairOrphanedTweets re-links orphaned tweets to their user. The user is
+// looked up by the tweets' Twitter user ID first; failing that, the profile
+// of the tweets' username is fetched and the user with its ID or handle is
+// used. Tweets are then linked to the user's id and username. Tweets whose
+// user can't be found are logged for review and marked, so the repair skips
+// them for orphanRetryAfter. It returns the number of re-linked tweets.
+func RepairOrphanedTweets(ctx context.Context, db *sql.DB, agentManager *twitter.AgentManager, logger logging.Logger, tweets []OrphanedTweet) (int64, error) {
+	var relinked int64
+	for _, group := range groupOrphans(tweets) {
+		userID, username, err := resolveOrphanUser(ctx, db, agentManager, group)
+		if err != nil {
+			logger.Error("Error resolving the user of %d orphaned tweets of %s: %v", len(group.TweetIDs), group.Username, err)
+			markUnresolvedOrphans(ctx, db, logger, group)
+			continue
+		}
+		if userID == 0 {
+			logger.Warning("No user found for %d orphaned tweets of %s (user ID %q), review them: %s",
+				len(group.TweetIDs), group.Username, group.TweeterUserID, strings.Join(group.TweetIDs, ", "))
+			markUnresolvedOrphans(ctx, db, logger, group)
+			continue
+		}
+
+		res, err := db.ExecContext(ctx, `UPDATE tweets SET user_id = $1, username = $2, orphan_unresolved_at = NULL WHERE id = ANY($3)`,
+			userID, username, pq.Array(group.TweetIDs))
+		if err != nil {
+			return relinked, fmt.Errorf("error re-linking tweets of %s: %v", group.Username, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return relinked, fmt.Errorf("error counting re-linked tweets: %v", err)
+		}
+		relinked += n
+		logger.Info("Re-linked %d orphaned tweets of %s to user %s", n, group.Username, username)
+	}
+	return relinked, nil
+}
+
+// markUnresolvedOrphans records that the user of the tweets of group
+// couldn't be found. Failing to do so is only logged.
+func markUnresolvedOrphans(ctx context.Context, db *sql.DB, logger logging.Logger, group *orphanGroup) {
+	if _, err := db.ExecContext(ctx, `UPDATE tweets SET orphan_unresolved_at = NOW() WHERE id = ANY($1)`, pq.Array(group.TweetIDs)); err != nil {
+		logger.Error("Error marking the orphaned tweets of %s as unresolved: %v", group.Username, err)
+	}
+}
+
+// resolveOrphanUser returns the id and username of the user the tweets of
+// group belong to, or a zero id when there is none
+func resolveOrphanUser(ctx context.Context, db *sql.DB, agentManager *twitter.AgentManager, group *orphanGroup) (int64, string, error) {
+	var id int64
+	var username string
+	if group.TweeterUserID != "" {
+		err := db.QueryRowContext(ctx, `SELECT id, username FROM users WHERE user_id = $1 LIMIT 1`, group.TweeterUserID).Scan(&id, &username)
+		if err == nil {
+			return id, username, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, "", fmt.Errorf("error looking up user %s: %v", group.TweeterUserID, err)
+		}
+	}
+	if group.Username == "" {
+		return 0, "", nil
+	}
+
+	// The stored user may not have its Twitter user ID yet, or the tweets
+	// may carry a handle the user changed to
+	profileData, _, err := agentManager.GetProfile(ctx, group.Username, false)
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting profile for %s: %v", group.Username, err)
+	}
+	profileBytes, err := json.Marshal(profileData)
+	if err != nil {
+		return 0, "", fmt.Errorf("error marshaling profile data: %v", err)
+	}
+	var profile twitter.UserProfile
+	if err := json.Unmarshal(profileBytes, &profile); err != nil {
+		return 0, "", fmt.Errorf("error unmarshaling profile data: %v", err)
+	}
+
+	err = db.QueryRowContext(ctx, `
+		SELECT id, username FROM users
+		WHERE (user_id = $1 AND user_id <> '') OR LOWER(username) = LOWER($2)
+		ORDER BY user_id = $1 DESC
+		LIMIT 1`, profile.UserID, profile.Username).Scan(&id, &username)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("error looking up user %s: %v", profile.Username, err)
+	}
+	return id, username, nil
+}
+
+// StartOrphanCheck starts a goroutine that looks for orphaned tweets every
+// config.Interval. They are only logged unless config.Repair is set, in
+// which case they are re-linked to their user where it can be found.
+func StartOrphanCheck(ctx context.Context, db *sql.DB, agentManager *twitter.AgentManager, config OrphanCheckConfig, logger logging.Logger) {
+	if config.Interval <= 0 {
+		config.Interval = DefaultOrphanCheckInterval
+	}
+	if config.Limit <= 0 {
+		config.Limit = DefaultOrphanReportLimit
+	}
+
+	mode := "reporting"
+	if config.Repair {
+		mode = "repairing"
+	}
+	logger.Info("Starting orphaned tweet check goroutine, %s orphaned tweets every %s", mode, config.Interval)
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			find := FindOrphanedTweets
+			if config.Repair {
+				find = findRepairableOrphans
+			}
+			report, err := find(ctx, db, config.Limit)
+			switch {
+			case err != nil:
+				logger.Error("Error looking for orphaned tweets: %v", err)
+			case report.Total == 0:
+				logger.Debug("No orphaned tweets found")
+			case !config.Repair:
+				logger.Warning("Found %d orphaned tweets, see /api/admin/orphans", report.Total)
+			default:
+				logger.Warning("Found %d orphaned tweets, repairing up to %d", report.Total, len(report.Tweets))
+				relinked, err := RepairOrphanedTweets(ctx, db, agentManager, logger, report.Tweets)
+				if err != nil {
+					logger.Error("Error repairing orphaned tweets: %v", err)
+				}
+				logger.Info("Re-linked %d of %d orphaned tweets", relinked, report.Total)
+			}
+
+			select {
+			case <-ctx.Done():
+				logger.Info("Stopping orphaned tweet check due to context cancellation")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupOrphans(t *testing.T) {
+	groups := groupOrphans([]OrphanedTweet{
+		{ID: "1", TweeterUserID: "100", Username: "alice"},
+		{ID: "2", Username: "Bob"},
+		{ID: "3", TweeterUserID: "100", Username: "alice_renamed"},
+		{ID: "4", Username: "bob"},
+	})
+
+	assert.Equal(t, []*orphanGroup{
+		{TweeterUserID: "100", Username: "alice", TweetIDs: []string{"1", "3"}},
+		{Username: "Bob", TweetIDs: []string{"2", "4"}},
+	}, groups)
+	assert.Empty(t, groupOrphans(nil))
+}
+
+func TestFindOrphanedTweets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM tweets t`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`LIMIT \$1`).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "tweeter_user_id", "username", "missing_user"}).
+			AddRow("1", nil, "100", "alice", true).
+			AddRow("2", 7, "", "old_handle", false))
+	report, err := FindOrphanedTweets(ctx, db, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	userID := int64(7)
+	assert.Equal(t, []OrphanedTweet{
+		{ID: "1", TweeterUserID: "100", Username: "alice", Reason: OrphanMissingUser},
+		{ID: "2", UserID: &userID, Username: "old_handle", Reason: OrphanUnknownUsername},
+	}, report.Tweets)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The repair leaves out the tweets whose user it recently failed to find
+	retryAfter := orphanRetryAfter.Seconds()
+	mock.ExpectQuery(`orphan_unresolved_at < NOW\(\) - \$1 \* INTERVAL`).WithArgs(retryAfter).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`orphan_unresolved_at < NOW\(\) - \$2 \* INTERVAL`).WithArgs(2, retryAfter).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "tweeter_user_id", "username", "missing_user"}).AddRow("1", nil, "100", "alice", true))
+	report, err = findRepairableOrphans(ctx, db, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Total)
+	assert.Len(t, report.Tweets, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepairOrphanedTweets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	tweets := []OrphanedTweet{
+		{ID: "1", TweeterUserID: "100", Username: "alice_old"},
+		{ID: "2", TweeterUserID: "200"},
+		{ID: "3", TweeterUserID: "100", Username: "alice_old"},
+	}
+
+	// Alice's tweets are found by her Twitter user ID and re-linked
+	mock.ExpectQuery(`SELECT id, username FROM users WHERE user_id = \$1`).WithArgs("100").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(5, "alice"))
+	mock.ExpectExec(`UPDATE tweets SET user_id = \$1, username = \$2, orphan_unresolved_at = NULL`).
+		WithArgs(5, "alice", pq.Array([]string{"1", "3"})).WillReturnResult(sqlmock.NewResult(0, 2))
+	// Nobody has user ID 200 and the tweet has no username to look up, so
+	// it's marked for the repair to skip
+	mock.ExpectQuery(`SELECT id, username FROM users WHERE user_id = \$1`).WithArgs("200").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}))
+	mock.ExpectExec(`UPDATE tweets SET orphan_unresolved_at = NOW\(\) WHERE id = ANY\(\$1\)`).
+		WithArgs(pq.Array([]string{"2"})).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	relinked, err := RepairOrphanedTweets(context.Background(), db, nil, logging.Default(), tweets)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), relinked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,205 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/asabya/x-go/pkg/logging"
+)
+
+// maxPendingAttempts is how many times processing a queued username may fail
+// before it is dropped from pending_smart_users. The periodic smart user
+// updates still pick it up, so a user that keeps failing isn't retried on
+// every start.
+const maxPendingAttempts = 3
+
+// UserQueue hands usernames from HTTP handlers to a background task. Unlike
+// a bare channel it can be closed while handlers may still be sending:
+// usernames enqueued after Close are dropped instead of panicking.
+type UserQueue struct {
+	mu     sync.RWMutex
+	ch     chan string
+	closed bool
+
+	// db, when set, keeps the queued usernames in pending_smart_users until
+	// they are processed, so they survive a restart
+	db     *sql.DB
+	logger logging.Logger
+
+	// queued holds the usernames of a persistent queue that are buffered or
+	// being processed, until Done is called for them
+	queuedMu sync.Mutex
+	queued   map[string]bool
+}
+
+// NewUserQueue creates a queue buffering up to size usernames
+func NewUserQueue(size int) *UserQueue {
+	return &UserQueue{ch: make(chan string, size)}
+}
+
+// NewPersistentUserQueue creates a queue buffering up to size usernames that
+// also stores them in pending_smart_users until Done is called for them.
+// Usernames left pending by a previous run are queued again with Restore.
+func NewPersistentUserQueue(db *sql.DB, size int, logger logging.Logger) *UserQueue {
+	return &UserQueue{ch: make(chan string, size), db: db, logger: logger, queued: make(map[string]bool)}
+}
+
+// Enqueue adds username without blocking. It returns false when the queue
+// is full or closed. A persistent queue doesn't queue a username that is
+// already buffered or being processed again, and keeps a username that didn't
+// fit for Restore. A username left pending by an overflow or a failed
+// attempt is queued again.
+func (q *UserQueue) Enqueue(username string) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+
+	if q.db != nil {
+		if !q.markQueued(username) {
+			q.logger.Debug("Smart user %s is already queued", username)
+			return true
+		}
+		if _, err := q.db.Exec(`
+			INSERT INTO pending_smart_users (username) VALUES ($1)
+			ON CONFLICT (username) DO NOTHING`, username); err != nil {
+			// Still queue it, it just won't survive a restart
+			q.logger.Error("Error storing pending smart user %s: %v", username, err)
+		}
+	}
+
+	select {
+	case q.ch <- username:
+		return true
+	default:
+		q.unmarkQueued(username)
+		return false
+	}
+}
+
+// markQueued records username as queued in a persistent queue, returning
+// false when it already is
+func (q *UserQueue) markQueued(username string) bool {
+	q.queuedMu.Lock()
+	defer q.queuedMu.Unlock()
+	if q.queued[username] {
+		return false
+	}
+	q.queued[username] = true
+	return true
+}
+
+func (q *UserQueue) unmarkQueued(username string) {
+	q.queuedMu.Lock()
+	defer q.queuedMu.Unlock()
+	delete(q.queued, username)
+}
+
+// Restore queues the usernames a previous run left pending, oldest first,
+// and returns how many were queued. Unlike Enqueue it waits for room in the
+// queue, so call it once the consumer is running. It stops early when ctx is
+// done or the queue is closed.
+func (q *UserQueue) Restore(ctx context.Context) (int, error) {
+	if q.db == nil {
+		return 0, nil
+	}
+
+	rows, err := q.db.QueryContext(ctx, "SELECT username FROM pending_smart_users ORDER BY enqueued_at")
+	if err != nil {
+		return 0, err
+	}
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		usernames = append(usernames, username)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	for _, username := range usernames {
+		if !q.markQueued(username) {
+			continue
+		}
+		if !q.send(ctx, username) {
+			q.unmarkQueued(username)
+			return restored, ctx.Err()
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// send adds username, waiting for room in the queue until ctx is done. It
+// returns false when the username wasn't added.
+func (q *UserQueue) send(ctx context.Context, username string) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.ch <- username:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Done records the outcome of processing username. Its pending row is only
+// removed once it was processed, or failed maxPendingAttempts times, so a
+// restart in between queues it again. Failures aren't queued again right
+// away, which would hammer Twitter with a user that keeps failing, but the
+// next Enqueue of the username queues it.
+func (q *UserQueue) Done(username string, processErr error) {
+	if q.db == nil {
+		return
+	}
+	defer q.unmarkQueued(username)
+
+	if processErr == nil {
+		if _, err := q.db.Exec("DELETE FROM pending_smart_users WHERE username = $1", username); err != nil {
+			q.logger.Error("Error removing pending smart user %s: %v", username, err)
+		}
+		return
+	}
+
+	if _, err := q.db.Exec("UPDATE pending_smart_users SET attempts = attempts + 1 WHERE username = $1", username); err != nil {
+		q.logger.Error("Error counting failed attempt of pending smart user %s: %v", username, err)
+		return
+	}
+	result, err := q.db.Exec("DELETE FROM pending_smart_users WHERE username = $1 AND attempts >= $2", username, maxPendingAttempts)
+	if err != nil {
+		q.logger.Error("Error removing pending smart user %s: %v", username, err)
+		return
+	}
+	if removed, _ := result.RowsAffected(); removed > 0 {
+		q.logger.Warning("Giving up on pending smart user %s after %d failed attempts", username, maxPendingAttempts)
+	}
+}
+
+// Usernames returns the channel the queued usernames are received from. It
+// is closed by Close once the buffered usernames have been drained.
+func (q *UserQueue) Usernames() <-chan string {
+	return q.ch
+}
+
+// Close stops the queue from accepting usernames. It is safe to call more
+// than once and concurrently with Enqueue. Usernames still buffered stay
+// pending in a persistent queue.
+func (q *UserQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+}
@@ -0,0 +1,163 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserQueue(t *testing.T) {
+	q := NewUserQueue(2)
+	assert.True(t, q.Enqueue("alice"))
+	assert.True(t, q.Enqueue("bob"))
+	assert.False(t, q.Enqueue("carol"), "full queue")
+
+	q.Close()
+	q.Close()
+	assert.False(t, q.Enqueue("dave"), "closed queue")
+
+	var received []string
+	for username := range q.Usernames() {
+		received = append(received, username)
+	}
+	assert.Equal(t, []string{"alice", "bob"}, received)
+}
+
+// Closing the queue while handlers are still enqueueing must not panic, which
+// is what happened when the shutdown closed a bare channel
+func TestUserQueueCloseWhileSending(t *testing.T) {
+	q := NewUserQueue(10)
+	go func() {
+		for range q.Usernames() {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				q.Enqueue(fmt.Sprintf("user%d-%d", i, j))
+			}
+		}(i)
+	}
+	q.Close()
+	wg.Wait()
+}
+
+// Restore waits for room in the queue, unlike Enqueue, but gives up once its
+// context is done or the queue is closed
+func TestUserQueueSend(t *testing.T) {
+	q := NewUserQueue(1)
+	assert.True(t, q.send(context.Background(), "alice"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, q.send(ctx, "bob"), "full queue")
+
+	sent := make(chan bool, 1)
+	go func() { sent <- q.send(context.Background(), "carol") }()
+	assert.Equal(t, "alice", <-q.Usernames())
+	assert.True(t, <-sent)
+
+	q.Close()
+	assert.False(t, q.send(context.Background(), "dave"), "closed queue")
+}
+
+// Without a database there is nothing to restore and Done has no effect
+func TestUserQueueWithoutDB(t *testing.T) {
+	q := NewUserQueue(1)
+	restored, err := q.Restore(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, restored)
+	q.Done("alice", nil)
+	q.Done("alice", errors.New("failed"))
+}
+
+func TestPersistentUserQueueEnqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	q := NewPersistentUserQueue(db, 1, logging.Default())
+
+	mock.ExpectExec(`INSERT INTO pending_smart_users`).WithArgs("alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.True(t, q.Enqueue("alice"))
+	// Already buffered, so neither stored nor queued again
+	assert.True(t, q.Enqueue("alice"))
+
+	// A username that doesn't fit is kept pending
+	mock.ExpectExec(`INSERT INTO pending_smart_users`).WithArgs("bob").WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.False(t, q.Enqueue("bob"), "full queue")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// A failed attempt is counted and the row kept
+	assert.Equal(t, "alice", <-q.Usernames())
+	mock.ExpectExec(`UPDATE pending_smart_users SET attempts = attempts \+ 1`).WithArgs("alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM pending_smart_users WHERE username = \$1 AND attempts >= \$2`).
+		WithArgs("alice", maxPendingAttempts).WillReturnResult(sqlmock.NewResult(0, 0))
+	q.Done("alice", errors.New("failed"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Still pending but no longer buffered, so it is queued again
+	mock.ExpectExec(`INSERT INTO pending_smart_users`).WithArgs("alice").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.True(t, q.Enqueue("alice"))
+	assert.Equal(t, "alice", <-q.Usernames())
+
+	mock.ExpectExec(`DELETE FROM pending_smart_users WHERE username = \$1`).WithArgs("alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	q.Done("alice", nil)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPersistentUserQueueDoneGivesUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	q := NewPersistentUserQueue(db, 1, logging.Default())
+
+	mock.ExpectExec(`UPDATE pending_smart_users SET attempts = attempts \+ 1`).WithArgs("alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM pending_smart_users WHERE username = \$1 AND attempts >= \$2`).
+		WithArgs("alice", maxPendingAttempts).WillReturnResult(sqlmock.NewResult(0, 1))
+	q.Done("alice", errors.New("failed"))
+
+	// Counting the attempt failed, so the row isn't removed
+	mock.ExpectExec(`UPDATE pending_smart_users SET attempts = attempts \+ 1`).WithArgs("bob").WillReturnError(errors.New("connection refused"))
+	q.Done("bob", errors.New("failed"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPersistentUserQueueRestore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	q := NewPersistentUserQueue(db, 2, logging.Default())
+
+	mock.ExpectQuery(`SELECT username FROM pending_smart_users ORDER BY enqueued_at`).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("alice").AddRow("bob"))
+	restored, err := q.Restore(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restored)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Restored usernames count as buffered
+	assert.True(t, q.Enqueue("alice"))
+	assert.Equal(t, "alice", <-q.Usernames())
+	assert.Equal(t, "bob", <-q.Usernames())
+
+	// Restore gives up once its context is done
+	mock.ExpectQuery(`SELECT username FROM pending_smart_users`).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("carol").AddRow("dave").AddRow("erin"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	restored, err = q.Restore(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, restored)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+)
+
+const (
+	// DefaultRetentionInterval is how often old tweets are pruned
+	DefaultRetentionInterval = 24 * time.Hour
+	// DefaultRetentionBatchSize is the number of tweets deleted per statement
+	DefaultRetentionBatchSize = 1000
+)
+
+// RetentionConfig configures the pruning of old tweets
+type RetentionConfig struct {
+	MaxAge    time.Duration // Tweets posted longer ago than this are deleted
+	Interval  time.Duration // Time between pruning runs
+	BatchSize int           // Tweets deleted per statement, to keep locks short
+}
+
+// StartTweetRetention starts a goroutine that deletes stored tweets older
+// than config.MaxAge on every interval. Pinned tweets are kept.
+func StartTweetRetention(ctx context.Context, db *sql.DB, config RetentionConfig, logger logging.Logger) {
+	if config.Interval <= 0 {
+		config.Interval = DefaultRetentionInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultRetentionBatchSize
+	}
+
+	logger.Info("Starting tweet retention goroutine, pruning tweets older than %s every %s", config.MaxAge, config.Interval)
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			pruned, err := pruneTweets(ctx, db, time.Now().Add(-config.MaxAge), config.BatchSize)
+			if err != nil {
+				logger.Error("Error pruning old tweets: %v", err)
+			}
+			logger.Info("Pruned %d tweets older than %s", pruned, config.MaxAge)
+
+			// Let Postgres reuse the space of the deleted rows and refresh the planner statistics
+			if pruned > 0 {
+				if _, err := db.ExecContext(ctx, "VACUUM ANALYZE tweets"); err != nil {
+					logger.Error("Error vacuuming tweets table: %v", err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				logger.Info("Stopping tweet retention due to context cancellation")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// pruneTweets deletes unpinned tweets posted before cutoff, batchSize rows at
+// a time, and returns the number of deleted tweets
+func pruneTweets(ctx context.Context, db *sql.DB, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		res, err := db.ExecContext(ctx, `
+			DELETE FROM tweets WHERE id IN (
+				SELECT id FROM tweets
+				WHERE time_parsed < $1 AND is_pin IS NOT TRUE
+				LIMIT $2
+			)`, cutoff, batchSize)
+		if err != nil {
+			return total, fmt.Errorf("error deleting tweets: %v", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("error counting deleted tweets: %v", err)
+		}
+		total += n
+		if n < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/lib/pq"
+)
+
+// Events webhooks can subscribe to
+const (
+	EventNewTweet       = "tweet.new"        // A tracked user posted a tweet that wasn't stored yet
+	EventEngagement     = "tweet.engagement" // A stored tweet crossed an engagement alert threshold
+	EventSmartUserAdded = "smart_user.added" // A smart follower was saved for the first time
+)
+
+// WebhookEvents lists the events webhooks can subscribe to
+var WebhookEvents = []string{EventNewTweet, EventEngagement, EventSmartUserAdded}
+
+// IsWebhookEvent reports whether event is one of WebhookEvents
+func IsWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of a delivery's body,
+// keyed with the webhook's secret and prefixed with "sha256="
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// WebhookEventHeader carries the event of a delivery
+const WebhookEventHeader = "X-Webhook-Event"
+
+// Webhook is a registered URL receiving events
+type Webhook struct {
+	ID     int64    `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs the deliveries. It is only returned when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookEvent is the JSON body posted to webhooks
+type WebhookEvent struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// NewTweetEvent is the data of an EventNewTweet
+type NewTweetEvent struct {
+	Username string `json:"username"`
+	Tweet    Tweet  `json:"tweet"`
+}
+
+// SmartUserAddedEvent is the data of an EventSmartUserAdded
+type SmartUserAddedEvent struct {
+	Username       string `json:"username"`
+	UserID         string `json:"user_id"`
+	Name           string `json:"name"`
+	FollowersCount int    `json:"followers_count"`
+	// FollowerOf is the user the smart follower was fetched for
+	FollowerOf string `json:"follower_of"`
+}
+
+// NewWebhookSecret returns a random secret for signing deliveries
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateWebhook registers url for events, returning the webhook with its secret
+func CreateWebhook(ctx context.Context, db *sql.DB, url string, events []string, secret string) (*Webhook, error) {
+	hook := &Webhook{URL: url, Events: events, Secret: secret}
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (url, events, secret) VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		url, pq.Array(events), secret).Scan(&hook.ID, &hook.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting webhook: %v", err)
+	}
+	return hook, nil
+}
+
+// ListWebhooks returns the registered webhooks without their secrets
+func ListWebhooks(ctx context.Context, db *sql.DB) ([]Webhook, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, url, events, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		var hook Webhook
+		if err := rows.Scan(&hook.ID, &hook.URL, pq.Array(&hook.Events), &hook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning webhook: %v", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading webhooks: %v", err)
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook removes the webhook with id, reporting whether it existed
+func DeleteWebhook(ctx context.Context, db *sql.DB, id int64) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting webhook: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting webhook: %v", err)
+	}
+	return deleted > 0, nil
+}
+
+// WebhookConfig configures the delivery of webhook events
+type WebhookConfig struct {
+	QueueSize   int           // Events buffered for delivery, and per webhook; events that don't fit are dropped
+	Workers     int           // Events matched to their webhooks concurrently
+	MaxAttempts int           // Attempts per delivery, including the first
+	RetryDelay  time.Duration // Delay before the first retry, doubled for every further one
+	Timeout     time.Duration // Timeout of a single delivery attempt
+}
+
+// DefaultWebhookConfig is used for the fields of a WebhookConfig left zero
+var DefaultWebhookConfig = WebhookConfig{
+	QueueSize:   1000,
+	Workers:     4,
+	MaxAttempts: 5,
+	RetryDelay:  time.Second,
+	Timeout:     10 * time.Second,
+}
+
+// hookIdleTimeout is how long the delivery goroutine of a webhook waits for
+// more deliveries before it exits
+const hookIdleTimeout = time.Minute
+
+// hookDelivery is an event waiting in the queue of the webhook it goes to
+type hookDelivery struct {
+	hook  Webhook
+	event string
+	body  []byte
+}
+
+// Webhooks delivers events to the registered webhooks subscribed to them.
+// Events are queued and matched to their webhooks by background workers, and
+// every webhook has its own delivery queue, so a slow or failing webhook
+// holds up neither the task publishing the event nor the other webhooks. A
+// nil *Webhooks drops every event.
+type Webhooks struct {
+	db     *sql.DB
+	config WebhookConfig
+	queue  chan WebhookEvent
+	client *http.Client
+	logger logging.Logger
+
+	hookMutex  sync.Mutex
+	hookQueues map[int64]chan hookDelivery // by webhook ID, while its goroutine runs
+}
+
+// NewWebhooks creates a Webhooks delivering to the webhooks registered in db.
+// Deliveries start with Start.
+func NewWebhooks(db *sql.DB, config WebhookConfig, logger logging.Logger) *Webhooks {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultWebhookConfig.QueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultWebhookConfig.Workers
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultWebhookConfig.MaxAttempts
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = DefaultWebhookConfig.RetryDelay
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultWebhookConfig.Timeout
+	}
+	return &Webhooks{
+		db:     db,
+		config: config,
+		queue:  make(chan WebhookEvent, config.QueueSize),
+		client: &http.Client{Timeout: config.Timeout},
+		logger: logger,
+
+		hookQueues: make(map[int64]chan hookDelivery),
+	}
+}
+
+// Publish queues event with data for delivery without blocking. It returns
+// false when the event is dropped because the queue is full.
+func (w *Webhooks) Publish(event string, data interface{}) bool {
+	if w == nil {
+		return false
+	}
+	select {
+	case w.queue <- WebhookEvent{Event: event, CreatedAt: time.Now().UTC(), Data: data}:
+		return true
+	default:
+		w.logger.Warning("Webhook queue full, dropping %s event", event)
+		return false
+	}
+}
+
+// Start starts the workers delivering queued events until ctx is done
+func (w *Webhooks) Start(ctx context.Context) {
+	w.logger.Info("Starting %d webhook delivery workers", w.config.Workers)
+	for i := 0; i < w.config.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-w.queue:
+					w.dispatch(ctx, event)
+				}
+			}
+		}()
+	}
+}
+
+// dispatch queues event for delivery to every webhook subscribed to it
+func (w *Webhooks) dispatch(ctx context.Context, event WebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		w.logger.Error("Error marshaling %s event: %v", event.Event, err)
+		return
+	}
+
+	rows, err := w.db.QueryContext(ctx, "SELECT id, url, secret FROM webhooks WHERE $1 = ANY(events)", event.Event)
+	if err != nil {
+		w.logger.Error("Error querying webhooks for %s event: %v", event.Event, err)
+		return
+	}
+	var hooks []Webhook
+	func() {
+		defer rows.Close()
+		for rows.Next() {
+			var hook Webhook
+			if err := rows.Scan(&hook.ID, &hook.URL, &hook.Secret); err != nil {
+				w.logger.Error("Error scanning webhook: %v", err)
+				continue
+			}
+			hooks = append(hooks, hook)
+		}
+	}()
+	if err := rows.Err(); err != nil {
+		w.logger.Error("Error reading webhooks for %s event: %v", event.Event, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		w.enqueue(ctx, hookDelivery{hook: hook, event: event.Event, body: body})
+	}
+}
+
+// enqueue adds delivery to the queue of its webhook without blocking,
+// starting the webhook's delivery goroutine when it isn't running. The
+// delivery is dropped when the queue is full.
+func (w *Webhooks) enqueue(ctx context.Context, delivery hookDelivery) {
+	// Sent under the lock so the goroutine can't exit between the lookup and the send
+	w.hookMutex.Lock()
+	defer w.hookMutex.Unlock()
+	queue, ok := w.hookQueues[delivery.hook.ID]
+	if !ok {
+		queue = make(chan hookDelivery, w.config.QueueSize)
+		w.hookQueues[delivery.hook.ID] = queue
+		go w.deliverQueued(ctx, delivery.hook.ID, queue)
+	}
+	select {
+	case queue <- delivery:
+	default:
+		w.logger.Warning("Delivery queue of webhook %d full, dropping %s event", delivery.hook.ID, delivery.event)
+	}
+}
+
+// deliverQueued delivers the queue of the webhook with the given ID in
+// order, until ctx is done or no delivery arrives for hookIdleTimeout
+func (w *Webhooks) deliverQueued(ctx context.Context, hookID int64, queue chan hookDelivery) {
+	idle := time.NewTimer(hookIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-queue:
+			if err := w.deliver(ctx, delivery.hook, delivery.event, delivery.body); err != nil {
+				w.logger.Error("Giving up delivering %s event to webhook %d: %v", delivery.event, hookID, err)
+			}
+			idle.Reset(hookIdleTimeout)
+		case <-idle.C:
+			w.hookMutex.Lock()
+			if len(queue) == 0 {
+				delete(w.hookQueues, hookID)
+				w.hookMutex.Unlock()
+				return
+			}
+			w.hookMutex.Unlock()
+			idle.Reset(hookIdleTimeout)
+		}
+	}
+}
+
+// deliver posts body to hook, retrying failed attempts with exponential
+// backoff up to the configured number of attempts
+func (w *Webhooks) deliver(ctx context.Context, hook Webhook, event string, body []byte) error {
+	delay := w.config.RetryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = w.post(ctx, hook, event, body); err == nil {
+			return nil
+		}
+		if attempt >= w.config.MaxAttempts {
+			return err
+		}
+		w.logger.Warning("Delivering %s event to webhook %d failed (attempt %d/%d), retrying in %s: %v",
+			event, hook.ID, attempt, w.config.MaxAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post makes a single signed delivery of body to hook
+func (w *Webhooks) post(ctx context.Context, hook Webhook, event string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookBody(hook.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookBody returns the WebhookSignatureHeader value of body signed with secret
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	body := []byte(`{"event":"tweet.new"}`)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		received, _ := io.ReadAll(r.Body)
+		assert.Equal(t, body, received)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, EventNewTweet, r.Header.Get(WebhookEventHeader))
+		assert.Equal(t, SignWebhookBody("secret", received), r.Header.Get(WebhookSignatureHeader))
+		// The first two attempts fail and are retried
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	webhooks := NewWebhooks(nil, WebhookConfig{MaxAttempts: 3, RetryDelay: time.Millisecond}, logging.Default())
+	hook := Webhook{ID: 1, URL: server.URL, Secret: "secret"}
+	assert.NoError(t, webhooks.deliver(context.Background(), hook, EventNewTweet, body))
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	webhooks.config.MaxAttempts = 2
+	assert.EqualError(t, webhooks.deliver(context.Background(), hook, EventNewTweet, body), "webhook responded with status 502")
+	assert.Equal(t, 2, attempts)
+}
+
+func TestWebhookQueuesPerHook(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	delivered := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer fast.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhooks := NewWebhooks(nil, WebhookConfig{}, logging.Default())
+	body := []byte(`{"event":"tweet.new"}`)
+	webhooks.enqueue(ctx, hookDelivery{hook: Webhook{ID: 1, URL: slow.URL}, event: EventNewTweet, body: body})
+	webhooks.enqueue(ctx, hookDelivery{hook: Webhook{ID: 2, URL: fast.URL}, event: EventNewTweet, body: body})
+
+	// The webhook stuck on its delivery doesn't hold up the other one
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery to the fast webhook waited for the slow one")
+	}
+}
+
+func TestSignWebhookBody(t *testing.T) {
+	// HMAC-SHA256 of "hello" keyed with "key"
+	assert.Equal(t, "sha256=9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b", SignWebhookBody("key", []byte("hello")))
+}
+
+func TestWebhooksPublish(t *testing.T) {
+	// A nil Webhooks drops events
+	var disabled *Webhooks
+	assert.False(t, disabled.Publish(EventNewTweet, nil))
+
+	// Events published to a full queue are dropped instead of blocking
+	webhooks := NewWebhooks(nil, WebhookConfig{QueueSize: 1}, logging.Default())
+	assert.True(t, webhooks.Publish(EventSmartUserAdded, SmartUserAddedEvent{Username: "bob"}))
+	assert.False(t, webhooks.Publish(EventSmartUserAdded, SmartUserAddedEvent{Username: "carol"}))
+
+	event := <-webhooks.queue
+	assert.Equal(t, EventSmartUserAdded, event.Event)
+	assert.Equal(t, SmartUserAddedEvent{Username: "bob"}, event.Data)
+}
@@ -2,15 +2,18 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"github.com/asabya/x-go/pkg/getmoni"
+	"github.com/asabya/x-go/pkg/logging"
 	"github.com/asabya/x-go/pkg/twitter"
+	"github.com/asabya/x-go/pkg/twitter/auth"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -21,8 +24,8 @@ func loadCookies(cookieFile string) ([]*http.Cookie, error) {
 		return nil, fmt.Errorf("error reading cookies: %v", err)
 	}
 
-	var cookies []*http.Cookie
-	if err = json.Unmarshal(data, &cookies); err != nil {
+	cookies, err := auth.ParseCookies(data)
+	if err != nil {
 		return nil, fmt.Errorf("error unmarshaling cookies: %v", err)
 	}
 
@@ -45,19 +48,23 @@ func loadCookies(cookieFile string) ([]*http.Cookie, error) {
 }
 
 func main() {
-	// Set up logging
-	logger := log.New(os.Stdout, "[twitter-mcp] ", log.LstdFlags|log.Lshortfile)
+	// Set up logging, with the level taken from XGO_LOG_LEVEL
+	logLevel, err := logging.ParseLevel(os.Getenv("XGO_LOG_LEVEL"))
+	if err != nil {
+		log.Fatalf("Invalid XGO_LOG_LEVEL: %v", err)
+	}
+	logger := logging.New(os.Stdout, "[twitter-mcp] ", log.LstdFlags|log.Lshortfile, logLevel)
 
 	// Get XGO path from environment variable
 	xgoPath := os.Getenv("XGO_PATH")
 	if xgoPath == "" {
-		logger.Fatalf("XGO_PATH is not set")
+		logger.Fatal("XGO_PATH is not set")
 	}
 
 	// Create agent manager
-	agentManager, err := twitter.NewAgentManager(xgoPath)
+	agentManager, err := twitter.NewAgentManager(xgoPath, twitter.WithLogger(logger))
 	if err != nil {
-		logger.Fatalf("Failed to create agent manager: %v", err)
+		logger.Fatal("Failed to create agent manager: %v", err)
 	}
 
 	// Check if at least one agent is logged in
@@ -68,7 +75,7 @@ func main() {
 			break
 		}
 	}
-	logger.Printf("Has logged in agent: %v", hasLoggedInAgent)
+	logger.Info("Has logged in agent: %v", hasLoggedInAgent)
 
 	// Create a new MCP server with session configuration
 	s := server.NewMCPServer(
@@ -87,11 +94,33 @@ func main() {
 	// Get the first agent to register tools
 	firstAgent, err := agentManager.GetAgent(0)
 	if err != nil {
-		logger.Fatalf("Failed to get first agent: %v", err)
+		logger.Fatal("Failed to get first agent: %v", err)
+	}
+
+	// Enable GetMoni tools when an API key is configured
+	if os.Getenv("GETMONI_API_KEY") != "" {
+		getmoniClient := getmoni.NewGetMoni("")
+		getmoniClient.SetLogger(logger)
+		firstAgent.SetGetMoni(getmoniClient)
+	}
+
+	// Only register the tools allowed by XGO_TOOLS_ALLOW and XGO_TOOLS_DENY,
+	// e.g. to keep a logged-in server read-only
+	toolFilter := twitter.ToolFilter{
+		Allow: twitter.ParseToolList(os.Getenv("XGO_TOOLS_ALLOW")),
+		Deny:  twitter.ParseToolList(os.Getenv("XGO_TOOLS_DENY")),
+	}
+	if unknown := toolFilter.Unknown(); len(unknown) > 0 {
+		logger.Warning("Ignoring unknown tools in XGO_TOOLS_ALLOW or XGO_TOOLS_DENY: %s", strings.Join(unknown, ", "))
 	}
 
 	// Register tools from the first agent
-	for _, tool := range firstAgent.GetTools() {
+	tools := firstAgent.GetTools()
+	allowedTools := toolFilter.Apply(tools)
+	if disabled := len(tools) - len(allowedTools); disabled > 0 {
+		logger.Info("Disabled %d of %d tools", disabled, len(tools))
+	}
+	for _, tool := range allowedTools {
 		s.AddTool(tool.Tool, tool.Handler)
 	}
 
@@ -107,6 +136,6 @@ func main() {
 
 	// Start the server
 	if err := server.ServeStdio(s); err != nil {
-		logger.Printf("Server error: %v", err)
+		logger.Error("Server error: %v", err)
 	}
 }
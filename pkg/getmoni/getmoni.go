@@ -1,12 +1,15 @@
 package getmoni
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -44,12 +47,95 @@ func (l *DefaultLogger) Warning(format string, args ...interface{}) {
 	l.Printf("[WARNING] "+format, args...)
 }
 
-// GetMoni represents the GetMoni API client
+// SetLogger replaces the logger used by the client
+func (g *GetMoni) SetLogger(logger Logger) {
+	g.logger = logger
+}
+
+// GetMoni represents the GetMoni API client. GetMoni rate limits each API
+// key, so requests are spread over all the client's keys in turn.
 type GetMoni struct {
-	baseURL string
-	apiKey  string
-	client  *http.Client
-	logger  Logger
+	baseURL     string
+	client      *http.Client
+	logger      Logger
+	maxRetries  int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	keysMutex sync.Mutex
+	keys      []*apiKey
+	nextKey   int // Index in keys of the key to try first for the next request
+}
+
+// apiKey is a GetMoni API key and when it may be used again after a 429
+type apiKey struct {
+	value        string
+	number       int // 1-based position among the client's keys, for logs that mustn't show the key
+	limitedUntil time.Time
+}
+
+const (
+	// DefaultMaxRetries is the number of attempts made on a rate limited request
+	DefaultMaxRetries = 10
+	// DefaultBaseBackoff is the wait after the first rate limited attempt, doubled on each retry
+	DefaultBaseBackoff = time.Second
+	// DefaultMaxBackoff caps the wait between attempts, including waits asked for by Retry-After
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Option configures a GetMoni client
+type Option func(*GetMoni)
+
+// WithMaxRetries sets the number of attempts made on a rate limited request
+func WithMaxRetries(n int) Option {
+	return func(g *GetMoni) {
+		if n > 0 {
+			g.maxRetries = n
+		}
+	}
+}
+
+// WithBaseBackoff sets the wait after the first rate limited attempt
+func WithBaseBackoff(d time.Duration) Option {
+	return func(g *GetMoni) {
+		if d > 0 {
+			g.baseBackoff = d
+		}
+	}
+}
+
+// WithMaxBackoff caps the wait between attempts
+func WithMaxBackoff(d time.Duration) Option {
+	return func(g *GetMoni) {
+		if d > 0 {
+			g.maxBackoff = d
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API requests, e.g. one
+// talking to an httptest.Server in tests
+func WithHTTPClient(client *http.Client) Option {
+	return func(g *GetMoni) {
+		if client != nil {
+			g.client = client
+		}
+	}
+}
+
+// WithAPIKeys adds API keys to rotate through besides the one the client is
+// created with. Empty and repeated keys are ignored.
+func WithAPIKeys(keys ...string) Option {
+	return func(g *GetMoni) {
+		g.addKeys(keys...)
+	}
+}
+
+// WithBaseURL points the client at a different API server
+func WithBaseURL(baseURL string) Option {
+	return func(g *GetMoni) {
+		g.baseURL = baseURL
+	}
 }
 
 // Link represents a social media link in the user's profile
@@ -87,47 +173,113 @@ type SmartFollowersResponse struct {
 
 // NewGetMoni creates a new GetMoni client
 func NewGetMoni(apiKey string) *GetMoni {
-	if apiKey == "" {
-		apiKey = os.Getenv("GETMONI_API_KEY")
-	}
+	return NewGetMoniWithOptions(apiKey)
+}
 
+// NewGetMoniWithOptions creates a new GetMoni client configured by opts.
+// Without apiKey or keys from WithAPIKeys, the GETMONI_API_KEY environment
+// variable is used.
+func NewGetMoniWithOptions(apiKey string, opts ...Option) *GetMoni {
 	client := &GetMoni{
-		baseURL: "https://api.discover.getmoni.io/api/v2",
-		apiKey:  apiKey,
-		client:  &http.Client{Timeout: 30 * time.Second},
-		logger:  NewDefaultLogger(),
+		baseURL:     "https://api.discover.getmoni.io/api/v2",
+		client:      &http.Client{Timeout: 30 * time.Second},
+		logger:      NewDefaultLogger(),
+		maxRetries:  DefaultMaxRetries,
+		baseBackoff: DefaultBaseBackoff,
+		maxBackoff:  DefaultMaxBackoff,
 	}
-
-	// Check server status on init
-	status, err := client.makeRequest("GET", "/status/server/", nil, nil)
-	if err != nil {
-		client.logger.Error("Failed to check GetMoni server status: %v", err)
-	} else {
-		client.logger.Info("GetMoni server status: %v", status)
+	client.addKeys(apiKey)
+	for _, opt := range opts {
+		opt(client)
+	}
+	if len(client.keys) == 0 {
+		client.addKeys(os.Getenv("GETMONI_API_KEY"))
 	}
 
 	return client
 }
 
-// makeRequest makes an HTTP request to the GetMoni API with exponential backoff retry logic
-func (g *GetMoni) makeRequest(method, endpoint string, params map[string]string, data interface{}) (map[string]interface{}, error) {
-	if g.apiKey == "" {
+// addKeys appends the keys that are neither empty nor already held
+func (g *GetMoni) addKeys(keys ...string) {
+	g.keysMutex.Lock()
+	defer g.keysMutex.Unlock()
+	for _, value := range keys {
+		if value == "" {
+			continue
+		}
+		known := false
+		for _, key := range g.keys {
+			known = known || key.value == value
+		}
+		if !known {
+			g.keys = append(g.keys, &apiKey{value: value, number: len(g.keys) + 1})
+		}
+	}
+}
+
+// acquireKey returns the next key in turn that isn't rate limited. When every
+// key is, it returns the one usable soonest along with the wait until then.
+func (g *GetMoni) acquireKey() (*apiKey, time.Duration) {
+	g.keysMutex.Lock()
+	defer g.keysMutex.Unlock()
+
+	now := time.Now()
+	var soonest *apiKey
+	for i := range g.keys {
+		key := g.keys[(g.nextKey+i)%len(g.keys)]
+		if !key.limitedUntil.After(now) {
+			g.nextKey = (g.nextKey + i + 1) % len(g.keys)
+			return key, 0
+		}
+		if soonest == nil || key.limitedUntil.Before(soonest.limitedUntil) {
+			soonest = key
+		}
+	}
+	return soonest, soonest.limitedUntil.Sub(now)
+}
+
+// limitKey keeps key out of rotation for wait after a 429
+func (g *GetMoni) limitKey(key *apiKey, wait time.Duration) {
+	g.keysMutex.Lock()
+	defer g.keysMutex.Unlock()
+	key.limitedUntil = time.Now().Add(wait)
+}
+
+// CheckStatus returns the status reported by the GetMoni server
+func (g *GetMoni) CheckStatus(ctx context.Context) (map[string]interface{}, error) {
+	return g.makeRequest(ctx, "GET", "/status/server/", nil, nil)
+}
+
+// makeRequest makes an HTTP request to the GetMoni API with exponential
+// backoff retry logic. A rate limited key backs off while the request is
+// retried right away with the next key; only once every key is backing off
+// does the request wait.
+func (g *GetMoni) makeRequest(ctx context.Context, method, endpoint string, params map[string]string, data interface{}) (map[string]interface{}, error) {
+	if len(g.keys) == 0 {
 		g.logger.Warning("GetMoni API key not available, skipping API call")
 		return map[string]interface{}{"error": "API key not available"}, nil
 	}
 
-	maxRetries := 10
-	baseWait := 1.0
+	for retryCount := 0; retryCount < g.maxRetries; retryCount++ {
+		key, waitTime := g.acquireKey()
+		if waitTime > 0 {
+			g.logger.Warning("Rate limited on %s. Retry attempt %d/%d. Waiting %.2f seconds...",
+				endpoint, retryCount, g.maxRetries, waitTime.Seconds())
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(waitTime):
+			}
+		}
 
-	for retryCount := 0; retryCount < maxRetries; retryCount++ {
 		url := g.baseURL + endpoint
-		req, err := http.NewRequest(method, url, nil)
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
 		if err != nil {
 			return nil, fmt.Errorf("error creating request: %v", err)
 		}
 
 		// Add headers
-		req.Header.Set("Api-Key", g.apiKey)
+		req.Header.Set("Api-Key", key.value)
 		req.Header.Set("accept", "application/json")
 
 		// Add query parameters
@@ -146,16 +298,12 @@ func (g *GetMoni) makeRequest(method, endpoint string, params map[string]string,
 
 		// Handle rate limiting
 		if resp.StatusCode == http.StatusTooManyRequests {
-			waitTime := baseWait * math.Pow(2, float64(retryCount))
-			if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-				if retryAfterFloat, err := time.ParseDuration(retryAfter + "s"); err == nil {
-					waitTime = float64(retryAfterFloat.Seconds())
-				}
+			backoff := g.backoff(retryCount, resp.Header.Get("Retry-After"))
+			g.limitKey(key, backoff)
+			if len(g.keys) > 1 {
+				g.logger.Warning("Rate limited on %s with API key %d of %d, backing it off for %.2f seconds",
+					endpoint, key.number, len(g.keys), backoff.Seconds())
 			}
-
-			g.logger.Warning("Rate limited on %s. Retry attempt %d/%d. Waiting %.2f seconds...",
-				endpoint, retryCount+1, maxRetries, waitTime)
-			time.Sleep(time.Duration(waitTime * float64(time.Second)))
 			continue
 		}
 
@@ -185,7 +333,56 @@ func (g *GetMoni) makeRequest(method, endpoint string, params map[string]string,
 		return result, nil
 	}
 
-	return nil, fmt.Errorf("max retries (%d) reached", maxRetries)
+	return nil, fmt.Errorf("max retries (%d) reached", g.maxRetries)
+}
+
+// backoff returns the wait before retrying a rate limited request. A
+// Retry-After header, in seconds or as an HTTP date, takes precedence over
+// the exponential backoff; either is capped at the client's max backoff.
+func (g *GetMoni) backoff(retryCount int, retryAfter string) time.Duration {
+	wait := g.maxBackoff
+	if exp := float64(g.baseBackoff) * math.Pow(2, float64(retryCount)); exp < float64(g.maxBackoff) {
+		wait = time.Duration(exp)
+	}
+	if retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			wait = g.maxBackoff
+			if seconds < int(g.maxBackoff/time.Second) {
+				wait = time.Duration(seconds) * time.Second
+			}
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			wait = max(time.Until(at), 0)
+		}
+	}
+	return min(wait, g.maxBackoff)
+}
+
+const (
+	// DefaultSmartFollowersLimit is the number of smart followers fetched when none is requested
+	DefaultSmartFollowersLimit = 100
+	// MaxSmartFollowersLimit caps the number of smart followers fetched in one request
+	MaxSmartFollowersLimit = 500
+	// DefaultSmartFollowersOrderBy orders smart followers by their follower count
+	DefaultSmartFollowersOrderBy = "FOLLOWERS_COUNT"
+	// DefaultSmartFollowersOrderDirection lists the largest accounts first
+	DefaultSmartFollowersOrderDirection = "DESC"
+)
+
+// SmartFollowersOrderFields are the fields smart followers can be ordered by
+var SmartFollowersOrderFields = []string{"FOLLOWERS_COUNT", "SMART_FOLLOWERS_COUNT", "CREATED_AT"}
+
+// ValidSmartFollowersOrder reports whether smart followers can be ordered by
+// orderBy in orderByDirection, which must be ASC or DESC
+func ValidSmartFollowersOrder(orderBy, orderByDirection string) bool {
+	if orderByDirection != "ASC" && orderByDirection != "DESC" {
+		return false
+	}
+	for _, field := range SmartFollowersOrderFields {
+		if field == orderBy {
+			return true
+		}
+	}
+	return false
 }
 
 // GetSmartFollowers gets smart followers for a Twitter username
@@ -197,7 +394,7 @@ func (g *GetMoni) GetSmartFollowers(username string, limit, offset int, orderBy,
 		"orderByDirection": orderByDirection,
 	}
 
-	result, err := g.makeRequest("GET", fmt.Sprintf("/twitters/%s/smart_followers/meta", username), params, nil)
+	result, err := g.makeRequest(context.Background(), "GET", fmt.Sprintf("/twitters/%s/smart_followers/meta", username), params, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -216,6 +413,13 @@ func (g *GetMoni) GetSmartFollowers(username string, limit, offset int, orderBy,
 	return &response, nil
 }
 
+const (
+	// DefaultSmartMentionsLimit is the number of smart mentions fetched when none is requested
+	DefaultSmartMentionsLimit = 100
+	// MaxSmartMentionsLimit caps the number of smart mentions fetched in one request
+	MaxSmartMentionsLimit = 500
+)
+
 // GetSmartMentions gets smart mentions for a Twitter username
 func (g *GetMoni) GetSmartMentions(username string, fromDate, toDate string, limit int) (map[string]interface{}, error) {
 	params := map[string]string{
@@ -229,5 +433,5 @@ func (g *GetMoni) GetSmartMentions(username string, fromDate, toDate string, lim
 		params["toDate"] = toDate
 	}
 
-	return g.makeRequest("GET", fmt.Sprintf("/twitters/%s/feed/smart_mentions", username), params, nil)
+	return g.makeRequest(context.Background(), "GET", fmt.Sprintf("/twitters/%s/feed/smart_mentions", username), params, nil)
 }
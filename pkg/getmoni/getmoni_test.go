@@ -0,0 +1,120 @@
+package getmoni
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeRequestMaxRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewGetMoniWithOptions("test-key",
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithMaxRetries(3),
+		WithBaseBackoff(time.Millisecond),
+		WithMaxBackoff(5*time.Millisecond),
+	)
+
+	_, err := client.GetSmartFollowers("heygordonai", 10, 0, "", "")
+	assert.EqualError(t, err, "max retries (3) reached")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestNewGetMoniNoNetwork(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "/status/server/", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("Api-Key"))
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	client := NewGetMoniWithOptions("test-key", WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+
+	status, err := client.CheckStatus(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"status": "ok"}, status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestBackoff(t *testing.T) {
+	client := &GetMoni{baseBackoff: time.Second, maxBackoff: 10 * time.Second}
+
+	tests := []struct {
+		name       string
+		retryCount int
+		retryAfter string
+		expected   time.Duration
+	}{
+		{name: "first retry", retryCount: 0, expected: time.Second},
+		{name: "doubles", retryCount: 2, expected: 4 * time.Second},
+		{name: "capped", retryCount: 5, expected: 10 * time.Second},
+		{name: "large exponent", retryCount: 100, expected: 10 * time.Second},
+		{name: "retry-after seconds", retryCount: 0, retryAfter: "3", expected: 3 * time.Second},
+		{name: "retry-after capped", retryCount: 0, retryAfter: "3600", expected: 10 * time.Second},
+		{name: "retry-after in the past", retryCount: 0, retryAfter: "Mon, 02 Jan 2006 15:04:05 GMT", expected: 0},
+		{name: "invalid retry-after", retryCount: 1, retryAfter: "soon", expected: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, client.backoff(tt.retryCount, tt.retryAfter))
+		})
+	}
+}
+
+func TestValidSmartFollowersOrder(t *testing.T) {
+	assert.True(t, ValidSmartFollowersOrder(DefaultSmartFollowersOrderBy, DefaultSmartFollowersOrderDirection))
+	assert.True(t, ValidSmartFollowersOrder("CREATED_AT", "ASC"))
+	assert.False(t, ValidSmartFollowersOrder("USERNAME", "DESC"))
+	assert.False(t, ValidSmartFollowersOrder("FOLLOWERS_COUNT", "UP"))
+}
+
+func TestMakeRequestRotatesKeys(t *testing.T) {
+	var limited, served int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") == "key-1" {
+			atomic.AddInt32(&limited, 1)
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		atomic.AddInt32(&served, 1)
+		assert.Equal(t, "key-2", r.Header.Get("Api-Key"))
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	client := NewGetMoniWithOptions("key-1",
+		WithAPIKeys("key-2", "key-1", ""),
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithMaxBackoff(time.Minute),
+	)
+	assert.Len(t, client.keys, 2)
+
+	// The rate limited key is skipped without waiting for its backoff
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		status, err := client.CheckStatus(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"status": "ok"}, status)
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&limited))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&served))
+}
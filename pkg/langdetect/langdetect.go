@@ -0,0 +1,159 @@
+// Package langdetect guesses the language of short texts such as tweets.
+//
+// Texts in a script used by a single language (Hangul, Thai, Greek, ...) are
+// identified by their script. Scripts shared by several languages, such as
+// Cyrillic, Arabic or Han characters without kana, don't identify a language
+// and are reported as unknown. Latin script texts are scored against lists of
+// common function words, which is enough to tell the major European languages
+// apart in a tweet but not reliable for a few words. When no language clearly
+// wins, Detect returns an empty string rather than guessing.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minScriptLetters is the number of letters needed to identify a script
+const minScriptLetters = 3
+
+// scriptLanguages maps the scripts used by a single language to that
+// language. Scripts missing here, e.g. Cyrillic (Russian, Ukrainian,
+// Bulgarian, ...) or Arabic (Arabic, Persian, Urdu, ...), aren't detected.
+var scriptLanguages = map[string]string{
+	"kana":   "ja",
+	"hangul": "ko",
+	"hebrew": "he",
+	"greek":  "el",
+	"thai":   "th",
+}
+
+// stopwords are frequent function words of the Latin script languages Detect
+// can tell apart. Words shared by several languages count for each of them.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "to", "of", "in", "that", "it", "for", "on", "with", "this", "you", "not", "be", "have", "at", "we", "they", "what", "just", "so", "but", "my", "your", "will", "from", "about"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "un", "una", "es", "por", "con", "para", "no", "lo", "se", "del", "al", "como", "pero", "más", "muy", "está", "son", "mi", "yo", "ya", "esto", "hay"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "un", "une", "du", "que", "qui", "dans", "pour", "pas", "sur", "avec", "ce", "il", "je", "nous", "vous", "sont", "mais", "au", "aux", "très", "cette", "c'est", "ne"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "ich", "zu", "den", "mit", "von", "sie", "es", "auf", "für", "dem", "sich", "auch", "wir", "aber", "noch", "wie", "bei", "im", "war", "sind", "hat", "oder"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "por", "mais", "dos", "das", "se", "na", "no", "mas", "como", "foi", "ao", "muito", "está", "isso", "você"},
+	"it": {"il", "la", "di", "che", "e", "è", "un", "una", "per", "non", "in", "con", "del", "della", "sono", "mi", "ma", "si", "lo", "gli", "le", "anche", "come", "questo", "più", "ho", "nel", "alla", "cosa", "tutto"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "ik", "je", "met", "voor", "zijn", "er", "maar", "ook", "aan", "wat", "dit", "bij", "nog", "wel", "naar", "heb", "om", "als", "we", "hij"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "ne", "çok", "ben", "sen", "mi", "var", "yok", "gibi", "daha", "ama", "olarak", "kadar", "en", "her", "şey", "o", "biz", "değil", "nasıl", "neden", "olan", "diye", "şu"},
+	"id": {"yang", "dan", "di", "ini", "itu", "dengan", "untuk", "tidak", "dari", "ada", "ke", "akan", "saya", "kita", "juga", "sudah", "bisa", "kami", "atau", "karena", "pada", "aku", "kamu", "apa", "jadi", "lagi", "mau", "ya", "sama", "belum"},
+}
+
+// stopwordLanguages maps each stopword to the languages using it
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], lang)
+		}
+	}
+	return index
+}()
+
+// Detect returns the ISO 639-1 code of the language text is most likely
+// written in, or an empty string when it can't be determined. URLs, mentions
+// and hashtags are ignored.
+func Detect(text string) string {
+	words := strings.Fields(strings.ToLower(text))
+	kept := words[:0]
+	for _, word := range words {
+		if strings.HasPrefix(word, "@") || strings.HasPrefix(word, "#") || strings.Contains(word, "://") {
+			continue
+		}
+		kept = append(kept, word)
+	}
+
+	script := detectScript(kept)
+	if script == "latin" {
+		return detectLatin(kept)
+	}
+	return scriptLanguages[script]
+}
+
+// detectScript returns the dominant script of words, or an empty string
+// when there are too few letters or no script dominates
+func detectScript(words []string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, word := range words {
+		for _, r := range word {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			letters++
+			switch {
+			case unicode.Is(unicode.Latin, r):
+				counts["latin"]++
+			case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+				counts["kana"]++
+			case unicode.Is(unicode.Han, r):
+				counts["han"]++
+			case unicode.Is(unicode.Hangul, r):
+				counts["hangul"]++
+			case unicode.Is(unicode.Cyrillic, r):
+				counts["cyrillic"]++
+			case unicode.Is(unicode.Arabic, r):
+				counts["arabic"]++
+			case unicode.Is(unicode.Hebrew, r):
+				counts["hebrew"]++
+			case unicode.Is(unicode.Greek, r):
+				counts["greek"]++
+			case unicode.Is(unicode.Thai, r):
+				counts["thai"]++
+			case unicode.Is(unicode.Devanagari, r):
+				counts["devanagari"]++
+			}
+		}
+	}
+	if letters < minScriptLetters {
+		return ""
+	}
+
+	// Japanese mixes kana with Han characters
+	if counts["kana"] > 0 {
+		counts["kana"] += counts["han"]
+		counts["han"] = 0
+	}
+
+	best, bestCount := "", 0
+	for script, count := range counts {
+		if count > bestCount || (count == bestCount && script < best) {
+			best, bestCount = script, count
+		}
+	}
+	if bestCount*2 < letters {
+		return ""
+	}
+	return best
+}
+
+// detectLatin scores words against the stopword lists and returns the
+// language with the most hits, or an empty string on a tie or no hits
+func detectLatin(words []string) string {
+	scores := make(map[string]int)
+	for _, word := range words {
+		word = strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\''
+		})
+		for _, lang := range stopwordLanguages[word] {
+			scores[lang]++
+		}
+	}
+
+	best, bestScore, tie := "", 0, false
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if bestScore == 0 || tie {
+		return ""
+	}
+	return best
+}
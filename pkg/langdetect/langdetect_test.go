@@ -0,0 +1,41 @@
+package langdetect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"The market is up today and we are ready for more", "en"},
+		{"Hoy es un día muy bueno para la comunidad y el proyecto", "es"},
+		{"Je pense que c'est une très bonne nouvelle pour nous", "fr"},
+		{"Das ist nicht so einfach, aber wir sind auf dem Weg", "de"},
+		{"Não sei se isso é muito bom para você", "pt"},
+		{"今日はとても良い天気ですね", "ja"},
+		{"오늘 날씨가 좋네요", "ko"},
+		{"Καλημέρα σε όλους", "el"},
+		// Scripts shared by several languages don't tell the language
+		{"Привет, как у тебя дела сегодня?", ""},
+		{"Привіт, як у тебе справи сьогодні?", ""},
+		{"今天天气很好", ""},
+		{"مرحبا بكم في العالم", ""},
+		{"سلام، حال شما چطور است؟", ""},
+		// Mentions, hashtags and links are ignored
+		{"@alice #crypto https://example.com the news is out", "en"},
+		// Too little to tell
+		{"gm", ""},
+		{"🚀🚀🚀", ""},
+		{"https://example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Detect(tt.text))
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Level is the minimum severity a logger writes
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the name used for the level in config files
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseLevel parses debug, info, warn or error. An empty string is info.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("invalid log level %q, must be one of debug, info, warn, error", s)
+	}
+}
+
+// Logger is a leveled logger. It is a superset of getmoni.Logger so the
+// same logger can be handed to the GetMoni client.
+type Logger interface {
+	Debug(format string, args ...interface{})
+	Info(format string, args ...interface{})
+	Warning(format string, args ...interface{})
+	Error(format string, args ...interface{})
+}
+
+// StdLogger implements Logger on top of the standard log package, dropping
+// messages below its level
+type StdLogger struct {
+	logger *log.Logger
+	level  Level
+	prefix string // Put before every message, after the level tag
+}
+
+// New creates a StdLogger; out, prefix and flag are as for log.New
+func New(out io.Writer, prefix string, flag int, level Level) *StdLogger {
+	return &StdLogger{
+		logger: log.New(out, prefix, flag),
+		level:  level,
+	}
+}
+
+// Default returns an info level logger writing to stderr, like log.Default
+func Default() *StdLogger {
+	return New(os.Stderr, "", log.LstdFlags, LevelInfo)
+}
+
+// output writes the message when level is enabled. The call depth skips
+// output and the level method so log.Lshortfile reports the caller.
+func (l *StdLogger) output(level Level, tag string, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	l.logger.Output(3, tag+" "+l.prefix+fmt.Sprintf(format, args...))
+}
+
+// Debug logs a debug message
+func (l *StdLogger) Debug(format string, args ...interface{}) {
+	l.output(LevelDebug, "[DEBUG]", format, args...)
+}
+
+// Info logs an info message
+func (l *StdLogger) Info(format string, args ...interface{}) {
+	l.output(LevelInfo, "[INFO]", format, args...)
+}
+
+// Warning logs a warning message
+func (l *StdLogger) Warning(format string, args ...interface{}) {
+	l.output(LevelWarn, "[WARNING]", format, args...)
+}
+
+// Error logs an error message
+func (l *StdLogger) Error(format string, args ...interface{}) {
+	l.output(LevelError, "[ERROR]", format, args...)
+}
+
+// Fatal logs an error message regardless of level and exits
+func (l *StdLogger) Fatal(format string, args ...interface{}) {
+	l.logger.Output(2, "[FATAL] "+l.prefix+fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// WithPrefix returns a Logger that writes through logger with prefix put
+// before every message, e.g. to tag the lines of one request
+func WithPrefix(logger Logger, prefix string) Logger {
+	if std, ok := logger.(*StdLogger); ok {
+		return &StdLogger{logger: std.logger, level: std.level, prefix: std.prefix + prefix}
+	}
+	return &prefixLogger{Logger: logger, prefix: prefix}
+}
+
+// prefixLogger prepends a fixed prefix to every message of any other Logger
+type prefixLogger struct {
+	Logger
+	prefix string
+}
+
+// Debug logs a debug message
+func (l *prefixLogger) Debug(format string, args ...interface{}) {
+	l.Logger.Debug("%s%s", l.prefix, fmt.Sprintf(format, args...))
+}
+
+// Info logs an info message
+func (l *prefixLogger) Info(format string, args ...interface{}) {
+	l.Logger.Info("%s%s", l.prefix, fmt.Sprintf(format, args...))
+}
+
+// Warning logs a warning message
+func (l *prefixLogger) Warning(format string, args ...interface{}) {
+	l.Logger.Warning("%s%s", l.prefix, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error message
+func (l *prefixLogger) Error(format string, args ...interface{}) {
+	l.Logger.Error("%s%s", l.prefix, fmt.Sprintf(format, args...))
+}
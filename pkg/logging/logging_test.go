@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Level
+		wantErr  bool
+	}{
+		{input: "debug", expected: LevelDebug},
+		{input: "INFO", expected: LevelInfo},
+		{input: "", expected: LevelInfo},
+		{input: "warn", expected: LevelWarn},
+		{input: "warning", expected: LevelWarn},
+		{input: " error ", expected: LevelError},
+		{input: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			level, err := ParseLevel(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, level)
+		})
+	}
+}
+
+func TestStdLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "", 0, LevelInfo)
+
+	logger.Debug("selected agent %s", "a")
+	logger.Info("loaded %d accounts", 2)
+	logger.Warning("slow response")
+	logger.Error("request failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		"[INFO] loaded 2 accounts",
+		"[WARNING] slow response",
+		"[ERROR] request failed",
+	}, lines)
+}
+
+func TestWithPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	logger := WithPrefix(New(&buf, "", 0, LevelDebug), "[request_id=abc] ")
+
+	logger.Debug("selected agent %s", "a")
+	logger.Error("100%% failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		"[DEBUG] [request_id=abc] selected agent a",
+		"[ERROR] [request_id=abc] 100% failed",
+	}, lines)
+}
@@ -0,0 +1,209 @@
+package twitter
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	// accountProfileTTL is how long an agent's own profile is cached
+	accountProfileTTL = time.Hour
+	// loginStatusTTL is how long the results of HasLoggedInAgent and of each
+	// agent's login check in AccountStatuses are cached
+	loginStatusTTL = time.Minute
+)
+
+// AccountStatus describes one of the accounts the manager is running as
+type AccountStatus struct {
+	Username       string `json:"username"`
+	LoggedIn       bool   `json:"logged_in"`
+	Name           string `json:"name,omitempty"`
+	FollowersCount int    `json:"followers_count,omitempty"`
+	ProfileError   string `json:"profile_error,omitempty"`
+}
+
+// cachedAccountProfile is the part of an agent's own profile shown in AccountStatus
+type cachedAccountProfile struct {
+	userID         string
+	name           string
+	followersCount int
+	fetchedAt      time.Time
+}
+
+// cachedLoginStatus is the login state of an agent as last checked
+type cachedLoginStatus struct {
+	loggedIn  bool
+	checkedAt time.Time
+}
+
+// AccountStatuses returns the login state of every agent, cached for
+// loginStatusTTL. For logged-in agents the account's own profile is
+// fetched, and cached for accountProfileTTL, to include the display name
+// and follower count.
+func (am *AgentManager) AccountStatuses(ctx context.Context) []AccountStatus {
+	am.mutex.RLock()
+	agents := make([]*Agent, len(am.agents))
+	copy(agents, am.agents)
+	am.mutex.RUnlock()
+
+	statuses := make([]AccountStatus, 0, len(agents))
+	for _, agent := range agents {
+		status := AccountStatus{
+			Username: agent.username,
+			LoggedIn: am.agentLoggedIn(agent),
+		}
+
+		if status.LoggedIn {
+			profile, err := am.accountProfile(ctx, agent)
+			if err != nil {
+				am.requestLogger(ctx).Error("Failed to get profile for account %s: %v", agent.username, err)
+				status.ProfileError = err.Error()
+			} else {
+				status.Name = profile.name
+				status.FollowersCount = profile.followersCount
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// accountProfile returns the cached profile of the agent's own account,
+// fetching it with the agent itself when missing or stale
+func (am *AgentManager) accountProfile(ctx context.Context, agent *Agent) (cachedAccountProfile, error) {
+	am.profileMutex.Lock()
+	cached, ok := am.profileCache[agent.username]
+	am.profileMutex.Unlock()
+	if ok && time.Since(cached.fetchedAt) < accountProfileTTL {
+		return cached, nil
+	}
+
+	if err := agent.limiter.waitForEndpoint(ctx, "get_profile"); err != nil {
+		return cachedAccountProfile{}, err
+	}
+
+	profile, err := agent.scraper.GetProfile(ctx, agent.username)
+	agent.limiter.doneEndpoint("get_profile")
+	if err != nil {
+		return cachedAccountProfile{}, err
+	}
+
+	cached = cachedAccountProfile{
+		userID:         profile.UserID,
+		name:           profile.Name,
+		followersCount: profile.FollowersCount,
+		fetchedAt:      time.Now(),
+	}
+
+	am.profileMutex.Lock()
+	am.profileCache[agent.username] = cached
+	am.profileMutex.Unlock()
+
+	return cached, nil
+}
+
+// agentLoggedIn reports whether agent is logged in. Checking a login costs a
+// request to Twitter, so the answer is cached per agent for loginStatusTTL.
+func (am *AgentManager) agentLoggedIn(agent *Agent) bool {
+	am.loginMutex.Lock()
+	defer am.loginMutex.Unlock()
+
+	if cached, ok := am.loginStatuses[agent.username]; ok && time.Since(cached.checkedAt) < loginStatusTTL {
+		return cached.loggedIn
+	}
+
+	loggedIn := agent.IsLoggedIn()
+	if am.loginStatuses == nil {
+		am.loginStatuses = make(map[string]cachedLoginStatus)
+	}
+	am.loginStatuses[agent.username] = cachedLoginStatus{loggedIn: loggedIn, checkedAt: time.Now()}
+	return loggedIn
+}
+
+// HasLoggedInAgent reports whether at least one agent is logged in. Checking
+// a login costs a request to Twitter, so the answer is cached for loginStatusTTL.
+func (am *AgentManager) HasLoggedInAgent() bool {
+	am.loginMutex.Lock()
+	defer am.loginMutex.Unlock()
+
+	if !am.loginCheckedAt.IsZero() && time.Since(am.loginCheckedAt) < loginStatusTTL {
+		return am.hasLoggedIn
+	}
+
+	am.mutex.RLock()
+	agents := make([]*Agent, len(am.agents))
+	copy(agents, am.agents)
+	am.mutex.RUnlock()
+
+	am.hasLoggedIn = false
+	for _, agent := range agents {
+		if agent.IsLoggedIn() {
+			am.hasLoggedIn = true
+			break
+		}
+	}
+	am.loginCheckedAt = time.Now()
+
+	return am.hasLoggedIn
+}
+
+// Startup states of a configured account
+const (
+	AgentStatusActive    = "active"    // Logged in and serving requests
+	AgentStatusSuspended = "suspended" // Twitter reports the account as suspended
+	AgentStatusLocked    = "locked"    // Login needs a challenge, e.g. email or 2FA verification
+	AgentStatusFailed    = "failed"    // Login failed for any other reason
+)
+
+// AgentStartupStatus is the outcome of starting one configured account
+type AgentStartupStatus struct {
+	Username string `json:"username"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	// Circuit is the state of the circuit breaker of an account in the rotation
+	Circuit *CircuitStatus `json:"circuit,omitempty"`
+}
+
+// classifyLoginError maps a twitter-scraper login error to a startup status.
+// The scraper only reports the failing login subtask or Twitter's error code
+// and message, so this matches on the error text.
+func classifyLoginError(err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "suspended"),
+		strings.Contains(msg, "denyloginsubtask"),
+		strings.Contains(msg, "auth error (64)"):
+		return AgentStatusSuspended
+	case strings.Contains(msg, "locked"),
+		strings.Contains(msg, "auth error (326)"),
+		strings.Contains(msg, "loginacid"),
+		strings.Contains(msg, "logintwofactorauthchallenge"),
+		strings.Contains(msg, "confirmation data required"):
+		return AgentStatusLocked
+	default:
+		return AgentStatusFailed
+	}
+}
+
+// StartupStatuses returns the startup outcome of every configured account,
+// including those that were skipped because they couldn't log in. Accounts
+// logged in again with Relogin report the outcome of that login. Accounts in
+// the rotation also report the current state of their circuit breaker.
+func (am *AgentManager) StartupStatuses() []AgentStartupStatus {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+	statuses := make([]AgentStartupStatus, len(am.startupStatuses))
+	copy(statuses, am.startupStatuses)
+	for i := range statuses {
+		for _, agent := range am.agents {
+			if agent.username == statuses[i].Username {
+				statuses[i].Circuit = agent.CircuitStatus()
+				break
+			}
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,48 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginCountingScraper counts the login checks made against Twitter
+type loginCountingScraper struct {
+	mockScraper
+	checks int
+}
+
+func (s *loginCountingScraper) IsLoggedIn() bool {
+	s.checks++
+	return s.isLoggedIn
+}
+
+func TestAccountStatusesCachesLogin(t *testing.T) {
+	scraper := &loginCountingScraper{mockScraper: mockScraper{isLoggedIn: true}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	manager := &AgentManager{
+		agents:       []*Agent{agent},
+		logger:       logging.Default(),
+		profileCache: map[string]cachedAccountProfile{},
+	}
+
+	statuses := manager.AccountStatuses(context.Background())
+	if assert.Len(t, statuses, 1) {
+		assert.True(t, statuses[0].LoggedIn)
+	}
+	// Repeated requests don't check the login again
+	manager.AccountStatuses(context.Background())
+	manager.AccountStatuses(context.Background())
+	assert.Equal(t, 1, scraper.checks)
+
+	// Once the cached state is stale, the login is checked again
+	manager.loginStatuses[agent.username] = cachedLoginStatus{loggedIn: true, checkedAt: time.Now().Add(-loginStatusTTL)}
+	scraper.isLoggedIn = false
+	statuses = manager.AccountStatuses(context.Background())
+	assert.False(t, statuses[0].LoggedIn)
+	assert.Equal(t, 2, scraper.checks)
+}
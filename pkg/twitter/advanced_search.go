@@ -0,0 +1,189 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrInvalidSearch is wrapped by the errors SearchTweetsAdvanced returns for
+// fields that can't be turned into a search query
+var ErrInvalidSearch = errors.New("invalid search")
+
+var (
+	// hashtagPattern matches a hashtag without its "#"
+	hashtagPattern = regexp.MustCompile(`^\w+$`)
+	// langPattern matches the language codes of the lang: operator, e.g. en or fil
+	langPattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+)
+
+// AdvancedSearch is a tweet search given as fields rather than a query
+// string. Every set field must match.
+type AdvancedSearch struct {
+	Keywords    string   `json:"keywords,omitempty"` // Free text, used as is, so it may hold operators too
+	From        string   `json:"from,omitempty"`     // Tweets by this user
+	To          string   `json:"to,omitempty"`       // Replies to this user
+	Mentions    []string `json:"mentions,omitempty"` // Users that must all be mentioned
+	Hashtags    []string `json:"hashtags,omitempty"` // Hashtags that must all be present, with or without "#"
+	MinLikes    int      `json:"min_likes,omitempty"`
+	MinRetweets int      `json:"min_retweets,omitempty"`
+	Lang        string   `json:"lang,omitempty"`  // Language code, e.g. en
+	Since       string   `json:"since,omitempty"` // Only tweets from this date (YYYY-MM-DD) on
+	Until       string   `json:"until,omitempty"` // Only tweets before this date (YYYY-MM-DD)
+}
+
+// Query returns the Twitter search query matching the fields of s, or an
+// error wrapping ErrInvalidSearch when a field is invalid or none is set
+func (s AdvancedSearch) Query() (string, error) {
+	var terms []string
+	if keywords := strings.TrimSpace(s.Keywords); keywords != "" {
+		terms = append(terms, keywords)
+	}
+
+	for _, user := range []struct{ operator, value string }{{"from:", s.From}, {"to:", s.To}} {
+		if user.value == "" {
+			continue
+		}
+		username, err := NormalizeUsername(user.value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s %v", ErrInvalidSearch, strings.TrimSuffix(user.operator, ":"), err)
+		}
+		terms = append(terms, user.operator+username)
+	}
+
+	for _, mention := range s.Mentions {
+		username, err := NormalizeUsername(mention)
+		if err != nil {
+			return "", fmt.Errorf("%w: mentions %v", ErrInvalidSearch, err)
+		}
+		terms = append(terms, "@"+username)
+	}
+
+	for _, hashtag := range s.Hashtags {
+		tag := strings.TrimPrefix(strings.TrimSpace(hashtag), "#")
+		if !hashtagPattern.MatchString(tag) {
+			return "", fmt.Errorf("%w: invalid hashtag %q, only letters, digits and underscores are allowed", ErrInvalidSearch, hashtag)
+		}
+		terms = append(terms, "#"+tag)
+	}
+
+	if s.MinLikes < 0 || s.MinRetweets < 0 {
+		return "", fmt.Errorf("%w: min_likes and min_retweets can't be negative", ErrInvalidSearch)
+	}
+	if s.MinLikes > 0 {
+		terms = append(terms, "min_faves:"+strconv.Itoa(s.MinLikes))
+	}
+	if s.MinRetweets > 0 {
+		terms = append(terms, "min_retweets:"+strconv.Itoa(s.MinRetweets))
+	}
+
+	if s.Lang != "" {
+		lang := strings.ToLower(strings.TrimSpace(s.Lang))
+		if !langPattern.MatchString(lang) {
+			return "", fmt.Errorf("%w: invalid lang %q, expected a language code such as en", ErrInvalidSearch, s.Lang)
+		}
+		terms = append(terms, "lang:"+lang)
+	}
+
+	// Dates alone would match every tweet of the period
+	if len(terms) == 0 {
+		return "", fmt.Errorf("%w: at least one of keywords, from, to, mentions, hashtags, min_likes, min_retweets or lang is required", ErrInvalidSearch)
+	}
+
+	opts := SearchOptions{Since: s.Since, Until: s.Until}
+	if err := opts.Validate(); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSearch, err)
+	}
+	return opts.apply(strings.Join(terms, " ")), nil
+}
+
+// advancedSearchFromArguments reads the fields of an AdvancedSearch from the
+// arguments of a search_tweets_advanced call. mentions and hashtags may be
+// given as arrays or comma-separated strings.
+func advancedSearchFromArguments(arguments map[string]interface{}) AdvancedSearch {
+	var s AdvancedSearch
+	s.Keywords, _ = arguments["keywords"].(string)
+	s.From, _ = arguments["from"].(string)
+	s.To, _ = arguments["to"].(string)
+	s.Mentions = stringListArgument(arguments["mentions"])
+	s.Hashtags = stringListArgument(arguments["hashtags"])
+	if minLikes, ok := arguments["min_likes"].(float64); ok {
+		s.MinLikes = int(minLikes)
+	}
+	if minRetweets, ok := arguments["min_retweets"].(float64); ok {
+		s.MinRetweets = int(minRetweets)
+	}
+	s.Lang, _ = arguments["lang"].(string)
+	s.Since, _ = arguments["since"].(string)
+	s.Until, _ = arguments["until"].(string)
+	return s
+}
+
+// stringListArgument returns the non-empty strings of an array argument or
+// the comma-separated values of a string argument
+func stringListArgument(value interface{}) []string {
+	var values []string
+	switch v := value.(type) {
+	case string:
+		values = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = v
+	}
+
+	list := make([]string, 0, len(values))
+	for _, s := range values {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// handleSearchTweetsAdvanced searches tweets with a query assembled from the
+// fields of the call, answering like search_tweets
+func (a *Agent) handleSearchTweetsAdvanced(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := advancedSearchFromArguments(request.Params.Arguments).Query()
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: err.Error(),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	arguments := map[string]interface{}{"query": query}
+	for _, name := range []string{"limit", "include_html"} {
+		if value, ok := request.Params.Arguments[name]; ok {
+			arguments[name] = value
+		}
+	}
+	request.Params.Arguments = arguments
+	return a.handleSearchTweets(ctx, request)
+}
+
+// SearchTweetsAdvanced searches tweets matching the fields of search using
+// the next available agent. An invalid search is returned as an error
+// wrapping ErrInvalidSearch without using an agent. Partial results are
+// reported as by GetUserTweets.
+func (am *AgentManager) SearchTweetsAdvanced(ctx context.Context, search AdvancedSearch, limit int, includeHTML bool) (interface{}, []string, string, error) {
+	query, err := search.Query()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return am.SearchTweets(ctx, query, limit, SearchOptions{IncludeHTML: includeHTML})
+}
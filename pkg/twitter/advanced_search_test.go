@@ -0,0 +1,135 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvancedSearchQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		search   AdvancedSearch
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "keywords only",
+			search:   AdvancedSearch{Keywords: "  golang generics "},
+			expected: "golang generics",
+		},
+		{
+			name:     "users",
+			search:   AdvancedSearch{From: "@Alice", To: "bob"},
+			expected: "from:alice to:bob",
+		},
+		{
+			name:     "mentions and hashtags",
+			search:   AdvancedSearch{Keywords: "release", Mentions: []string{"@Carol", "dave"}, Hashtags: []string{"#golang", "go_1"}},
+			expected: "release @carol @dave #golang #go_1",
+		},
+		{
+			name:     "engagement and language",
+			search:   AdvancedSearch{From: "alice", MinLikes: 100, MinRetweets: 5, Lang: "EN"},
+			expected: "from:alice min_faves:100 min_retweets:5 lang:en",
+		},
+		{
+			name: "every field",
+			search: AdvancedSearch{
+				Keywords: `"x-go" OR mcp`, From: "alice", To: "bob", Mentions: []string{"carol"}, Hashtags: []string{"ai"},
+				MinLikes: 10, MinRetweets: 2, Lang: "fil", Since: "2024-01-01", Until: "2024-02-01",
+			},
+			expected: `"x-go" OR mcp from:alice to:bob @carol #ai min_faves:10 min_retweets:2 lang:fil since:2024-01-01 until:2024-02-01`,
+		},
+		{
+			name:    "dates only",
+			search:  AdvancedSearch{Since: "2024-01-01"},
+			wantErr: "invalid search: at least one of keywords, from, to, mentions, hashtags, min_likes, min_retweets or lang is required",
+		},
+		{
+			name:    "invalid from",
+			search:  AdvancedSearch{From: "not a user"},
+			wantErr: `invalid search: from invalid username "not a user": only letters, digits and underscores are allowed`,
+		},
+		{
+			name:    "invalid hashtag",
+			search:  AdvancedSearch{Hashtags: []string{"two words"}},
+			wantErr: `invalid search: invalid hashtag "two words", only letters, digits and underscores are allowed`,
+		},
+		{
+			name:    "invalid lang",
+			search:  AdvancedSearch{Keywords: "hi", Lang: "english"},
+			wantErr: `invalid search: invalid lang "english", expected a language code such as en`,
+		},
+		{
+			name:    "negative count",
+			search:  AdvancedSearch{Keywords: "hi", MinLikes: -1},
+			wantErr: "invalid search: min_likes and min_retweets can't be negative",
+		},
+		{
+			name:    "since after until",
+			search:  AdvancedSearch{Keywords: "hi", Since: "2024-03-01", Until: "2024-02-01"},
+			wantErr: "invalid search: since date 2024-03-01 is after until date 2024-02-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.search.Query()
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidSearch)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
+
+// queryScraper records the query of the last search
+type queryScraper struct {
+	mockScraper
+	query string
+}
+
+func (s *queryScraper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	s.query = query
+	ch := make(chan *twitterscraper.TweetResult)
+	close(ch)
+	return ch
+}
+
+func TestSearchTweetsAdvanced(t *testing.T) {
+	scraper := &queryScraper{mockScraper: mockScraper{isLoggedIn: true}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	_, _, _, err := manager.SearchTweetsAdvanced(context.Background(), AdvancedSearch{}, 10, false)
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+	assert.Empty(t, scraper.query)
+
+	_, _, _, err = manager.SearchTweetsAdvanced(context.Background(), AdvancedSearch{From: "alice", Hashtags: []string{"go"}}, 10, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "from:alice #go", scraper.query)
+
+	// The tool takes lists as arrays or comma-separated strings
+	agent.limiter.lastCallTime = time.Time{}
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]interface{}{
+		"mentions":  []interface{}{"bob"},
+		"hashtags":  "go, mcp",
+		"min_likes": float64(3),
+	}
+	result, err := agent.handleSearchTweetsAdvanced(context.Background(), request)
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "@bob #go #mcp min_faves:3", scraper.query)
+}
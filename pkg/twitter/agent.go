@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/asabya/x-go/pkg/getmoni"
+	"github.com/asabya/x-go/pkg/logging"
 	twitterscraper "github.com/imperatrona/twitter-scraper"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -16,36 +18,137 @@ import (
 type Scraper interface {
 	IsLoggedIn() bool
 	SetCookies([]*http.Cookie)
+	SetUserAgent(userAgent string)
 	GetProfile(ctx context.Context, username string) (*twitterscraper.Profile, error)
 	GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult
+	GetMediaTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult
 	GetTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error)
+	TweetVisibility(ctx context.Context, id string) (string, error)
 	GetTweetReplies(id string, cursor string) ([]*twitterscraper.Tweet, []*twitterscraper.ThreadCursor, error)
 	SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult
+	FetchSearchTweets(ctx context.Context, query string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error)
+	FetchTweetsAndReplies(ctx context.Context, username string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error)
 	Tweet(ctx context.Context, text string) (*twitterscraper.Tweet, error)
+	ReplyTweet(ctx context.Context, text string, inReplyToID string) (*twitterscraper.Tweet, error)
+	QuoteTweet(ctx context.Context, text string, quotedID string, mediaIDs []string) (*twitterscraper.Tweet, error)
+	SetMediaAltText(ctx context.Context, mediaID string, altText string) error
+	TweetWithPoll(ctx context.Context, text string, poll Poll) (*twitterscraper.Tweet, error)
 	LikeTweet(ctx context.Context, id string) error
 	UnlikeTweet(ctx context.Context, id string) error
 	CreateRetweet(ctx context.Context, id string) error
 	CreateScheduledTweet(ctx context.Context, text string, scheduleTime string) error
 	Follow(ctx context.Context, id string) error
 	Unfollow(ctx context.Context, id string) error
+	GetRelationship(ctx context.Context, sourceUserID, targetUserID string) (following, followedBy, blocked, muted bool, err error)
 	Login(credentials ...string) error
 	GetCookies() []*http.Cookie
-	FetchFollowers(username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error)
+	FetchFollowers(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error)
+	FetchFollowing(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error)
+	GetTrends(ctx context.Context) ([]string, error)
+	GetNotifications(ctx context.Context, cursor string) ([]Notification, string, error)
+	GetListTweets(ctx context.Context, listID string, maxTweets int) ([]*twitterscraper.Tweet, error)
+	GetListMembers(ctx context.Context, listID string, cursor string) ([]*twitterscraper.Profile, string, error)
+}
+
+// SimplifiedTweet is a flattened tweet without the nested tweet references
+// that twitter-scraper uses, so it can be safely marshaled to JSON
+type SimplifiedTweet struct {
+	ID         string    `json:"id"`
+	Text       string    `json:"text"`
+	Username   string    `json:"username"`
+	Name       string    `json:"name"`
+	Likes      int       `json:"likes"`
+	Retweets   int       `json:"retweets"`
+	Replies    int       `json:"replies"`
+	TimeParsed time.Time `json:"timestamp"`
+	Hashtags   []string  `json:"hashtags,omitempty"`
+	Mentions   []string  `json:"mentions,omitempty"`
+	URLs       []string  `json:"urls,omitempty"`
+}
+
+// newSimplifiedTweet converts a scraper tweet into a SimplifiedTweet
+func newSimplifiedTweet(tweet *twitterscraper.Tweet) SimplifiedTweet {
+	return SimplifiedTweet{
+		ID:         tweet.ID,
+		Text:       tweet.Text,
+		Username:   tweet.Username,
+		Name:       tweet.Name,
+		Likes:      tweet.Likes,
+		Retweets:   tweet.Retweets,
+		Replies:    tweet.Replies,
+		TimeParsed: tweet.TimeParsed,
+		Hashtags:   tweet.Hashtags,
+		Mentions:   mentionUsernames(tweet),
+		URLs:       tweet.URLs,
+	}
+}
+
+// mentionUsernames returns the usernames mentioned in a tweet
+func mentionUsernames(tweet *twitterscraper.Tweet) []string {
+	var usernames []string
+	for _, mention := range tweet.Mentions {
+		usernames = append(usernames, mention.Username)
+	}
+	return usernames
+}
+
+// includeHTMLDescription documents the include_html argument of the tools
+// returning tweets
+const includeHTMLDescription = "Include the tweet text rendered as HTML, with links, mentions and hashtags as anchors (default: false)"
+
+// includeHTML reports whether the include_html argument of request is set
+func includeHTML(request mcp.CallToolRequest) bool {
+	include, _ := request.Params.Arguments["include_html"].(bool)
+	return include
+}
+
+// tweetJSON marshals a tweet like twitterscraper.Tweet, whose fields have no
+// JSON tags, but leaves HTML out unless it's set, so include_html false
+// drops the field instead of sending it empty
+type tweetJSON struct {
+	*twitterscraper.Tweet
+	HTML string `json:"HTML,omitempty"`
+}
+
+// tweetResultJSON marshals a timeline tweet like tweetJSON
+type tweetResultJSON struct {
+	twitterscraper.TweetResult
+	HTML string `json:"HTML,omitempty"`
+}
+
+// addTweetEntities adds the non-empty hashtags, mentions and expanded URLs of tweet to result
+func addTweetEntities(result map[string]interface{}, tweet *twitterscraper.Tweet) {
+	if len(tweet.Hashtags) > 0 {
+		result["hashtags"] = tweet.Hashtags
+	}
+	if mentions := mentionUsernames(tweet); len(mentions) > 0 {
+		result["mentions"] = mentions
+	}
+	if len(tweet.URLs) > 0 {
+		result["urls"] = tweet.URLs
+	}
 }
 
 // Agent represents a Twitter MCP agent
 type Agent struct {
-	scraper  Scraper
-	limiter  *rateLimiter
-	username string
+	scraper    Scraper
+	limiter    *rateLimiter
+	username   string
+	getmoni    *getmoni.GetMoni
+	logger     logging.Logger
+	maxResults int             // Cap on the limit of tweet searches and timelines, 0 for none
+	tweetStore TweetStore      // Locally stored tweets, checked before fetching parents of a conversation
+	breaker    *circuitBreaker // Refuses calls to Twitter after repeated failures, nil when disabled
 }
 
 // NewAgent creates a new Twitter MCP agent
 func NewAgent(username string) *Agent {
 	return &Agent{
-		scraper:  newScraperWrapper(),
-		limiter:  newRateLimiter(),
-		username: username,
+		scraper:    newScraperWrapper(),
+		limiter:    newRateLimiter(),
+		username:   username,
+		logger:     logging.Default(),
+		maxResults: DefaultMaxResults,
 	}
 }
 
@@ -54,6 +157,90 @@ func (a *Agent) SetCookies(cookies []*http.Cookie) {
 	a.scraper.SetCookies(cookies)
 }
 
+// SetLogger sets the logger used for the agent's own messages
+func (a *Agent) SetLogger(logger logging.Logger) {
+	a.logger = logger
+}
+
+// SetMaxResults sets the largest limit accepted by the search and timeline
+// tools; larger limits are capped with a warning. 0 disables the cap.
+func (a *Agent) SetMaxResults(maxResults int) {
+	a.maxResults = maxResults
+}
+
+// SetEndpointConcurrency caps how many calls to each endpoint of limits the
+// agent runs at once; endpoints missing from limits aren't capped
+func (a *Agent) SetEndpointConcurrency(limits map[string]int) {
+	a.limiter.setConcurrency(limits)
+}
+
+// SetTweetStore sets the store checked for tweets before fetching them
+// from Twitter when building a conversation context
+func (a *Agent) SetTweetStore(store TweetStore) {
+	a.tweetStore = store
+}
+
+// SetGetMoni sets the GetMoni client used by the smart followers tool
+func (a *Agent) SetGetMoni(client *getmoni.GetMoni) {
+	a.getmoni = client
+}
+
+// SetUserAgent sets the User-Agent header sent with every request.
+// An empty value keeps the scraper's default.
+func (a *Agent) SetUserAgent(userAgent string) error {
+	if userAgent == "" {
+		return nil
+	}
+	if err := validateHeaderValue(userAgent); err != nil {
+		return fmt.Errorf("invalid user agent: %w", err)
+	}
+	a.scraper.SetUserAgent(userAgent)
+	return nil
+}
+
+// SetRetryConfig makes the agent retry calls that fail with transient
+// errors. A MaxAttempts below 2 removes any retrying.
+func (a *Agent) SetRetryConfig(config RetryConfig) {
+	if rs, ok := a.scraper.(*retryScraper); ok {
+		a.scraper = rs.Scraper
+	}
+	if config.MaxAttempts > 1 {
+		a.scraper = newRetryScraper(a.scraper, config)
+	}
+}
+
+// SetCircuitBreaker makes the agent stop calling Twitter for a cooldown
+// after repeated failures. A FailureThreshold of 0 removes the breaker.
+func (a *Agent) SetCircuitBreaker(config CircuitBreakerConfig) {
+	// The breaker sits below the retrying, if any
+	retry, retrying := a.scraper.(*retryScraper)
+	if retrying {
+		a.scraper = retry.Scraper
+	}
+	if bs, ok := a.scraper.(*breakerScraper); ok {
+		a.scraper = bs.Scraper
+	}
+	a.breaker = nil
+	if config.FailureThreshold > 0 {
+		a.breaker = newCircuitBreaker(config)
+		a.scraper = &breakerScraper{Scraper: a.scraper, breaker: a.breaker}
+	}
+	if retrying {
+		retry.Scraper = a.scraper
+		a.scraper = retry
+	}
+}
+
+// CircuitStatus returns the state of the agent's circuit breaker, or nil
+// when it has none
+func (a *Agent) CircuitStatus() *CircuitStatus {
+	if a.breaker == nil {
+		return nil
+	}
+	status := a.breaker.status()
+	return &status
+}
+
 // GetCookies returns the current cookies for the agent
 func (a *Agent) GetCookies() []*http.Cookie {
 	return a.scraper.GetCookies()
@@ -83,6 +270,18 @@ func (a *Agent) GetTools() []server.ServerTool {
 							"type":        "boolean",
 							"description": "Sort tweets by oldest",
 						},
+						"since": map[string]interface{}{
+							"type":        "string",
+							"description": "Only return tweets from this time (RFC 3339) on; the timeline is read until older tweets are reached, up to limit tweets",
+						},
+						"since_id": map[string]interface{}{
+							"type":        "string",
+							"description": "Only return tweets newer than this tweet ID; the timeline is read until the tweet or an older one is reached, up to limit tweets",
+						},
+						"include_html": map[string]interface{}{
+							"type":        "boolean",
+							"description": includeHTMLDescription,
+						},
 					},
 					Required: []string{"username"},
 				},
@@ -94,6 +293,33 @@ func (a *Agent) GetTools() []server.ServerTool {
 			},
 			Handler: a.handleGetUserTweets,
 		},
+		{
+			Tool: mcp.Tool{
+				Name:        "get_media_tweets",
+				Description: "Get the tweets of a user that have photos, videos or GIFs, with their media URLs",
+				InputSchema: mcp.ToolInputSchema{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"username": map[string]interface{}{
+							"type":        "string",
+							"description": "Twitter username",
+						},
+						"limit": map[string]interface{}{
+							"type":        "number",
+							"description": "Maximum number of tweets to fetch",
+							"default":     50,
+						},
+					},
+					Required: []string{"username"},
+				},
+				Annotations: mcp.ToolAnnotation{
+					Title:         "Get Media Tweets",
+					ReadOnlyHint:  BoolPtr(true),
+					OpenWorldHint: BoolPtr(true),
+				},
+			},
+			Handler: a.handleGetMediaTweets,
+		},
 		{
 			Tool: mcp.Tool{
 				Name:        "get_profile",
@@ -105,6 +331,10 @@ func (a *Agent) GetTools() []server.ServerTool {
 							"type":        "string",
 							"description": "Twitter username",
 						},
+						"include_pinned": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Include the full pinned tweet as pinned_tweet (default: false)",
+						},
 					},
 					Required: []string{"username"},
 				},
@@ -127,6 +357,10 @@ func (a *Agent) GetTools() []server.ServerTool {
 							"type":        "string",
 							"description": "Tweet ID",
 						},
+						"include_html": map[string]interface{}{
+							"type":        "boolean",
+							"description": includeHTMLDescription,
+						},
 					},
 					Required: []string{"tweet_id"},
 				},
@@ -138,6 +372,28 @@ func (a *Agent) GetTools() []server.ServerTool {
 			},
 			Handler: a.handleGetTweet,
 		},
+		{
+			Tool: mcp.Tool{
+				Name:        "get_tweet_stats",
+				Description: "Get the current likes, retweets, replies and views of a tweet, without the rest of the tweet",
+				InputSchema: mcp.ToolInputSchema{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"tweet_id": map[string]interface{}{
+							"type":        "string",
+							"description": "Tweet ID",
+						},
+					},
+					Required: []string{"tweet_id"},
+				},
+				Annotations: mcp.ToolAnnotation{
+					Title:         "Get Tweet Stats",
+					ReadOnlyHint:  BoolPtr(true),
+					OpenWorldHint: BoolPtr(true),
+				},
+			},
+			Handler: a.handleGetTweetStats,
+		},
 		{
 			Tool: mcp.Tool{
 				Name:        "get_followers",
@@ -195,6 +451,104 @@ func (a *Agent) GetTools() []server.ServerTool {
 			},
 			Handler: a.handleGetTweetReplies,
 		},
+		{
+			Tool: mcp.Tool{
+				Name:        "get_tweet_thread",
+				Description: "Get a tweet together with its nested replies as a conversation tree",
+				InputSchema: mcp.ToolInputSchema{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"tweet_id": map[string]interface{}{
+							"type":        "string",
+							"description": "ID of the tweet to build the thread from",
+						},
+						"max_depth": map[string]interface{}{
+							"type":        "number",
+							"description": "Maximum depth of replies to follow",
+							"default":     defaultThreadDepth,
+						},
+					},
+					Required: []string{"tweet_id"},
+				},
+				Annotations: mcp.ToolAnnotation{
+					Title:         "Get Tweet Thread",
+					ReadOnlyHint:  BoolPtr(true),
+					OpenWorldHint: BoolPtr(true),
+				},
+			},
+			Handler: a.handleGetTweetThread,
+		},
+		{
+			Tool: mcp.Tool{
+				Name:        "get_conversation_context",
+				Description: "Get the chain of parent tweets a reply belongs to, ordered from the root of the conversation to the tweet",
+				InputSchema: mcp.ToolInputSchema{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"tweet_id": map[string]interface{}{
+							"type":        "string",
+							"description": "ID of the tweet to get the context of",
+						},
+						"max_depth": map[string]interface{}{
+							"type":        "number",
+							"description": "Maximum number of parent tweets to follow",
+							"default":     defaultContextDepth,
+						},
+					},
+					Required: []string{"tweet_id"},
+				},
+				Annotations: mcp.ToolAnnotation{
+					Title:         "Get Conversation Context",
+					ReadOnlyHint:  BoolPtr(true),
+					OpenWorldHint: BoolPtr(true),
+				},
+			},
+			Handler: a.handleGetConversationContext,
+		},
+		{
+			Tool: mcp.Tool{
+				Name:        "get_tweets",
+				Description: "Get several tweets by ID in one call. Tweets that can't be fetched get an error entry instead of failing the call",
+				InputSchema: mcp.ToolInputSchema{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"tweet_ids": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]interface{}{"type": "string"},
+							"description": fmt.Sprintf("IDs of the tweets to get, at most %d", maxBatchTweets),
+						},
+						"use_cache": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Serve tweets that are already stored locally without fetching them",
+							"default":     false,
+						},
+					},
+					Required: []string{"tweet_ids"},
+				},
+				Annotations: mcp.ToolAnnotation{
+					Title:         "Get Tweets",
+					ReadOnlyHint:  BoolPtr(true),
+					OpenWorldHint: BoolPtr(true),
+				},
+			},
+			Handler: a.handleGetTweets,
+		},
+		{
+			Tool: mcp.Tool{
+				Name:        "get_trends",
+				Description: "Get the current trending topics",
+				InputSchema: mcp.ToolInputSchema{
+					Type:       "object",
+					Properties: map[string]interface{}{},
+				},
+				Annotations: mcp.ToolAnnotation{
+					Title:         "Get Trends",
+					ReadOnlyHint:  BoolPtr(true),
+					OpenWorldHint: BoolPtr(true),
+				},
+			},
+			Handler: a.handleGetTrends,
+		},
 	}
 
 	// Add tools that require login only if logged in
@@ -216,6 +570,18 @@ func (a *Agent) GetTools() []server.ServerTool {
 								"description": "Maximum number of tweets to fetch",
 								"default":     50,
 							},
+							"since": map[string]interface{}{
+								"type":        "string",
+								"description": "Only tweets from this date on (YYYY-MM-DD)",
+							},
+							"until": map[string]interface{}{
+								"type":        "string",
+								"description": "Only tweets before this date (YYYY-MM-DD)",
+							},
+							"include_html": map[string]interface{}{
+								"type":        "boolean",
+								"description": includeHTMLDescription,
+							},
 						},
 						Required: []string{"query"},
 					},
@@ -227,6 +593,74 @@ func (a *Agent) GetTools() []server.ServerTool {
 				},
 				Handler: a.handleSearchTweets,
 			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "search_tweets_advanced",
+					Description: "Search for tweets by fields instead of a query string. Every given field must match and at least one besides since and until is required",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"keywords": map[string]interface{}{
+								"type":        "string",
+								"description": "Words to search for, used as is",
+							},
+							"from": map[string]interface{}{
+								"type":        "string",
+								"description": "Only tweets by this username",
+							},
+							"to": map[string]interface{}{
+								"type":        "string",
+								"description": "Only replies to this username",
+							},
+							"mentions": map[string]interface{}{
+								"type":        "array",
+								"items":       map[string]interface{}{"type": "string"},
+								"description": "Usernames that must all be mentioned",
+							},
+							"hashtags": map[string]interface{}{
+								"type":        "array",
+								"items":       map[string]interface{}{"type": "string"},
+								"description": "Hashtags that must all be present, with or without #",
+							},
+							"min_likes": map[string]interface{}{
+								"type":        "number",
+								"description": "Only tweets with at least this many likes",
+							},
+							"min_retweets": map[string]interface{}{
+								"type":        "number",
+								"description": "Only tweets with at least this many retweets",
+							},
+							"lang": map[string]interface{}{
+								"type":        "string",
+								"description": "Only tweets in this language, as a code such as en",
+							},
+							"since": map[string]interface{}{
+								"type":        "string",
+								"description": "Only tweets from this date on (YYYY-MM-DD)",
+							},
+							"until": map[string]interface{}{
+								"type":        "string",
+								"description": "Only tweets before this date (YYYY-MM-DD)",
+							},
+							"limit": map[string]interface{}{
+								"type":        "number",
+								"description": "Maximum number of tweets to fetch",
+								"default":     50,
+							},
+							"include_html": map[string]interface{}{
+								"type":        "boolean",
+								"description": includeHTMLDescription,
+							},
+						},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Advanced Search Tweets",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleSearchTweetsAdvanced,
+			},
 			server.ServerTool{
 				Tool: mcp.Tool{
 					Name:        "create_tweet",
@@ -242,6 +676,32 @@ func (a *Agent) GetTools() []server.ServerTool {
 								"type":        "string",
 								"description": "Optional ISO8601 timestamp for scheduled tweets",
 							},
+							"auto_thread": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Split text longer than 280 characters into a numbered self-thread instead of rejecting it",
+							},
+							"poll": map[string]interface{}{
+								"type":        "object",
+								"description": "Optional poll to attach to the tweet",
+								"properties": map[string]interface{}{
+									"options": map[string]interface{}{
+										"type":        "array",
+										"description": "2 to 4 poll choices",
+										"items": map[string]interface{}{
+											"type": "string",
+										},
+									},
+									"duration_minutes": map[string]interface{}{
+										"type":        "number",
+										"description": "How long the poll runs, from 5 minutes to 7 days (default: 1 day)",
+									},
+								},
+								"required": []string{"options"},
+							},
+							"dry_run": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Log the action and return a synthetic success without calling Twitter",
+							},
 						},
 						Required: []string{"text"},
 					},
@@ -251,6 +711,79 @@ func (a *Agent) GetTools() []server.ServerTool {
 				},
 				Handler: a.handleCreateTweet,
 			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "reply_tweet",
+					Description: "Reply to a tweet",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"tweet_id": map[string]interface{}{
+								"type":        "string",
+								"description": "ID of the tweet to reply to",
+							},
+							"text": map[string]interface{}{
+								"type":        "string",
+								"description": "Reply text content",
+							},
+							"dry_run": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Log the action and return a synthetic success without calling Twitter",
+							},
+						},
+						Required: []string{"tweet_id", "text"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title: "Reply To Tweet",
+					},
+				},
+				Handler: a.handleReplyTweet,
+			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "quote_tweet",
+					Description: "Quote a tweet",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"tweet_id": map[string]interface{}{
+								"type":        "string",
+								"description": "ID of the tweet to quote",
+							},
+							"text": map[string]interface{}{
+								"type":        "string",
+								"description": "Text posted above the quoted tweet",
+							},
+							"media": map[string]interface{}{
+								"type":        "array",
+								"description": "Optional uploaded media to attach, at most 4: media IDs, or objects with the media id and an alt_text of at most 1000 characters",
+								"items": map[string]interface{}{
+									"oneOf": []interface{}{
+										map[string]interface{}{"type": "string"},
+										map[string]interface{}{
+											"type": "object",
+											"properties": map[string]interface{}{
+												"id":       map[string]interface{}{"type": "string"},
+												"alt_text": map[string]interface{}{"type": "string"},
+											},
+											"required": []string{"id"},
+										},
+									},
+								},
+							},
+							"dry_run": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Log the action and return a synthetic success without calling Twitter",
+							},
+						},
+						Required: []string{"tweet_id", "text"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title: "Quote Tweet",
+					},
+				},
+				Handler: a.handleQuoteTweet,
+			},
 			server.ServerTool{
 				Tool: mcp.Tool{
 					Name:        "like_tweet",
@@ -262,6 +795,10 @@ func (a *Agent) GetTools() []server.ServerTool {
 								"type":        "string",
 								"description": "ID of the tweet to like",
 							},
+							"dry_run": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Log the action and return a synthetic success without calling Twitter",
+							},
 						},
 						Required: []string{"tweet_id"},
 					},
@@ -282,6 +819,10 @@ func (a *Agent) GetTools() []server.ServerTool {
 								"type":        "string",
 								"description": "ID of the tweet to unlike",
 							},
+							"dry_run": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Log the action and return a synthetic success without calling Twitter",
+							},
 						},
 						Required: []string{"tweet_id"},
 					},
@@ -302,6 +843,10 @@ func (a *Agent) GetTools() []server.ServerTool {
 								"type":        "string",
 								"description": "ID of the tweet to retweet",
 							},
+							"dry_run": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Log the action and return a synthetic success without calling Twitter",
+							},
 						},
 						Required: []string{"tweet_id"},
 					},
@@ -309,72 +854,1189 @@ func (a *Agent) GetTools() []server.ServerTool {
 						Title: "Retweet",
 					},
 				},
-				Handler: a.handleRetweet,
+				Handler: a.handleRetweet,
+			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "get_relationship",
+					Description: "Get whether the logged-in account and a user follow each other. Block and mute state isn't available and is never reported",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"target_user_id": map[string]interface{}{
+								"type":        "string",
+								"description": "ID of the user to get the relationship with",
+							},
+							"source_user_id": map[string]interface{}{
+								"type":        "string",
+								"description": "ID of the logged-in account, defaults to it",
+							},
+						},
+						Required: []string{"target_user_id"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Get Relationship",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleGetRelationship,
+			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "get_notifications",
+					Description: "Get the notifications of the logged-in account, newest first: mentions, replies, likes, retweets and follows with the acting user and the related tweet ID",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"cursor": map[string]interface{}{
+								"type":        "string",
+								"description": "next_cursor of the previous page, to get older notifications",
+							},
+						},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Get Notifications",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleGetNotifications,
+			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "get_quotes",
+					Description: "Get the tweets quoting a tweet, one page at a time. Quotes are found by search, so quotes by protected accounts and some older ones may be missing",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"tweet_id": map[string]interface{}{
+								"type":        "string",
+								"description": "ID of the quoted tweet",
+							},
+							"cursor": map[string]interface{}{
+								"type":        "string",
+								"description": "next_cursor of the previous page",
+							},
+						},
+						Required: []string{"tweet_id"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Get Quote Tweets",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleGetQuoteTweets,
+			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "get_user_replies",
+					Description: "Get only the replies a user posted, one page at a time, each with the ID of the tweet it replies to",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"username": map[string]interface{}{
+								"type":        "string",
+								"description": "Twitter username",
+							},
+							"cursor": map[string]interface{}{
+								"type":        "string",
+								"description": "next_cursor of the previous page",
+							},
+						},
+						Required: []string{"username"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Get User Replies",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleGetUserReplies,
+			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "get_list_tweets",
+					Description: "Get the latest tweets of a list, newest first",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"list_id": map[string]interface{}{
+								"type":        "string",
+								"description": "ID of the list",
+							},
+							"limit": map[string]interface{}{
+								"type":        "number",
+								"description": "Maximum number of tweets to fetch",
+								"default":     defaultListTweetsLimit,
+							},
+						},
+						Required: []string{"list_id"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Get List Tweets",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleGetListTweets,
+			},
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "get_list_members",
+					Description: "Get the members of a list, one page at a time",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"list_id": map[string]interface{}{
+								"type":        "string",
+								"description": "ID of the list",
+							},
+							"cursor": map[string]interface{}{
+								"type":        "string",
+								"description": "next_cursor of the previous page",
+							},
+						},
+						Required: []string{"list_id"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Get List Members",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleGetListMembers,
+			},
+		)
+	}
+
+	// Add GetMoni tools only if a client is configured
+	if a.getmoni != nil {
+		tools = append(tools,
+			server.ServerTool{
+				Tool: mcp.Tool{
+					Name:        "get_smart_followers",
+					Description: "Get the smart followers of a user from GetMoni",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"username": map[string]interface{}{
+								"type":        "string",
+								"description": "Twitter username",
+							},
+							"limit": map[string]interface{}{
+								"type":        "number",
+								"description": "Maximum number of smart followers to fetch",
+								"default":     100,
+							},
+							"order_by": map[string]interface{}{
+								"type":        "string",
+								"description": "Field to order smart followers by",
+								"default":     "FOLLOWERS_COUNT",
+							},
+							"order_direction": map[string]interface{}{
+								"type":        "string",
+								"description": "Order direction, ASC or DESC",
+								"default":     "DESC",
+							},
+						},
+						Required: []string{"username"},
+					},
+					Annotations: mcp.ToolAnnotation{
+						Title:         "Get Smart Followers",
+						ReadOnlyHint:  BoolPtr(true),
+						OpenWorldHint: BoolPtr(true),
+					},
+				},
+				Handler: a.handleGetSmartFollowers,
+			},
+		)
+	}
+
+	return tools
+}
+
+// Tool handlers
+func (a *Agent) handleGetUserTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	username, ok := request.Params.Arguments["username"].(string)
+	if !ok || username == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "username parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	limit := 50
+	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
+		limit = int(limitVal)
+	}
+	limit, limitWarning := ClampLimit(limit, a.maxResults)
+
+	var since time.Time
+	if sinceVal, ok := request.Params.Arguments["since"].(string); ok && sinceVal != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, sinceVal); err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("invalid since time %q, expected RFC 3339", sinceVal),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	sinceID, _ := request.Params.Arguments["since_id"].(string)
+	if sinceID != "" && !validTweetID(sinceID) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("invalid since_id %q, expected a tweet ID", sinceID),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_user_tweets"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	defer a.limiter.doneEndpoint("get_user_tweets")
+
+	withHTML := includeHTML(request)
+
+	// Cancelling fetchCtx stops the scraper from requesting further pages
+	// once the timeline has gone past since or since_id
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tweets := a.scraper.GetTweets(fetchCtx, username, limit)
+	var results []tweetResultJSON
+
+	err := drainTweets(ctx, tweets, func(tweet *twitterscraper.TweetResult) error {
+		if tweet.Error != nil {
+			return tweet.Error
+		}
+		if (!since.IsZero() && tweet.TimeParsed.Before(since)) ||
+			(sinceID != "" && compareTweetIDs(tweet.ID, sinceID) <= 0) {
+			// Pinned tweets and retweets are out of order, so only an
+			// ordinary tweet marks the end of the window
+			if tweet.IsPin || tweet.IsRetweet {
+				return nil
+			}
+			cancel()
+			return errTimelineEnd
+		}
+		result := tweetResultJSON{TweetResult: *tweet}
+		if withHTML {
+			result.HTML = tweet.HTML
+		}
+		results = append(results, result)
+		return nil
+	})
+	if ctx.Err() != nil {
+		// The caller has gone away, so abandon the fetch
+		return nil, ctx.Err()
+	}
+	if err == errTimelineEnd {
+		err = nil
+	}
+	// A page failing mid-stream, e.g. on a rate limit, keeps the tweets
+	// already read
+	var partialWarning string
+	if err != nil && len(results) > 0 {
+		partialWarning = partialResultsWarning(len(results), err)
+		err = nil
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting tweets: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return withWarnings(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, limitWarning, partialWarning), nil
+}
+
+func (a *Agent) handleGetMediaTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	username, ok := request.Params.Arguments["username"].(string)
+	if !ok || username == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "username parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	limit := 50
+	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
+		limit = int(limitVal)
+	}
+	limit, limitWarning := ClampLimit(limit, a.maxResults)
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_media_tweets"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	defer a.limiter.doneEndpoint("get_media_tweets")
+
+	tweets := a.scraper.GetMediaTweets(ctx, username, limit)
+	results := make([]MediaTweet, 0)
+
+	for tweet := range tweets {
+		if tweet.Error != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("error getting media tweets: %v", tweet.Error),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		results = append(results, newMediaTweet(&tweet.Tweet))
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return withWarnings(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, limitWarning), nil
+}
+
+func (a *Agent) handleGetProfile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	username, ok := request.Params.Arguments["username"].(string)
+	if !ok || username == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "username parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_profile"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	profile, err := a.scraper.GetProfile(ctx, username)
+	a.limiter.doneEndpoint("get_profile")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting profile: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	response := newUserProfile(profile)
+	if includePinned, _ := request.Params.Arguments["include_pinned"].(bool); includePinned {
+		a.addPinnedTweet(ctx, &response)
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+func (a *Agent) handleGetTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "tweet_id parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_tweet"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweet, err := a.scraper.GetTweet(ctx, tweetID)
+	a.limiter.doneEndpoint("get_tweet")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting tweet: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	result := tweetJSON{Tweet: tweet}
+	if includeHTML(request) {
+		result.HTML = tweet.HTML
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+func (a *Agent) handleSearchTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	query, ok := request.Params.Arguments["query"].(string)
+	if !ok || query == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "query parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	limit := 50
+	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
+		limit = int(limitVal)
+	}
+	limit, limitWarning := ClampLimit(limit, a.maxResults)
+
+	var opts SearchOptions
+	opts.Since, _ = request.Params.Arguments["since"].(string)
+	opts.Until, _ = request.Params.Arguments["until"].(string)
+	if err := opts.Validate(); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: err.Error(),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	query = opts.apply(query)
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "search_tweets"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	defer a.limiter.doneEndpoint("search_tweets")
+
+	withHTML := includeHTML(request)
+	tweets := a.scraper.SearchTweets(ctx, query, limit)
+	var results []map[string]interface{}
+
+	err := drainTweets(ctx, tweets, func(tweet *twitterscraper.TweetResult) error {
+		if tweet.Error != nil {
+			return tweet.Error
+		}
+		result := map[string]interface{}{
+			"id":        tweet.ID,
+			"text":      tweet.Text,
+			"likes":     tweet.Likes,
+			"retweets":  tweet.Retweets,
+			"replies":   tweet.Replies,
+			"timestamp": tweet.TimeParsed,
+			"author": map[string]interface{}{
+				"username": tweet.Username,
+				"name":     tweet.Name,
+			},
+		}
+		addTweetEntities(result, &tweet.Tweet)
+		if withHTML && tweet.HTML != "" {
+			result["html"] = tweet.HTML
+		}
+		results = append(results, result)
+		return nil
+	})
+	if ctx.Err() != nil {
+		// The caller has gone away, so abandon the search
+		return nil, ctx.Err()
+	}
+	var partialWarning string
+	if err != nil && len(results) > 0 {
+		partialWarning = partialResultsWarning(len(results), err)
+		err = nil
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error searching tweets: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return withWarnings(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, limitWarning, partialWarning), nil
+}
+
+func (a *Agent) handleCreateTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	text, ok := request.Params.Arguments["text"].(string)
+	if !ok || text == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "text parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	autoThread, _ := request.Params.Arguments["auto_thread"].(bool)
+
+	if length := tweetLength(text); length > maxTweetLength && !autoThread {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("tweet exceeds %d characters (counted %d)", maxTweetLength, length),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	parts := []string{text}
+	if autoThread {
+		parts = splitThread(text)
+	}
+
+	var poll *Poll
+	if pollArg, ok := request.Params.Arguments["poll"]; ok && pollArg != nil {
+		var err error
+		poll, err = parsePollArgument(pollArg)
+		if err == nil && len(parts) > 1 {
+			err = fmt.Errorf("a poll can't be attached to an auto thread")
+		}
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Type: "text",
+						Text: err.Error(),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would create %d tweet(s): %q", a.username, len(parts), parts)
+		result := map[string]interface{}{
+			"dry_run": true,
+			"text":    text,
+		}
+		if len(parts) > 1 {
+			result["parts"] = parts
+		}
+		if poll != nil {
+			result["poll"] = poll
+		}
+		jsonData, _ := json.Marshal(result)
+		return dryRunResult(string(jsonData)), nil
+	}
+
+	if len(parts) > 1 {
+		ids, err := a.createThread(ctx, parts)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("error creating thread after posting %v: %v", ids, err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+
+		jsonData, err := json.Marshal(map[string]interface{}{
+			"tweet_ids": ids,
+		})
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("error marshaling results: %v", err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: string(jsonData),
+				},
+			},
+		}, nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "create_tweet"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	var tweet *twitterscraper.Tweet
+	var err error
+	if poll != nil {
+		tweet, err = a.scraper.TweetWithPoll(ctx, text, *poll)
+	} else {
+		tweet, err = a.scraper.Tweet(ctx, text)
+	}
+	a.limiter.doneEndpoint("create_tweet")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error creating tweet: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	jsonData, err := json.Marshal(tweet)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+func (a *Agent) handleReplyTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "tweet_id parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	text, ok := request.Params.Arguments["text"].(string)
+	if !ok || text == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "text parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if length := tweetLength(text); length > maxTweetLength {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("tweet exceeds %d characters (counted %d)", maxTweetLength, length),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would reply to tweet %s: %q", a.username, tweetID, text)
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"dry_run":     true,
+			"in_reply_to": tweetID,
+			"text":        text,
+		})
+		return dryRunResult(string(jsonData)), nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "create_tweet"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweet, err := a.scraper.ReplyTweet(ctx, text, tweetID)
+	a.limiter.doneEndpoint("create_tweet")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error replying to tweet: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	jsonData, err := json.Marshal(tweet)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+func (a *Agent) handleQuoteTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "tweet_id parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	text, _ := request.Params.Arguments["text"].(string)
+	if length := tweetLength(text); length > maxTweetLength {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("tweet exceeds %d characters (counted %d)", maxTweetLength, length),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	var media []MediaAttachment
+	if mediaArg, ok := request.Params.Arguments["media"]; ok && mediaArg != nil {
+		var err error
+		if media, err = parseMediaArgument(mediaArg); err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Type: "text",
+						Text: err.Error(),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	// A quote posted through POST /api/tweet may be media alone
+	if text == "" && len(media) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "text parameter is required unless media is attached",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would quote tweet %s with %d media: %q", a.username, tweetID, len(media), text)
+		result := map[string]interface{}{
+			"dry_run":  true,
+			"quote_of": tweetID,
+			"text":     text,
+		}
+		if len(media) > 0 {
+			result["media"] = media
+		}
+		jsonData, _ := json.Marshal(result)
+		return dryRunResult(string(jsonData)), nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "create_tweet"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Alt text has to be set before the media is attached. The media stays
+	// uploaded when it fails, so the quote isn't posted and the caller can
+	// decide whether to post it without alt text.
+	for _, m := range media {
+		if m.AltText == "" {
+			continue
+		}
+		if err := a.scraper.SetMediaAltText(ctx, m.ID, m.AltText); err != nil {
+			a.limiter.doneEndpoint("create_tweet")
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("error setting alt text of media %s, which is uploaded but the tweet wasn't posted: %v", m.ID, err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	tweet, err := a.scraper.QuoteTweet(ctx, text, tweetID, mediaIDs(media))
+	a.limiter.doneEndpoint("create_tweet")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error quoting tweet: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	jsonData, err := json.Marshal(tweet)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
 			},
-		)
+			IsError: true,
+		}, nil
 	}
 
-	return tools
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
 }
 
-// Tool handlers
-func (a *Agent) handleGetUserTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	username, ok := request.Params.Arguments["username"].(string)
-	if !ok || username == "" {
+func (a *Agent) handleLikeTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "username parameter is required",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	limit := 50
-	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
-		limit = int(limitVal)
-	}
-
-	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "get_user_tweets"); err != nil {
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("rate limit error: %v", err),
+					Text: "tweet_id is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	tweets := a.scraper.GetTweets(ctx, username, limit)
-	var results []twitterscraper.TweetResult
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would like tweet %s", a.username, tweetID)
+		return dryRunResult("Dry run: tweet would be liked"), nil
+	}
 
-	for tweet := range tweets {
-		if tweet.Error != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					&mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("error getting tweets: %v", tweet.Error),
-					},
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "like_tweet"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
 				},
-				IsError: true,
-			}, nil
-		}
-		results = append(results, *tweet)
+			},
+			IsError: true,
+		}, nil
 	}
 
-	jsonData, err := json.Marshal(results)
+	err := a.scraper.LikeTweet(ctx, tweetID)
+	a.limiter.doneEndpoint("like_tweet")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error marshaling results: %v", err),
+					Text: fmt.Sprintf("error liking tweet: %v", err),
 				},
 			},
 			IsError: true,
@@ -385,59 +2047,64 @@ func (a *Agent) handleGetUserTweets(ctx context.Context, request mcp.CallToolReq
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Type: "text",
-				Text: string(jsonData),
+				Text: "Tweet liked successfully",
 			},
 		},
 	}, nil
 }
 
-func (a *Agent) handleGetProfile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	username, ok := request.Params.Arguments["username"].(string)
-	if !ok || username == "" {
+func (a *Agent) handleFollowUser(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "username parameter is required",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "get_profile"); err != nil {
+	userID, ok := request.Params.Arguments["user_id"].(string)
+	if !ok || userID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("rate limit error: %v", err),
+					Text: "user_id is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	profile, err := a.scraper.GetProfile(ctx, username)
-	if err != nil {
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would follow user %s", a.username, userID)
+		return dryRunResult("Dry run: user would be followed"), nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "follow_user"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error getting profile: %v", err),
+					Text: fmt.Sprintf("rate limit error: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	jsonData, err := json.Marshal(profile)
+	err := a.scraper.Follow(ctx, userID)
+	a.limiter.doneEndpoint("follow_user")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error marshaling results: %v", err),
+					Text: fmt.Sprintf("error following user: %v", err),
 				},
 			},
 			IsError: true,
@@ -448,59 +2115,64 @@ func (a *Agent) handleGetProfile(ctx context.Context, request mcp.CallToolReques
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Type: "text",
-				Text: string(jsonData),
+				Text: "User followed successfully",
 			},
 		},
 	}, nil
 }
 
-func (a *Agent) handleGetTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
-	if !ok || tweetID == "" {
+func (a *Agent) handleUnfollowUser(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "tweet_id parameter is required",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "get_tweet"); err != nil {
+	userID, ok := request.Params.Arguments["user_id"].(string)
+	if !ok || userID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("rate limit error: %v", err),
+					Text: "user_id is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	tweet, err := a.scraper.GetTweet(ctx, tweetID)
-	if err != nil {
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would unfollow user %s", a.username, userID)
+		return dryRunResult("Dry run: user would be unfollowed"), nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "unfollow_user"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error getting tweet: %v", err),
+					Text: fmt.Sprintf("rate limit error: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	jsonData, err := json.Marshal(tweet)
+	err := a.scraper.Unfollow(ctx, userID)
+	a.limiter.doneEndpoint("unfollow_user")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error marshaling results: %v", err),
+					Text: fmt.Sprintf("error unfollowing user: %v", err),
 				},
 			},
 			IsError: true,
@@ -511,13 +2183,13 @@ func (a *Agent) handleGetTweet(ctx context.Context, request mcp.CallToolRequest)
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Type: "text",
-				Text: string(jsonData),
+				Text: "User unfollowed successfully",
 			},
 		},
 	}, nil
 }
 
-func (a *Agent) handleSearchTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (a *Agent) handleUnlikeTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if !a.scraper.IsLoggedIn() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -530,26 +2202,26 @@ func (a *Agent) handleSearchTweets(ctx context.Context, request mcp.CallToolRequ
 		}, nil
 	}
 
-	query, ok := request.Params.Arguments["query"].(string)
-	if !ok || query == "" {
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "query parameter is required",
+					Text: "tweet_id is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	limit := 50
-	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
-		limit = int(limitVal)
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would unlike tweet %s", a.username, tweetID)
+		return dryRunResult("Dry run: tweet would be unliked"), nil
 	}
 
 	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "search_tweets"); err != nil {
+	if err := a.limiter.waitForEndpoint(ctx, "unlike_tweet"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
@@ -561,42 +2233,14 @@ func (a *Agent) handleSearchTweets(ctx context.Context, request mcp.CallToolRequ
 		}, nil
 	}
 
-	tweets := a.scraper.SearchTweets(ctx, query, limit)
-	var results []map[string]interface{}
-
-	for tweet := range tweets {
-		if tweet.Error != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					&mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("error searching tweets: %v", tweet.Error),
-					},
-				},
-				IsError: true,
-			}, nil
-		}
-		results = append(results, map[string]interface{}{
-			"id":        tweet.ID,
-			"text":      tweet.Text,
-			"likes":     tweet.Likes,
-			"retweets":  tweet.Retweets,
-			"replies":   tweet.Replies,
-			"timestamp": tweet.TimeParsed,
-			"author": map[string]interface{}{
-				"username": tweet.Username,
-				"name":     tweet.Name,
-			},
-		})
-	}
-
-	jsonData, err := json.Marshal(results)
+	err := a.scraper.UnlikeTweet(ctx, tweetID)
+	a.limiter.doneEndpoint("unlike_tweet")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error marshaling results: %v", err),
+					Text: fmt.Sprintf("error unliking tweet: %v", err),
 				},
 			},
 			IsError: true,
@@ -607,13 +2251,13 @@ func (a *Agent) handleSearchTweets(ctx context.Context, request mcp.CallToolRequ
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Type: "text",
-				Text: string(jsonData),
+				Text: "Tweet unliked successfully",
 			},
 		},
 	}, nil
 }
 
-func (a *Agent) handleCreateTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (a *Agent) handleRetweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if !a.scraper.IsLoggedIn() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -626,21 +2270,26 @@ func (a *Agent) handleCreateTweet(ctx context.Context, request mcp.CallToolReque
 		}, nil
 	}
 
-	text, ok := request.Params.Arguments["text"].(string)
-	if !ok || text == "" {
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "text parameter is required",
+					Text: "tweet_id is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
+	if isDryRunRequest(request) {
+		a.logger.Info("Dry run: agent %s would retweet tweet %s", a.username, tweetID)
+		return dryRunResult("Dry run: tweet would be retweeted"), nil
+	}
+
 	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "create_tweet"); err != nil {
+	if err := a.limiter.waitForEndpoint(ctx, "retweet"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
@@ -652,20 +2301,87 @@ func (a *Agent) handleCreateTweet(ctx context.Context, request mcp.CallToolReque
 		}, nil
 	}
 
-	tweet, err := a.scraper.Tweet(ctx, text)
+	err := a.scraper.CreateRetweet(ctx, tweetID)
+	a.limiter.doneEndpoint("retweet")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error creating tweet: %v", err),
+					Text: fmt.Sprintf("error retweeting: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	jsonData, err := json.Marshal(tweet)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: "Tweet retweeted successfully",
+			},
+		},
+	}, nil
+}
+
+func (a *Agent) handleGetFollowers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	username, ok := request.Params.Arguments["username"].(string)
+	if !ok || username == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "username parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	limit := 50
+	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
+		limit = int(limitVal)
+	}
+
+	cursor := ""
+	if cursorVal, ok := request.Params.Arguments["cursor"].(string); ok {
+		cursor = cursorVal
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_followers"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	followers, nextCursor, err := a.scraper.FetchFollowers(ctx, username, limit, cursor)
+	a.limiter.doneEndpoint("get_followers")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting followers: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	result := map[string]interface{}{
+		"followers":   followers,
+		"next_cursor": nextCursor,
+	}
+
+	jsonData, err := json.Marshal(result)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -688,52 +2404,91 @@ func (a *Agent) handleCreateTweet(ctx context.Context, request mcp.CallToolReque
 	}, nil
 }
 
-func (a *Agent) handleLikeTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if !a.scraper.IsLoggedIn() {
+func (a *Agent) handleGetTweetReplies(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+					Text: "tweet_id parameter is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
-	if !ok || tweetID == "" {
+	cursor := ""
+	if cursorVal, ok := request.Params.Arguments["cursor"].(string); ok {
+		cursor = cursorVal
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_tweet_replies"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "tweet_id is required",
+					Text: fmt.Sprintf("rate limit error: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "like_tweet"); err != nil {
+	replies, nextCursor, err := a.scraper.GetTweetReplies(tweetID, cursor)
+	a.limiter.doneEndpoint("get_tweet_replies")
+	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("rate limit error: %v", err),
+					Text: fmt.Sprintf("error getting tweet replies: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	err := a.scraper.LikeTweet(ctx, tweetID)
+	// Create simplified tweet structures to avoid circular references
+	simplifiedReplies := make([]SimplifiedTweet, 0, len(replies))
+	for _, reply := range replies {
+		simplifiedReplies = append(simplifiedReplies, newSimplifiedTweet(reply))
+	}
+
+	// Create simplified cursor structure
+	type SimplifiedCursor struct {
+		FocalTweetID string `json:"focal_tweet_id"`
+		ThreadID     string `json:"thread_id"`
+		Cursor       string `json:"cursor"`
+		CursorType   string `json:"cursor_type"`
+	}
+
+	simplifiedCursors := make([]SimplifiedCursor, 0, len(nextCursor))
+	for _, cursor := range nextCursor {
+		simplifiedCursors = append(simplifiedCursors, SimplifiedCursor{
+			FocalTweetID: cursor.FocalTweetID,
+			ThreadID:     cursor.ThreadID,
+			Cursor:       cursor.Cursor,
+			CursorType:   cursor.CursorType,
+		})
+	}
+
+	result := struct {
+		Replies    []SimplifiedTweet  `json:"replies"`
+		NextCursor []SimplifiedCursor `json:"next_cursor"`
+	}{
+		Replies:    simplifiedReplies,
+		NextCursor: simplifiedCursors,
+	}
+
+	jsonData, err := json.Marshal(result)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error liking tweet: %v", err),
+					Text: fmt.Sprintf("error marshaling results: %v", err),
 				},
 			},
 			IsError: true,
@@ -744,40 +2499,36 @@ func (a *Agent) handleLikeTweet(ctx context.Context, request mcp.CallToolRequest
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Type: "text",
-				Text: "Tweet liked successfully",
+				Text: string(jsonData),
 			},
 		},
 	}, nil
 }
 
-func (a *Agent) handleFollowUser(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if !a.scraper.IsLoggedIn() {
+func (a *Agent) handleGetTweetThread(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+					Text: "tweet_id parameter is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	userID, ok := request.Params.Arguments["user_id"].(string)
-	if !ok || userID == "" {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Type: "text",
-					Text: "user_id is required",
-				},
-			},
-			IsError: true,
-		}, nil
+	maxDepth := defaultThreadDepth
+	if depthVal, ok := request.Params.Arguments["max_depth"].(float64); ok && depthVal > 0 {
+		maxDepth = int(depthVal)
+	}
+	if maxDepth > maxThreadDepth {
+		maxDepth = maxThreadDepth
 	}
 
 	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "follow_user"); err != nil {
+	if err := a.limiter.waitForEndpoint(ctx, "get_tweet"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
@@ -789,75 +2540,79 @@ func (a *Agent) handleFollowUser(ctx context.Context, request mcp.CallToolReques
 		}, nil
 	}
 
-	err := a.scraper.Follow(ctx, userID)
+	focal, err := a.scraper.GetTweet(ctx, tweetID)
+	a.limiter.doneEndpoint("get_tweet")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error following user: %v", err),
+					Text: fmt.Sprintf("error getting tweet: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			&mcp.TextContent{
-				Type: "text",
-				Text: "User followed successfully",
-			},
-		},
-	}, nil
-}
-
-func (a *Agent) handleUnfollowUser(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if !a.scraper.IsLoggedIn() {
+	fetched := 0
+	thread, err := a.buildThread(ctx, focal, 0, maxDepth, &fetched)
+	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+					Text: fmt.Sprintf("error getting tweet thread: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	userID, ok := request.Params.Arguments["user_id"].(string)
-	if !ok || userID == "" {
+	jsonData, err := json.Marshal(thread)
+	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "user_id is required",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "unfollow_user"); err != nil {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+func (a *Agent) handleGetTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ids, err := parseTweetIDs(request.Params.Arguments["tweet_ids"])
+	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("rate limit error: %v", err),
+					Text: err.Error(),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
+	useCache, _ := request.Params.Arguments["use_cache"].(bool)
 
-	err := a.scraper.Unfollow(ctx, userID)
+	jsonData, err := json.Marshal(a.fetchTweets(ctx, ids, useCache))
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error unfollowing user: %v", err),
+					Text: fmt.Sprintf("error marshaling results: %v", err),
 				},
 			},
 			IsError: true,
@@ -868,58 +2623,54 @@ func (a *Agent) handleUnfollowUser(ctx context.Context, request mcp.CallToolRequ
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Type: "text",
-				Text: "User unfollowed successfully",
+				Text: string(jsonData),
 			},
 		},
 	}, nil
 }
 
-func (a *Agent) handleUnlikeTweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if !a.scraper.IsLoggedIn() {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Type: "text",
-					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
-				},
-			},
-			IsError: true,
-		}, nil
-	}
-
+func (a *Agent) handleGetConversationContext(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
 	if !ok || tweetID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "tweet_id is required",
+					Text: "tweet_id parameter is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "unlike_tweet"); err != nil {
+	maxDepth := defaultContextDepth
+	if depthVal, ok := request.Params.Arguments["max_depth"].(float64); ok && depthVal > 0 {
+		maxDepth = int(depthVal)
+	}
+	if maxDepth > maxContextDepth {
+		maxDepth = maxContextDepth
+	}
+
+	conversation, err := a.buildConversationContext(ctx, tweetID, maxDepth)
+	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("rate limit error: %v", err),
+					Text: fmt.Sprintf("error getting tweet: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	err := a.scraper.UnlikeTweet(ctx, tweetID)
+	jsonData, err := json.Marshal(conversation)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error unliking tweet: %v", err),
+					Text: fmt.Sprintf("error marshaling results: %v", err),
 				},
 			},
 			IsError: true,
@@ -930,13 +2681,13 @@ func (a *Agent) handleUnlikeTweet(ctx context.Context, request mcp.CallToolReque
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Type: "text",
-				Text: "Tweet unliked successfully",
+				Text: string(jsonData),
 			},
 		},
 	}, nil
 }
 
-func (a *Agent) handleRetweet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (a *Agent) handleGetRelationship(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if !a.scraper.IsLoggedIn() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -949,21 +2700,22 @@ func (a *Agent) handleRetweet(ctx context.Context, request mcp.CallToolRequest)
 		}, nil
 	}
 
-	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
-	if !ok || tweetID == "" {
+	targetUserID, ok := request.Params.Arguments["target_user_id"].(string)
+	if !ok || targetUserID == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "tweet_id is required",
+					Text: "target_user_id is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
+	sourceUserID, _ := request.Params.Arguments["source_user_id"].(string)
 
 	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "retweet"); err != nil {
+	if err := a.limiter.waitForEndpoint(ctx, "get_relationship"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
@@ -975,55 +2727,53 @@ func (a *Agent) handleRetweet(ctx context.Context, request mcp.CallToolRequest)
 		}, nil
 	}
 
-	err := a.scraper.CreateRetweet(ctx, tweetID)
+	following, followedBy, blocked, muted, err := a.scraper.GetRelationship(ctx, sourceUserID, targetUserID)
+	a.limiter.doneEndpoint("get_relationship")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error retweeting: %v", err),
+					Text: fmt.Sprintf("error getting relationship: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			&mcp.TextContent{
-				Type: "text",
-				Text: "Tweet retweeted successfully",
-			},
-		},
-	}, nil
-}
-
-func (a *Agent) handleGetFollowers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	username, ok := request.Params.Arguments["username"].(string)
-	if !ok || username == "" {
+	jsonData, err := json.Marshal(Relationship{
+		SourceUserID: sourceUserID,
+		TargetUserID: targetUserID,
+		Following:    following,
+		FollowedBy:   followedBy,
+		Blocked:      blocked,
+		Muted:        muted,
+	})
+	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "username parameter is required",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	limit := 50
-	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
-		limit = int(limitVal)
-	}
-
-	cursor := ""
-	if cursorVal, ok := request.Params.Arguments["cursor"].(string); ok {
-		cursor = cursorVal
-	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
 
+func (a *Agent) handleGetTrends(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "get_followers"); err != nil {
+	if err := a.limiter.waitForEndpoint(ctx, "get_trends"); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
@@ -1035,25 +2785,21 @@ func (a *Agent) handleGetFollowers(ctx context.Context, request mcp.CallToolRequ
 		}, nil
 	}
 
-	followers, nextCursor, err := a.scraper.FetchFollowers(username, limit, cursor)
+	trends, err := a.scraper.GetTrends(ctx)
+	a.limiter.doneEndpoint("get_trends")
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error getting followers: %v", err),
+					Text: fmt.Sprintf("error getting trends: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	result := map[string]interface{}{
-		"followers":   followers,
-		"next_cursor": nextCursor,
-	}
-
-	jsonData, err := json.Marshal(result)
+	jsonData, err := json.Marshal(trends)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -1076,103 +2822,48 @@ func (a *Agent) handleGetFollowers(ctx context.Context, request mcp.CallToolRequ
 	}, nil
 }
 
-func (a *Agent) handleGetTweetReplies(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
-	if !ok || tweetID == "" {
+func (a *Agent) handleGetSmartFollowers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	username, ok := request.Params.Arguments["username"].(string)
+	if !ok || username == "" {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: "tweet_id parameter is required",
+					Text: "username parameter is required",
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	cursor := ""
-	if cursorVal, ok := request.Params.Arguments["cursor"].(string); ok {
-		cursor = cursorVal
+	limit := 100
+	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
+		limit = int(limitVal)
 	}
 
-	// Wait for rate limit
-	if err := a.limiter.waitForEndpoint(ctx, "get_tweet_replies"); err != nil {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Type: "text",
-					Text: fmt.Sprintf("rate limit error: %v", err),
-				},
-			},
-			IsError: true,
-		}, nil
+	orderBy := "FOLLOWERS_COUNT"
+	if orderByVal, ok := request.Params.Arguments["order_by"].(string); ok && orderByVal != "" {
+		orderBy = orderByVal
 	}
 
-	replies, nextCursor, err := a.scraper.GetTweetReplies(tweetID, cursor)
+	orderDirection := "DESC"
+	if directionVal, ok := request.Params.Arguments["order_direction"].(string); ok && directionVal != "" {
+		orderDirection = directionVal
+	}
+
+	result, err := a.getmoni.GetSmartFollowers(username, limit, 0, orderBy, orderDirection)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("error getting tweet replies: %v", err),
+					Text: fmt.Sprintf("error getting smart followers: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	// Create simplified tweet structures to avoid circular references
-	type SimplifiedTweet struct {
-		ID         string    `json:"id"`
-		Text       string    `json:"text"`
-		Username   string    `json:"username"`
-		Name       string    `json:"name"`
-		Likes      int       `json:"likes"`
-		Retweets   int       `json:"retweets"`
-		Replies    int       `json:"replies"`
-		TimeParsed time.Time `json:"timestamp"`
-	}
-
-	simplifiedReplies := make([]SimplifiedTweet, 0, len(replies))
-	for _, reply := range replies {
-		simplifiedReplies = append(simplifiedReplies, SimplifiedTweet{
-			ID:         reply.ID,
-			Text:       reply.Text,
-			Username:   reply.Username,
-			Name:       reply.Name,
-			Likes:      reply.Likes,
-			Retweets:   reply.Retweets,
-			Replies:    reply.Replies,
-			TimeParsed: reply.TimeParsed,
-		})
-	}
-
-	// Create simplified cursor structure
-	type SimplifiedCursor struct {
-		FocalTweetID string `json:"focal_tweet_id"`
-		ThreadID     string `json:"thread_id"`
-		Cursor       string `json:"cursor"`
-		CursorType   string `json:"cursor_type"`
-	}
-
-	simplifiedCursors := make([]SimplifiedCursor, 0, len(nextCursor))
-	for _, cursor := range nextCursor {
-		simplifiedCursors = append(simplifiedCursors, SimplifiedCursor{
-			FocalTweetID: cursor.FocalTweetID,
-			ThreadID:     cursor.ThreadID,
-			Cursor:       cursor.Cursor,
-			CursorType:   cursor.CursorType,
-		})
-	}
-
-	result := struct {
-		Replies    []SimplifiedTweet  `json:"replies"`
-		NextCursor []SimplifiedCursor `json:"next_cursor"`
-	}{
-		Replies:    simplifiedReplies,
-		NextCursor: simplifiedCursors,
-	}
-
 	jsonData, err := json.Marshal(result)
 	if err != nil {
 		return &mcp.CallToolResult{
@@ -1220,6 +2911,7 @@ func (a *Agent) HandleGetUserTweets(ctx context.Context, username string, limit
 				"username":       username,
 				"limit":          float64(limit),
 				"sort_by_oldest": sortByOldest,
+				"include_html":   true,
 			},
 		},
 	})
@@ -1275,7 +2967,8 @@ func (a *Agent) HandleGetTweet(ctx context.Context, tweetID string) (interface{}
 			} `json:"_meta,omitempty"`
 		}{
 			Arguments: map[string]interface{}{
-				"tweet_id": tweetID,
+				"tweet_id":     tweetID,
+				"include_html": true,
 			},
 		},
 	})
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/asabya/x-go/pkg/logging"
 	"github.com/asabya/x-go/pkg/twitter/auth"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -18,83 +20,391 @@ import (
 var (
 	ErrInvalidAgentIndex = errors.New("invalid agent index")
 	ErrNoAccounts        = errors.New("no accounts found")
+	// ErrInvalidTweetRequest is wrapped by the errors PostTweet returns for
+	// requests combining options that can't be posted together
+	ErrInvalidTweetRequest = errors.New("invalid tweet request")
 )
 
 // AgentManager manages multiple Twitter agents and rotates between them for API calls
 type AgentManager struct {
-	agents      []*Agent
-	mutex       sync.RWMutex
-	index       uint32 // For round-robin agent selection
-	authManager *auth.AccountManager
-	logger      *log.Logger
+	agents        []*Agent
+	mutex         sync.RWMutex
+	index         uint32 // For round-robin agent selection
+	authManager   *auth.AccountManager
+	logger        logging.Logger
+	dryRun        bool                 // Skip the scraper for write operations
+	userAgent     string               // Default User-Agent for accounts that don't set one
+	retryConfig   RetryConfig          // Retrying of transient errors, applied to every agent
+	breakerConfig CircuitBreakerConfig // Circuit breaking of failing agents, applied to every agent
+	maxResults    int                  // Cap on search and timeline limits, applied to every agent
+	tweetStore    TweetStore           // Locally stored tweets, applied to every agent
+	concurrency   map[string]int       // Cap on concurrent calls per endpoint, applied to every agent
+
+	loginThrottle LoginThrottle  // Spacing of the logins made at startup and on reload
+	newScraper    func() Scraper // Creates the scrapers of new agents; nil uses twitter-scraper
+
+	reloadMutex sync.Mutex // Serializes ReloadAccounts calls
+
+	startupStatuses []AgentStartupStatus // Outcome of the latest login of each configured account
+
+	cookieMutex  sync.Mutex
+	savedCookies map[string]string // Fingerprint of the cookies last saved for each agent, by username
+
+	profileMutex sync.Mutex
+	profileCache map[string]cachedAccountProfile // Own profile of each agent, by username
+
+	userIDMutex sync.Mutex
+	userIDCache map[string]cachedUserID // User IDs resolved by ResolveUserID, by username
+
+	loginMutex     sync.Mutex
+	hasLoggedIn    bool                         // Cached result of HasLoggedInAgent
+	loginCheckedAt time.Time                    // When hasLoggedIn was last refreshed
+	loginStatuses  map[string]cachedLoginStatus // Login state of each agent shown by AccountStatuses, by username
+
+	trendsMutex     sync.Mutex
+	trends          interface{} // Cached result of GetTrends
+	trendsAgent     string      // Agent that fetched the cached trends
+	trendsFetchedAt time.Time   // When trends was last refreshed
+}
+
+// trendsTTL is how long GetTrends serves cached trends before fetching again
+const trendsTTL = 5 * time.Minute
+
+// ManagerOption configures optional AgentManager behaviour
+type ManagerOption func(*AgentManager)
+
+// WithDryRun makes every write operation log the intended action and return
+// a synthetic success response instead of calling Twitter
+func WithDryRun(enabled bool) ManagerOption {
+	return func(am *AgentManager) {
+		am.dryRun = enabled
+	}
+}
+
+// WithUserAgent sets the User-Agent used by agents whose account doesn't set
+// its own. An empty value keeps the scraper's default.
+func WithUserAgent(userAgent string) ManagerOption {
+	return func(am *AgentManager) {
+		am.userAgent = userAgent
+	}
+}
+
+// WithLogger sets the logger used by the manager and its agents, replacing
+// an info level logger on stderr
+func WithLogger(logger logging.Logger) ManagerOption {
+	return func(am *AgentManager) {
+		am.logger = logger
+	}
+}
+
+// WithRetryConfig sets how agents retry transient Twitter errors, replacing
+// DefaultRetryConfig. A MaxAttempts below 2 disables retries.
+func WithRetryConfig(config RetryConfig) ManagerOption {
+	return func(am *AgentManager) {
+		am.retryConfig = config
+	}
+}
+
+// WithCircuitBreaker sets when agents stop calling Twitter after repeated
+// failures, replacing DefaultCircuitBreakerConfig. A FailureThreshold of 0
+// disables circuit breaking.
+func WithCircuitBreaker(config CircuitBreakerConfig) ManagerOption {
+	return func(am *AgentManager) {
+		am.breakerConfig = config
+	}
+}
+
+// WithMaxResults sets the largest limit agents accept for tweet searches and
+// timelines, replacing DefaultMaxResults. 0 disables the cap.
+func WithMaxResults(maxResults int) ManagerOption {
+	return func(am *AgentManager) {
+		am.maxResults = maxResults
+	}
+}
+
+// WithEndpointConcurrency caps how many calls to each endpoint of limits,
+// such as search_tweets, every agent runs at once. Further calls wait for a
+// running one to finish. Endpoints missing from limits aren't capped.
+func WithEndpointConcurrency(limits map[string]int) ManagerOption {
+	return func(am *AgentManager) {
+		am.concurrency = limits
+	}
+}
+
+// WithTweetStore lets agents read tweets from store instead of Twitter
+// when building conversation contexts
+func WithTweetStore(store TweetStore) ManagerOption {
+	return func(am *AgentManager) {
+		am.tweetStore = store
+	}
+}
+
+// WithLoginThrottle sets the wait between the login attempts made at
+// startup, replacing DefaultLoginThrottle. A zero LoginThrottle disables it.
+func WithLoginThrottle(throttle LoginThrottle) ManagerOption {
+	return func(am *AgentManager) {
+		am.loginThrottle = throttle
+	}
 }
 
 // NewAgentManager creates a new AgentManager with the provided agents
-func NewAgentManager(xgoPath string) (*AgentManager, error) {
+func NewAgentManager(xgoPath string, opts ...ManagerOption) (*AgentManager, error) {
 	authManager := auth.NewAccountManager(xgoPath)
 
-	// Load accounts from accounts.json
-	accounts, err := authManager.LoadAccounts()
+	am := &AgentManager{
+		index:         0,
+		authManager:   authManager,
+		logger:        logging.Default(),
+		profileCache:  make(map[string]cachedAccountProfile),
+		retryConfig:   DefaultRetryConfig,
+		breakerConfig: DefaultCircuitBreakerConfig,
+		maxResults:    DefaultMaxResults,
+		loginThrottle: DefaultLoginThrottle,
+	}
+	for _, opt := range opts {
+		opt(am)
+	}
+
+	// Load accounts from accounts.json and XGO_ACCOUNTS_JSON
+	accounts, err := authManager.LoadAllAccounts()
 	if err != nil {
-		log.Printf("Failed to load accounts: %v", err)
+		am.logger.Error("Failed to load accounts: %v", err)
 		return nil, fmt.Errorf("failed to load accounts: %w", err)
 	}
 
 	if len(accounts) == 0 {
-		log.Printf("No accounts found in accounts.json")
+		am.logger.Error("No accounts found in accounts.json or %s", auth.AccountsEnvVar)
 		return nil, ErrNoAccounts
 	}
 
-	agents := make([]*Agent, len(accounts))
-	for i, account := range accounts {
-		agent := NewAgent(account.Username)
-
-		// Try to load cookies first
-		if authManager.CookiesExist(account.Username) {
-			cookies, err := authManager.LoadCookies(account.Username)
-			if err == nil {
-				agent.SetCookies(cookies)
-				log.Printf("Loaded cookies for account: %s", account.Username)
-			} else {
-				log.Printf("Failed to load cookies for account %s: %v", account.Username, err)
+	agents := make([]*Agent, 0, len(accounts))
+	throttle := am.newLoginThrottle(context.Background())
+	for _, account := range accounts {
+		agent, err := am.startAccountAgent(account, throttle)
+		if err != nil {
+			// An account that can't log in is skipped so the others keep working
+			var loginErr *LoginError
+			if !errors.As(err, &loginErr) {
+				return nil, err
 			}
+			am.logger.Error("Failed to login account %s (%s), skipping it: %v", account.Username, loginErr.Status, loginErr.Err)
+			am.startupStatuses = append(am.startupStatuses, AgentStartupStatus{
+				Username: account.Username,
+				Status:   loginErr.Status,
+				Error:    loginErr.Err.Error(),
+			})
+			continue
 		}
 
-		// If not logged in (either no cookies or invalid cookies), try to login
-		if !agent.IsLoggedIn() {
-			log.Printf("Attempting to login account: %s", account.Username)
-			if err := agent.Login(account.Username, account.Password); err != nil {
-				log.Printf("Failed to login account %s: %v", account.Username, err)
-				return nil, fmt.Errorf("failed to login account %s: %w", account.Username, err)
-			}
-			log.Printf("Successfully logged in account: %s", account.Username)
+		am.startupStatuses = append(am.startupStatuses, AgentStartupStatus{
+			Username: account.Username,
+			Status:   AgentStatusActive,
+		})
+		agents = append(agents, agent)
+	}
+
+	if len(agents) == 0 {
+		am.logger.Error("None of the %d accounts could log in", len(accounts))
+		return nil, fmt.Errorf("%w: none of the %d accounts could log in", ErrNoAccounts, len(accounts))
+	}
 
-			// Save cookies after successful login
-			cookies := agent.GetCookies()
-			if err := authManager.SaveCookies(account.Username, cookies); err != nil {
-				log.Printf("Failed to save cookies for account %s: %v", account.Username, err)
-				return nil, fmt.Errorf("failed to save cookies for account %s: %w", account.Username, err)
+	am.agents = agents
+	if am.dryRun {
+		am.logger.Warning("Dry run mode enabled, write operations will not reach Twitter")
+	}
+
+	return am, nil
+}
+
+// newAccountAgent creates a not yet logged in agent for account, configured
+// with the manager's settings
+func (am *AgentManager) newAccountAgent(account auth.Account) (*Agent, error) {
+	agent := NewAgent(account.Username)
+	if am.newScraper != nil {
+		agent.scraper = am.newScraper()
+	}
+	agent.SetLogger(am.logger)
+	agent.SetRetryConfig(am.retryConfig)
+	agent.SetCircuitBreaker(am.breakerConfig)
+	agent.SetMaxResults(am.maxResults)
+	agent.SetTweetStore(am.tweetStore)
+	agent.SetEndpointConcurrency(am.concurrency)
+
+	userAgent := account.UserAgent
+	if userAgent == "" {
+		userAgent = am.userAgent
+	}
+	if err := agent.SetUserAgent(userAgent); err != nil {
+		return nil, fmt.Errorf("account %s: %w", account.Username, err)
+	}
+	return agent, nil
+}
+
+// newLoginThrottle returns a function to call before each login attempt,
+// which waits the login throttle before every attempt but the first. The
+// wait is cut short, returning ctx's error, when ctx is done.
+func (am *AgentManager) newLoginThrottle(ctx context.Context) func(username string) error {
+	attemptedLogin := false
+	return func(username string) error {
+		if attemptedLogin {
+			if wait := am.loginThrottle.wait(); wait > 0 {
+				am.logger.Info("Waiting %s before logging in account: %s", wait, username)
+				timer := time.NewTimer(wait)
+				defer timer.Stop()
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-timer.C:
+				}
 			}
-			log.Printf("Saved cookies for account: %s", account.Username)
 		}
+		attemptedLogin = true
+		return nil
+	}
+}
 
-		agents[i] = agent
+// startAccountAgent creates a logged in agent for account. Its saved cookies
+// are used when they are still valid; otherwise throttle is called and the
+// account logs in with its password, saving the new cookies. A refused login
+// is returned as a *LoginError.
+func (am *AgentManager) startAccountAgent(account auth.Account, throttle func(username string) error) (*Agent, error) {
+	agent, err := am.newAccountAgent(account)
+	if err != nil {
+		return nil, err
 	}
 
-	return &AgentManager{
-		agents:      agents,
-		index:       0,
-		authManager: authManager,
-		logger:      log.Default(),
-	}, nil
+	// Try to load cookies first
+	if am.authManager.CookiesExist(account.Username) {
+		cookies, err := am.authManager.LoadCookies(account.Username)
+		if err == nil {
+			agent.SetCookies(cookies)
+			am.markCookiesSaved(account.Username, agent.GetCookies())
+			am.logger.Info("Loaded cookies for account: %s", account.Username)
+		} else {
+			am.logger.Error("Failed to load cookies for account %s: %v", account.Username, err)
+		}
+	}
+	if agent.IsLoggedIn() {
+		return agent, nil
+	}
+
+	// Not logged in, either no cookies or invalid cookies
+	if err := throttle(account.Username); err != nil {
+		return nil, err
+	}
+	am.logger.Info("Attempting to login account: %s", account.Username)
+	if err := agent.Login(account.Username, account.Password); err != nil {
+		return nil, &LoginError{Username: account.Username, Status: classifyLoginError(err), Err: err}
+	}
+	am.logger.Info("Successfully logged in account: %s", account.Username)
+
+	// Save cookies after successful login
+	cookies := agent.GetCookies()
+	if err := am.authManager.SaveCookies(account.Username, cookies); err != nil {
+		am.logger.Error("Failed to save cookies for account %s: %v", account.Username, err)
+	} else {
+		am.markCookiesSaved(account.Username, cookies)
+		am.logger.Info("Saved cookies for account: %s", account.Username)
+	}
+	return agent, nil
+}
+
+// isDryRun reports whether write operations for this request should be skipped
+func (am *AgentManager) isDryRun(ctx context.Context) bool {
+	return am.dryRun || DryRunFromContext(ctx)
+}
+
+// getNextAgent returns the next agent in a round-robin fashion, along with
+// how it was selected. Agents whose circuit breaker is open are passed over
+// unless every agent's is.
+func (am *AgentManager) getNextAgent(ctx context.Context) (*Agent, AgentSelection) {
+	// The rotation is replaced rather than modified, so a snapshot stays valid
+	am.mutex.RLock()
+	agents := am.agents
+	am.mutex.RUnlock()
+
+	first := int(atomic.AddUint32(&am.index, 1) % uint32(len(agents)))
+	index := first
+	var skipped []string
+	for i := 0; i < len(agents); i++ {
+		candidate := (first + i) % len(agents)
+		if breaker := agents[candidate].breaker; breaker == nil || !breaker.isOpen() {
+			index = candidate
+			break
+		}
+		skipped = append(skipped, agents[candidate].username)
+	}
+	if len(skipped) == len(agents) {
+		skipped = nil
+	}
+	agent := agents[index]
+	selection := AgentSelection{
+		Strategy: SelectionRoundRobin,
+		Agent:    agent.username,
+		Index:    index,
+		PoolSize: len(agents),
+		Skipped:  skipped,
+	}
+	am.recordSelection(ctx, selection)
+	return agent, selection
+}
+
+// getNextLoggedInAgent returns the next agent in rotation that is logged in
+// and whose circuit breaker is closed, reporting the agents passed over as
+// skipped. It fails with ErrNotLoggedIn when no agent is logged in.
+func (am *AgentManager) getNextLoggedInAgent(ctx context.Context) (*Agent, AgentSelection, error) {
+	am.mutex.RLock()
+	agents := am.agents
+	am.mutex.RUnlock()
+
+	first := int(atomic.AddUint32(&am.index, 1) % uint32(len(agents)))
+	var skipped []string
+	for i := 0; i < len(agents); i++ {
+		index := (first + i) % len(agents)
+		agent := agents[index]
+		if breaker := agent.breaker; (breaker != nil && breaker.isOpen()) || !agent.IsLoggedIn() {
+			skipped = append(skipped, agent.username)
+			continue
+		}
+		selection := AgentSelection{
+			Strategy: SelectionRoundRobin,
+			Agent:    agent.username,
+			Index:    index,
+			PoolSize: len(agents),
+			Skipped:  skipped,
+		}
+		am.recordSelection(ctx, selection)
+		return agent, selection, nil
+	}
+	return nil, AgentSelection{}, &AgentError{Kind: ErrNotLoggedIn, Message: "no logged-in account is available"}
 }
 
-// getNextAgent returns the next agent in a round-robin fashion
-func (am *AgentManager) getNextAgent() (*Agent, string) {
-	index := atomic.AddUint32(&am.index, 1)
-	agent := am.agents[index%uint32(len(am.agents))]
-	am.logger.Printf("Selected agent: %s", agent.username)
-	return agent, agent.username
+// resolveAgent returns the agent with the given username, or the next agent
+// in round-robin order when username is empty
+func (am *AgentManager) resolveAgent(ctx context.Context, username string) (*Agent, string, error) {
+	logger := am.requestLogger(ctx)
+	if username == "" {
+		agent, selection := am.getNextAgent(ctx)
+		return agent, selection.Agent, nil
+	}
+
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	for i, agent := range am.agents {
+		if strings.EqualFold(agent.username, username) {
+			am.recordSelection(ctx, AgentSelection{
+				Strategy: SelectionByUsername,
+				Agent:    agent.username,
+				Index:    i,
+				PoolSize: len(am.agents),
+			})
+			return agent, agent.username, nil
+		}
+	}
+
+	logger.Warning("No agent found with username: %s", username)
+	return nil, "", ErrInvalidAgentIndex
 }
 
 // SetCookies sets the cookies for authentication for a specific agent
@@ -103,19 +413,23 @@ func (am *AgentManager) SetCookies(agentIndex int, cookies []*http.Cookie) error
 	defer am.mutex.RUnlock()
 
 	if agentIndex < 0 || agentIndex >= len(am.agents) {
-		am.logger.Printf("Invalid agent index: %d", agentIndex)
+		am.logger.Warning("Invalid agent index: %d", agentIndex)
 		return ErrInvalidAgentIndex
 	}
 
 	am.agents[agentIndex].SetCookies(cookies)
-	am.logger.Printf("Set cookies for agent index: %d", agentIndex)
+	am.logger.Info("Set cookies for agent index: %d", agentIndex)
 	return nil
 }
 
-// GetUserTweets gets tweets from a specific user using the next available agent
-func (am *AgentManager) GetUserTweets(ctx context.Context, username string, limit int, sortByOldest bool) (interface{}, string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Getting tweets for user %s using agent %s", username, agentUsername)
+// GetUserTweets gets tweets from a specific user using the next available agent.
+// When reading the timeline failed after some tweets were read, those are
+// returned with the reason in partial.
+func (am *AgentManager) GetUserTweets(ctx context.Context, username string, limit int, sortByOldest bool) (data interface{}, partial []string, agentUsername string, err error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername = selection.Agent
+	logger.Debug("Getting tweets for user %s using agent %s", username, agentUsername)
 
 	result, err := agent.handleGetUserTweets(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -130,33 +444,130 @@ func (am *AgentManager) GetUserTweets(ctx context.Context, username string, limi
 				"username":       username,
 				"limit":          float64(limit),
 				"sort_by_oldest": sortByOldest,
+				"include_html":   true,
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error getting tweets for user %s: %v", username, err)
+		logger.Error("Error getting tweets for user %s: %v", username, err)
+		return nil, nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for user %s: %s", username, errMsg)
+		return nil, nil, agentUsername, toolError(errMsg)
+	}
+
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling response for user %s: %v", username, err)
+		return nil, nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved tweets for user %s", username)
+	return data, partialResults(result), agentUsername, nil
+}
+
+// GetUserTweetsSince gets the tweets a user posted since the given time,
+// using the next available agent. The timeline is read only until it reaches
+// older tweets, so unlike GetUserTweets the fetch is bounded by the time
+// window rather than a count; at most maxResults tweets are returned. Partial
+// results are reported as by GetUserTweets.
+func (am *AgentManager) GetUserTweetsSince(ctx context.Context, username string, since time.Time) (data interface{}, partial []string, agentUsername string, err error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername = selection.Agent
+	logger.Debug("Getting tweets since %s for user %s using agent %s", since.Format(time.RFC3339), username, agentUsername)
+
+	limit := am.maxResults
+	if limit <= 0 {
+		limit = DefaultMaxResults
+	}
+	result, err := agent.handleGetUserTweets(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_user_tweets",
+			Arguments: map[string]interface{}{
+				"username":     username,
+				"limit":        float64(limit),
+				"since":        since.Format(time.RFC3339),
+				"include_html": true,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting tweets for user %s: %v", username, err)
+		return nil, nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for user %s: %s", username, errMsg)
+		return nil, nil, agentUsername, toolError(errMsg)
+	}
+
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling response for user %s: %v", username, err)
+		return nil, nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved tweets since %s for user %s", since.Format(time.RFC3339), username)
+	return data, partialResults(result), agentUsername, nil
+}
+
+// GetMediaTweets gets the tweets of a user that have media attached, using
+// the next available agent
+func (am *AgentManager) GetMediaTweets(ctx context.Context, username string, limit int) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting media tweets for user %s using agent %s", username, agentUsername)
+
+	result, err := agent.handleGetMediaTweets(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_media_tweets",
+			Arguments: map[string]interface{}{
+				"username": username,
+				"limit":    float64(limit),
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting media tweets for user %s: %v", username, err)
 		return nil, agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for user %s: %s", username, errMsg)
-		return nil, agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for media tweets of %s: %s", username, errMsg)
+		return nil, agentUsername, toolError(errMsg)
 	}
 
 	var data interface{}
 	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
-		am.logger.Printf("Error unmarshaling response for user %s: %v", username, err)
+		logger.Error("Error unmarshaling media tweets for user %s: %v", username, err)
 		return nil, agentUsername, err
 	}
 
-	am.logger.Printf("Successfully retrieved tweets for user %s", username)
+	logger.Debug("Successfully retrieved media tweets for user %s", username)
 	return data, agentUsername, nil
 }
 
-// GetProfile gets user profile information using the next available agent
-func (am *AgentManager) GetProfile(ctx context.Context, username string) (interface{}, string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Getting profile for user %s using agent %s", username, agentUsername)
+// GetProfile gets user profile information using the next available agent.
+// With includePinned the user's pinned tweet is fetched and embedded too.
+func (am *AgentManager) GetProfile(ctx context.Context, username string, includePinned bool) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting profile for user %s using agent %s", username, agentUsername)
 
 	result, err := agent.handleGetProfile(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -168,34 +579,37 @@ func (am *AgentManager) GetProfile(ctx context.Context, username string) (interf
 		}{
 			Name: "get_profile",
 			Arguments: map[string]interface{}{
-				"username": username,
+				"username":       username,
+				"include_pinned": includePinned,
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error getting profile for user %s: %v", username, err)
+		logger.Error("Error getting profile for user %s: %v", username, err)
 		return nil, agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for profile %s: %s", username, errMsg)
-		return nil, agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for profile %s: %s", username, errMsg)
+		return nil, agentUsername, toolError(errMsg)
 	}
 
 	var data interface{}
 	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
-		am.logger.Printf("Error unmarshaling profile response for user %s: %v", username, err)
+		logger.Error("Error unmarshaling profile response for user %s: %v", username, err)
 		return nil, agentUsername, err
 	}
 
-	am.logger.Printf("Successfully retrieved profile for user %s", username)
+	logger.Debug("Successfully retrieved profile for user %s", username)
 	return data, agentUsername, nil
 }
 
 // GetTweet gets a specific tweet using the next available agent
 func (am *AgentManager) GetTweet(ctx context.Context, tweetID string) (interface{}, string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Getting tweet %s using agent %s", tweetID, agentUsername)
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting tweet %s using agent %s", tweetID, agentUsername)
 
 	result, err := agent.handleGetTweet(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -207,34 +621,129 @@ func (am *AgentManager) GetTweet(ctx context.Context, tweetID string) (interface
 		}{
 			Name: "get_tweet",
 			Arguments: map[string]interface{}{
-				"tweet_id": tweetID,
+				"tweet_id":     tweetID,
+				"include_html": true,
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error getting tweet %s: %v", tweetID, err)
+		logger.Error("Error getting tweet %s: %v", tweetID, err)
 		return nil, agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for tweet %s: %s", tweetID, errMsg)
-		return nil, agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for tweet %s: %s", tweetID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
 	}
 
 	var data interface{}
 	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
-		am.logger.Printf("Error unmarshaling tweet response for %s: %v", tweetID, err)
+		logger.Error("Error unmarshaling tweet response for %s: %v", tweetID, err)
 		return nil, agentUsername, err
 	}
 
-	am.logger.Printf("Successfully retrieved tweet %s", tweetID)
+	logger.Debug("Successfully retrieved tweet %s", tweetID)
 	return data, agentUsername, nil
 }
 
-// SearchTweets searches for tweets using the next available agent
-func (am *AgentManager) SearchTweets(ctx context.Context, query string, limit int) (interface{}, string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Searching tweets with query '%s' using agent %s", query, agentUsername)
+// GetTweets gets several tweets by ID. The IDs are spread round-robin over
+// the agents, which fetch their share concurrently within their own rate
+// limits. With useCache set, tweets held by the tweet store aren't fetched.
+// The result maps each ID to its BatchTweetResult; a tweet that can't be
+// fetched gets an error entry without failing the others. The returned
+// string lists the agents used, comma separated.
+func (am *AgentManager) GetTweets(ctx context.Context, ids []string, useCache bool) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	ids, err := parseTweetIDs(ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Keep the agents in order of first use so the reported usernames are stable
+	var agents []*Agent
+	groups := make(map[*Agent][]interface{})
+	for _, id := range ids {
+		agent, _ := am.getNextAgent(ctx)
+		if _, ok := groups[agent]; !ok {
+			agents = append(agents, agent)
+		}
+		groups[agent] = append(groups[agent], id)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]BatchTweetResult, len(ids))
+	usernames := make([]string, 0, len(agents))
+	for _, agent := range agents {
+		usernames = append(usernames, agent.username)
+		logger.Debug("Getting %d tweets using agent %s", len(groups[agent]), agent.username)
+
+		wg.Add(1)
+		go func(agent *Agent, group []interface{}) {
+			defer wg.Done()
+			groupResults, err := am.getTweetsWithAgent(ctx, agent, group, useCache)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range group {
+				id := id.(string)
+				if err != nil {
+					results[id] = BatchTweetResult{Error: err.Error()}
+					continue
+				}
+				results[id] = groupResults[id]
+			}
+		}(agent, groups[agent])
+	}
+	wg.Wait()
+
+	logger.Debug("Successfully retrieved %d tweets", len(results))
+	return results, strings.Join(usernames, ","), nil
+}
+
+// getTweetsWithAgent fetches ids with the get_tweets tool of agent
+func (am *AgentManager) getTweetsWithAgent(ctx context.Context, agent *Agent, ids []interface{}, useCache bool) (map[string]BatchTweetResult, error) {
+	logger := am.requestLogger(ctx)
+	result, err := agent.handleGetTweets(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_tweets",
+			Arguments: map[string]interface{}{
+				"tweet_ids": ids,
+				"use_cache": useCache,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting tweets with agent %s: %v", agent.username, err)
+		return nil, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for tweets with agent %s: %s", agent.username, errMsg)
+		return nil, toolError(errMsg)
+	}
+
+	var results map[string]BatchTweetResult
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &results); err != nil {
+		logger.Error("Error unmarshaling tweets response: %v", err)
+		return nil, err
+	}
+	return results, nil
+}
+
+// SearchTweets searches for tweets using the next available agent. Partial
+// results are reported as by GetUserTweets.
+func (am *AgentManager) SearchTweets(ctx context.Context, query string, limit int, opts SearchOptions) (data interface{}, partial []string, agentUsername string, err error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername = selection.Agent
+	logger.Debug("Searching tweets with query '%s' using agent %s", query, agentUsername)
 
 	result, err := agent.handleSearchTweets(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -246,37 +755,150 @@ func (am *AgentManager) SearchTweets(ctx context.Context, query string, limit in
 		}{
 			Name: "search_tweets",
 			Arguments: map[string]interface{}{
-				"query": query,
-				"limit": float64(limit),
+				"query":        query,
+				"limit":        float64(limit),
+				"since":        opts.Since,
+				"until":        opts.Until,
+				"include_html": opts.IncludeHTML,
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error searching tweets with query '%s': %v", query, err)
+		logger.Error("Error searching tweets with query '%s': %v", query, err)
+		return nil, nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for search query '%s': %s", query, errMsg)
+		return nil, nil, agentUsername, toolError(errMsg)
+	}
+
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling search response for query '%s': %v", query, err)
+		return nil, nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully searched tweets with query '%s'", query)
+	return data, partialResults(result), agentUsername, nil
+}
+
+// CreateTweetOptions holds the optional settings of a new tweet
+type CreateTweetOptions struct {
+	ScheduleTime string // ISO8601 time to schedule the tweet for
+	AutoThread   bool   // Split text over 280 characters into a self-thread
+	Poll         *Poll  // Poll to attach, if any
+}
+
+// CreateTweet creates a new tweet using the agent named targetUsername,
+// or the next available agent when targetUsername is empty
+func (am *AgentManager) CreateTweet(ctx context.Context, text string, opts CreateTweetOptions, targetUsername string) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return nil, "", err
+	}
+	logger.Debug("Creating tweet using agent %s", agentUsername)
+
+	arguments := map[string]interface{}{
+		"text":          text,
+		"schedule_time": opts.ScheduleTime,
+		"auto_thread":   opts.AutoThread,
+		"dry_run":       am.isDryRun(ctx),
+	}
+	if opts.Poll != nil {
+		arguments["poll"] = opts.Poll
+	}
+
+	result, err := agent.handleCreateTweet(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name:      "create_tweet",
+			Arguments: arguments,
+		},
+	})
+	if err != nil {
+		logger.Error("Error creating tweet: %v", err)
 		return nil, agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for search query '%s': %s", query, errMsg)
-		return nil, agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for creating tweet: %s", errMsg)
+		return nil, agentUsername, toolError(errMsg)
 	}
 
 	var data interface{}
 	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
-		am.logger.Printf("Error unmarshaling search response for query '%s': %v", query, err)
+		logger.Error("Error unmarshaling create tweet response: %v", err)
 		return nil, agentUsername, err
 	}
 
-	am.logger.Printf("Successfully searched tweets with query '%s'", query)
+	logger.Info("Successfully created tweet")
 	return data, agentUsername, nil
 }
 
-// CreateTweet creates a new tweet using the next available agent
-func (am *AgentManager) CreateTweet(ctx context.Context, text string, scheduleTime string) (interface{}, string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Creating tweet using agent %s", agentUsername)
+// Reply replies to a tweet using the agent named targetUsername,
+// or the next available agent when targetUsername is empty
+func (am *AgentManager) Reply(ctx context.Context, tweetID string, text string, targetUsername string) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return nil, "", err
+	}
+	logger.Debug("Replying to tweet %s using agent %s", tweetID, agentUsername)
 
-	result, err := agent.handleCreateTweet(ctx, mcp.CallToolRequest{
+	result, err := agent.handleReplyTweet(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "reply_tweet",
+			Arguments: map[string]interface{}{
+				"tweet_id": tweetID,
+				"text":     text,
+				"dry_run":  am.isDryRun(ctx),
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error replying to tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for replying to tweet %s: %s", tweetID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling reply response for tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Info("Successfully replied to tweet %s", tweetID)
+	return data, agentUsername, nil
+}
+
+// QuoteTweet quotes a tweet with the uploaded media attached, if any, using
+// the agent named targetUsername, or the next available agent when
+// targetUsername is empty
+func (am *AgentManager) QuoteTweet(ctx context.Context, tweetID string, text string, media []MediaAttachment, targetUsername string) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return nil, "", err
+	}
+	logger.Debug("Quoting tweet %s using agent %s", tweetID, agentUsername)
+
+	result, err := agent.handleQuoteTweet(ctx, mcp.CallToolRequest{
 		Params: struct {
 			Name      string                 `json:"name"`
 			Arguments map[string]interface{} `json:"arguments,omitempty"`
@@ -284,37 +906,82 @@ func (am *AgentManager) CreateTweet(ctx context.Context, text string, scheduleTi
 				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
 			} `json:"_meta,omitempty"`
 		}{
-			Name: "create_tweet",
+			Name: "quote_tweet",
 			Arguments: map[string]interface{}{
-				"text":          text,
-				"schedule_time": scheduleTime,
+				"tweet_id": tweetID,
+				"text":     text,
+				"media":    media,
+				"dry_run":  am.isDryRun(ctx),
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error creating tweet: %v", err)
+		logger.Error("Error quoting tweet %s: %v", tweetID, err)
 		return nil, agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for creating tweet: %s", errMsg)
-		return nil, agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for quoting tweet %s: %s", tweetID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
 	}
 
 	var data interface{}
 	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
-		am.logger.Printf("Error unmarshaling create tweet response: %v", err)
+		logger.Error("Error unmarshaling quote response for tweet %s: %v", tweetID, err)
 		return nil, agentUsername, err
 	}
 
-	am.logger.Printf("Successfully created tweet")
+	logger.Info("Successfully quoted tweet %s", tweetID)
 	return data, agentUsername, nil
 }
 
-// LikeTweet likes a tweet using the next available agent
-func (am *AgentManager) LikeTweet(ctx context.Context, tweetID string) (string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Liking tweet %s using agent %s", tweetID, agentUsername)
+// PostTweetRequest describes a tweet to post. At most one of ReplyTo and
+// QuoteOf may be set; replies and quotes can't be scheduled, threaded or
+// carry a poll. Only quotes can carry media.
+type PostTweetRequest struct {
+	Text    string
+	ReplyTo string            // ID of the tweet to reply to, if any
+	QuoteOf string            // ID of the tweet to quote, if any
+	Media   []MediaAttachment // uploaded media to attach to the quote, at most 4
+	CreateTweetOptions
+	AgentUsername string
+}
+
+// validate reports the first reason req can't be posted, if it can't. See
+// ValidateTweet for all of them.
+func (req PostTweetRequest) validate() error {
+	if issues := req.issues(time.Now()); len(issues) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidTweetRequest, issues[0].Message)
+	}
+	return nil
+}
+
+// PostTweet posts req as a plain tweet, a reply or a quote, depending on
+// which of ReplyTo and QuoteOf is set
+func (am *AgentManager) PostTweet(ctx context.Context, req PostTweetRequest) (interface{}, string, error) {
+	if err := req.validate(); err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case req.ReplyTo != "":
+		return am.Reply(ctx, req.ReplyTo, req.Text, req.AgentUsername)
+	case req.QuoteOf != "":
+		return am.QuoteTweet(ctx, req.QuoteOf, req.Text, req.Media, req.AgentUsername)
+	default:
+		return am.CreateTweet(ctx, req.Text, req.CreateTweetOptions, req.AgentUsername)
+	}
+}
+
+// LikeTweet likes a tweet using the agent named targetUsername,
+// or the next available agent when targetUsername is empty
+func (am *AgentManager) LikeTweet(ctx context.Context, tweetID string, targetUsername string) (string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("Liking tweet %s using agent %s", tweetID, agentUsername)
 
 	result, err := agent.handleLikeTweet(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -327,27 +994,33 @@ func (am *AgentManager) LikeTweet(ctx context.Context, tweetID string) (string,
 			Name: "like_tweet",
 			Arguments: map[string]interface{}{
 				"tweet_id": tweetID,
+				"dry_run":  am.isDryRun(ctx),
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error liking tweet %s: %v", tweetID, err)
+		logger.Error("Error liking tweet %s: %v", tweetID, err)
 		return agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for liking tweet %s: %s", tweetID, errMsg)
-		return agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for liking tweet %s: %s", tweetID, errMsg)
+		return agentUsername, toolError(errMsg)
 	}
 
-	am.logger.Printf("Successfully liked tweet %s", tweetID)
+	logger.Info("Successfully liked tweet %s", tweetID)
 	return agentUsername, nil
 }
 
-// UnlikeTweet unlikes a tweet using the next available agent
-func (am *AgentManager) UnlikeTweet(ctx context.Context, tweetID string) (string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Unliking tweet %s using agent %s", tweetID, agentUsername)
+// UnlikeTweet unlikes a tweet using the agent named targetUsername,
+// or the next available agent when targetUsername is empty
+func (am *AgentManager) UnlikeTweet(ctx context.Context, tweetID string, targetUsername string) (string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("Unliking tweet %s using agent %s", tweetID, agentUsername)
 
 	result, err := agent.handleUnlikeTweet(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -360,27 +1033,33 @@ func (am *AgentManager) UnlikeTweet(ctx context.Context, tweetID string) (string
 			Name: "unlike_tweet",
 			Arguments: map[string]interface{}{
 				"tweet_id": tweetID,
+				"dry_run":  am.isDryRun(ctx),
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error unliking tweet %s: %v", tweetID, err)
+		logger.Error("Error unliking tweet %s: %v", tweetID, err)
 		return agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for unliking tweet %s: %s", tweetID, errMsg)
-		return agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for unliking tweet %s: %s", tweetID, errMsg)
+		return agentUsername, toolError(errMsg)
 	}
 
-	am.logger.Printf("Successfully unliked tweet %s", tweetID)
+	logger.Info("Successfully unliked tweet %s", tweetID)
 	return agentUsername, nil
 }
 
-// Retweet retweets a tweet using the next available agent
-func (am *AgentManager) Retweet(ctx context.Context, tweetID string) (string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Retweeting tweet %s using agent %s", tweetID, agentUsername)
+// Retweet retweets a tweet using the agent named targetUsername,
+// or the next available agent when targetUsername is empty
+func (am *AgentManager) Retweet(ctx context.Context, tweetID string, targetUsername string) (string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("Retweeting tweet %s using agent %s", tweetID, agentUsername)
 
 	result, err := agent.handleRetweet(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -393,27 +1072,33 @@ func (am *AgentManager) Retweet(ctx context.Context, tweetID string) (string, er
 			Name: "retweet",
 			Arguments: map[string]interface{}{
 				"tweet_id": tweetID,
+				"dry_run":  am.isDryRun(ctx),
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error retweeting tweet %s: %v", tweetID, err)
+		logger.Error("Error retweeting tweet %s: %v", tweetID, err)
 		return agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for retweeting tweet %s: %s", tweetID, errMsg)
-		return agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for retweeting tweet %s: %s", tweetID, errMsg)
+		return agentUsername, toolError(errMsg)
 	}
 
-	am.logger.Printf("Successfully retweeted tweet %s", tweetID)
+	logger.Info("Successfully retweeted tweet %s", tweetID)
 	return agentUsername, nil
 }
 
-// Follow follows a user using the next available agent
-func (am *AgentManager) Follow(ctx context.Context, userID string) (string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Following user %s using agent %s", userID, agentUsername)
+// Follow follows a user using the agent named targetUsername,
+// or the next available agent when targetUsername is empty
+func (am *AgentManager) Follow(ctx context.Context, userID string, targetUsername string) (string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("Following user %s using agent %s", userID, agentUsername)
 
 	result, err := agent.handleFollowUser(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -426,27 +1111,33 @@ func (am *AgentManager) Follow(ctx context.Context, userID string) (string, erro
 			Name: "follow",
 			Arguments: map[string]interface{}{
 				"user_id": userID,
+				"dry_run": am.isDryRun(ctx),
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error following user %s: %v", userID, err)
+		logger.Error("Error following user %s: %v", userID, err)
 		return agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for following user %s: %s", userID, errMsg)
-		return agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for following user %s: %s", userID, errMsg)
+		return agentUsername, toolError(errMsg)
 	}
 
-	am.logger.Printf("Successfully followed user %s", userID)
+	logger.Info("Successfully followed user %s", userID)
 	return agentUsername, nil
 }
 
-// Unfollow unfollows a user using the next available agent
-func (am *AgentManager) Unfollow(ctx context.Context, userID string) (string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Unfollowing user %s using agent %s", userID, agentUsername)
+// Unfollow unfollows a user using the agent named targetUsername,
+// or the next available agent when targetUsername is empty
+func (am *AgentManager) Unfollow(ctx context.Context, userID string, targetUsername string) (string, error) {
+	logger := am.requestLogger(ctx)
+	agent, agentUsername, err := am.resolveAgent(ctx, targetUsername)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("Unfollowing user %s using agent %s", userID, agentUsername)
 
 	result, err := agent.handleUnfollowUser(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -459,20 +1150,21 @@ func (am *AgentManager) Unfollow(ctx context.Context, userID string) (string, er
 			Name: "unfollow",
 			Arguments: map[string]interface{}{
 				"user_id": userID,
+				"dry_run": am.isDryRun(ctx),
 			},
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error unfollowing user %s: %v", userID, err)
+		logger.Error("Error unfollowing user %s: %v", userID, err)
 		return agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for unfollowing user %s: %s", userID, errMsg)
-		return agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for unfollowing user %s: %s", userID, errMsg)
+		return agentUsername, toolError(errMsg)
 	}
 
-	am.logger.Printf("Successfully unfollowed user %s", userID)
+	logger.Info("Successfully unfollowed user %s", userID)
 	return agentUsername, nil
 }
 
@@ -482,11 +1174,11 @@ func (am *AgentManager) GetAgent(index int) (*Agent, error) {
 	defer am.mutex.RUnlock()
 
 	if index < 0 || index >= len(am.agents) {
-		am.logger.Printf("Invalid agent index requested: %d", index)
+		am.logger.Warning("Invalid agent index requested: %d", index)
 		return nil, ErrInvalidAgentIndex
 	}
 
-	am.logger.Printf("Retrieved agent at index %d", index)
+	am.logger.Debug("Retrieved agent at index %d", index)
 	return am.agents[index], nil
 }
 
@@ -495,14 +1187,16 @@ func (am *AgentManager) GetAgentCount() int {
 	am.mutex.RLock()
 	defer am.mutex.RUnlock()
 	count := len(am.agents)
-	am.logger.Printf("Current agent count: %d", count)
+	am.logger.Debug("Current agent count: %d", count)
 	return count
 }
 
 // GetFollowers gets followers of a specific user using the next available agent
 func (am *AgentManager) GetFollowers(ctx context.Context, username string, limit int, cursor string) (interface{}, string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Getting followers for user %s using agent %s", username, agentUsername)
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting followers for user %s using agent %s", username, agentUsername)
 
 	result, err := agent.handleGetFollowers(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -521,29 +1215,134 @@ func (am *AgentManager) GetFollowers(ctx context.Context, username string, limit
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error getting followers for user %s: %v", username, err)
+		logger.Error("Error getting followers for user %s: %v", username, err)
 		return nil, agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for followers %s: %s", username, errMsg)
-		return nil, agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for followers %s: %s", username, errMsg)
+		return nil, agentUsername, toolError(errMsg)
 	}
 
 	var data interface{}
 	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
-		am.logger.Printf("Error unmarshaling followers response for user %s: %v", username, err)
+		logger.Error("Error unmarshaling followers response for user %s: %v", username, err)
 		return nil, agentUsername, err
 	}
 
-	am.logger.Printf("Successfully retrieved followers for user %s", username)
+	logger.Debug("Successfully retrieved followers for user %s", username)
 	return data, agentUsername, nil
 }
 
+// followersPageSize is the number of followers requested per page by GetAllFollowers
+const followersPageSize = 50
+
+// followersPage is one page of the get_followers tool response
+type followersPage struct {
+	Followers  []interface{} `json:"followers"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+// GetAllFollowers gets up to max followers of a user, following the page
+// cursor until max is reached or the followers run out. All pages are
+// fetched by the same agent and are subject to its rate limits. A max of 0
+// or less fetches up to the manager's result cap. The response has the same
+// shape as GetFollowers; next_cursor is only set when max cut the listing
+// short. When max cut a page in half, next_cursor is the cursor that page was
+// requested with, so the caller re-requests that page and skips the followers
+// it already has rather than missing the rest of it.
+func (am *AgentManager) GetAllFollowers(ctx context.Context, username string, max int) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	if max <= 0 {
+		max = am.maxResults
+	}
+	max, _ = ClampLimit(max, am.maxResults)
+
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting up to %d followers for user %s using agent %s", max, username, agentUsername)
+
+	all := followersPage{Followers: make([]interface{}, 0)}
+	seenCursors := make(map[string]bool)
+	cursor := ""
+
+	for max <= 0 || len(all.Followers) < max {
+		if err := ctx.Err(); err != nil {
+			return nil, agentUsername, err
+		}
+
+		pageSize := followersPageSize
+		if max > 0 && max-len(all.Followers) < pageSize {
+			pageSize = max - len(all.Followers)
+		}
+
+		result, err := agent.handleGetFollowers(ctx, mcp.CallToolRequest{
+			Params: struct {
+				Name      string                 `json:"name"`
+				Arguments map[string]interface{} `json:"arguments,omitempty"`
+				Meta      *struct {
+					ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+				} `json:"_meta,omitempty"`
+			}{
+				Name: "get_followers",
+				Arguments: map[string]interface{}{
+					"username": username,
+					"limit":    float64(pageSize),
+					"cursor":   cursor,
+				},
+			},
+		})
+		if err != nil {
+			logger.Error("Error getting followers for user %s: %v", username, err)
+			return nil, agentUsername, err
+		}
+		if result.IsError {
+			errMsg := result.Content[0].(*mcp.TextContent).Text
+			logger.Error("Error in response for followers %s: %s", username, errMsg)
+			// Report a cancelled request as such rather than as a failed page
+			if ctx.Err() != nil {
+				return nil, agentUsername, ctx.Err()
+			}
+			return nil, agentUsername, toolError(errMsg)
+		}
+
+		var page followersPage
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &page); err != nil {
+			logger.Error("Error unmarshaling followers response for user %s: %v", username, err)
+			return nil, agentUsername, err
+		}
+
+		if max > 0 && len(all.Followers)+len(page.Followers) > max {
+			all.Followers = append(all.Followers, page.Followers[:max-len(all.Followers)]...)
+			all.NextCursor = cursor
+			break
+		}
+		all.Followers = append(all.Followers, page.Followers...)
+		all.NextCursor = page.NextCursor
+
+		// Stop at the end of the listing, and when Twitter hands back a cursor
+		// that was already followed, which would otherwise loop forever
+		if len(page.Followers) == 0 || page.NextCursor == "" || seenCursors[page.NextCursor] {
+			if seenCursors[page.NextCursor] {
+				logger.Warning("Followers cursor for user %s repeated, stopping pagination", username)
+			}
+			all.NextCursor = ""
+			break
+		}
+		seenCursors[page.NextCursor] = true
+		cursor = page.NextCursor
+	}
+
+	logger.Debug("Successfully retrieved %d followers for user %s", len(all.Followers), username)
+	return all, agentUsername, nil
+}
+
 // GetTweetReplies gets replies to a specific tweet using the next available agent
 func (am *AgentManager) GetTweetReplies(ctx context.Context, tweetID string, cursor string) (interface{}, string, error) {
-	agent, agentUsername := am.getNextAgent()
-	am.logger.Printf("Getting replies for tweet %s using agent %s", tweetID, agentUsername)
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting replies for tweet %s using agent %s", tweetID, agentUsername)
 
 	result, err := agent.handleGetTweetReplies(ctx, mcp.CallToolRequest{
 		Params: struct {
@@ -561,21 +1360,216 @@ func (am *AgentManager) GetTweetReplies(ctx context.Context, tweetID string, cur
 		},
 	})
 	if err != nil {
-		am.logger.Printf("Error getting replies for tweet %s: %v", tweetID, err)
+		logger.Error("Error getting replies for tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for tweet replies %s: %s", tweetID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling replies response for tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved replies for tweet %s", tweetID)
+	return data, agentUsername, nil
+}
+
+// GetTweetThread gets a tweet and its nested replies as a tree using the next available agent
+func (am *AgentManager) GetTweetThread(ctx context.Context, tweetID string, maxDepth int) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting thread for tweet %s using agent %s", tweetID, agentUsername)
+
+	result, err := agent.handleGetTweetThread(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_tweet_thread",
+			Arguments: map[string]interface{}{
+				"tweet_id":  tweetID,
+				"max_depth": float64(maxDepth),
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting thread for tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for tweet thread %s: %s", tweetID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling thread response for tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved thread for tweet %s", tweetID)
+	return data, agentUsername, nil
+}
+
+// GetConversationContext gets the chain of parent tweets leading to a tweet,
+// ordered from the root of the conversation, using the next available agent.
+// At most defaultContextDepth parents are followed.
+func (am *AgentManager) GetConversationContext(ctx context.Context, tweetID string) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting conversation context for tweet %s using agent %s", tweetID, agentUsername)
+
+	result, err := agent.handleGetConversationContext(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_conversation_context",
+			Arguments: map[string]interface{}{
+				"tweet_id": tweetID,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting conversation context for tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for conversation context %s: %s", tweetID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling conversation context for tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved conversation context for tweet %s", tweetID)
+	return data, agentUsername, nil
+}
+
+// GetRelationship gets how the target user relates to the source user.
+// Relationships can only be seen from a logged-in account, so the lookup runs
+// on the agent whose account is the source; an empty source uses the next
+// available agent's account.
+func (am *AgentManager) GetRelationship(ctx context.Context, sourceUserID, targetUserID string) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	var agent *Agent
+	var agentUsername string
+	if sourceUserID == "" {
+		var selection AgentSelection
+		agent, selection = am.getNextAgent(ctx)
+		agentUsername = selection.Agent
+	} else {
+		var err error
+		agent, err = am.agentForUserID(ctx, sourceUserID)
+		if err != nil {
+			logger.Error("Error getting relationship of %s with %s: %v", sourceUserID, targetUserID, err)
+			return nil, "", err
+		}
+		agentUsername = agent.username
+	}
+	logger.Debug("Getting relationship with %s using agent %s", targetUserID, agentUsername)
+
+	result, err := agent.handleGetRelationship(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_relationship",
+			Arguments: map[string]interface{}{
+				"source_user_id": sourceUserID,
+				"target_user_id": targetUserID,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting relationship with %s: %v", targetUserID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for relationship with %s: %s", targetUserID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
+		logger.Error("Error unmarshaling relationship with %s: %v", targetUserID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved relationship with %s", targetUserID)
+	return data, agentUsername, nil
+}
+
+// GetTrends gets the current trending topics using the next available agent.
+// Trends are cached for trendsTTL so repeated calls don't use up the rate limit.
+func (am *AgentManager) GetTrends(ctx context.Context) (interface{}, string, error) {
+	logger := am.requestLogger(ctx)
+	am.trendsMutex.Lock()
+	defer am.trendsMutex.Unlock()
+
+	if am.trends != nil && time.Since(am.trendsFetchedAt) < trendsTTL {
+		logger.Debug("Returning cached trends from agent %s", am.trendsAgent)
+		return am.trends, am.trendsAgent, nil
+	}
+
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting trends using agent %s", agentUsername)
+
+	result, err := agent.handleGetTrends(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name:      "get_trends",
+			Arguments: map[string]interface{}{},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting trends: %v", err)
 		return nil, agentUsername, err
 	}
 	if result.IsError {
 		errMsg := result.Content[0].(*mcp.TextContent).Text
-		am.logger.Printf("Error in response for tweet replies %s: %s", tweetID, errMsg)
-		return nil, agentUsername, fmt.Errorf(errMsg)
+		logger.Error("Error in response for trends: %s", errMsg)
+		return nil, agentUsername, toolError(errMsg)
 	}
 
 	var data interface{}
 	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &data); err != nil {
-		am.logger.Printf("Error unmarshaling replies response for tweet %s: %v", tweetID, err)
+		logger.Error("Error unmarshaling trends response: %v", err)
 		return nil, agentUsername, err
 	}
 
-	am.logger.Printf("Successfully retrieved replies for tweet %s", tweetID)
+	am.trends = data
+	am.trendsAgent = agentUsername
+	am.trendsFetchedAt = time.Now()
+
+	logger.Debug("Successfully retrieved trends")
 	return data, agentUsername, nil
 }
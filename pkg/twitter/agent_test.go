@@ -3,12 +3,18 @@ package twitter
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/asabya/x-go/pkg/logging"
 	twitterscraper "github.com/imperatrona/twitter-scraper"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,20 +42,78 @@ func (m *mockScraper) GetTweets(ctx context.Context, username string, maxTweetsN
 	return ch
 }
 
+func (m *mockScraper) GetMediaTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	ch := make(chan *twitterscraper.TweetResult)
+	close(ch)
+	return ch
+}
+
 func (m *mockScraper) GetTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
 	return &twitterscraper.Tweet{}, nil
 }
 
+func (m *mockScraper) TweetVisibility(ctx context.Context, id string) (string, error) {
+	return TweetAvailable, nil
+}
+
 func (m *mockScraper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
 	ch := make(chan *twitterscraper.TweetResult)
 	close(ch)
 	return ch
 }
 
+func (m *mockScraper) FetchSearchTweets(ctx context.Context, query string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	return nil, "", nil
+}
+
+func (m *mockScraper) FetchTweetsAndReplies(ctx context.Context, username string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	return nil, "", nil
+}
+
+func (m *mockScraper) FetchFollowers(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	return nil, "", nil
+}
+
+func (m *mockScraper) FetchFollowing(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	return nil, "", nil
+}
+
 func (m *mockScraper) Tweet(ctx context.Context, text string) (*twitterscraper.Tweet, error) {
 	return &twitterscraper.Tweet{}, nil
 }
 
+func (m *mockScraper) ReplyTweet(ctx context.Context, text string, inReplyToID string) (*twitterscraper.Tweet, error) {
+	return &twitterscraper.Tweet{InReplyToStatusID: inReplyToID}, nil
+}
+
+func (m *mockScraper) QuoteTweet(ctx context.Context, text string, quotedID string, mediaIDs []string) (*twitterscraper.Tweet, error) {
+	return &twitterscraper.Tweet{Text: text, QuotedStatusID: quotedID}, nil
+}
+
+func (m *mockScraper) SetMediaAltText(ctx context.Context, mediaID string, altText string) error {
+	return nil
+}
+
+func (m *mockScraper) TweetWithPoll(ctx context.Context, text string, poll Poll) (*twitterscraper.Tweet, error) {
+	return &twitterscraper.Tweet{Text: text}, nil
+}
+
+func (m *mockScraper) GetNotifications(ctx context.Context, cursor string) ([]Notification, string, error) {
+	return []Notification{{ID: "1", Type: NotificationLike, Actor: "alice", TweetID: "42"}}, "next", nil
+}
+
+func (m *mockScraper) GetTrends(ctx context.Context) ([]string, error) {
+	return []string{"#golang", "MCP"}, nil
+}
+
+func (m *mockScraper) GetListTweets(ctx context.Context, listID string, maxTweets int) ([]*twitterscraper.Tweet, error) {
+	return []*twitterscraper.Tweet{{ID: "1", Text: "hello", Username: "alice"}}, nil
+}
+
+func (m *mockScraper) GetListMembers(ctx context.Context, listID string, cursor string) ([]*twitterscraper.Profile, string, error) {
+	return []*twitterscraper.Profile{{UserID: "42", Username: "alice"}}, "next", nil
+}
+
 func (m *mockScraper) LikeTweet(ctx context.Context, id string) error {
 	return nil
 }
@@ -66,6 +130,18 @@ func (m *mockScraper) CreateScheduledTweet(ctx context.Context, text string, sch
 	return nil
 }
 
+func (m *mockScraper) Follow(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockScraper) Unfollow(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockScraper) GetRelationship(ctx context.Context, sourceUserID, targetUserID string) (bool, bool, bool, bool, error) {
+	return false, false, false, false, nil
+}
+
 func TestNewAgent(t *testing.T) {
 	agent := newMockAgent()
 	assert.NotNil(t, agent)
@@ -74,135 +150,69 @@ func TestNewAgent(t *testing.T) {
 
 func TestGetTools(t *testing.T) {
 	agent := newMockAgent()
-	tools := agent.GetTools()
 
-	// Without login, only basic tools should be available
-	assert.Equal(t, 3, len(tools), "Without login, only 3 basic tools should be available")
-
-	// Map of expected tool names and their required parameters
-	expectedBasicTools := map[string]struct {
-		required   []string
-		readOnly   bool
-		openWorld  bool
-		hasHandler bool
-	}{
-		"get_user_tweets": {
-			required:   []string{"username"},
-			readOnly:   true,
-			openWorld:  true,
-			hasHandler: true,
-		},
-		"get_profile": {
-			required:   []string{"username"},
-			readOnly:   true,
-			openWorld:  true,
-			hasHandler: true,
-		},
-		"get_tweet": {
-			required:   []string{"tweet_id"},
-			readOnly:   true,
-			openWorld:  true,
-			hasHandler: true,
-		},
+	type expectedTool struct {
+		required []string
+		readOnly bool
 	}
-
-	for _, tool := range tools {
-		// Check if tool exists in expected tools
-		expected, exists := expectedBasicTools[tool.Tool.Name]
-		assert.True(t, exists, "Unexpected tool: %s", tool.Tool.Name)
-
-		// Check required parameters
-		assert.Equal(t, expected.required, tool.Tool.InputSchema.Required, "Incorrect required parameters for %s", tool.Tool.Name)
-
-		// Check annotations
-		assert.Equal(t, expected.readOnly, tool.Tool.Annotations.ReadOnlyHint, "Incorrect ReadOnlyHint for %s", tool.Tool.Name)
-		assert.Equal(t, expected.openWorld, tool.Tool.Annotations.OpenWorldHint, "Incorrect OpenWorldHint for %s", tool.Tool.Name)
-		assert.NotEmpty(t, tool.Tool.Annotations.Title, "Missing Title for %s", tool.Tool.Name)
-
-		// Check handler
-		assert.NotNil(t, tool.Handler, "Missing handler for %s", tool.Tool.Name)
+	// Tools reading from Twitter are marked read only and open world; tools
+	// writing to it carry no hints
+	basicTools := map[string]expectedTool{
+		"get_user_tweets":          {required: []string{"username"}, readOnly: true},
+		"get_media_tweets":         {required: []string{"username"}, readOnly: true},
+		"get_profile":              {required: []string{"username"}, readOnly: true},
+		"get_tweet":                {required: []string{"tweet_id"}, readOnly: true},
+		"get_tweet_stats":          {required: []string{"tweet_id"}, readOnly: true},
+		"get_followers":            {required: []string{"username"}, readOnly: true},
+		"get_tweet_replies":        {required: []string{"tweet_id"}, readOnly: true},
+		"get_tweet_thread":         {required: []string{"tweet_id"}, readOnly: true},
+		"get_conversation_context": {required: []string{"tweet_id"}, readOnly: true},
+		"get_tweets":               {required: []string{"tweet_ids"}, readOnly: true},
+		"get_trends":               {readOnly: true},
+	}
+	allTools := map[string]expectedTool{
+		"search_tweets":          {required: []string{"query"}, readOnly: true},
+		"search_tweets_advanced": {readOnly: true},
+		"create_tweet":           {required: []string{"text"}},
+		"reply_tweet":            {required: []string{"tweet_id", "text"}},
+		"quote_tweet":            {required: []string{"tweet_id", "text"}},
+		"like_tweet":             {required: []string{"tweet_id"}},
+		"unlike_tweet":           {required: []string{"tweet_id"}},
+		"retweet":                {required: []string{"tweet_id"}},
+		"get_relationship":       {required: []string{"target_user_id"}, readOnly: true},
+		"get_notifications":      {readOnly: true},
+		"get_quotes":             {required: []string{"tweet_id"}, readOnly: true},
+		"get_user_replies":       {required: []string{"username"}, readOnly: true},
+		"get_list_tweets":        {required: []string{"list_id"}, readOnly: true},
+		"get_list_members":       {required: []string{"list_id"}, readOnly: true},
+	}
+	for name, tool := range basicTools {
+		allTools[name] = tool
 	}
 
-	// Now test with login
-	agent.scraper.(*mockScraper).isLoggedIn = true
-	tools = agent.GetTools()
+	hint := func(b *bool) bool {
+		return b != nil && *b
+	}
+	check := func(tools []server.ServerTool, expectedTools map[string]expectedTool) {
+		assert.Len(t, tools, len(expectedTools))
+		for _, tool := range tools {
+			expected, exists := expectedTools[tool.Tool.Name]
+			if !assert.True(t, exists, "Unexpected tool: %s", tool.Tool.Name) {
+				continue
+			}
+			assert.ElementsMatch(t, expected.required, tool.Tool.InputSchema.Required, "Incorrect required parameters for %s", tool.Tool.Name)
+			assert.Equal(t, expected.readOnly, hint(tool.Tool.Annotations.ReadOnlyHint), "Incorrect ReadOnlyHint for %s", tool.Tool.Name)
+			assert.Equal(t, expected.readOnly, hint(tool.Tool.Annotations.OpenWorldHint), "Incorrect OpenWorldHint for %s", tool.Tool.Name)
+			assert.NotEmpty(t, tool.Tool.Annotations.Title, "Missing Title for %s", tool.Tool.Name)
+			assert.NotNil(t, tool.Handler, "Missing handler for %s", tool.Tool.Name)
+		}
+	}
 
-	// With login, all tools should be available
-	assert.Equal(t, 8, len(tools), "With login, all tools should be available")
+	// Without login, only the tools reading public data are available
+	check(agent.GetTools(), basicTools)
 
-	// Map of expected tool names and their required parameters
-	expectedAllTools := map[string]struct {
-		required   []string
-		readOnly   bool
-		openWorld  bool
-		hasHandler bool
-	}{
-		"get_user_tweets": {
-			required:   []string{"username"},
-			readOnly:   true,
-			openWorld:  true,
-			hasHandler: true,
-		},
-		"get_profile": {
-			required:   []string{"username"},
-			readOnly:   true,
-			openWorld:  true,
-			hasHandler: true,
-		},
-		"get_tweet": {
-			required:   []string{"tweet_id"},
-			readOnly:   true,
-			openWorld:  true,
-			hasHandler: true,
-		},
-		"search_tweets": {
-			required:   []string{"query"},
-			readOnly:   true,
-			openWorld:  true,
-			hasHandler: true,
-		},
-		"create_tweet": {
-			required:   []string{"text"},
-			readOnly:   false,
-			openWorld:  false,
-			hasHandler: true,
-		},
-		"like_tweet": {
-			required:   []string{"tweet_id"},
-			readOnly:   false,
-			openWorld:  false,
-			hasHandler: true,
-		},
-		"unlike_tweet": {
-			required:   []string{"tweet_id"},
-			readOnly:   false,
-			openWorld:  false,
-			hasHandler: true,
-		},
-		"retweet": {
-			required:   []string{"tweet_id"},
-			readOnly:   false,
-			openWorld:  false,
-			hasHandler: true,
-		},
-	}
-
-	for _, tool := range tools {
-		// Check if tool exists in expected tools
-		expected, exists := expectedAllTools[tool.Tool.Name]
-		assert.True(t, exists, "Unexpected tool: %s", tool.Tool.Name)
-
-		// Check required parameters
-		assert.Equal(t, expected.required, tool.Tool.InputSchema.Required, "Incorrect required parameters for %s", tool.Tool.Name)
-
-		// Check annotations
-		assert.Equal(t, expected.readOnly, tool.Tool.Annotations.ReadOnlyHint, "Incorrect ReadOnlyHint for %s", tool.Tool.Name)
-		assert.Equal(t, expected.openWorld, tool.Tool.Annotations.OpenWorldHint, "Incorrect OpenWorldHint for %s", tool.Tool.Name)
-		assert.NotEmpty(t, tool.Tool.Annotations.Title, "Missing Title for %s", tool.Tool.Name)
-
-		// Check handler
-		assert.NotNil(t, tool.Handler, "Missing handler for %s", tool.Tool.Name)
-	}
+	agent.scraper.(*mockScraper).isLoggedIn = true
+	check(agent.GetTools(), allTools)
 }
 
 func TestHandleGetUserTweetsValidation(t *testing.T) {
@@ -384,6 +394,8 @@ func TestHandleGetTweetValidation(t *testing.T) {
 
 func TestHandleSearchTweetsValidation(t *testing.T) {
 	agent := newMockAgent()
+	// Searching requires login, which is checked before the arguments
+	agent.scraper.(*mockScraper).isLoggedIn = true
 	ctx := context.Background()
 
 	tests := []struct {
@@ -425,6 +437,7 @@ func TestHandleSearchTweetsValidation(t *testing.T) {
 				},
 			}
 
+			agent.limiter.lastCallTime = time.Time{}
 			result, err := agent.handleSearchTweets(ctx, request)
 			assert.NoError(t, err)
 
@@ -432,10 +445,7 @@ func TestHandleSearchTweetsValidation(t *testing.T) {
 				assert.True(t, result.IsError)
 				assert.Equal(t, tt.errorString, result.Content[0].(*mcp.TextContent).Text)
 			} else {
-				// Skip validation if there's an error from the Twitter API
-				if result.IsError {
-					t.Skip("Skipping due to Twitter API error")
-				}
+				assert.False(t, result.IsError, result.Content[0].(*mcp.TextContent).Text)
 			}
 		})
 	}
@@ -505,39 +515,29 @@ func TestJSONResponseFormat(t *testing.T) {
 				err := json.Unmarshal([]byte(jsonStr), &tweet)
 				assert.NoError(t, err)
 
-				// Check required fields
+				// The tweet keeps the scraper's field names
 				requiredFields := []string{
-					"id", "text", "likes", "retweets", "replies",
-					"timestamp", "author",
+					"ID", "Text", "Likes", "Retweets", "Replies",
+					"Timestamp", "Username", "Name", "PermanentURL",
 				}
 				for _, field := range requiredFields {
 					_, exists := tweet[field]
 					assert.True(t, exists, "Missing field: %s", field)
 				}
 
-				// Check author fields
-				author, ok := tweet["author"].(map[string]interface{})
-				assert.True(t, ok)
-				authorFields := []string{"username", "name", "verified"}
-				for _, field := range authorFields {
-					_, exists := author[field]
-					assert.True(t, exists, "Missing author field: %s", field)
-				}
+				// HTML is only included when asked for
+				_, exists := tweet["HTML"]
+				assert.False(t, exists, "Unexpected field: HTML")
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			agent.limiter.lastCallTime = time.Time{}
 			result, err := tt.handler(ctx, tt.request)
-			if err != nil {
-				// Skip validation if there's an error from the Twitter API
-				t.Skip("Skipping due to Twitter API error")
-				return
-			}
-
-			if result.IsError {
-				t.Skip("Skipping due to Twitter API error")
+			assert.NoError(t, err)
+			if !assert.False(t, result.IsError, result.Content[0].(*mcp.TextContent).Text) {
 				return
 			}
 
@@ -553,6 +553,8 @@ func TestHandleLoginWithCookies(t *testing.T) {
 
 func TestHandleCreateTweet(t *testing.T) {
 	agent := newMockAgent()
+	// Tweeting requires login, which is checked before the arguments
+	agent.scraper.(*mockScraper).isLoggedIn = true
 	ctx := context.Background()
 
 	tests := []struct {
@@ -565,13 +567,13 @@ func TestHandleCreateTweet(t *testing.T) {
 			name:        "missing text",
 			params:      map[string]interface{}{},
 			wantError:   true,
-			errorString: "tweet text is required",
+			errorString: "text parameter is required",
 		},
 		{
 			name:        "empty text",
 			params:      map[string]interface{}{"text": ""},
 			wantError:   true,
-			errorString: "tweet text is required",
+			errorString: "text parameter is required",
 		},
 		{
 			name: "valid text",
@@ -580,13 +582,12 @@ func TestHandleCreateTweet(t *testing.T) {
 			},
 		},
 		{
-			name: "invalid schedule time",
+			name: "text too long",
 			params: map[string]interface{}{
-				"text":          "Test tweet",
-				"schedule_time": "invalid-time",
+				"text": strings.Repeat("a", maxTweetLength+1),
 			},
 			wantError:   true,
-			errorString: "invalid schedule time format",
+			errorString: fmt.Sprintf("tweet exceeds %d characters", maxTweetLength),
 		},
 		{
 			name: "valid schedule time",
@@ -612,12 +613,15 @@ func TestHandleCreateTweet(t *testing.T) {
 				},
 			}
 
+			agent.limiter.lastCallTime = time.Time{}
 			result, err := agent.handleCreateTweet(ctx, request)
 			assert.NoError(t, err)
 
 			if tt.wantError {
 				assert.True(t, result.IsError)
 				assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, tt.errorString)
+			} else {
+				assert.False(t, result.IsError, result.Content[0].(*mcp.TextContent).Text)
 			}
 		})
 	}
@@ -625,6 +629,8 @@ func TestHandleCreateTweet(t *testing.T) {
 
 func TestHandleLikeUnlikeTweet(t *testing.T) {
 	agent := newMockAgent()
+	// Liking requires login, which is checked before the arguments
+	agent.scraper.(*mockScraper).isLoggedIn = true
 	ctx := context.Background()
 
 	tests := []struct {
@@ -693,12 +699,15 @@ func TestHandleLikeUnlikeTweet(t *testing.T) {
 				},
 			}
 
+			agent.limiter.lastCallTime = time.Time{}
 			result, err := tt.handler(ctx, request)
 			assert.NoError(t, err)
 
 			if tt.wantError {
 				assert.True(t, result.IsError)
 				assert.Equal(t, tt.errorString, result.Content[0].(*mcp.TextContent).Text)
+			} else {
+				assert.False(t, result.IsError, result.Content[0].(*mcp.TextContent).Text)
 			}
 		})
 	}
@@ -706,6 +715,8 @@ func TestHandleLikeUnlikeTweet(t *testing.T) {
 
 func TestHandleRetweet(t *testing.T) {
 	agent := newMockAgent()
+	// Retweeting requires login, which is checked before the arguments
+	agent.scraper.(*mockScraper).isLoggedIn = true
 	ctx := context.Background()
 
 	tests := []struct {
@@ -749,12 +760,15 @@ func TestHandleRetweet(t *testing.T) {
 				},
 			}
 
+			agent.limiter.lastCallTime = time.Time{}
 			result, err := agent.handleRetweet(ctx, request)
 			assert.NoError(t, err)
 
 			if tt.wantError {
 				assert.True(t, result.IsError)
 				assert.Equal(t, tt.errorString, result.Content[0].(*mcp.TextContent).Text)
+			} else {
+				assert.False(t, result.IsError, result.Content[0].(*mcp.TextContent).Text)
 			}
 		})
 	}
@@ -862,5 +876,778 @@ func newMockAgent() *Agent {
 			isLoggedIn: false,
 		},
 		limiter: newRateLimiter(),
+		logger:  logging.Default(),
+	}
+}
+
+func TestHandleCreateTweetLength(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper.(*mockScraper).isLoggedIn = true
+	ctx := context.Background()
+
+	request := mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name:      "create_tweet",
+			Arguments: map[string]interface{}{"text": strings.Repeat("a", 300)},
+		},
+	}
+
+	result, err := agent.handleCreateTweet(ctx, request)
+	assert.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "tweet exceeds 280 characters (counted 300)", result.Content[0].(*mcp.TextContent).Text)
+}
+
+func TestSimplifiedTweetEntitiesJSON(t *testing.T) {
+	tweet := &twitterscraper.Tweet{
+		ID:       "1",
+		Text:     "hello @alice #golang https://t.co/abc",
+		Hashtags: []string{"golang"},
+		Mentions: []twitterscraper.Mention{{ID: "42", Username: "alice", Name: "Alice"}},
+		URLs:     []string{"https://example.com/post"},
+	}
+
+	data, err := json.Marshal(newSimplifiedTweet(tweet))
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, []interface{}{"golang"}, decoded["hashtags"])
+	assert.Equal(t, []interface{}{"alice"}, decoded["mentions"])
+	assert.Equal(t, []interface{}{"https://example.com/post"}, decoded["urls"])
+
+	// Entities are omitted when the tweet has none
+	data, err = json.Marshal(newSimplifiedTweet(&twitterscraper.Tweet{ID: "2", Text: "plain"}))
+	assert.NoError(t, err)
+	for _, field := range []string{"hashtags", "mentions", "urls"} {
+		assert.NotContains(t, string(data), `"`+field+`"`)
+	}
+}
+
+func TestAgentSetUserAgent(t *testing.T) {
+	// Without a custom user agent the scraper default is kept
+	agent := NewAgent("testuser")
+	assert.NoError(t, agent.SetUserAgent(""))
+	assert.Equal(t, twitterscraper.DefaultUserAgent, agent.scraper.(*scraperWrapper).GetUserAgent())
+
+	// A custom user agent replaces the default
+	agent = NewAgent("testuser")
+	assert.NoError(t, agent.SetUserAgent("x-go-test/1.0"))
+	assert.Equal(t, "x-go-test/1.0", agent.scraper.(*scraperWrapper).GetUserAgent())
+
+	// Header injection is rejected and leaves the user agent unchanged
+	agent = NewAgent("testuser")
+	assert.Error(t, agent.SetUserAgent("x-go-test/1.0\r\nX-Injected: 1"))
+	assert.Equal(t, twitterscraper.DefaultUserAgent, agent.scraper.(*scraperWrapper).GetUserAgent())
+}
+
+func TestHandleCreateTweetAutoThread(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper.(*mockScraper).isLoggedIn = true
+	ctx := context.Background()
+
+	request := mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "create_tweet",
+			Arguments: map[string]interface{}{
+				"text":        strings.Repeat("This sentence is about forty characters. ", 15),
+				"auto_thread": true,
+			},
+		},
+	}
+
+	result, err := agent.handleCreateTweet(ctx, request)
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	var response struct {
+		TweetIDs []string `json:"tweet_ids"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	assert.Len(t, response.TweetIDs, 3)
+}
+
+func TestHandleCreateTweetPollValidation(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper.(*mockScraper).isLoggedIn = true
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		poll    interface{}
+		wantErr string
+	}{
+		{
+			name:    "one option",
+			poll:    map[string]interface{}{"options": []interface{}{"yes"}},
+			wantErr: "poll must have between 2 and 4 options, got 1",
+		},
+		{
+			name:    "five options",
+			poll:    map[string]interface{}{"options": []interface{}{"a", "b", "c", "d", "e"}},
+			wantErr: "poll must have between 2 and 4 options, got 5",
+		},
+		{
+			name:    "empty option",
+			poll:    map[string]interface{}{"options": []interface{}{"a", ""}},
+			wantErr: "poll option 2 is empty",
+		},
+		{
+			name:    "duration too short",
+			poll:    map[string]interface{}{"options": []interface{}{"a", "b"}, "duration_minutes": float64(1)},
+			wantErr: "poll duration must be between 5 and 10080 minutes, got 1",
+		},
+		{
+			name:    "duration too long",
+			poll:    &Poll{Options: []string{"a", "b"}, DurationMinutes: 20000},
+			wantErr: "poll duration must be between 5 and 10080 minutes, got 20000",
+		},
+		{
+			name: "valid poll",
+			poll: map[string]interface{}{"options": []interface{}{"a", "b", "c"}, "duration_minutes": float64(60)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{
+				Params: struct {
+					Name      string                 `json:"name"`
+					Arguments map[string]interface{} `json:"arguments,omitempty"`
+					Meta      *struct {
+						ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+					} `json:"_meta,omitempty"`
+				}{
+					Name: "create_tweet",
+					Arguments: map[string]interface{}{
+						"text":    "Which one?",
+						"poll":    tt.poll,
+						"dry_run": true,
+					},
+				},
+			}
+
+			result, err := agent.handleCreateTweet(ctx, request)
+			assert.NoError(t, err)
+			if tt.wantErr == "" {
+				assert.False(t, result.IsError)
+				return
+			}
+			assert.True(t, result.IsError)
+			assert.Equal(t, tt.wantErr, result.Content[0].(*mcp.TextContent).Text)
+		})
+	}
+}
+
+func TestHandleGetTrends(t *testing.T) {
+	agent := newMockAgent()
+
+	result, err := agent.handleGetTrends(context.Background(), mcp.CallToolRequest{})
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	var trends []string
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &trends))
+	assert.Equal(t, []string{"#golang", "MCP"}, trends)
+}
+
+func TestHandleReplyTweet(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper.(*mockScraper).isLoggedIn = true
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		wantErr   string
+	}{
+		{
+			name:      "missing tweet id",
+			arguments: map[string]interface{}{"text": "Agreed"},
+			wantErr:   "tweet_id parameter is required",
+		},
+		{
+			name:      "empty text",
+			arguments: map[string]interface{}{"tweet_id": "123", "text": ""},
+			wantErr:   "text parameter is required",
+		},
+		{
+			name:      "too long",
+			arguments: map[string]interface{}{"tweet_id": "123", "text": strings.Repeat("a", 281)},
+			wantErr:   "tweet exceeds 280 characters (counted 281)",
+		},
+		{
+			name:      "valid reply",
+			arguments: map[string]interface{}{"tweet_id": "123", "text": "Agreed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{
+				Params: struct {
+					Name      string                 `json:"name"`
+					Arguments map[string]interface{} `json:"arguments,omitempty"`
+					Meta      *struct {
+						ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+					} `json:"_meta,omitempty"`
+				}{
+					Name:      "reply_tweet",
+					Arguments: tt.arguments,
+				},
+			}
+
+			result, err := agent.handleReplyTweet(ctx, request)
+			assert.NoError(t, err)
+			if tt.wantErr != "" {
+				assert.True(t, result.IsError)
+				assert.Equal(t, tt.wantErr, result.Content[0].(*mcp.TextContent).Text)
+				return
+			}
+
+			assert.False(t, result.IsError)
+			var tweet twitterscraper.Tweet
+			assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &tweet))
+			assert.Equal(t, "123", tweet.InReplyToStatusID)
+		})
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	// Contexts without a request ID fall back to NoRequestID
+	assert.Equal(t, NoRequestID, RequestIDFromContext(context.Background()))
+
+	ctx := ContextWithRequestID(context.Background(), "abc123")
+	assert.Equal(t, "abc123", RequestIDFromContext(ctx))
+
+	// Generated IDs are unique hex strings
+	id := NewRequestID()
+	assert.Len(t, id, 16)
+	assert.NotEqual(t, id, NewRequestID())
+}
+
+func TestClassifyLoginError(t *testing.T) {
+	tests := []struct {
+		err      string
+		expected string
+	}{
+		{err: "auth error: DenyLoginSubtask", expected: AgentStatusSuspended},
+		{err: "auth error (64): Your account is suspended and is not permitted to access this feature.", expected: AgentStatusSuspended},
+		{err: "auth error: LoginAcid", expected: AgentStatusLocked},
+		{err: "auth error: LoginTwoFactorAuthChallenge", expected: AgentStatusLocked},
+		{err: "auth error (326): To protect our users from spam and other malicious activity, this account is temporarily locked.", expected: AgentStatusLocked},
+		{err: "confirmation data required for LoginAcid", expected: AgentStatusLocked},
+		{err: "invalid credentials", expected: AgentStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyLoginError(errors.New(tt.err)))
+		})
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	limit, warning := ClampLimit(50, 1000)
+	assert.Equal(t, 50, limit)
+	assert.Empty(t, warning)
+
+	limit, warning = ClampLimit(1000000, 1000)
+	assert.Equal(t, 1000, limit)
+	assert.Equal(t, "limit 1000000 exceeds the maximum of 1000, returning at most 1000 results", warning)
+
+	// A max of 0 disables the cap
+	limit, warning = ClampLimit(1000000, 0)
+	assert.Equal(t, 1000000, limit)
+	assert.Empty(t, warning)
+}
+
+// limitRecordingScraper records the limit the tweet channels are requested with
+type limitRecordingScraper struct {
+	mockScraper
+	limit int
+}
+
+func (s *limitRecordingScraper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	s.limit = maxTweetsNb
+	return s.mockScraper.GetTweets(ctx, username, maxTweetsNb)
+}
+
+func (s *limitRecordingScraper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	s.limit = maxTweetsNb
+	return s.mockScraper.SearchTweets(ctx, query, maxTweetsNb)
+}
+
+func TestTweetLimitClamp(t *testing.T) {
+	scraper := &limitRecordingScraper{mockScraper: mockScraper{isLoggedIn: true}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.SetMaxResults(100)
+	ctx := context.Background()
+
+	handlers := map[string]func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error){
+		"get_user_tweets": agent.handleGetUserTweets,
+		"search_tweets":   agent.handleSearchTweets,
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range []struct {
+				limit       float64
+				expected    int
+				wantWarning bool
+			}{
+				{limit: 50, expected: 50},
+				{limit: 1000000, expected: 100, wantWarning: true},
+			} {
+				request := mcp.CallToolRequest{
+					Params: struct {
+						Name      string                 `json:"name"`
+						Arguments map[string]interface{} `json:"arguments,omitempty"`
+						Meta      *struct {
+							ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+						} `json:"_meta,omitempty"`
+					}{
+						Name: name,
+						Arguments: map[string]interface{}{
+							"username": "testuser",
+							"query":    "test",
+							"limit":    tt.limit,
+						},
+					},
+				}
+
+				result, err := handler(ctx, request)
+				assert.NoError(t, err)
+				assert.False(t, result.IsError)
+				assert.Equal(t, tt.expected, scraper.limit)
+
+				if tt.wantWarning {
+					assert.Len(t, result.Content, 2)
+					assert.Equal(t, "warning: limit 1000000 exceeds the maximum of 100, returning at most 100 results",
+						result.Content[1].(*mcp.TextContent).Text)
+				} else {
+					assert.Len(t, result.Content, 1)
+				}
+			}
+		})
+	}
+}
+
+// failingStreamScraper yields two tweets and then an error, as when a later
+// page of a timeline or search is rate limited
+type failingStreamScraper struct {
+	mockScraper
+}
+
+func (s *failingStreamScraper) stream() <-chan *twitterscraper.TweetResult {
+	ch := make(chan *twitterscraper.TweetResult, 3)
+	ch <- &twitterscraper.TweetResult{Tweet: twitterscraper.Tweet{ID: "2", Text: "second"}}
+	ch <- &twitterscraper.TweetResult{Tweet: twitterscraper.Tweet{ID: "1", Text: "first"}}
+	ch <- &twitterscraper.TweetResult{Error: errors.New("response status 429 Too Many Requests")}
+	close(ch)
+	return ch
+}
+
+func (s *failingStreamScraper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.stream()
+}
+
+func (s *failingStreamScraper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.stream()
+}
+
+func TestTweetStreamPartialResults(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &failingStreamScraper{mockScraper{isLoggedIn: true}}
+
+	// Timeline tweets are returned as scraped, search results are reshaped
+	for _, tt := range []struct {
+		name    string
+		handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		idKey   string
+	}{
+		{name: "get_user_tweets", handler: agent.handleGetUserTweets, idKey: "ID"},
+		{name: "search_tweets", handler: agent.handleSearchTweets, idKey: "id"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			agent.limiter.lastCallTime = time.Time{}
+			var request mcp.CallToolRequest
+			request.Params.Name = tt.name
+			request.Params.Arguments = map[string]interface{}{"username": "testuser", "query": "test"}
+
+			result, err := tt.handler(context.Background(), request)
+			assert.NoError(t, err)
+			assert.False(t, result.IsError)
+
+			// The tweets read before the error are kept
+			var tweets []map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &tweets))
+			if assert.Len(t, tweets, 2) {
+				assert.Equal(t, "2", tweets[0][tt.idKey])
+				assert.Equal(t, "1", tweets[1][tt.idKey])
+			}
+
+			if assert.Len(t, result.Content, 2) {
+				assert.Equal(t, "warning: partial results, only 2 tweets were read before an error: response status 429 Too Many Requests",
+					result.Content[1].(*mcp.TextContent).Text)
+			}
+		})
+	}
+
+	// The manager reports them
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+	agent.limiter.lastCallTime = time.Time{}
+	_, partial, _, err := manager.GetUserTweets(context.Background(), "testuser", 10, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"partial results, only 2 tweets were read before an error: response status 429 Too Many Requests"}, partial)
+
+	agent.limiter.lastCallTime = time.Time{}
+	_, partial, _, err = manager.SearchTweets(context.Background(), "test", 10, SearchOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, partial, 1)
+
+	// since_id polling doesn't move past the tweets that weren't read
+	agent.limiter.lastCallTime = time.Time{}
+	result, newestID, partial, _, err := manager.GetNewUserTweets(context.Background(), "testuser", "0")
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Len(t, partial, 1)
+	assert.Equal(t, "0", newestID)
+}
+
+// pagingScraper serves followers in pages of two, keyed by cursor
+type pagingScraper struct {
+	mockScraper
+	pages map[string][]string // usernames per cursor
+	next  map[string]string   // next cursor per cursor
+	calls int
+	// cancel, when set, is called after serving the first page
+	cancel context.CancelFunc
+}
+
+func (s *pagingScraper) FetchFollowers(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	s.calls++
+	if s.cancel != nil {
+		defer s.cancel()
+	}
+	var profiles []*twitterscraper.Profile
+	for _, name := range s.pages[cursor] {
+		profiles = append(profiles, &twitterscraper.Profile{Username: name})
+	}
+	return profiles, s.next[cursor], nil
+}
+
+func TestGetAllFollowers(t *testing.T) {
+	newManager := func(scraper Scraper) *AgentManager {
+		agent := newMockAgent()
+		agent.scraper = scraper
+		return &AgentManager{
+			agents:     []*Agent{agent},
+			logger:     logging.Default(),
+			maxResults: DefaultMaxResults,
+		}
+	}
+
+	followerNames := func(t *testing.T, result interface{}) ([]string, string) {
+		data, err := json.Marshal(result)
+		assert.NoError(t, err)
+		var page struct {
+			Followers []struct {
+				Username string `json:"Username"`
+			} `json:"followers"`
+			NextCursor string `json:"next_cursor"`
+		}
+		assert.NoError(t, json.Unmarshal(data, &page))
+		var names []string
+		for _, follower := range page.Followers {
+			names = append(names, follower.Username)
+		}
+		return names, page.NextCursor
+	}
+
+	t.Run("follows cursors until exhausted", func(t *testing.T) {
+		scraper := &pagingScraper{
+			pages: map[string][]string{"": {"a", "b"}, "c1": {"c", "d"}},
+			next:  map[string]string{"": "c1"},
+		}
+		result, _, err := newManager(scraper).GetAllFollowers(context.Background(), "testuser", 10)
+		assert.NoError(t, err)
+		names, next := followerNames(t, result)
+		assert.Equal(t, []string{"a", "b", "c", "d"}, names)
+		assert.Empty(t, next)
+		assert.Equal(t, 2, scraper.calls)
+	})
+
+	t.Run("stops at max", func(t *testing.T) {
+		scraper := &pagingScraper{
+			pages: map[string][]string{"": {"a", "b"}, "c1": {"c", "d"}},
+			next:  map[string]string{"": "c1", "c1": "c2"},
+		}
+		result, _, err := newManager(scraper).GetAllFollowers(context.Background(), "testuser", 3)
+		assert.NoError(t, err)
+		names, next := followerNames(t, result)
+		assert.Equal(t, []string{"a", "b", "c"}, names)
+		// "d" wasn't returned, so the cut page is requested again
+		assert.Equal(t, "c1", next)
+	})
+
+	t.Run("stops at max at the end of a page", func(t *testing.T) {
+		scraper := &pagingScraper{
+			pages: map[string][]string{"": {"a", "b"}, "c1": {"c", "d"}},
+			next:  map[string]string{"": "c1", "c1": "c2"},
+		}
+		result, _, err := newManager(scraper).GetAllFollowers(context.Background(), "testuser", 2)
+		assert.NoError(t, err)
+		names, next := followerNames(t, result)
+		assert.Equal(t, []string{"a", "b"}, names)
+		assert.Equal(t, "c1", next)
+	})
+
+	t.Run("stops on a repeated cursor", func(t *testing.T) {
+		scraper := &pagingScraper{
+			pages: map[string][]string{"": {"a", "b"}, "c1": {"c", "d"}},
+			next:  map[string]string{"": "c1", "c1": "c1"},
+		}
+		result, _, err := newManager(scraper).GetAllFollowers(context.Background(), "testuser", 10)
+		assert.NoError(t, err)
+		names, _ := followerNames(t, result)
+		assert.Equal(t, []string{"a", "b", "c", "d"}, names)
+		assert.Equal(t, 2, scraper.calls)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := newManager(&pagingScraper{}).GetAllFollowers(ctx, "testuser", 10)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("cancelled between pages", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		scraper := &pagingScraper{
+			pages:  map[string][]string{"": {"a", "b"}, "c1": {"c", "d"}},
+			next:   map[string]string{"": "c1"},
+			cancel: cancel,
+		}
+		_, _, err := newManager(scraper).GetAllFollowers(ctx, "testuser", 10)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, scraper.calls)
+	})
+}
+
+func TestPostTweet(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &mockScraper{isLoggedIn: true}
+	manager := &AgentManager{
+		agents: []*Agent{agent},
+		logger: logging.Default(),
+	}
+
+	result, _, err := manager.PostTweet(context.Background(), PostTweetRequest{Text: "quoting", QuoteOf: "42"})
+	assert.NoError(t, err)
+	assert.Equal(t, "42", result.(map[string]interface{})["QuotedStatusID"])
+
+	result, _, err = manager.PostTweet(context.Background(), PostTweetRequest{Text: "replying", ReplyTo: "43"})
+	assert.NoError(t, err)
+	assert.Equal(t, "43", result.(map[string]interface{})["InReplyToStatusID"])
+
+	_, _, err = manager.PostTweet(context.Background(), PostTweetRequest{Text: "both", ReplyTo: "43", QuoteOf: "42"})
+	assert.ErrorIs(t, err, ErrInvalidTweetRequest)
+
+	_, _, err = manager.PostTweet(context.Background(), PostTweetRequest{
+		Text:               "scheduled reply",
+		ReplyTo:            "43",
+		CreateTweetOptions: CreateTweetOptions{ScheduleTime: "2030-01-01T00:00:00Z"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidTweetRequest)
+
+	result, _, err = manager.PostTweet(context.Background(), PostTweetRequest{Text: "quoting", QuoteOf: "42", Media: []MediaAttachment{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "42", result.(map[string]interface{})["QuotedStatusID"])
+
+	_, _, err = manager.PostTweet(context.Background(), PostTweetRequest{Text: "quoting", QuoteOf: "42", Media: []MediaAttachment{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "5"}}})
+	assert.ErrorIs(t, err, ErrInvalidTweetRequest)
+
+	_, _, err = manager.PostTweet(context.Background(), PostTweetRequest{Text: "plain", Media: []MediaAttachment{{ID: "1"}}})
+	assert.ErrorIs(t, err, ErrInvalidTweetRequest)
+}
+
+// stalledScraper streams one tweet and then stalls until release is closed
+type stalledScraper struct {
+	mockScraper
+	sent    chan struct{}
+	release chan struct{}
+}
+
+func (s *stalledScraper) stream(ctx context.Context) <-chan *twitterscraper.TweetResult {
+	ch := make(chan *twitterscraper.TweetResult)
+	go func() {
+		defer close(ch)
+		ch <- &twitterscraper.TweetResult{Tweet: twitterscraper.Tweet{ID: "1"}}
+		close(s.sent)
+		<-s.release
+		ch <- &twitterscraper.TweetResult{Error: ctx.Err()}
+	}()
+	return ch
+}
+
+func (s *stalledScraper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.stream(ctx)
+}
+
+func (s *stalledScraper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.stream(ctx)
+}
+
+func TestTweetStreamCancellation(t *testing.T) {
+	handlers := map[string]func(*Agent) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error){
+		"get_user_tweets": func(a *Agent) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return a.handleGetUserTweets
+		},
+		"search_tweets": func(a *Agent) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return a.handleSearchTweets
+		},
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			scraper := &stalledScraper{
+				mockScraper: mockScraper{isLoggedIn: true},
+				sent:        make(chan struct{}),
+				release:     make(chan struct{}),
+			}
+			defer close(scraper.release)
+			agent := newMockAgent()
+			agent.scraper = scraper
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-scraper.sent
+				cancel()
+			}()
+
+			var request mcp.CallToolRequest
+			request.Params.Arguments = map[string]interface{}{"username": "alice", "query": "golang"}
+			result, err := handler(agent)(ctx, request)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+// timelineScraper streams tweets in order until ctx is cancelled, counting
+// the tweets it sent
+type timelineScraper struct {
+	mockScraper
+	tweets []twitterscraper.Tweet
+	sent   int32
+}
+
+func (s *timelineScraper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	ch := make(chan *twitterscraper.TweetResult)
+	go func() {
+		defer close(ch)
+		for _, tweet := range s.tweets {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- &twitterscraper.TweetResult{Tweet: tweet}:
+				atomic.AddInt32(&s.sent, 1)
+			}
+		}
+	}()
+	return ch
+}
+
+func TestGetUserTweetsSince(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.UTC) }
+	scraper := &timelineScraper{tweets: []twitterscraper.Tweet{
+		{ID: "pinned", TimeParsed: day(1), IsPin: true},
+		{ID: "5", TimeParsed: day(5)},
+		{ID: "retweet", TimeParsed: day(2), IsRetweet: true},
+		{ID: "4", TimeParsed: day(4)},
+		{ID: "3", TimeParsed: day(3)},
+		{ID: "2", TimeParsed: day(2)},
+		{ID: "1", TimeParsed: day(1)},
+	}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	result, _, _, err := manager.GetUserTweetsSince(context.Background(), "alice", day(4).Add(-time.Hour))
+	assert.NoError(t, err)
+	var ids []string
+	for _, tweet := range result.([]interface{}) {
+		ids = append(ids, tweet.(map[string]interface{})["ID"].(string))
+	}
+	assert.Equal(t, []string{"5", "4"}, ids)
+	// Reading stops at the first ordinary tweet before the window, give or
+	// take the one send racing the cancellation
+	assert.LessOrEqual(t, atomic.LoadInt32(&scraper.sent), int32(6))
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]interface{}{"username": "alice", "since": "yesterday"}
+	toolResult, err := agent.handleGetUserTweets(context.Background(), request)
+	assert.NoError(t, err)
+	assert.True(t, toolResult.IsError)
+}
+
+func TestGetNewUserTweets(t *testing.T) {
+	scraper := &timelineScraper{tweets: []twitterscraper.Tweet{
+		{ID: "90", IsPin: true},
+		{ID: "1010"},
+		{ID: "80", IsRetweet: true},
+		{ID: "999"},
+		{ID: "100"},
+		{ID: "99"},
+	}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	// IDs compare numerically, not as strings
+	result, newestID, _, _, err := manager.GetNewUserTweets(context.Background(), "alice", "100")
+	assert.NoError(t, err)
+	var ids []string
+	for _, tweet := range result.([]interface{}) {
+		ids = append(ids, tweet.(map[string]interface{})["ID"].(string))
 	}
+	assert.Equal(t, []string{"1010", "999"}, ids)
+	assert.Equal(t, "1010", newestID)
+	assert.LessOrEqual(t, atomic.LoadInt32(&scraper.sent), int32(6))
+
+	// Without new tweets the newest ID is since_id itself
+	agent.limiter.lastCallTime = time.Time{}
+	result, newestID, _, _, err = manager.GetNewUserTweets(context.Background(), "alice", "1010")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Equal(t, "1010", newestID)
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]interface{}{"username": "alice", "since_id": "latest"}
+	toolResult, err := agent.handleGetUserTweets(context.Background(), request)
+	assert.NoError(t, err)
+	assert.True(t, toolResult.IsError)
 }
@@ -2,15 +2,20 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 )
 
+// AccountsEnvVar holds accounts as raw JSON, in the same format as accounts.json
+const AccountsEnvVar = "XGO_ACCOUNTS_JSON"
+
 type Account struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	UserAgent string `json:"user_agent,omitempty"` // Overrides the scraper's default User-Agent
 }
 
 type AccountManager struct {
@@ -41,6 +46,53 @@ func (am *AccountManager) LoadAccounts() ([]Account, error) {
 	return accounts, nil
 }
 
+// LoadAccountsFromEnv reads accounts from the XGO_ACCOUNTS_JSON environment
+// variable. It returns no accounts and no error when the variable is unset.
+func (am *AccountManager) LoadAccountsFromEnv() ([]Account, error) {
+	data := os.Getenv(AccountsEnvVar)
+	if data == "" {
+		return nil, nil
+	}
+
+	var accounts []Account
+	if err := json.Unmarshal([]byte(data), &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", AccountsEnvVar, err)
+	}
+
+	return accounts, nil
+}
+
+// LoadAllAccounts merges the accounts from accounts.json and XGO_ACCOUNTS_JSON.
+// Either source may be missing, but not both. When both define the same
+// username the environment entry wins, so secrets can override the file.
+func (am *AccountManager) LoadAllAccounts() ([]Account, error) {
+	envAccounts, err := am.LoadAccountsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	fileAccounts, err := am.LoadAccounts()
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || envAccounts == nil {
+			return nil, err
+		}
+		fileAccounts = nil
+	}
+
+	accounts := make([]Account, 0, len(fileAccounts)+len(envAccounts))
+	index := make(map[string]int)
+	for _, account := range append(fileAccounts, envAccounts...) {
+		if i, ok := index[account.Username]; ok {
+			accounts[i] = account
+			continue
+		}
+		index[account.Username] = len(accounts)
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
 func (am *AccountManager) SaveCookies(username string, cookies []*http.Cookie) error {
 	if err := os.MkdirAll(am.CookiesPath, 0755); err != nil {
 		return fmt.Errorf("failed to create cookies directory: %w", err)
@@ -66,8 +118,8 @@ func (am *AccountManager) LoadCookies(username string) ([]*http.Cookie, error) {
 		return nil, fmt.Errorf("failed to read cookies file: %w", err)
 	}
 
-	var cookies []*http.Cookie
-	if err := json.Unmarshal(data, &cookies); err != nil {
+	cookies, err := ParseCookies(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse cookies file: %w", err)
 	}
 
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeAccountsFile(t *testing.T, dir, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "accounts.json"), []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write accounts file: %v", err)
+	}
+}
+
+func TestLoadAllAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      string
+		expected []Account
+		wantErr  bool
+	}{
+		{
+			name: "env only",
+			env:  `[{"username": "env_user", "password": "env_pass"}]`,
+			expected: []Account{
+				{Username: "env_user", Password: "env_pass"},
+			},
+		},
+		{
+			name: "file only",
+			file: `[{"username": "file_user", "password": "file_pass"}]`,
+			expected: []Account{
+				{Username: "file_user", Password: "file_pass"},
+			},
+		},
+		{
+			name: "merged with env overriding file",
+			file: `[{"username": "shared", "password": "old"}, {"username": "file_user", "password": "file_pass"}]`,
+			env:  `[{"username": "shared", "password": "new"}, {"username": "env_user", "password": "env_pass"}]`,
+			expected: []Account{
+				{Username: "shared", Password: "new"},
+				{Username: "file_user", Password: "file_pass"},
+				{Username: "env_user", Password: "env_pass"},
+			},
+		},
+		{
+			name:    "neither source",
+			wantErr: true,
+		},
+		{
+			name:    "invalid env",
+			file:    `[{"username": "file_user", "password": "file_pass"}]`,
+			env:     `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.file != "" {
+				writeAccountsFile(t, dir, tt.file)
+			}
+			t.Setenv(AccountsEnvVar, tt.env)
+
+			accounts, err := NewAccountManager(dir).LoadAllAccounts()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, accounts)
+		})
+	}
+}
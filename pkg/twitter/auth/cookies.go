@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// exportedCookie is a cookie in the format browser extensions such as
+// EditThisCookie and Cookie-Editor export, with the expiry in Unix seconds
+type exportedCookie struct {
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+	Domain         string   `json:"domain"`
+	Path           string   `json:"path"`
+	ExpirationDate *float64 `json:"expirationDate"`
+	HTTPOnly       bool     `json:"httpOnly"`
+	Secure         bool     `json:"secure"`
+	SameSite       string   `json:"sameSite"`
+}
+
+// ParseCookies parses a cookies file. Both the JSON shape of http.Cookie
+// written by SaveCookies and the array of {name, value, domain,
+// expirationDate, ...} objects exported by browser extensions are accepted.
+// The format is detected from the lowercase keys of the exported one; anything
+// else is parsed strictly as http.Cookie.
+func ParseCookies(data []byte) ([]*http.Cookie, error) {
+	if !isExportedFormat(data) {
+		var cookies []*http.Cookie
+		if err := json.Unmarshal(data, &cookies); err != nil {
+			return nil, err
+		}
+		return cookies, nil
+	}
+
+	var exported []exportedCookie
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, err
+	}
+
+	cookies := make([]*http.Cookie, 0, len(exported))
+	for _, e := range exported {
+		if e.Name == "" {
+			return nil, fmt.Errorf("exported cookie without a name")
+		}
+		cookie := &http.Cookie{
+			Name:     e.Name,
+			Value:    e.Value,
+			Domain:   e.Domain,
+			Path:     e.Path,
+			HttpOnly: e.HTTPOnly,
+			Secure:   e.Secure,
+			SameSite: parseSameSite(e.SameSite),
+		}
+		// Session cookies have no expiration date
+		if e.ExpirationDate != nil {
+			sec, frac := math.Modf(*e.ExpirationDate)
+			cookie.Expires = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// isExportedFormat reports whether data is an array of cookies in the
+// browser extension format, recognized by the lowercase name key that
+// http.Cookie never marshals
+func isExportedFormat(data []byte) bool {
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil || len(objects) == 0 {
+		return false
+	}
+	_, ok := objects[0]["name"]
+	return ok
+}
+
+// parseSameSite converts the sameSite value of an exported cookie
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "no_restriction", "none":
+		return http.SameSiteNoneMode
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCookies(t *testing.T) {
+	t.Run("http.Cookie format", func(t *testing.T) {
+		saved := []*http.Cookie{
+			{Name: "auth_token", Value: "token", Domain: ".x.com", Expires: time.Unix(1900000000, 0).UTC()},
+			{Name: "ct0", Value: "csrf", Domain: ".x.com"},
+		}
+		data, err := json.Marshal(saved)
+		assert.NoError(t, err)
+
+		cookies, err := ParseCookies(data)
+		assert.NoError(t, err)
+		assert.Equal(t, saved, cookies)
+	})
+
+	t.Run("browser extension format", func(t *testing.T) {
+		data := []byte(`[
+			{"domain": ".x.com", "expirationDate": 1900000000.5, "hostOnly": false, "httpOnly": true,
+			 "name": "auth_token", "path": "/", "sameSite": "no_restriction", "secure": true,
+			 "session": false, "storeId": "0", "value": "token"},
+			{"domain": ".x.com", "httpOnly": false, "name": "ct0", "path": "/", "sameSite": "lax",
+			 "secure": true, "session": true, "value": "csrf"}
+		]`)
+
+		cookies, err := ParseCookies(data)
+		assert.NoError(t, err)
+		assert.Equal(t, []*http.Cookie{
+			{
+				Name:     "auth_token",
+				Value:    "token",
+				Domain:   ".x.com",
+				Path:     "/",
+				Expires:  time.Unix(1900000000, 500000000).UTC(),
+				HttpOnly: true,
+				Secure:   true,
+				SameSite: http.SameSiteNoneMode,
+			},
+			{
+				Name:     "ct0",
+				Value:    "csrf",
+				Domain:   ".x.com",
+				Path:     "/",
+				Secure:   true,
+				SameSite: http.SameSiteLaxMode,
+			},
+		}, cookies)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseCookies([]byte(`{"auth_token": "token"}`))
+		assert.Error(t, err)
+
+		_, err = ParseCookies([]byte(`[{"name": "", "value": "x"}]`))
+		assert.EqualError(t, err, "exported cookie without a name")
+	})
+}
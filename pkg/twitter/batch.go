@@ -0,0 +1,87 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxBatchTweets caps the number of tweet IDs accepted by one get_tweets call
+const maxBatchTweets = 100
+
+// BatchTweetResult is the outcome of fetching one tweet of a batch. Either
+// Tweet or Error is set.
+type BatchTweetResult struct {
+	Tweet  *SimplifiedTweet `json:"tweet,omitempty"`
+	Source string           `json:"source,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// parseTweetIDs reads a list of tweet IDs from a tool argument, dropping
+// duplicates and empty IDs
+func parseTweetIDs(arg interface{}) ([]string, error) {
+	var raw []string
+	switch v := arg.(type) {
+	case []string:
+		raw = v
+	case []interface{}:
+		for _, item := range v {
+			id, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("tweet_ids must be an array of strings")
+			}
+			raw = append(raw, id)
+		}
+	default:
+		return nil, fmt.Errorf("tweet_ids parameter is required")
+	}
+
+	ids := make([]string, 0, len(raw))
+	seen := make(map[string]bool)
+	for _, id := range raw {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("tweet_ids parameter is required")
+	}
+	if len(ids) > maxBatchTweets {
+		return nil, fmt.Errorf("too many tweet_ids: %d, the maximum is %d", len(ids), maxBatchTweets)
+	}
+	return ids, nil
+}
+
+// fetchTweets gets each of ids, from the tweet store first when useCache is
+// set. A tweet that can't be fetched gets an error entry and doesn't stop
+// the others.
+func (a *Agent) fetchTweets(ctx context.Context, ids []string, useCache bool) map[string]BatchTweetResult {
+	results := make(map[string]BatchTweetResult, len(ids))
+	for _, id := range ids {
+		if useCache && a.tweetStore != nil {
+			tweet, err := a.tweetStore.GetStoredTweet(ctx, id)
+			if err != nil {
+				a.logger.Error("Error reading stored tweet %s: %v", id, err)
+			} else if tweet != nil {
+				simplified := newSimplifiedTweet(tweet)
+				results[id] = BatchTweetResult{Tweet: &simplified, Source: TweetSourceStore}
+				continue
+			}
+		}
+
+		if err := a.limiter.waitForEndpoint(ctx, "get_tweet"); err != nil {
+			results[id] = BatchTweetResult{Error: fmt.Sprintf("rate limit error: %v", err)}
+			continue
+		}
+		tweet, err := a.scraper.GetTweet(ctx, id)
+		a.limiter.doneEndpoint("get_tweet")
+		if err != nil {
+			results[id] = BatchTweetResult{Error: fmt.Sprintf("error getting tweet: %v", err)}
+			continue
+		}
+		simplified := newSimplifiedTweet(tweet)
+		results[id] = BatchTweetResult{Tweet: &simplified, Source: TweetSourceLive}
+	}
+	return results
+}
@@ -0,0 +1,74 @@
+package twitter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTweetIDs(t *testing.T) {
+	ids, err := parseTweetIDs([]interface{}{"1", "2", "1", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+
+	_, err = parseTweetIDs([]interface{}{"1", 2.0})
+	assert.EqualError(t, err, "tweet_ids must be an array of strings")
+
+	_, err = parseTweetIDs(nil)
+	assert.EqualError(t, err, "tweet_ids parameter is required")
+
+	tooMany := make([]string, maxBatchTweets+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("1", i+1)
+	}
+	_, err = parseTweetIDs(tooMany)
+	assert.EqualError(t, err, "too many tweet_ids: 101, the maximum is 100")
+}
+
+func TestGetTweets(t *testing.T) {
+	tweets := map[string]*twitterscraper.Tweet{
+		"1": {ID: "1", Text: "first"},
+		"2": {ID: "2", Text: "second"},
+	}
+	store := mapTweetStore{"3": {ID: "3", Text: "stored"}}
+
+	newAgent := func(username string) (*Agent, *tweetMapScraper) {
+		scraper := &tweetMapScraper{tweets: tweets}
+		agent := newMockAgent()
+		agent.username = username
+		agent.scraper = scraper
+		agent.SetTweetStore(store)
+		return agent, scraper
+	}
+	agentA, scraperA := newAgent("a")
+	agentB, scraperB := newAgent("b")
+	manager := &AgentManager{
+		agents: []*Agent{agentA, agentB},
+		logger: logging.Default(),
+	}
+
+	result, agentUsernames, err := manager.GetTweets(context.Background(), []string{"1", "2", "3", "4"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "b,a", agentUsernames)
+
+	results := result.(map[string]BatchTweetResult)
+	assert.Len(t, results, 4)
+	assert.Equal(t, "first", results["1"].Tweet.Text)
+	assert.Equal(t, TweetSourceLive, results["1"].Source)
+	assert.Equal(t, "second", results["2"].Tweet.Text)
+	assert.Equal(t, "stored", results["3"].Tweet.Text)
+	assert.Equal(t, TweetSourceStore, results["3"].Source)
+	assert.Nil(t, results["4"].Tweet)
+	assert.Equal(t, "error getting tweet: tweet 4 not found", results["4"].Error)
+
+	// The IDs not served from the store were spread over both agents
+	assert.Equal(t, []string{"2", "4"}, scraperA.fetched)
+	assert.Equal(t, []string{"1"}, scraperB.fetched)
+
+	_, _, err = manager.GetTweets(context.Background(), nil, false)
+	assert.EqualError(t, err, "tweet_ids parameter is required")
+}
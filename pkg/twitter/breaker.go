@@ -0,0 +1,388 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// States of an agent's circuit breaker
+const (
+	CircuitClosed   = "closed"    // Calls go through
+	CircuitOpen     = "open"      // Calls fail immediately until the cooldown is over
+	CircuitHalfOpen = "half_open" // One trial call goes through to test recovery
+)
+
+// ErrCircuitOpen is returned for scraper calls refused by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitBreakerConfig controls when an agent stops calling Twitter. Once
+// Twitter fails FailureThreshold calls of an agent in a row, e.g. because
+// its IP got blocked, the agent's calls fail immediately for Cooldown
+// instead of worsening the block. After that a single trial call is let
+// through: the circuit closes again if it succeeds and stays open for
+// another Cooldown if it fails.
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // Consecutive failed calls that open the circuit; 0 disables the breaker
+	Cooldown         time.Duration // How long an open circuit refuses calls
+}
+
+// DefaultCircuitBreakerConfig is used by the AgentManager unless WithCircuitBreaker is given
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureThreshold: 5,
+	Cooldown:         time.Minute,
+}
+
+// CircuitStatus is the state of an agent's circuit breaker
+type CircuitStatus struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	// RetryAt is when an open circuit lets a trial call through
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// circuitBreaker tracks the consecutive failures of an agent's calls
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   CircuitBreakerConfig
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // A half-open trial call is in flight
+	now      func() time.Time
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{config: config, state: CircuitClosed, now: time.Now}
+}
+
+// allow reports whether a call may go through. An open circuit whose
+// cooldown is over turns half-open and lets one trial call through.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen {
+		retryAt := b.openedAt.Add(b.config.Cooldown)
+		if b.now().Before(retryAt) {
+			return fmt.Errorf("%w, retry after %s", ErrCircuitOpen, retryAt.Format(time.RFC3339))
+		}
+		b.state = CircuitHalfOpen
+	}
+	if b.state == CircuitHalfOpen {
+		if b.probing {
+			return fmt.Errorf("%w, a trial call is in progress", ErrCircuitOpen)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record counts the outcome of a call let through by allow
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	probe := b.state == CircuitHalfOpen
+	b.probing = false
+	switch {
+	case !isCircuitFailure(err):
+		// A call that reached Twitter, or a cancelled trial call, says
+		// nothing about a block when it didn't succeed
+		if err == nil || toolErrorKind(err.Error()) == ErrNotFound {
+			b.state = CircuitClosed
+			b.failures = 0
+		}
+	case probe:
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	default:
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.state = CircuitOpen
+			b.openedAt = b.now()
+		}
+	}
+}
+
+// isOpen reports whether calls would currently be refused, to pass the agent
+// over when selecting one
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return (b.state == CircuitOpen && b.now().Before(b.openedAt.Add(b.config.Cooldown))) ||
+		(b.state == CircuitHalfOpen && b.probing)
+}
+
+func (b *circuitBreaker) status() CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := CircuitStatus{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state == CircuitOpen {
+		retryAt := b.openedAt.Add(b.config.Cooldown)
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// isCircuitFailure reports whether err counts towards opening the circuit.
+// Missing users and tweets are answers from a working Twitter, and
+// cancellations aren't answers at all.
+func isCircuitFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	return toolErrorKind(err.Error()) != ErrNotFound
+}
+
+// breakerScraper decorates a Scraper, refusing calls to Twitter while the
+// agent's circuit breaker is open. It sits below the retryScraper so every
+// attempt is counted and an open circuit ends the retries.
+type breakerScraper struct {
+	Scraper
+	breaker *circuitBreaker
+}
+
+// call runs fn if the breaker allows it and records its outcome
+func (s *breakerScraper) call(fn func() error) error {
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	s.breaker.record(err)
+	return err
+}
+
+// stream forwards the results of a streaming call and records its outcome
+// once the stream ends: its first error, or success if tweets came through
+// before the consumer stopped reading. A consumer that stops reading has ctx
+// cancelled; the rest of the stream is then drained.
+func (s *breakerScraper) stream(ctx context.Context, fn func() <-chan *twitterscraper.TweetResult) <-chan *twitterscraper.TweetResult {
+	out := make(chan *twitterscraper.TweetResult)
+	if err := s.breaker.allow(); err != nil {
+		go func() {
+			defer close(out)
+			select {
+			case out <- &twitterscraper.TweetResult{Error: err}:
+			case <-ctx.Done():
+			}
+		}()
+		return out
+	}
+
+	in := fn()
+	go func() {
+		defer close(out)
+		var outcome error
+		received := false
+		abandoned := false
+		for result := range in {
+			switch {
+			case result.Error == nil:
+				received = true
+			case outcome == nil && (isCircuitFailure(result.Error) || !received):
+				outcome = result.Error
+			}
+			if abandoned {
+				continue
+			}
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				abandoned = true
+			}
+		}
+		if outcome == nil && abandoned && !received {
+			outcome = ctx.Err()
+		}
+		s.breaker.record(outcome)
+	}()
+	return out
+}
+
+func (s *breakerScraper) GetProfile(ctx context.Context, username string) (profile *twitterscraper.Profile, err error) {
+	err = s.call(func() error {
+		profile, err = s.Scraper.GetProfile(ctx, username)
+		return err
+	})
+	return profile, err
+}
+
+func (s *breakerScraper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.stream(ctx, func() <-chan *twitterscraper.TweetResult {
+		return s.Scraper.GetTweets(ctx, username, maxTweetsNb)
+	})
+}
+
+func (s *breakerScraper) GetMediaTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.stream(ctx, func() <-chan *twitterscraper.TweetResult {
+		return s.Scraper.GetMediaTweets(ctx, username, maxTweetsNb)
+	})
+}
+
+func (s *breakerScraper) GetTweet(ctx context.Context, id string) (tweet *twitterscraper.Tweet, err error) {
+	err = s.call(func() error {
+		tweet, err = s.Scraper.GetTweet(ctx, id)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *breakerScraper) TweetVisibility(ctx context.Context, id string) (visibility string, err error) {
+	err = s.call(func() error {
+		visibility, err = s.Scraper.TweetVisibility(ctx, id)
+		return err
+	})
+	return visibility, err
+}
+
+func (s *breakerScraper) GetTweetReplies(id string, cursor string) (tweets []*twitterscraper.Tweet, cursors []*twitterscraper.ThreadCursor, err error) {
+	err = s.call(func() error {
+		tweets, cursors, err = s.Scraper.GetTweetReplies(id, cursor)
+		return err
+	})
+	return tweets, cursors, err
+}
+
+func (s *breakerScraper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.stream(ctx, func() <-chan *twitterscraper.TweetResult {
+		return s.Scraper.SearchTweets(ctx, query, maxTweetsNb)
+	})
+}
+
+func (s *breakerScraper) Tweet(ctx context.Context, text string) (tweet *twitterscraper.Tweet, err error) {
+	err = s.call(func() error {
+		tweet, err = s.Scraper.Tweet(ctx, text)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *breakerScraper) ReplyTweet(ctx context.Context, text string, inReplyToID string) (tweet *twitterscraper.Tweet, err error) {
+	err = s.call(func() error {
+		tweet, err = s.Scraper.ReplyTweet(ctx, text, inReplyToID)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *breakerScraper) QuoteTweet(ctx context.Context, text string, quotedID string, mediaIDs []string) (tweet *twitterscraper.Tweet, err error) {
+	err = s.call(func() error {
+		tweet, err = s.Scraper.QuoteTweet(ctx, text, quotedID, mediaIDs)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *breakerScraper) SetMediaAltText(ctx context.Context, mediaID string, altText string) error {
+	return s.call(func() error { return s.Scraper.SetMediaAltText(ctx, mediaID, altText) })
+}
+
+func (s *breakerScraper) TweetWithPoll(ctx context.Context, text string, poll Poll) (tweet *twitterscraper.Tweet, err error) {
+	err = s.call(func() error {
+		tweet, err = s.Scraper.TweetWithPoll(ctx, text, poll)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *breakerScraper) LikeTweet(ctx context.Context, id string) error {
+	return s.call(func() error { return s.Scraper.LikeTweet(ctx, id) })
+}
+
+func (s *breakerScraper) UnlikeTweet(ctx context.Context, id string) error {
+	return s.call(func() error { return s.Scraper.UnlikeTweet(ctx, id) })
+}
+
+func (s *breakerScraper) CreateRetweet(ctx context.Context, id string) error {
+	return s.call(func() error { return s.Scraper.CreateRetweet(ctx, id) })
+}
+
+func (s *breakerScraper) CreateScheduledTweet(ctx context.Context, text string, scheduleTime string) error {
+	return s.call(func() error { return s.Scraper.CreateScheduledTweet(ctx, text, scheduleTime) })
+}
+
+func (s *breakerScraper) Follow(ctx context.Context, id string) error {
+	return s.call(func() error { return s.Scraper.Follow(ctx, id) })
+}
+
+func (s *breakerScraper) Unfollow(ctx context.Context, id string) error {
+	return s.call(func() error { return s.Scraper.Unfollow(ctx, id) })
+}
+
+func (s *breakerScraper) GetRelationship(ctx context.Context, sourceUserID, targetUserID string) (following, followedBy, blocked, muted bool, err error) {
+	err = s.call(func() error {
+		following, followedBy, blocked, muted, err = s.Scraper.GetRelationship(ctx, sourceUserID, targetUserID)
+		return err
+	})
+	return following, followedBy, blocked, muted, err
+}
+
+func (s *breakerScraper) FetchSearchTweets(ctx context.Context, query string, maxTweetsNbr int, cursor string) (tweets []*twitterscraper.Tweet, next string, err error) {
+	err = s.call(func() error {
+		tweets, next, err = s.Scraper.FetchSearchTweets(ctx, query, maxTweetsNbr, cursor)
+		return err
+	})
+	return tweets, next, err
+}
+
+func (s *breakerScraper) FetchTweetsAndReplies(ctx context.Context, username string, maxTweetsNbr int, cursor string) (tweets []*twitterscraper.Tweet, next string, err error) {
+	err = s.call(func() error {
+		tweets, next, err = s.Scraper.FetchTweetsAndReplies(ctx, username, maxTweetsNbr, cursor)
+		return err
+	})
+	return tweets, next, err
+}
+
+func (s *breakerScraper) FetchFollowers(ctx context.Context, username string, maxUsersNbr int, cursor string) (profiles []*twitterscraper.Profile, next string, err error) {
+	err = s.call(func() error {
+		profiles, next, err = s.Scraper.FetchFollowers(ctx, username, maxUsersNbr, cursor)
+		return err
+	})
+	return profiles, next, err
+}
+
+func (s *breakerScraper) FetchFollowing(ctx context.Context, username string, maxUsersNbr int, cursor string) (profiles []*twitterscraper.Profile, next string, err error) {
+	err = s.call(func() error {
+		profiles, next, err = s.Scraper.FetchFollowing(ctx, username, maxUsersNbr, cursor)
+		return err
+	})
+	return profiles, next, err
+}
+
+func (s *breakerScraper) GetNotifications(ctx context.Context, cursor string) (notifications []Notification, next string, err error) {
+	err = s.call(func() error {
+		notifications, next, err = s.Scraper.GetNotifications(ctx, cursor)
+		return err
+	})
+	return notifications, next, err
+}
+
+func (s *breakerScraper) GetTrends(ctx context.Context) (trends []string, err error) {
+	err = s.call(func() error {
+		trends, err = s.Scraper.GetTrends(ctx)
+		return err
+	})
+	return trends, err
+}
+
+func (s *breakerScraper) GetListTweets(ctx context.Context, listID string, maxTweets int) (tweets []*twitterscraper.Tweet, err error) {
+	err = s.call(func() error {
+		tweets, err = s.Scraper.GetListTweets(ctx, listID, maxTweets)
+		return err
+	})
+	return tweets, err
+}
+
+func (s *breakerScraper) GetListMembers(ctx context.Context, listID string, cursor string) (profiles []*twitterscraper.Profile, next string, err error) {
+	err = s.call(func() error {
+		profiles, next, err = s.Scraper.GetListMembers(ctx, listID, cursor)
+		return err
+	})
+	return profiles, next, err
+}
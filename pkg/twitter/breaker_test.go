@@ -0,0 +1,155 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingScraper fails profile lookups and timelines with err, when set
+type failingScraper struct {
+	mockScraper
+	err   error
+	calls int
+}
+
+func (s *failingScraper) GetProfile(ctx context.Context, username string) (*twitterscraper.Profile, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &twitterscraper.Profile{Username: username}, nil
+}
+
+func (s *failingScraper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	s.calls++
+	ch := make(chan *twitterscraper.TweetResult, 1)
+	if s.err != nil {
+		ch <- &twitterscraper.TweetResult{Error: s.err}
+	}
+	close(ch)
+	return ch
+}
+
+func newBreakerAgent(username string, scraper Scraper) (*Agent, *time.Time) {
+	agent := newMockAgent()
+	agent.username = username
+	agent.scraper = scraper
+	agent.SetCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	agent.breaker.now = func() time.Time { return now }
+	return agent, &now
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	scraper := &failingScraper{err: errors.New("response status 403 Forbidden: blocked")}
+	agent, now := newBreakerAgent("alice", scraper)
+	ctx := context.Background()
+
+	// Closed: failures go through to Twitter until the threshold
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, CircuitClosed, agent.CircuitStatus().State)
+		_, err := agent.scraper.GetProfile(ctx, "bob")
+		assert.ErrorContains(t, err, "403")
+	}
+	assert.Equal(t, 3, scraper.calls)
+
+	// Open: calls fail without reaching Twitter
+	status := agent.CircuitStatus()
+	assert.Equal(t, CircuitOpen, status.State)
+	assert.Equal(t, 3, status.ConsecutiveFailures)
+	assert.Equal(t, now.Add(time.Minute), *status.RetryAt)
+	_, err := agent.scraper.GetProfile(ctx, "bob")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	result := <-agent.scraper.GetTweets(ctx, "bob", 10)
+	assert.ErrorIs(t, result.Error, ErrCircuitOpen)
+	assert.Equal(t, 3, scraper.calls)
+
+	// Half-open: a failed trial call opens the circuit for another cooldown
+	*now = now.Add(time.Minute)
+	_, err = agent.scraper.GetProfile(ctx, "bob")
+	assert.ErrorContains(t, err, "403")
+	assert.Equal(t, 4, scraper.calls)
+	assert.Equal(t, CircuitOpen, agent.CircuitStatus().State)
+	_, err = agent.scraper.GetProfile(ctx, "bob")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// Half-open: a successful trial call closes the circuit
+	*now = now.Add(time.Minute)
+	scraper.err = nil
+	assert.NoError(t, agent.breaker.allow())
+	assert.Equal(t, CircuitHalfOpen, agent.CircuitStatus().State)
+	_, err = agent.scraper.GetProfile(ctx, "bob")
+	assert.ErrorIs(t, err, ErrCircuitOpen, "only one trial call goes through at a time")
+	agent.breaker.record(nil)
+
+	assert.Equal(t, &CircuitStatus{State: CircuitClosed}, agent.CircuitStatus())
+	profile, err := agent.scraper.GetProfile(ctx, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", profile.Username)
+}
+
+func TestCircuitBreakerIgnoredErrors(t *testing.T) {
+	scraper := &failingScraper{err: errors.New("response status 500 Internal Server Error")}
+	agent, _ := newBreakerAgent("alice", scraper)
+	ctx := context.Background()
+
+	_, _ = agent.scraper.GetProfile(ctx, "bob")
+	_, _ = agent.scraper.GetProfile(ctx, "bob")
+	assert.Equal(t, 2, agent.CircuitStatus().ConsecutiveFailures)
+
+	// Cancellations don't count, a missing user shows Twitter is answering
+	scraper.err = context.Canceled
+	_, _ = agent.scraper.GetProfile(ctx, "bob")
+	assert.Equal(t, 2, agent.CircuitStatus().ConsecutiveFailures)
+	scraper.err = errors.New("user not found")
+	_, _ = agent.scraper.GetProfile(ctx, "bob")
+	assert.Equal(t, &CircuitStatus{State: CircuitClosed}, agent.CircuitStatus())
+}
+
+func TestCircuitBreakerWithRetries(t *testing.T) {
+	scraper := &failingScraper{err: errors.New("response status 503 Service Unavailable")}
+	agent, _ := newBreakerAgent("alice", scraper)
+	agent.SetRetryConfig(RetryConfig{MaxAttempts: 5})
+
+	// Every attempt counts and the open circuit ends the retries
+	_, err := agent.scraper.GetProfile(context.Background(), "bob")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, scraper.calls)
+	assert.Equal(t, CircuitOpen, agent.CircuitStatus().State)
+
+	// Replacing the retrying keeps the breaker
+	agent.SetRetryConfig(RetryConfig{MaxAttempts: 1})
+	_, err = agent.scraper.GetProfile(context.Background(), "bob")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestSelectionSkipsOpenCircuits(t *testing.T) {
+	alice, _ := newBreakerAgent("alice", &failingScraper{err: errors.New("response status 403 Forbidden")})
+	bob, _ := newBreakerAgent("bob", &failingScraper{})
+	manager := &AgentManager{agents: []*Agent{bob, alice}, logger: logging.Default()}
+	for i := 0; i < 3; i++ {
+		_, _ = alice.scraper.GetProfile(context.Background(), "carol")
+	}
+
+	agent, selection := manager.getNextAgent(context.Background())
+	assert.Equal(t, "bob", agent.username)
+	assert.Equal(t, []string{"alice"}, selection.Skipped)
+	agent, selection = manager.getNextAgent(context.Background())
+	assert.Equal(t, "bob", agent.username)
+	assert.Empty(t, selection.Skipped)
+
+	// With every circuit open the agent next in rotation is used
+	bob.scraper.(*breakerScraper).Scraper.(*failingScraper).err = errors.New("response status 403 Forbidden")
+	for i := 0; i < 3; i++ {
+		_, _ = bob.scraper.GetProfile(context.Background(), "carol")
+	}
+	agent, selection = manager.getNextAgent(context.Background())
+	assert.Equal(t, "alice", agent.username)
+	assert.Empty(t, selection.Skipped)
+}
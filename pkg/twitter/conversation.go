@@ -0,0 +1,117 @@
+package twitter
+
+import (
+	"context"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+const (
+	// defaultContextDepth is the number of parent tweets followed when none is requested
+	defaultContextDepth = 10
+	// maxContextDepth caps how many parent tweets are followed
+	maxContextDepth = 50
+
+	// TweetSourceStore marks a tweet read from the tweet store
+	TweetSourceStore = "db"
+	// TweetSourceLive marks a tweet fetched from Twitter
+	TweetSourceLive = "live"
+)
+
+// TweetStore looks up tweets that are already stored locally, so they
+// don't have to be fetched from Twitter
+type TweetStore interface {
+	// GetStoredTweet returns the stored tweet with the given ID, or nil when it isn't stored
+	GetStoredTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error)
+}
+
+// ConversationTweet is one tweet of a conversation's parent chain
+type ConversationTweet struct {
+	SimplifiedTweet
+	InReplyToStatusID string `json:"in_reply_to_status_id,omitempty"`
+	Source            string `json:"source"`
+}
+
+// ConversationContext is the chain of tweets leading to a focal tweet,
+// ordered from the root of the conversation to the focal tweet
+type ConversationContext struct {
+	Tweets []ConversationTweet `json:"tweets"`
+	// Truncated is set when the chain continues above the first tweet,
+	// because the depth cap was reached or a parent couldn't be fetched
+	Truncated bool `json:"truncated"`
+	// MissingParentID is the parent that couldn't be fetched, e.g. a deleted tweet
+	MissingParentID string `json:"missing_parent_id,omitempty"`
+}
+
+// buildConversationContext walks up the in_reply_to links from tweetID,
+// following at most maxDepth parents. Parents held by the agent's tweet store
+// are used without calling Twitter.
+func (a *Agent) buildConversationContext(ctx context.Context, tweetID string, maxDepth int) (*ConversationContext, error) {
+	focal, source, err := a.lookupTweet(ctx, tweetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collected from the focal tweet upwards and reversed at the end
+	chain := []ConversationTweet{newConversationTweet(focal, source)}
+	seen := map[string]bool{focal.ID: true}
+	result := &ConversationContext{}
+
+	parentID := focal.InReplyToStatusID
+	for parentID != "" && !seen[parentID] {
+		if len(chain) > maxDepth {
+			result.Truncated = true
+			break
+		}
+
+		parent, source, err := a.lookupTweet(ctx, parentID)
+		if err != nil {
+			a.logger.Debug("Stopping conversation context of %s at parent %s: %v", tweetID, parentID, err)
+			result.Truncated = true
+			result.MissingParentID = parentID
+			break
+		}
+
+		chain = append(chain, newConversationTweet(parent, source))
+		seen[parent.ID] = true
+		parentID = parent.InReplyToStatusID
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	result.Tweets = chain
+	return result, nil
+}
+
+// lookupTweet returns the tweet with the given ID from the tweet store when
+// it holds it, or else from Twitter, along with where it came from
+func (a *Agent) lookupTweet(ctx context.Context, id string) (*twitterscraper.Tweet, string, error) {
+	if a.tweetStore != nil {
+		tweet, err := a.tweetStore.GetStoredTweet(ctx, id)
+		if err != nil {
+			a.logger.Error("Error reading stored tweet %s: %v", id, err)
+		} else if tweet != nil {
+			return tweet, TweetSourceStore, nil
+		}
+	}
+
+	if err := a.limiter.waitForEndpoint(ctx, "get_tweet"); err != nil {
+		return nil, "", err
+	}
+	tweet, err := a.scraper.GetTweet(ctx, id)
+	a.limiter.doneEndpoint("get_tweet")
+	if err != nil {
+		return nil, "", err
+	}
+	return tweet, TweetSourceLive, nil
+}
+
+// newConversationTweet converts a scraper tweet into a ConversationTweet
+func newConversationTweet(tweet *twitterscraper.Tweet, source string) ConversationTweet {
+	return ConversationTweet{
+		SimplifiedTweet:   newSimplifiedTweet(tweet),
+		InReplyToStatusID: tweet.InReplyToStatusID,
+		Source:            source,
+	}
+}
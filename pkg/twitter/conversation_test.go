@@ -0,0 +1,95 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// tweetMapScraper serves the tweets it holds by ID and records which were fetched
+type tweetMapScraper struct {
+	mockScraper
+	tweets  map[string]*twitterscraper.Tweet
+	fetched []string
+}
+
+func (s *tweetMapScraper) GetTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
+	s.fetched = append(s.fetched, id)
+	tweet, ok := s.tweets[id]
+	if !ok {
+		return nil, fmt.Errorf("tweet %s not found", id)
+	}
+	return tweet, nil
+}
+
+// mapTweetStore is a TweetStore backed by a map
+type mapTweetStore map[string]*twitterscraper.Tweet
+
+func (s mapTweetStore) GetStoredTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
+	return s[id], nil
+}
+
+func TestBuildConversationContext(t *testing.T) {
+	tweets := map[string]*twitterscraper.Tweet{
+		"1": {ID: "1", Text: "root"},
+		"2": {ID: "2", Text: "reply", InReplyToStatusID: "1"},
+		"3": {ID: "3", Text: "focal", InReplyToStatusID: "2"},
+	}
+
+	newAgent := func(scraper *tweetMapScraper, store TweetStore) *Agent {
+		agent := newMockAgent()
+		agent.scraper = scraper
+		agent.SetTweetStore(store)
+		return agent
+	}
+
+	ids := func(conversation *ConversationContext) ([]string, []string) {
+		var ids, sources []string
+		for _, tweet := range conversation.Tweets {
+			ids = append(ids, tweet.ID)
+			sources = append(sources, tweet.Source)
+		}
+		return ids, sources
+	}
+
+	t.Run("orders from root and prefers stored tweets", func(t *testing.T) {
+		scraper := &tweetMapScraper{tweets: tweets}
+		conversation, err := newAgent(scraper, mapTweetStore{"2": tweets["2"]}).buildConversationContext(context.Background(), "3", defaultContextDepth)
+		assert.NoError(t, err)
+		gotIDs, gotSources := ids(conversation)
+		assert.Equal(t, []string{"1", "2", "3"}, gotIDs)
+		assert.Equal(t, []string{TweetSourceLive, TweetSourceStore, TweetSourceLive}, gotSources)
+		assert.Equal(t, []string{"3", "1"}, scraper.fetched)
+		assert.False(t, conversation.Truncated)
+	})
+
+	t.Run("stops at max depth", func(t *testing.T) {
+		store := mapTweetStore{"2": tweets["2"], "3": tweets["3"]}
+		conversation, err := newAgent(&tweetMapScraper{tweets: tweets}, store).buildConversationContext(context.Background(), "3", 1)
+		assert.NoError(t, err)
+		gotIDs, _ := ids(conversation)
+		assert.Equal(t, []string{"2", "3"}, gotIDs)
+		assert.True(t, conversation.Truncated)
+		assert.Empty(t, conversation.MissingParentID)
+	})
+
+	t.Run("reports a missing parent", func(t *testing.T) {
+		store := mapTweetStore{"3": tweets["3"]}
+		scraper := &tweetMapScraper{tweets: map[string]*twitterscraper.Tweet{}}
+		conversation, err := newAgent(scraper, store).buildConversationContext(context.Background(), "3", defaultContextDepth)
+		assert.NoError(t, err)
+		gotIDs, _ := ids(conversation)
+		assert.Equal(t, []string{"3"}, gotIDs)
+		assert.True(t, conversation.Truncated)
+		assert.Equal(t, "2", conversation.MissingParentID)
+	})
+
+	t.Run("fails when the focal tweet can't be fetched", func(t *testing.T) {
+		scraper := &tweetMapScraper{tweets: map[string]*twitterscraper.Tweet{}}
+		_, err := newAgent(scraper, nil).buildConversationContext(context.Background(), "3", defaultContextDepth)
+		assert.EqualError(t, err, "tweet 3 not found")
+	})
+}
@@ -0,0 +1,136 @@
+package twitter
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// authCookieNames are the cookies a logged in session can't work without:
+// auth_token is the session and ct0 its CSRF token
+var authCookieNames = []string{"auth_token", "ct0"}
+
+// cookieExpiry returns when cookie, received at now, expires, or the zero
+// time for a session cookie. MaxAge takes precedence over Expires, as in
+// browsers; a negative MaxAge means the cookie is already expired.
+func cookieExpiry(cookie *http.Cookie, now time.Time) time.Time {
+	switch {
+	case cookie.MaxAge < 0:
+		return time.Unix(1, 0)
+	case cookie.MaxAge > 0:
+		return now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+	return cookie.Expires
+}
+
+func isAuthCookie(name string) bool {
+	for _, authName := range authCookieNames {
+		if name == authName {
+			return true
+		}
+	}
+	return false
+}
+
+// expiryJar passes cookies on to the scraper's cookie jar, reporting them to
+// note first. The jar keeps the expiry of the cookies to itself.
+type expiryJar struct {
+	http.CookieJar
+	note func(cookies []*http.Cookie)
+}
+
+func (j *expiryJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.note(cookies)
+	j.CookieJar.SetCookies(u, cookies)
+}
+
+// scraperClient returns the HTTP client of scraper, or nil if it can't be
+// reached. twitter-scraper neither exposes it nor has a hook for responses,
+// so the unexported field is read through reflection.
+func scraperClient(scraper *twitterscraper.Scraper) *http.Client {
+	field := reflect.ValueOf(scraper).Elem().FieldByName("client")
+	if !field.IsValid() || field.Type() != reflect.TypeOf((*http.Client)(nil)) || field.IsNil() {
+		return nil
+	}
+	return (*http.Client)(field.UnsafePointer())
+}
+
+// watchCookieJar notes the auth cookies Twitter sets, e.g. at login or when
+// it rotates them, by wrapping the scraper's cookie jar. Without the jar only
+// the expiry of cookies set with SetCookies is known.
+func (s *scraperWrapper) watchCookieJar() {
+	client := scraperClient(s.Scraper)
+	if client == nil || client.Jar == nil {
+		return
+	}
+	client.Jar = &expiryJar{CookieJar: client.Jar, note: s.noteAuthCookies}
+}
+
+// noteAuthCookies records when the auth cookies among cookies expire.
+// Cookies without an expiry replace what was known about them.
+func (s *scraperWrapper) noteAuthCookies(cookies []*http.Cookie) {
+	now := time.Now()
+	s.cookieMutex.Lock()
+	defer s.cookieMutex.Unlock()
+	for _, cookie := range cookies {
+		if !isAuthCookie(cookie.Name) {
+			continue
+		}
+		if expiry := cookieExpiry(cookie, now); !expiry.IsZero() {
+			if s.authCookieExpiries == nil {
+				s.authCookieExpiries = make(map[string]time.Time)
+			}
+			s.authCookieExpiries[cookie.Name] = expiry
+		} else {
+			delete(s.authCookieExpiries, cookie.Name)
+		}
+	}
+}
+
+// SetCookies sets the session cookies, noting when its auth cookies expire
+func (s *scraperWrapper) SetCookies(cookies []*http.Cookie) {
+	s.noteAuthCookies(cookies)
+	s.Scraper.SetCookies(cookies)
+}
+
+// GetCookies returns the session cookies. The jar only reports names and
+// values, so the auth cookies get back the expiry noted for them, as an
+// absolute time, and keep it when they are saved and loaded again.
+func (s *scraperWrapper) GetCookies() []*http.Cookie {
+	cookies := s.Scraper.GetCookies()
+	s.cookieMutex.Lock()
+	defer s.cookieMutex.Unlock()
+	for _, cookie := range cookies {
+		if expiry, ok := s.authCookieExpiries[cookie.Name]; ok {
+			cookie.Expires = expiry
+		}
+	}
+	return cookies
+}
+
+// Login logs in with credentials, replacing any session set with SetCookies.
+// The cookies Twitter sets while logging in are noted by the cookie jar.
+func (s *scraperWrapper) Login(credentials ...string) error {
+	defer scraperCalls.start()()
+	s.cookieMutex.Lock()
+	s.authCookieExpiries = nil
+	s.cookieMutex.Unlock()
+	return s.Scraper.Login(credentials...)
+}
+
+// authCookiesExpired reports whether any of the auth cookies has expired, in
+// which case the session is gone without asking Twitter
+func (s *scraperWrapper) authCookiesExpired() bool {
+	s.cookieMutex.Lock()
+	defer s.cookieMutex.Unlock()
+	now := time.Now()
+	for _, expiry := range s.authCookieExpiries {
+		if !now.Before(expiry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package twitter
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/twitter/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieExpiry(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(24 * time.Hour)
+
+	assert.Equal(t, expires, cookieExpiry(&http.Cookie{Name: "ct0", Expires: expires}, now))
+	// MaxAge is counted from when the cookie was received and wins over Expires
+	assert.Equal(t, now.Add(time.Hour), cookieExpiry(&http.Cookie{Name: "ct0", Expires: expires, MaxAge: 3600}, now))
+	// A negative MaxAge deletes the cookie
+	assert.True(t, cookieExpiry(&http.Cookie{Name: "ct0", MaxAge: -1}, now).Before(now))
+	// Session cookies have no expiry
+	assert.True(t, cookieExpiry(&http.Cookie{Name: "ct0"}, now).IsZero())
+}
+
+func TestIsLoggedInExpiredCookies(t *testing.T) {
+	scraper := newScraperWrapper()
+	scraper.SetCookies([]*http.Cookie{
+		{Name: "guest_id", Value: "guest", Expires: time.Now().Add(-time.Hour)},
+		{Name: "auth_token", Value: "token", Expires: time.Now().Add(-time.Minute)},
+		{Name: "ct0", Value: "csrf", Expires: time.Now().Add(time.Hour)},
+	})
+
+	// Reported logged out without asking Twitter
+	assert.True(t, scraper.authCookiesExpired())
+	assert.False(t, scraper.IsLoggedIn())
+
+	scraper.SetCookies([]*http.Cookie{
+		{Name: "auth_token", Value: "token", Expires: time.Now().Add(time.Hour)},
+		{Name: "ct0", Value: "csrf", Expires: time.Now().Add(time.Hour)},
+	})
+	assert.False(t, scraper.authCookiesExpired())
+}
+
+// roundTripFunc answers the requests of an http.Client without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSavedCookiesKeepExpiry(t *testing.T) {
+	// Twitter sets the auth cookies, e.g. at login, with an expiry the cookie
+	// jar doesn't report back
+	scraper := newScraperWrapper()
+	client := scraperClient(scraper.Scraper)
+	if !assert.NotNil(t, client) {
+		return
+	}
+	client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Add("Set-Cookie", "auth_token=token; Domain=.twitter.com; Path=/; Max-Age=3600")
+		header.Add("Set-Cookie", "ct0=csrf; Domain=.twitter.com; Path=/; Max-Age=7200")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("{}")), Request: r}, nil
+	})
+	resp, err := client.Get("https://twitter.com/i/api/1.1/onboarding/task.json")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+
+	cookies := scraper.GetCookies()
+	expiry := map[string]time.Time{}
+	for _, cookie := range cookies {
+		expiry[cookie.Name] = cookie.Expires
+	}
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiry["auth_token"], time.Minute)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), expiry["ct0"], time.Minute)
+
+	// The expiry is saved with the cookies and noted again when they are loaded
+	accounts := auth.NewAccountManager(t.TempDir())
+	assert.NoError(t, accounts.SaveCookies("alice", cookies))
+	loaded, err := accounts.LoadCookies("alice")
+	assert.NoError(t, err)
+	restarted := newScraperWrapper()
+	restarted.SetCookies(loaded)
+	assert.False(t, restarted.authCookiesExpired())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), restarted.authCookieExpiries["auth_token"], time.Minute)
+
+	// Loaded after the session expired, it is reported logged out
+	for _, cookie := range loaded {
+		cookie.Expires = cookie.Expires.Add(-3 * time.Hour)
+	}
+	restarted.SetCookies(loaded)
+	assert.True(t, restarted.authCookiesExpired())
+}
@@ -0,0 +1,86 @@
+package twitter
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCookieSaveInterval is how often StartCookieSaving checks for rotated cookies by default
+const DefaultCookieSaveInterval = 10 * time.Minute
+
+// cookieFingerprint identifies a set of session cookies by their names and
+// values, to tell whether the scraper rotated any of them
+func cookieFingerprint(cookies []*http.Cookie) string {
+	pairs := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
+		pairs = append(pairs, cookie.Name+"="+cookie.Value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "; ")
+}
+
+// markCookiesSaved records cookies as the ones on disk for username
+func (am *AgentManager) markCookiesSaved(username string, cookies []*http.Cookie) {
+	am.cookieMutex.Lock()
+	defer am.cookieMutex.Unlock()
+	if am.savedCookies == nil {
+		am.savedCookies = make(map[string]string)
+	}
+	am.savedCookies[username] = cookieFingerprint(cookies)
+}
+
+// StartCookieSaving saves the cookies of every agent every interval when
+// they changed since they were last saved. twitter-scraper rotates the ct0
+// and auth tokens during a session, and without re-saving a restart would
+// load the stale cookies from login. Agents whose cookies didn't change
+// aren't written, so the disk is only touched after a rotation.
+func (am *AgentManager) StartCookieSaving(ctx context.Context, interval time.Duration) {
+	am.logger.Info("Starting cookie saving goroutine, saving rotated cookies every %s", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				am.logger.Info("Stopping cookie saving due to context cancellation")
+				return
+			case <-ticker.C:
+				am.saveChangedCookies()
+			}
+		}
+	}()
+}
+
+// saveChangedCookies saves the cookies of the agents whose cookies changed
+// since they were last saved, returning how many were saved
+func (am *AgentManager) saveChangedCookies() int {
+	am.mutex.RLock()
+	agents := make([]*Agent, len(am.agents))
+	copy(agents, am.agents)
+	am.mutex.RUnlock()
+
+	saved := 0
+	for _, agent := range agents {
+		cookies := agent.GetCookies()
+		am.cookieMutex.Lock()
+		unchanged := am.savedCookies[agent.username] == cookieFingerprint(cookies)
+		am.cookieMutex.Unlock()
+		// An agent without cookies has no session worth keeping
+		if unchanged || len(cookies) == 0 {
+			continue
+		}
+
+		if err := am.authManager.SaveCookies(agent.username, cookies); err != nil {
+			am.logger.Error("Failed to save rotated cookies for account %s: %v", agent.username, err)
+			continue
+		}
+		am.markCookiesSaved(agent.username, cookies)
+		am.logger.Debug("Saved rotated cookies for account: %s", agent.username)
+		saved++
+	}
+	return saved
+}
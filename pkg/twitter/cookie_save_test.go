@@ -0,0 +1,54 @@
+package twitter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// cookieScraper holds a session whose cookies the test rotates
+type cookieScraper struct {
+	mockScraper
+	cookies []*http.Cookie
+}
+
+func (s *cookieScraper) GetCookies() []*http.Cookie {
+	return s.cookies
+}
+
+func TestSaveChangedCookies(t *testing.T) {
+	scraper := &cookieScraper{cookies: []*http.Cookie{{Name: "auth_token", Value: "a"}, {Name: "ct0", Value: "1"}}}
+	agent := newMockAgent()
+	agent.username = "alice"
+	agent.scraper = scraper
+	idle := newMockAgent()
+	idle.username = "bob"
+	idle.scraper = &cookieScraper{}
+	manager := &AgentManager{
+		agents:      []*Agent{agent, idle},
+		authManager: auth.NewAccountManager(t.TempDir()),
+		logger:      logging.Default(),
+	}
+	manager.markCookiesSaved("alice", scraper.cookies)
+
+	// Cookies as they were saved at login aren't written again
+	assert.Equal(t, 0, manager.saveChangedCookies())
+	assert.False(t, manager.authManager.CookiesExist("alice"))
+
+	// A rotated token is saved once
+	scraper.cookies = []*http.Cookie{{Name: "ct0", Value: "2"}, {Name: "auth_token", Value: "a"}}
+	assert.Equal(t, 1, manager.saveChangedCookies())
+	cookies, err := manager.authManager.LoadCookies("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "ct0", cookies[0].Name)
+	assert.Equal(t, "2", cookies[0].Value)
+	assert.Equal(t, 0, manager.saveChangedCookies())
+
+	// Reordered cookies are the same session
+	scraper.cookies = []*http.Cookie{{Name: "auth_token", Value: "a"}, {Name: "ct0", Value: "2"}}
+	assert.Equal(t, 0, manager.saveChangedCookies())
+	assert.False(t, manager.authManager.CookiesExist("bob"))
+}
@@ -0,0 +1,93 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// scraperCalls counts the calls to Twitter in progress in the process, over
+// all agents, so shutdown can let them finish instead of cutting a session
+// off mid-request
+var scraperCalls = &callTracker{}
+
+// callTracker counts calls in progress. Unlike a sync.WaitGroup, calls may
+// start while it is being waited on, as background tasks wind down.
+type callTracker struct {
+	mu    sync.Mutex
+	count int
+	// idle is closed when count drops to zero
+	idle chan struct{}
+}
+
+// start records a call starting and returns the function recording its end
+func (t *callTracker) start() func() {
+	t.mu.Lock()
+	if t.count == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.count++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.count--
+			if t.count == 0 {
+				close(t.idle)
+			}
+			t.mu.Unlock()
+		})
+	}
+}
+
+// wait blocks until no call is in progress or ctx is done, in which case
+// the number of calls still running is reported
+func (t *callTracker) wait(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		count, idle := t.count, t.idle
+		t.mu.Unlock()
+		if count == 0 {
+			return nil
+		}
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			t.mu.Lock()
+			count = t.count
+			t.mu.Unlock()
+			return fmt.Errorf("%d scraper calls still running: %w", count, ctx.Err())
+		}
+	}
+}
+
+// stream forwards the results of a streaming call, counting it as running
+// until the stream ends. Results are dropped once ctx is done, as nobody
+// reads them anymore, but the stream still runs to its end.
+func (t *callTracker) stream(ctx context.Context, in <-chan *twitterscraper.TweetResult) <-chan *twitterscraper.TweetResult {
+	done := t.start()
+	out := make(chan *twitterscraper.TweetResult)
+	go func() {
+		defer done()
+		defer close(out)
+		for result := range in {
+			select {
+			case out <- result:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out
+}
+
+// WaitForScraperCalls waits until no agent is calling Twitter, or ctx is
+// done. Call it on shutdown after stopping the HTTP server and cancelling
+// the background tasks, so no new calls are started meanwhile.
+func WaitForScraperCalls(ctx context.Context) error {
+	return scraperCalls.wait(ctx)
+}
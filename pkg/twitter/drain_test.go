@@ -0,0 +1,56 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallTrackerWait(t *testing.T) {
+	tracker := &callTracker{}
+
+	// Nothing running
+	assert.NoError(t, tracker.wait(context.Background()))
+
+	first := tracker.start()
+	second := tracker.start()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.EqualError(t, tracker.wait(ctx), "2 scraper calls still running: context deadline exceeded")
+
+	// Ending a call twice counts once
+	first()
+	first()
+	waited := make(chan error, 1)
+	go func() { waited <- tracker.wait(context.Background()) }()
+	select {
+	case <-waited:
+		t.Fatal("wait returned with a call running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	second()
+	assert.NoError(t, <-waited)
+}
+
+func TestCallTrackerStream(t *testing.T) {
+	tracker := &callTracker{}
+	in := make(chan *twitterscraper.TweetResult)
+	ctx, cancel := context.WithCancel(context.Background())
+	out := tracker.stream(ctx, in)
+
+	in <- &twitterscraper.TweetResult{Tweet: twitterscraper.Tweet{ID: "1"}}
+	assert.Equal(t, "1", (<-out).ID)
+
+	// The consumer stops reading; the stream still counts until it ends
+	cancel()
+	in <- &twitterscraper.TweetResult{Tweet: twitterscraper.Tweet{ID: "2"}}
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer waitCancel()
+	assert.Error(t, tracker.wait(waitCtx))
+
+	close(in)
+	assert.NoError(t, tracker.wait(context.Background()))
+}
@@ -0,0 +1,38 @@
+package twitter
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type dryRunKey struct{}
+
+// ContextWithDryRun returns a copy of ctx that marks write operations as dry runs
+func ContextWithDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, dryRun)
+}
+
+// DryRunFromContext reports whether ctx marks write operations as dry runs
+func DryRunFromContext(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// isDryRunRequest reports whether a tool call asked for a dry run
+func isDryRunRequest(request mcp.CallToolRequest) bool {
+	dryRun, _ := request.Params.Arguments["dry_run"].(bool)
+	return dryRun
+}
+
+// dryRunResult builds the synthetic success response returned instead of calling the scraper
+func dryRunResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: text,
+			},
+		},
+	}
+}
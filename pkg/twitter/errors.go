@@ -0,0 +1,80 @@
+package twitter
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Kinds of agent failure, for callers to tell apart with errors.Is
+var (
+	// ErrRateLimited is returned when the agent's rate limiter or Twitter refused the call
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNotLoggedIn is returned when the call needs a logged-in account and the agent has none
+	ErrNotLoggedIn = errors.New("not logged in")
+	// ErrNotFound is returned when Twitter doesn't have the requested user or tweet
+	ErrNotFound = errors.New("not found")
+	// ErrUpstream is returned for any other failure of the call to Twitter
+	ErrUpstream = errors.New("upstream error")
+	// ErrInvalidArgument is returned when a tool rejected its arguments
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// AgentError is a failed tool call of an agent. Its message is the tool
+// result's text and it wraps the kind of failure, if it could be told.
+type AgentError struct {
+	Kind    error
+	Message string
+}
+
+func (e *AgentError) Error() string {
+	return e.Message
+}
+
+func (e *AgentError) Unwrap() error {
+	return e.Kind
+}
+
+// toolError classifies the error text of a failed tool result. Tool results
+// only carry text, so the kind is recognized from the messages the handlers
+// and twitter-scraper produce; errors of no known kind, such as failing to
+// marshal a result, are returned without one.
+func toolError(message string) error {
+	return &AgentError{Kind: toolErrorKind(message), Message: message}
+}
+
+func toolErrorKind(message string) error {
+	lower := strings.ToLower(message)
+	status := 0
+	if match := statusCodePattern.FindStringSubmatch(message); match != nil {
+		status, _ = strconv.Atoi(match[1])
+	}
+
+	switch {
+	case strings.HasPrefix(lower, "rate limit error"), status == 429:
+		return ErrRateLimited
+	case strings.HasPrefix(lower, "this tool requires login"), status == 401, strings.Contains(lower, "auth error"):
+		return ErrNotLoggedIn
+	case status == 404, strings.Contains(lower, "not found") && !strings.Contains(lower, "guest_token"),
+		strings.Contains(lower, "does not exist"), strings.Contains(lower, "user is suspended"):
+		return ErrNotFound
+	case status != 0, isScraperFailure(lower):
+		return ErrUpstream
+	case isArgumentError(lower):
+		return ErrInvalidArgument
+	}
+	return nil
+}
+
+// isArgumentError reports whether a tool error message rejects the tool's
+// arguments, e.g. "username parameter is required" or "invalid tweet_id ..."
+func isArgumentError(lower string) bool {
+	return strings.Contains(lower, " is required") || strings.HasPrefix(lower, "invalid ") ||
+		strings.HasPrefix(lower, "tweet exceeds ")
+}
+
+// isScraperFailure reports whether a tool error message reports a failed
+// scraper call, which the handlers word as "error <doing something>: ..."
+func isScraperFailure(lower string) bool {
+	return strings.HasPrefix(lower, "error ") && !strings.HasPrefix(lower, "error marshaling")
+}
@@ -0,0 +1,42 @@
+package twitter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolError(t *testing.T) {
+	tests := []struct {
+		message string
+		kind    error
+	}{
+		{"rate limit error: context deadline exceeded", ErrRateLimited},
+		{"error getting tweets: response status 429 Too Many Requests: {}", ErrRateLimited},
+		{"This tool requires login. Please provide Twitter cookies to use this tool.", ErrNotLoggedIn},
+		{"error liking tweet: response status 401 Unauthorized: {}", ErrNotLoggedIn},
+		{"error getting profile: user not found", ErrNotFound},
+		{"error getting profile: either @nobody does not exist or is private", ErrNotFound},
+		{"error getting tweet: tweet with ID 1 not found", ErrNotFound},
+		{"error getting trends: response status 503 Service Unavailable: {}", ErrUpstream},
+		{"error searching tweets: connection reset by peer", ErrUpstream},
+		{"error getting tweets: guest_token not found", ErrUpstream},
+		{"error marshaling results: unsupported value", nil},
+		{"username parameter is required", ErrInvalidArgument},
+		{`invalid tweet_id "abc", expected a tweet ID`, ErrInvalidArgument},
+		{"tweet exceeds 280 characters (counted 300)", ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			err := toolError(tt.message)
+			assert.EqualError(t, err, tt.message)
+			var agentErr *AgentError
+			assert.ErrorAs(t, err, &agentErr)
+			assert.Equal(t, tt.kind, agentErr.Kind)
+			if tt.kind != nil {
+				assert.ErrorIs(t, err, tt.kind)
+			}
+		})
+	}
+}
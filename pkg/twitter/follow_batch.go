@@ -0,0 +1,114 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// maxBatchFollows caps the number of user IDs accepted by one batch follow or unfollow
+	maxBatchFollows = 50
+	// maxConsecutiveFollowFailures is how many follows in a row may fail before
+	// the rest of a batch is abandoned. A run of failures usually means an
+	// account was flagged, and retrying makes that worse.
+	maxConsecutiveFollowFailures = 5
+)
+
+// ErrInvalidFollowBatch is wrapped by the errors BatchFollow and BatchUnfollow
+// return for an empty or oversized batch
+var ErrInvalidFollowBatch = errors.New("invalid follow batch")
+
+// FollowResult is the outcome of following or unfollowing one user of a batch
+type FollowResult struct {
+	UserID  string `json:"user_id"`
+	Agent   string `json:"agent,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Skipped is set for users not attempted because the batch was aborted or cancelled
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// FollowBatchResult is the outcome of a batch follow or unfollow, with one
+// result per user in request order
+type FollowBatchResult struct {
+	Results   []FollowResult `json:"results"`
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+	Aborted   bool           `json:"aborted"`
+	// Cancelled is set when the request context ended before every user was attempted
+	Cancelled bool `json:"cancelled"`
+}
+
+// BatchFollow follows each of userIDs, rotating between the agents. Every
+// follow waits for its agent's rate limiter, so a batch paces itself rather
+// than bursting. A user that can't be followed doesn't stop the others, but
+// after maxConsecutiveFollowFailures failures in a row the remaining users
+// are skipped. When ctx ends mid-batch, the users not yet attempted are
+// skipped too and the partial result is returned with Cancelled set.
+func (am *AgentManager) BatchFollow(ctx context.Context, userIDs []string) (*FollowBatchResult, error) {
+	return am.batchFollow(ctx, "follow", userIDs, am.Follow)
+}
+
+// BatchUnfollow unfollows each of userIDs like BatchFollow follows them
+func (am *AgentManager) BatchUnfollow(ctx context.Context, userIDs []string) (*FollowBatchResult, error) {
+	return am.batchFollow(ctx, "unfollow", userIDs, am.Unfollow)
+}
+
+func (am *AgentManager) batchFollow(ctx context.Context, action string, userIDs []string,
+	follow func(ctx context.Context, userID string, targetUsername string) (string, error)) (*FollowBatchResult, error) {
+	logger := am.requestLogger(ctx)
+
+	ids := make([]string, 0, len(userIDs))
+	seen := make(map[string]bool)
+	for _, id := range userIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: user_ids is required", ErrInvalidFollowBatch)
+	}
+	if len(ids) > maxBatchFollows {
+		return nil, fmt.Errorf("%w: too many user_ids: %d, the maximum is %d", ErrInvalidFollowBatch, len(ids), maxBatchFollows)
+	}
+
+	logger.Info("Starting batch %s of %d users", action, len(ids))
+	batch := &FollowBatchResult{Results: make([]FollowResult, 0, len(ids))}
+	consecutiveFailures := 0
+	for _, id := range ids {
+		if !batch.Aborted && !batch.Cancelled && ctx.Err() != nil {
+			logger.Warning("Cancelling batch %s: %v", action, ctx.Err())
+			batch.Cancelled = true
+		}
+
+		if batch.Aborted || batch.Cancelled {
+			batch.Results = append(batch.Results, FollowResult{UserID: id, Skipped: true})
+			continue
+		}
+
+		agentUsername, err := follow(ctx, id, "")
+		if err != nil {
+			batch.Results = append(batch.Results, FollowResult{UserID: id, Agent: agentUsername, Error: err.Error()})
+			batch.Failed++
+			// A user that doesn't exist says nothing about the account
+			if !errors.Is(err, ErrNotFound) {
+				consecutiveFailures++
+			}
+			if consecutiveFailures >= maxConsecutiveFollowFailures {
+				logger.Warning("Aborting batch %s after %d consecutive failures, the account may be flagged", action, consecutiveFailures)
+				batch.Aborted = true
+			}
+			continue
+		}
+
+		batch.Results = append(batch.Results, FollowResult{UserID: id, Agent: agentUsername, Success: true})
+		batch.Succeeded++
+		consecutiveFailures = 0
+	}
+
+	logger.Info("Finished batch %s: %d succeeded, %d failed", action, batch.Succeeded, batch.Failed)
+	return batch, nil
+}
@@ -0,0 +1,85 @@
+package twitter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchFollow(t *testing.T) {
+	manager := &AgentManager{logger: logging.Default()}
+	ctx := context.Background()
+
+	var followed []string
+	follow := func(ctx context.Context, userID string, targetUsername string) (string, error) {
+		followed = append(followed, userID)
+		switch userID {
+		case "404":
+			return "alice", toolError("error following user: user not found")
+		case "500":
+			return "alice", toolError("error following user: response status 500 Internal Server Error")
+		}
+		return "alice", nil
+	}
+
+	result, err := manager.batchFollow(ctx, "follow", []string{"1", "404", "1", "", "2"}, follow)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "404", "2"}, followed)
+	assert.Equal(t, []FollowResult{
+		{UserID: "1", Agent: "alice", Success: true},
+		{UserID: "404", Agent: "alice", Error: "error following user: user not found"},
+		{UserID: "2", Agent: "alice", Success: true},
+	}, result.Results)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 1, result.Failed)
+	assert.False(t, result.Aborted)
+
+	// Missing users don't count toward aborting, upstream failures do
+	followed = nil
+	ids := []string{"500", "404", "502", "503", "504", "505", "6", "7"}
+	result, err = manager.batchFollow(ctx, "follow", ids, func(ctx context.Context, userID string, targetUsername string) (string, error) {
+		followed = append(followed, userID)
+		if userID == "404" {
+			return "alice", toolError("user not found")
+		}
+		return "alice", toolError("error following user: response status 500 Internal Server Error")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"500", "404", "502", "503", "504", "505"}, followed)
+	assert.True(t, result.Aborted)
+	assert.Equal(t, 6, result.Failed)
+	assert.Equal(t, FollowResult{UserID: "6", Skipped: true}, result.Results[6])
+	assert.Equal(t, FollowResult{UserID: "7", Skipped: true}, result.Results[7])
+
+	_, err = manager.batchFollow(ctx, "follow", []string{""}, follow)
+	assert.ErrorIs(t, err, ErrInvalidFollowBatch)
+
+	tooMany := make([]string, maxBatchFollows+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i)
+	}
+	_, err = manager.batchFollow(ctx, "follow", tooMany, follow)
+	assert.ErrorIs(t, err, ErrInvalidFollowBatch)
+
+	// Cancelling mid-batch reports the users followed so far
+	cancelled, cancel := context.WithCancel(ctx)
+	followed = nil
+	result, err = manager.batchFollow(cancelled, "follow", []string{"1", "2", "3"}, func(ctx context.Context, userID string, targetUsername string) (string, error) {
+		followed = append(followed, userID)
+		cancel()
+		return "alice", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, followed)
+	assert.True(t, result.Cancelled)
+	assert.False(t, result.Aborted)
+	assert.Equal(t, 1, result.Succeeded)
+	assert.Equal(t, []FollowResult{
+		{UserID: "1", Agent: "alice", Success: true},
+		{UserID: "2", Skipped: true},
+		{UserID: "3", Skipped: true},
+	}, result.Results)
+}
@@ -0,0 +1,567 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// listTweetsPageSize is the number of list tweets requested per page
+	listTweetsPageSize = 20
+	// listMembersPageSize is the number of list members requested per page
+	listMembersPageSize = 20
+	// defaultListTweetsLimit is the number of list tweets returned when no limit is given
+	defaultListTweetsLimit = 20
+)
+
+// ErrInvalidList is wrapped by the errors returned for a malformed list ID
+var ErrInvalidList = errors.New("invalid list")
+
+// ListTweets is the latest tweets of a list, newest first
+type ListTweets struct {
+	Tweets []SimplifiedTweet `json:"tweets"`
+}
+
+// ListMembersPage is a page of the members of a list
+type ListMembersPage struct {
+	Members []UserProfile `json:"members"`
+	// NextCursor fetches the next page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// validListID reports whether id looks like a list ID, which is numeric
+// like a tweet ID
+func validListID(id string) bool {
+	return validTweetID(id)
+}
+
+// graphQLTimeline is the part of a GraphQL timeline that is used: entries
+// holding a tweet or a user, and the cursor of the next page
+type graphQLTimeline struct {
+	Instructions []struct {
+		Type    string `json:"type"`
+		Entries []struct {
+			EntryID string `json:"entryId"`
+			Content struct {
+				CursorType  string `json:"cursorType"`
+				Value       string `json:"value"`
+				ItemContent struct {
+					TweetResults struct {
+						Result *graphQLTweet `json:"result"`
+					} `json:"tweet_results"`
+					UserResults struct {
+						Result *graphQLUser `json:"result"`
+					} `json:"user_results"`
+				} `json:"itemContent"`
+			} `json:"content"`
+		} `json:"entries"`
+	} `json:"instructions"`
+}
+
+// graphQLTweet is a tweet result of a GraphQL timeline
+type graphQLTweet struct {
+	Typename string `json:"__typename"`
+	RestID   string `json:"rest_id"`
+	Core     struct {
+		UserResults struct {
+			Result graphQLUser `json:"result"`
+		} `json:"user_results"`
+	} `json:"core"`
+	Legacy struct {
+		FullText          string `json:"full_text"`
+		CreatedAt         string `json:"created_at"`
+		FavoriteCount     int    `json:"favorite_count"`
+		RetweetCount      int    `json:"retweet_count"`
+		ReplyCount        int    `json:"reply_count"`
+		ConversationID    string `json:"conversation_id_str"`
+		InReplyToStatusID string `json:"in_reply_to_status_id_str"`
+		QuotedStatusID    string `json:"quoted_status_id_str"`
+		IsQuoteStatus     bool   `json:"is_quote_status"`
+		UserID            string `json:"user_id_str"`
+		Entities          struct {
+			Hashtags []struct {
+				Text string `json:"text"`
+			} `json:"hashtags"`
+			UserMentions []struct {
+				IDStr      string `json:"id_str"`
+				Name       string `json:"name"`
+				ScreenName string `json:"screen_name"`
+			} `json:"user_mentions"`
+			URLs []struct {
+				ExpandedURL string `json:"expanded_url"`
+			} `json:"urls"`
+		} `json:"entities"`
+		RetweetedStatusResult struct {
+			Result *graphQLTweet `json:"result"`
+		} `json:"retweeted_status_result"`
+	} `json:"legacy"`
+	Views struct {
+		Count string `json:"count"`
+	} `json:"views"`
+	// Tweet is the actual tweet of a TweetWithVisibilityResults
+	Tweet *graphQLTweet `json:"tweet"`
+}
+
+// graphQLUser is a user result of a GraphQL timeline
+type graphQLUser struct {
+	RestID         string `json:"rest_id"`
+	IsBlueVerified bool   `json:"is_blue_verified"`
+	Legacy         struct {
+		ScreenName      string `json:"screen_name"`
+		Name            string `json:"name"`
+		Description     string `json:"description"`
+		Location        string `json:"location"`
+		ProfileImageURL string `json:"profile_image_url_https"`
+		ProfileBanner   string `json:"profile_banner_url"`
+		CreatedAt       string `json:"created_at"`
+		FollowersCount  int    `json:"followers_count"`
+		FriendsCount    int    `json:"friends_count"`
+		StatusesCount   int    `json:"statuses_count"`
+		FavouritesCount int    `json:"favourites_count"`
+		MediaCount      int    `json:"media_count"`
+		ListedCount     int    `json:"listed_count"`
+		Verified        bool   `json:"verified"`
+		Protected       bool   `json:"protected"`
+	} `json:"legacy"`
+}
+
+// listTweetsResponse is the ListLatestTweetsTimeline response
+type listTweetsResponse struct {
+	Data struct {
+		List struct {
+			TweetsTimeline struct {
+				Timeline graphQLTimeline `json:"timeline"`
+			} `json:"tweets_timeline"`
+		} `json:"list"`
+	} `json:"data"`
+}
+
+// listMembersResponse is the ListMembers response
+type listMembersResponse struct {
+	Data struct {
+		List struct {
+			MembersTimeline struct {
+				Timeline graphQLTimeline `json:"timeline"`
+			} `json:"members_timeline"`
+		} `json:"list"`
+	} `json:"data"`
+}
+
+// parseTweets returns the tweets of the timeline entries, in timeline order,
+// and the cursor of the next page. Tombstones and unavailable tweets are left out.
+func (t *graphQLTimeline) parseTweets() ([]*twitterscraper.Tweet, string) {
+	var tweets []*twitterscraper.Tweet
+	var cursor string
+	for _, instruction := range t.Instructions {
+		for _, entry := range instruction.Entries {
+			if entry.Content.CursorType == "Bottom" {
+				cursor = entry.Content.Value
+				continue
+			}
+			if tweet := entry.Content.ItemContent.TweetResults.Result.parse(); tweet != nil {
+				tweets = append(tweets, tweet)
+			}
+		}
+	}
+	return tweets, cursor
+}
+
+// parseUsers returns the users of the timeline entries, in timeline order,
+// and the cursor of the next page, which is empty on the last page
+func (t *graphQLTimeline) parseUsers() ([]*twitterscraper.Profile, string) {
+	var users []*twitterscraper.Profile
+	var cursor string
+	for _, instruction := range t.Instructions {
+		for _, entry := range instruction.Entries {
+			if entry.Content.CursorType == "Bottom" {
+				cursor = entry.Content.Value
+				continue
+			}
+			if user := entry.Content.ItemContent.UserResults.Result; user != nil && user.RestID != "" {
+				users = append(users, user.parse())
+			}
+		}
+	}
+	// The last page still has a bottom cursor, which starts with 0|
+	if strings.HasPrefix(cursor, "0|") {
+		cursor = ""
+	}
+	return users, cursor
+}
+
+// parse converts the tweet result into a scraper tweet, or returns nil when
+// the result holds no tweet
+func (r *graphQLTweet) parse() *twitterscraper.Tweet {
+	if r == nil {
+		return nil
+	}
+	if r.Tweet != nil {
+		return r.Tweet.parse()
+	}
+	if r.RestID == "" {
+		return nil
+	}
+
+	legacy := r.Legacy
+	author := r.Core.UserResults.Result.Legacy
+	tweet := &twitterscraper.Tweet{
+		ID:                r.RestID,
+		UserID:            legacy.UserID,
+		Username:          author.ScreenName,
+		Name:              author.Name,
+		Text:              legacy.FullText,
+		Likes:             legacy.FavoriteCount,
+		Retweets:          legacy.RetweetCount,
+		Replies:           legacy.ReplyCount,
+		ConversationID:    legacy.ConversationID,
+		InReplyToStatusID: legacy.InReplyToStatusID,
+		IsReply:           legacy.InReplyToStatusID != "",
+		QuotedStatusID:    legacy.QuotedStatusID,
+		IsQuoted:          legacy.IsQuoteStatus,
+		PermanentURL:      fmt.Sprintf("https://twitter.com/%s/status/%s", author.ScreenName, r.RestID),
+	}
+	if createdAt, err := time.Parse(time.RubyDate, legacy.CreatedAt); err == nil {
+		tweet.TimeParsed = createdAt
+		tweet.Timestamp = createdAt.Unix()
+	}
+	if views, err := strconv.Atoi(r.Views.Count); err == nil {
+		tweet.Views = views
+	}
+	for _, hashtag := range legacy.Entities.Hashtags {
+		tweet.Hashtags = append(tweet.Hashtags, hashtag.Text)
+	}
+	for _, mention := range legacy.Entities.UserMentions {
+		tweet.Mentions = append(tweet.Mentions, twitterscraper.Mention{ID: mention.IDStr, Username: mention.ScreenName, Name: mention.Name})
+	}
+	for _, url := range legacy.Entities.URLs {
+		tweet.URLs = append(tweet.URLs, url.ExpandedURL)
+	}
+	if retweeted := legacy.RetweetedStatusResult.Result.parse(); retweeted != nil {
+		tweet.IsRetweet = true
+		tweet.RetweetedStatusID = retweeted.ID
+	}
+	return tweet
+}
+
+// parse converts the user result into a scraper profile
+func (u *graphQLUser) parse() *twitterscraper.Profile {
+	legacy := u.Legacy
+	profile := &twitterscraper.Profile{
+		UserID:         u.RestID,
+		Username:       legacy.ScreenName,
+		Name:           legacy.Name,
+		Biography:      legacy.Description,
+		Location:       legacy.Location,
+		Avatar:         legacy.ProfileImageURL,
+		Banner:         legacy.ProfileBanner,
+		URL:            "https://twitter.com/" + legacy.ScreenName,
+		FollowersCount: legacy.FollowersCount,
+		FollowingCount: legacy.FriendsCount,
+		FriendsCount:   legacy.FriendsCount,
+		TweetsCount:    legacy.StatusesCount,
+		LikesCount:     legacy.FavouritesCount,
+		MediaCount:     legacy.MediaCount,
+		ListedCount:    legacy.ListedCount,
+		IsVerified:     legacy.Verified,
+		IsPrivate:      legacy.Protected,
+		IsBlueVerified: u.IsBlueVerified,
+	}
+	if joined, err := time.Parse(time.RubyDate, legacy.CreatedAt); err == nil {
+		profile.Joined = &joined
+	}
+	return profile
+}
+
+// handleGetListTweets gets the latest tweets of a list
+func (a *Agent) handleGetListTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	listID, ok := request.Params.Arguments["list_id"].(string)
+	if !ok || listID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "list_id parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	if !validListID(listID) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("invalid list_id %q, expected a list ID", listID),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	limit := defaultListTweetsLimit
+	if limitVal, ok := request.Params.Arguments["limit"].(float64); ok && limitVal > 0 {
+		limit = int(limitVal)
+	}
+	limit, limitWarning := ClampLimit(limit, a.maxResults)
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_list_tweets"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweets, err := a.scraper.GetListTweets(ctx, listID, limit)
+	a.limiter.doneEndpoint("get_list_tweets")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting list tweets: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	result := ListTweets{Tweets: make([]SimplifiedTweet, 0, len(tweets))}
+	for _, tweet := range tweets {
+		result.Tweets = append(result.Tweets, newSimplifiedTweet(tweet))
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return withWarnings(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, limitWarning), nil
+}
+
+// handleGetListMembers gets a page of the members of a list
+func (a *Agent) handleGetListMembers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	listID, ok := request.Params.Arguments["list_id"].(string)
+	if !ok || listID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "list_id parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	if !validListID(listID) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("invalid list_id %q, expected a list ID", listID),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	cursor, _ := request.Params.Arguments["cursor"].(string)
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_list_members"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	members, next, err := a.scraper.GetListMembers(ctx, listID, cursor)
+	a.limiter.doneEndpoint("get_list_members")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting list members: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	page := ListMembersPage{Members: make([]UserProfile, 0, len(members)), NextCursor: next}
+	for _, member := range members {
+		page.Members = append(page.Members, newUserProfile(member))
+	}
+
+	jsonData, err := json.Marshal(page)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// GetListTweets gets up to limit of the latest tweets of list listID using
+// the next available agent
+func (am *AgentManager) GetListTweets(ctx context.Context, listID string, limit int) (*ListTweets, string, error) {
+	logger := am.requestLogger(ctx)
+	if !validListID(listID) {
+		return nil, "", fmt.Errorf("%w: invalid list ID %q", ErrInvalidList, listID)
+	}
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting tweets of list %s using agent %s", listID, agentUsername)
+
+	result, err := agent.handleGetListTweets(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_list_tweets",
+			Arguments: map[string]interface{}{
+				"list_id": listID,
+				"limit":   float64(limit),
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting tweets of list %s: %v", listID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for tweets of list %s: %s", listID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var tweets ListTweets
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &tweets); err != nil {
+		logger.Error("Error unmarshaling tweets of list %s: %v", listID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved %d tweets of list %s", len(tweets.Tweets), listID)
+	return &tweets, agentUsername, nil
+}
+
+// GetListMembers gets a page of the members of list listID using the next
+// available agent, starting at cursor or at the first page when it is empty
+func (am *AgentManager) GetListMembers(ctx context.Context, listID string, cursor string) (*ListMembersPage, string, error) {
+	logger := am.requestLogger(ctx)
+	if !validListID(listID) {
+		return nil, "", fmt.Errorf("%w: invalid list ID %q", ErrInvalidList, listID)
+	}
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting members of list %s using agent %s", listID, agentUsername)
+
+	result, err := agent.handleGetListMembers(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_list_members",
+			Arguments: map[string]interface{}{
+				"list_id": listID,
+				"cursor":  cursor,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting members of list %s: %v", listID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for members of list %s: %s", listID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var page ListMembersPage
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &page); err != nil {
+		logger.Error("Error unmarshaling members of list %s: %v", listID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved %d members of list %s", len(page.Members), listID)
+	return &page, agentUsername, nil
+}
@@ -0,0 +1,128 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListTweets(t *testing.T) {
+	body := `{"data": {"list": {"tweets_timeline": {"timeline": {"instructions": [{
+		"type": "TimelineAddEntries",
+		"entries": [
+			{"entryId": "tweet-100", "content": {"itemContent": {"tweet_results": {"result": {
+				"__typename": "Tweet",
+				"rest_id": "100",
+				"core": {"user_results": {"result": {"rest_id": "1", "legacy": {"screen_name": "alice", "name": "Alice"}}}},
+				"legacy": {
+					"full_text": "hello #go @bob",
+					"created_at": "Mon Jan 02 15:04:05 +0000 2006",
+					"favorite_count": 3, "retweet_count": 2, "reply_count": 1,
+					"user_id_str": "1",
+					"entities": {"hashtags": [{"text": "go"}], "user_mentions": [{"id_str": "2", "screen_name": "bob", "name": "Bob"}]}
+				},
+				"views": {"count": "40"}
+			}}}}},
+			{"entryId": "tweet-101", "content": {"itemContent": {"tweet_results": {"result": {
+				"__typename": "TweetWithVisibilityResults",
+				"tweet": {
+					"rest_id": "101",
+					"core": {"user_results": {"result": {"rest_id": "2", "legacy": {"screen_name": "bob", "name": "Bob"}}}},
+					"legacy": {"full_text": "reply", "in_reply_to_status_id_str": "100", "user_id_str": "2"}
+				}
+			}}}}},
+			{"entryId": "tweet-102", "content": {"itemContent": {"tweet_results": {"result": {"__typename": "TweetTombstone"}}}}},
+			{"entryId": "cursor-top-1", "content": {"cursorType": "Top", "value": "newer"}},
+			{"entryId": "cursor-bottom-1", "content": {"cursorType": "Bottom", "value": "older"}}
+		]
+	}]}}}}}`
+	var response listTweetsResponse
+	assert.NoError(t, json.Unmarshal([]byte(body), &response))
+
+	tweets, cursor := response.Data.List.TweetsTimeline.Timeline.parseTweets()
+	assert.Equal(t, "older", cursor)
+	if assert.Len(t, tweets, 2) {
+		assert.Equal(t, "100", tweets[0].ID)
+		assert.Equal(t, "alice", tweets[0].Username)
+		assert.Equal(t, "Alice", tweets[0].Name)
+		assert.Equal(t, 3, tweets[0].Likes)
+		assert.Equal(t, 40, tweets[0].Views)
+		assert.Equal(t, []string{"go"}, tweets[0].Hashtags)
+		assert.Equal(t, "bob", tweets[0].Mentions[0].Username)
+		assert.Equal(t, "https://twitter.com/alice/status/100", tweets[0].PermanentURL)
+		assert.True(t, time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Equal(tweets[0].TimeParsed))
+		// Tweets with visibility results are unwrapped
+		assert.Equal(t, "101", tweets[1].ID)
+		assert.Equal(t, "bob", tweets[1].Username)
+		assert.True(t, tweets[1].IsReply)
+	}
+}
+
+func TestParseListMembers(t *testing.T) {
+	body := `{"data": {"list": {"members_timeline": {"timeline": {"instructions": [{
+		"type": "TimelineAddEntries",
+		"entries": [
+			{"entryId": "user-1", "content": {"itemContent": {"user_results": {"result": {
+				"rest_id": "1", "is_blue_verified": true,
+				"legacy": {"screen_name": "alice", "name": "Alice", "followers_count": 10, "friends_count": 5}
+			}}}}},
+			{"entryId": "user-2", "content": {"itemContent": {"user_results": {"result": {"rest_id": "2", "legacy": {"screen_name": "bob"}}}}}},
+			{"entryId": "cursor-bottom-1", "content": {"cursorType": "Bottom", "value": "more"}}
+		]
+	}]}}}}}`
+	var response listMembersResponse
+	assert.NoError(t, json.Unmarshal([]byte(body), &response))
+
+	members, cursor := response.Data.List.MembersTimeline.Timeline.parseUsers()
+	assert.Equal(t, "more", cursor)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, "1", members[0].UserID)
+		assert.Equal(t, "alice", members[0].Username)
+		assert.Equal(t, 10, members[0].FollowersCount)
+		assert.Equal(t, 5, members[0].FollowingCount)
+		assert.True(t, members[0].IsBlueVerified)
+		assert.Equal(t, "bob", members[1].Username)
+	}
+
+	// The last page has a bottom cursor starting with 0|, which isn't returned
+	var last listMembersResponse
+	assert.NoError(t, json.Unmarshal([]byte(`{"data": {"list": {"members_timeline": {"timeline": {"instructions": [{
+		"entries": [{"entryId": "cursor-bottom-0", "content": {"cursorType": "Bottom", "value": "0|123"}}]
+	}]}}}}}`), &last))
+	members, cursor = last.Data.List.MembersTimeline.Timeline.parseUsers()
+	assert.Empty(t, members)
+	assert.Empty(t, cursor)
+}
+
+func TestGetListTweetsAndMembers(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &mockScraper{isLoggedIn: true}
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{
+		agents: []*Agent{agent},
+		logger: logging.Default(),
+	}
+
+	_, _, err := manager.GetListTweets(context.Background(), "not-a-list", 10)
+	assert.ErrorIs(t, err, ErrInvalidList)
+	_, _, err = manager.GetListMembers(context.Background(), "", "")
+	assert.ErrorIs(t, err, ErrInvalidList)
+
+	tweets, _, err := manager.GetListTweets(context.Background(), "123", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, tweets.Tweets, 1) {
+		assert.Equal(t, "1", tweets.Tweets[0].ID)
+		assert.Equal(t, "alice", tweets.Tweets[0].Username)
+	}
+
+	members, _, err := manager.GetListMembers(context.Background(), "123", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "next", members.NextCursor)
+	if assert.Len(t, members.Members, 1) {
+		assert.Equal(t, "alice", members.Members[0].Username)
+	}
+}
@@ -0,0 +1,24 @@
+package twitter
+
+import (
+	"math/rand"
+	"time"
+)
+
+// LoginThrottle spaces out the logins NewAgentManager makes at startup
+type LoginThrottle struct {
+	Delay  time.Duration // Wait between two login attempts
+	Jitter time.Duration // Random extra wait of up to Jitter added to Delay
+}
+
+// DefaultLoginThrottle waits 5 seconds between login attempts
+var DefaultLoginThrottle = LoginThrottle{Delay: 5 * time.Second}
+
+// wait returns how long to wait before the next login attempt
+func (t LoginThrottle) wait() time.Duration {
+	wait := max(t.Delay, 0)
+	if t.Jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(t.Jitter) + 1))
+	}
+	return wait
+}
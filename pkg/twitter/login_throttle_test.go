@@ -0,0 +1,20 @@
+package twitter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottleWait(t *testing.T) {
+	assert.Equal(t, time.Duration(0), LoginThrottle{}.wait())
+	assert.Equal(t, 5*time.Second, DefaultLoginThrottle.wait())
+
+	throttle := LoginThrottle{Delay: time.Second, Jitter: 500 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		wait := throttle.wait()
+		assert.GreaterOrEqual(t, wait, time.Second)
+		assert.LessOrEqual(t, wait, 1500*time.Millisecond)
+	}
+}
@@ -0,0 +1,137 @@
+package twitter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// maxTweetMedia is the most media Twitter allows on one tweet
+const maxTweetMedia = 4
+
+// maxAltTextLength is the most characters Twitter allows in the alt text of
+// a media
+const maxAltTextLength = 1000
+
+// Media types of TweetMedia
+const (
+	MediaTypePhoto = "photo"
+	MediaTypeVideo = "video"
+	MediaTypeGIF   = "gif"
+)
+
+// TweetMedia is a photo, video or GIF attached to a tweet
+type TweetMedia struct {
+	Type       string `json:"type"`
+	URL        string `json:"url"`
+	PreviewURL string `json:"preview_url,omitempty"`
+}
+
+// MediaTweet is a SimplifiedTweet with the media attached to it
+type MediaTweet struct {
+	SimplifiedTweet
+	Media []TweetMedia `json:"media"`
+}
+
+// newMediaTweet converts a scraper tweet into a MediaTweet
+func newMediaTweet(tweet *twitterscraper.Tweet) MediaTweet {
+	return MediaTweet{
+		SimplifiedTweet: newSimplifiedTweet(tweet),
+		Media:           tweetMedia(tweet),
+	}
+}
+
+// tweetMedia returns the photos, videos and GIFs of a tweet, in that order
+func tweetMedia(tweet *twitterscraper.Tweet) []TweetMedia {
+	media := make([]TweetMedia, 0, len(tweet.Photos)+len(tweet.Videos)+len(tweet.GIFs))
+	for _, photo := range tweet.Photos {
+		media = append(media, TweetMedia{Type: MediaTypePhoto, URL: photo.URL})
+	}
+	for _, video := range tweet.Videos {
+		media = append(media, TweetMedia{Type: MediaTypeVideo, URL: video.URL, PreviewURL: video.Preview})
+	}
+	for _, gif := range tweet.GIFs {
+		media = append(media, TweetMedia{Type: MediaTypeGIF, URL: gif.URL, PreviewURL: gif.Preview})
+	}
+	return media
+}
+
+// MediaAttachment is an uploaded media to attach to a new tweet, with the
+// alt text describing it to screen reader users, if any
+type MediaAttachment struct {
+	ID      string `json:"id"`
+	AltText string `json:"alt_text,omitempty"`
+}
+
+// UnmarshalJSON reads a media ID alone as well as an object with alt text,
+// so both ["456"] and [{"id": "456", "alt_text": "A cat"}] can be attached
+func (m *MediaAttachment) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*m = MediaAttachment{ID: id}
+		return nil
+	}
+
+	// attachment has no UnmarshalJSON, so this doesn't recurse
+	type attachment MediaAttachment
+	var a attachment
+	if err := json.Unmarshal(data, &a); err != nil {
+		return errors.New("media must be a media ID or an object with an id and alt_text")
+	}
+	*m = MediaAttachment(a)
+	return nil
+}
+
+// mediaIDs returns the IDs of media, in order
+func mediaIDs(media []MediaAttachment) []string {
+	ids := make([]string, 0, len(media))
+	for _, m := range media {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+// validateMedia checks uploaded media to attach to a new tweet against
+// Twitter's limits: at most 4, each a numeric media ID, without duplicates,
+// with alt text of at most 1000 characters
+func validateMedia(media []MediaAttachment) error {
+	if len(media) > maxTweetMedia {
+		return fmt.Errorf("a tweet can have at most %d media, got %d", maxTweetMedia, len(media))
+	}
+	seen := make(map[string]bool, len(media))
+	for i, m := range media {
+		if !validTweetID(m.ID) {
+			return fmt.Errorf("media %d has an invalid media ID %q", i+1, m.ID)
+		}
+		if seen[m.ID] {
+			return fmt.Errorf("media ID %s is attached more than once", m.ID)
+		}
+		seen[m.ID] = true
+		if length := utf8.RuneCountInString(m.AltText); length > maxAltTextLength {
+			return fmt.Errorf("alt text of media %d exceeds %d characters (counted %d)", i+1, maxAltTextLength, length)
+		}
+	}
+	return nil
+}
+
+// parseMediaArgument reads the media tool argument, which arrives either as
+// decoded JSON from an MCP client or as a []MediaAttachment from the
+// AgentManager
+func parseMediaArgument(value interface{}) ([]MediaAttachment, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media: %v", err)
+	}
+
+	var media []MediaAttachment
+	if err := json.Unmarshal(data, &media); err != nil {
+		return nil, fmt.Errorf("invalid media: %v", err)
+	}
+	if err := validateMedia(media); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
@@ -0,0 +1,147 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// mediaScraper serves a fixed media timeline
+type mediaScraper struct {
+	mockScraper
+	tweets []twitterscraper.Tweet
+}
+
+func (s *mediaScraper) GetMediaTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	ch := make(chan *twitterscraper.TweetResult, len(s.tweets))
+	for _, tweet := range s.tweets {
+		ch <- &twitterscraper.TweetResult{Tweet: tweet}
+	}
+	close(ch)
+	return ch
+}
+
+func TestHandleGetMediaTweets(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &mediaScraper{tweets: []twitterscraper.Tweet{
+		{
+			ID:     "1",
+			Text:   "photos",
+			Photos: []twitterscraper.Photo{{ID: "p1", URL: "https://pbs.twimg.com/p1.jpg"}},
+		},
+		{
+			ID:     "2",
+			Text:   "video",
+			Videos: []twitterscraper.Video{{ID: "v1", URL: "https://video.twimg.com/v1.mp4", Preview: "https://pbs.twimg.com/v1.jpg"}},
+		},
+	}}
+
+	result, err := agent.handleGetMediaTweets(context.Background(), mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name:      "get_media_tweets",
+			Arguments: map[string]interface{}{"username": "alice"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	var tweets []MediaTweet
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &tweets))
+	assert.Len(t, tweets, 2)
+	assert.Equal(t, []TweetMedia{{Type: MediaTypePhoto, URL: "https://pbs.twimg.com/p1.jpg"}}, tweets[0].Media)
+	assert.Equal(t, []TweetMedia{{Type: MediaTypeVideo, URL: "https://video.twimg.com/v1.mp4", PreviewURL: "https://pbs.twimg.com/v1.jpg"}}, tweets[1].Media)
+}
+
+func TestMediaAttachmentJSON(t *testing.T) {
+	var media []MediaAttachment
+	assert.NoError(t, json.Unmarshal([]byte(`["1", {"id": "2", "alt_text": "A cat"}]`), &media))
+	assert.Equal(t, []MediaAttachment{{ID: "1"}, {ID: "2", AltText: "A cat"}}, media)
+
+	assert.Error(t, json.Unmarshal([]byte(`[3]`), &media))
+}
+
+func TestValidateMedia(t *testing.T) {
+	// Alt text is counted in characters, not bytes
+	assert.NoError(t, validateMedia([]MediaAttachment{{ID: "1", AltText: strings.Repeat("é", maxAltTextLength)}}))
+	assert.EqualError(t, validateMedia([]MediaAttachment{{ID: "1"}, {ID: "2", AltText: strings.Repeat("a", maxAltTextLength+1)}}),
+		"alt text of media 2 exceeds 1000 characters (counted 1001)")
+	assert.EqualError(t, validateMedia([]MediaAttachment{{ID: "1"}, {ID: "1"}}), "media ID 1 is attached more than once")
+}
+
+// altTextScraper records the alt text set on media and the quotes posted,
+// failing to set alt text of the media failID
+type altTextScraper struct {
+	mockScraper
+	failID   string
+	calls    []string
+	altTexts map[string]string
+}
+
+func (s *altTextScraper) SetMediaAltText(ctx context.Context, mediaID string, altText string) error {
+	s.calls = append(s.calls, "alt_text "+mediaID)
+	if mediaID == s.failID {
+		return errors.New("response status 400 Bad Request")
+	}
+	s.altTexts[mediaID] = altText
+	return nil
+}
+
+func (s *altTextScraper) QuoteTweet(ctx context.Context, text string, quotedID string, mediaIDs []string) (*twitterscraper.Tweet, error) {
+	s.calls = append(s.calls, "quote "+strings.Join(mediaIDs, ","))
+	return &twitterscraper.Tweet{ID: "100", QuotedStatusID: quotedID}, nil
+}
+
+func TestQuoteTweetAltText(t *testing.T) {
+	scraper := &altTextScraper{mockScraper: mockScraper{isLoggedIn: true}, altTexts: map[string]string{}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	manager := &AgentManager{
+		agents: []*Agent{agent},
+		logger: logging.Default(),
+	}
+
+	// Alt text is set before the media is attached, skipping media without it
+	agent.limiter.lastCallTime = time.Time{}
+	_, _, err := manager.PostTweet(context.Background(), PostTweetRequest{
+		QuoteOf: "42",
+		Media:   []MediaAttachment{{ID: "1", AltText: "A cat"}, {ID: "2"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alt_text 1", "quote 1,2"}, scraper.calls)
+	assert.Equal(t, map[string]string{"1": "A cat"}, scraper.altTexts)
+
+	// Failing to set alt text doesn't post the quote
+	scraper.calls = nil
+	scraper.failID = "2"
+	agent.limiter.lastCallTime = time.Time{}
+	_, _, err = manager.PostTweet(context.Background(), PostTweetRequest{
+		QuoteOf: "42",
+		Media:   []MediaAttachment{{ID: "1", AltText: "A cat"}, {ID: "2", AltText: "A dog"}},
+	})
+	assert.ErrorIs(t, err, ErrUpstream)
+	assert.ErrorContains(t, err, "error setting alt text of media 2, which is uploaded but the tweet wasn't posted")
+	assert.Equal(t, []string{"alt_text 1", "alt_text 2"}, scraper.calls)
+
+	// Alt text over the limit is rejected before anything is sent
+	scraper.calls = nil
+	_, _, err = manager.PostTweet(context.Background(), PostTweetRequest{
+		QuoteOf: "42",
+		Media:   []MediaAttachment{{ID: "1", AltText: strings.Repeat("a", maxAltTextLength+1)}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidTweetRequest)
+	assert.Empty(t, scraper.calls)
+}
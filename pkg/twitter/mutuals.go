@@ -0,0 +1,120 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// maxMutualFollowsFetch caps how much of each following list MutualFollows
+// reads, so a target following hundreds of thousands of accounts can't turn
+// one request into thousands of scraper calls
+const maxMutualFollowsFetch = 1000
+
+// MutualFollowsResult lists the users both an account and a target follow
+type MutualFollowsResult struct {
+	// Account is the logged-in account whose following list was compared
+	Account string                    `json:"account"`
+	Target  string                    `json:"target"`
+	Mutuals []*twitterscraper.Profile `json:"mutuals"`
+	// Truncated is set when either following list was longer than the fetch
+	// cap, in which case some mutuals may be missing
+	Truncated bool `json:"truncated"`
+}
+
+// MutualFollows returns the users followed by both targetUsername and the
+// next logged-in account, matched by user ID. The two following lists are
+// fetched by different logged-in agents when more than one is available, and
+// each is read only up to maxMutualFollowsFetch users.
+func (am *AgentManager) MutualFollows(ctx context.Context, targetUsername string) (*MutualFollowsResult, string, error) {
+	logger := am.requestLogger(ctx)
+	agent, selection, err := am.getNextLoggedInAgent(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	agentUsername := selection.Agent
+	targetAgent, _, err := am.getNextLoggedInAgent(ctx)
+	if err != nil {
+		return nil, agentUsername, err
+	}
+	logger.Debug("Getting mutual follows of %s and agent %s, using agent %s for %s", targetUsername, agentUsername, targetAgent.username, targetUsername)
+
+	own, ownTruncated, err := agent.fetchFollowing(ctx, agent.username, maxMutualFollowsFetch)
+	if err != nil {
+		logger.Error("Error getting following of agent %s: %v", agentUsername, err)
+		return nil, agentUsername, err
+	}
+	target, targetTruncated, err := targetAgent.fetchFollowing(ctx, targetUsername, maxMutualFollowsFetch)
+	if err != nil {
+		logger.Error("Error getting following of user %s: %v", targetUsername, err)
+		return nil, agentUsername, err
+	}
+
+	followed := make(map[string]bool, len(own))
+	for _, profile := range own {
+		followed[profile.UserID] = true
+	}
+	result := &MutualFollowsResult{
+		Account:   agentUsername,
+		Target:    targetUsername,
+		Mutuals:   make([]*twitterscraper.Profile, 0),
+		Truncated: ownTruncated || targetTruncated,
+	}
+	for _, profile := range target {
+		if profile.UserID != "" && followed[profile.UserID] {
+			result.Mutuals = append(result.Mutuals, profile)
+			// A user listed twice across pages is reported once
+			delete(followed, profile.UserID)
+		}
+	}
+
+	logger.Info("Found %d mutual follows of %s and %s", len(result.Mutuals), targetUsername, agentUsername)
+	return result, agentUsername, nil
+}
+
+// fetchFollowing pages through the users username follows until max of them
+// were read, reporting whether the list went on beyond that
+func (a *Agent) fetchFollowing(ctx context.Context, username string, max int) ([]*twitterscraper.Profile, bool, error) {
+	if !a.scraper.IsLoggedIn() {
+		return nil, false, toolError("This tool requires login. Please provide Twitter cookies to use this tool.")
+	}
+
+	var following []*twitterscraper.Profile
+	seenCursors := make(map[string]bool)
+	cursor := ""
+	for len(following) < max {
+		if err := a.limiter.waitForEndpoint(ctx, "get_following"); err != nil {
+			if ctx.Err() != nil {
+				return nil, false, ctx.Err()
+			}
+			return nil, false, toolError(fmt.Sprintf("rate limit error: %v", err))
+		}
+
+		pageSize := followersPageSize
+		if max-len(following) < pageSize {
+			pageSize = max - len(following)
+		}
+		page, next, err := a.scraper.FetchFollowing(ctx, username, pageSize, cursor)
+		a.limiter.doneEndpoint("get_following")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, false, ctx.Err()
+			}
+			return nil, false, toolError(fmt.Sprintf("error getting following of %s: %v", username, err))
+		}
+		if len(page) > max-len(following) {
+			page = page[:max-len(following)]
+		}
+		following = append(following, page...)
+
+		// Stop at the end of the listing, and when Twitter hands back a cursor
+		// that was already followed
+		if len(page) == 0 || next == "" || seenCursors[next] {
+			return following, false, nil
+		}
+		seenCursors[next] = true
+		cursor = next
+	}
+	return following, true, nil
+}
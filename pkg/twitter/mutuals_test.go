@@ -0,0 +1,82 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// followingScraper serves the following list of each user in one page
+type followingScraper struct {
+	mockScraper
+	following map[string][]string // user IDs per username
+	calls     []string
+}
+
+func (s *followingScraper) FetchFollowing(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	s.calls = append(s.calls, username)
+	var profiles []*twitterscraper.Profile
+	for _, id := range s.following[username] {
+		profiles = append(profiles, &twitterscraper.Profile{UserID: id})
+	}
+	if len(profiles) > maxUsersNbr {
+		return profiles[:maxUsersNbr], "more", nil
+	}
+	return profiles, "", nil
+}
+
+func TestMutualFollows(t *testing.T) {
+	following := map[string][]string{
+		"alice":  {"1", "2", "3"},
+		"bob":    {"9"},
+		"target": {"3", "4", "1", "3"},
+	}
+	newAgent := func(username string) (*Agent, *followingScraper) {
+		scraper := &followingScraper{mockScraper: mockScraper{isLoggedIn: true}, following: following}
+		agent := newMockAgent()
+		agent.username = username
+		agent.scraper = scraper
+		agent.limiter.lastCallTime = time.Time{}
+		return agent, scraper
+	}
+	alice, aliceScraper := newAgent("alice")
+	bob, bobScraper := newAgent("bob")
+	manager := &AgentManager{agents: []*Agent{bob, alice}, logger: logging.Default()}
+
+	result, agentUsername, err := manager.MutualFollows(context.Background(), "target")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", agentUsername)
+	assert.Equal(t, "alice", result.Account)
+	assert.False(t, result.Truncated)
+	var ids []string
+	for _, profile := range result.Mutuals {
+		ids = append(ids, profile.UserID)
+	}
+	assert.Equal(t, []string{"3", "1"}, ids)
+	// The target's list is fetched by the other agent
+	assert.Equal(t, []string{"alice"}, aliceScraper.calls)
+	assert.Equal(t, []string{"target"}, bobScraper.calls)
+
+	profiles, truncated, err := bob.fetchFollowing(context.Background(), "target", 2)
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 2)
+	assert.True(t, truncated)
+
+	// Accounts that aren't logged in are passed over
+	bob.scraper.(*followingScraper).isLoggedIn = false
+	aliceScraper.calls, bobScraper.calls = nil, nil
+	result, agentUsername, err = manager.MutualFollows(context.Background(), "target")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", agentUsername)
+	assert.Len(t, result.Mutuals, 2)
+	assert.Equal(t, []string{"alice", "target"}, aliceScraper.calls)
+	assert.Empty(t, bobScraper.calls)
+
+	alice.scraper.(*followingScraper).isLoggedIn = false
+	_, _, err = manager.MutualFollows(context.Background(), "target")
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+}
@@ -0,0 +1,295 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Types of Notification
+const (
+	NotificationMention = "mention" // A tweet mentioning the account
+	NotificationReply   = "reply"   // A reply to a tweet of the account
+	NotificationLike    = "like"
+	NotificationRetweet = "retweet"
+	NotificationFollow  = "follow"
+	NotificationOther   = "other" // Anything else, e.g. login alerts or recommendations
+)
+
+// notificationIconTypes maps the icon of an aggregated notification to its type
+var notificationIconTypes = map[string]string{
+	"heart_icon":   NotificationLike,
+	"retweet_icon": NotificationRetweet,
+	"person_icon":  NotificationFollow,
+}
+
+// Notification is an entry of the notifications timeline of an account
+type Notification struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`               // One of the Notification types
+	Actor     string    `json:"actor,omitempty"`    // Username of the user who acted, the first one of aggregated notifications
+	ActorID   string    `json:"actor_id,omitempty"` // User ID of the actor
+	TweetID   string    `json:"tweet_id,omitempty"` // The mention or reply, or the account's tweet that was liked or retweeted
+	Message   string    `json:"message,omitempty"`  // Text shown for aggregated notifications, e.g. "alice liked your post"
+	Timestamp time.Time `json:"timestamp"`          // When it happened
+}
+
+// NotificationsPage is a page of the notifications timeline, newest first
+type NotificationsPage struct {
+	Notifications []Notification `json:"notifications"`
+	// NextCursor fetches the next, older page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// notificationsResponse is the part of the notifications timeline response
+// that is used. Users, tweets and aggregated notifications are kept in
+// globalObjects and referenced by ID from the timeline entries.
+type notificationsResponse struct {
+	GlobalObjects struct {
+		Users map[string]struct {
+			ScreenName string `json:"screen_name"`
+		} `json:"users"`
+		Tweets map[string]struct {
+			UserID            string `json:"user_id_str"`
+			InReplyToStatusID string `json:"in_reply_to_status_id_str"`
+			CreatedAt         string `json:"created_at"`
+		} `json:"tweets"`
+		Notifications map[string]struct {
+			TimestampMs string `json:"timestampMs"`
+			Icon        struct {
+				ID string `json:"id"`
+			} `json:"icon"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Template struct {
+				AggregateUserActionsV1 struct {
+					TargetObjects []struct {
+						Tweet struct {
+							ID string `json:"id"`
+						} `json:"tweet"`
+					} `json:"targetObjects"`
+					FromUsers []struct {
+						User struct {
+							ID string `json:"id"`
+						} `json:"user"`
+					} `json:"fromUsers"`
+				} `json:"aggregateUserActionsV1"`
+			} `json:"template"`
+		} `json:"notifications"`
+	} `json:"globalObjects"`
+	Timeline struct {
+		Instructions []struct {
+			AddEntries struct {
+				Entries []struct {
+					EntryID string `json:"entryId"`
+					Content struct {
+						Item struct {
+							Content struct {
+								Notification struct {
+									ID string `json:"id"`
+								} `json:"notification"`
+								Tweet struct {
+									ID string `json:"id"`
+								} `json:"tweet"`
+							} `json:"content"`
+						} `json:"item"`
+						Operation struct {
+							Cursor struct {
+								Value      string `json:"value"`
+								CursorType string `json:"cursorType"`
+							} `json:"cursor"`
+						} `json:"operation"`
+					} `json:"content"`
+				} `json:"entries"`
+			} `json:"addEntries"`
+		} `json:"instructions"`
+	} `json:"timeline"`
+}
+
+// parse returns the notifications of the timeline entries, in timeline
+// order, and the cursor of the next page. Mentions and replies are timeline
+// tweets; likes, retweets, follows and the rest are aggregated notifications.
+func (r *notificationsResponse) parse() ([]Notification, string) {
+	objects := r.GlobalObjects
+	notifications := make([]Notification, 0)
+	var cursor string
+	for _, instruction := range r.Timeline.Instructions {
+		for _, entry := range instruction.AddEntries.Entries {
+			content := entry.Content
+			if content.Operation.Cursor.CursorType == "Bottom" {
+				cursor = content.Operation.Cursor.Value
+				continue
+			}
+
+			if tweetID := content.Item.Content.Tweet.ID; tweetID != "" {
+				tweet := objects.Tweets[tweetID]
+				notification := Notification{
+					ID:      entry.EntryID,
+					Type:    NotificationMention,
+					Actor:   objects.Users[tweet.UserID].ScreenName,
+					ActorID: tweet.UserID,
+					TweetID: tweetID,
+				}
+				if tweet.InReplyToStatusID != "" {
+					notification.Type = NotificationReply
+				}
+				if createdAt, err := time.Parse(time.RubyDate, tweet.CreatedAt); err == nil {
+					notification.Timestamp = createdAt
+				}
+				notifications = append(notifications, notification)
+				continue
+			}
+
+			id := content.Item.Content.Notification.ID
+			aggregated, ok := objects.Notifications[id]
+			if id == "" || !ok {
+				continue
+			}
+			notification := Notification{
+				ID:      id,
+				Type:    NotificationOther,
+				Message: aggregated.Message.Text,
+			}
+			if notificationType, ok := notificationIconTypes[aggregated.Icon.ID]; ok {
+				notification.Type = notificationType
+			}
+			template := aggregated.Template.AggregateUserActionsV1
+			if len(template.FromUsers) > 0 {
+				notification.ActorID = template.FromUsers[0].User.ID
+				notification.Actor = objects.Users[notification.ActorID].ScreenName
+			}
+			if len(template.TargetObjects) > 0 {
+				notification.TweetID = template.TargetObjects[0].Tweet.ID
+			}
+			if ms, err := strconv.ParseInt(aggregated.TimestampMs, 10, 64); err == nil {
+				notification.Timestamp = time.UnixMilli(ms).UTC()
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, cursor
+}
+
+// handleGetNotifications gets a page of the notifications of the agent's
+// own account
+func (a *Agent) handleGetNotifications(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	cursor, _ := request.Params.Arguments["cursor"].(string)
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_notifications"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	notifications, next, err := a.scraper.GetNotifications(ctx, cursor)
+	a.limiter.doneEndpoint("get_notifications")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting notifications: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	if notifications == nil {
+		notifications = make([]Notification, 0)
+	}
+
+	jsonData, err := json.Marshal(NotificationsPage{Notifications: notifications, NextCursor: next})
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// GetNotifications gets a page of the notifications of the account of the
+// agent named agentUsername, starting at cursor or at the newest when it is
+// empty. Notifications belong to an account, so unlike most calls the agent
+// has to be named; it is never picked by rotation.
+func (am *AgentManager) GetNotifications(ctx context.Context, agentUsername string, cursor string) (*NotificationsPage, string, error) {
+	logger := am.requestLogger(ctx)
+	if agentUsername == "" {
+		return nil, "", fmt.Errorf("%w: notifications are per account, so an agent must be named", ErrInvalidAgentIndex)
+	}
+	agent, agentUsername, err := am.resolveAgent(ctx, agentUsername)
+	if err != nil {
+		return nil, "", err
+	}
+	logger.Debug("Getting notifications of agent %s", agentUsername)
+
+	result, err := agent.handleGetNotifications(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_notifications",
+			Arguments: map[string]interface{}{
+				"cursor": cursor,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting notifications of agent %s: %v", agentUsername, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for notifications of agent %s: %s", agentUsername, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var page NotificationsPage
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &page); err != nil {
+		logger.Error("Error unmarshaling notifications of agent %s: %v", agentUsername, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved %d notifications of agent %s", len(page.Notifications), agentUsername)
+	return &page, agentUsername, nil
+}
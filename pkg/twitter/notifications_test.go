@@ -0,0 +1,86 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotifications(t *testing.T) {
+	body := `{
+		"globalObjects": {
+			"users": {"1": {"screen_name": "alice"}, "2": {"screen_name": "bob"}},
+			"tweets": {
+				"100": {"user_id_str": "1", "created_at": "Mon Jan 02 15:04:05 +0000 2006"},
+				"101": {"user_id_str": "2", "in_reply_to_status_id_str": "50", "created_at": "Mon Jan 02 15:04:05 +0000 2006"}
+			},
+			"notifications": {
+				"n1": {
+					"timestampMs": "1136214245000",
+					"icon": {"id": "heart_icon"},
+					"message": {"text": "bob and alice liked your post"},
+					"template": {"aggregateUserActionsV1": {
+						"targetObjects": [{"tweet": {"id": "50"}}],
+						"fromUsers": [{"user": {"id": "2"}}, {"user": {"id": "1"}}]
+					}}
+				},
+				"n2": {"icon": {"id": "bell_icon"}, "message": {"text": "New login"}}
+			}
+		},
+		"timeline": {"instructions": [{"addEntries": {"entries": [
+			{"entryId": "cursor-top-1", "content": {"operation": {"cursor": {"value": "top", "cursorType": "Top"}}}},
+			{"entryId": "notification-100", "content": {"item": {"content": {"tweet": {"id": "100"}}}}},
+			{"entryId": "notification-101", "content": {"item": {"content": {"tweet": {"id": "101"}}}}},
+			{"entryId": "notification-n1", "content": {"item": {"content": {"notification": {"id": "n1"}}}}},
+			{"entryId": "notification-n2", "content": {"item": {"content": {"notification": {"id": "n2"}}}}},
+			{"entryId": "notification-n3", "content": {"item": {"content": {"notification": {"id": "n3"}}}}},
+			{"entryId": "cursor-bottom-1", "content": {"operation": {"cursor": {"value": "older", "cursorType": "Bottom"}}}}
+		]}}]}
+	}`
+	var response notificationsResponse
+	assert.NoError(t, json.Unmarshal([]byte(body), &response))
+
+	notifications, cursor := response.parse()
+	assert.Equal(t, "older", cursor)
+	created := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	if assert.Len(t, notifications, 4) {
+		assert.Equal(t, Notification{ID: "notification-100", Type: NotificationMention, Actor: "alice", ActorID: "1", TweetID: "100"}, withoutTimestamp(notifications[0]))
+		assert.True(t, created.Equal(notifications[0].Timestamp))
+		assert.Equal(t, Notification{ID: "notification-101", Type: NotificationReply, Actor: "bob", ActorID: "2", TweetID: "101"}, withoutTimestamp(notifications[1]))
+		assert.Equal(t, Notification{ID: "n1", Type: NotificationLike, Actor: "bob", ActorID: "2", TweetID: "50", Message: "bob and alice liked your post", Timestamp: created}, notifications[2])
+		assert.Equal(t, Notification{ID: "n2", Type: NotificationOther, Message: "New login"}, notifications[3])
+	}
+}
+
+// withoutTimestamp returns n without its timestamp, whose location depends on parsing
+func withoutTimestamp(n Notification) Notification {
+	n.Timestamp = time.Time{}
+	return n
+}
+
+func TestGetNotifications(t *testing.T) {
+	agent := newMockAgent()
+	agent.username = "alice"
+	agent.scraper = &mockScraper{isLoggedIn: true}
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{
+		agents: []*Agent{agent},
+		logger: logging.Default(),
+	}
+
+	// Notifications are per account, so the agent must be named
+	_, _, err := manager.GetNotifications(context.Background(), "", "")
+	assert.ErrorIs(t, err, ErrInvalidAgentIndex)
+	_, _, err = manager.GetNotifications(context.Background(), "bob", "")
+	assert.ErrorIs(t, err, ErrInvalidAgentIndex)
+
+	page, agentUsername, err := manager.GetNotifications(context.Background(), "ALICE", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", agentUsername)
+	assert.Equal(t, "next", page.NextCursor)
+	assert.Equal(t, []Notification{{ID: "1", Type: NotificationLike, Actor: "alice", TweetID: "42"}}, page.Notifications)
+}
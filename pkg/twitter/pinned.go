@@ -0,0 +1,21 @@
+package twitter
+
+import (
+	"context"
+)
+
+// addPinnedTweet fetches the pinned tweet of profile into it. A failed
+// fetch is reported in PinnedTweetError rather than failing the profile.
+func (a *Agent) addPinnedTweet(ctx context.Context, profile *UserProfile) {
+	if len(profile.PinnedTweetIDs) == 0 {
+		return
+	}
+
+	tweet, _, err := a.lookupTweet(ctx, profile.PinnedTweetIDs[0])
+	if err != nil {
+		a.logger.Warning("Error getting pinned tweet %s of %s: %v", profile.PinnedTweetIDs[0], profile.Username, err)
+		profile.PinnedTweetError = err.Error()
+		return
+	}
+	profile.PinnedTweet = tweet
+}
@@ -0,0 +1,36 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddPinnedTweet(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &tweetMapScraper{tweets: map[string]*twitterscraper.Tweet{"1": {ID: "1", Text: "pinned"}}}
+
+	t.Run("resolves the pinned tweet", func(t *testing.T) {
+		profile := &UserProfile{PinnedTweetIDs: []string{"1"}}
+		agent.addPinnedTweet(context.Background(), profile)
+		assert.Equal(t, "pinned", profile.PinnedTweet.Text)
+		assert.Empty(t, profile.PinnedTweetError)
+	})
+
+	t.Run("no pinned tweet", func(t *testing.T) {
+		profile := &UserProfile{}
+		agent.addPinnedTweet(context.Background(), profile)
+		assert.Nil(t, profile.PinnedTweet)
+		assert.Empty(t, profile.PinnedTweetError)
+	})
+
+	t.Run("keeps the profile when the fetch fails", func(t *testing.T) {
+		profile := &UserProfile{Username: "alice", PinnedTweetIDs: []string{"2"}}
+		agent.addPinnedTweet(context.Background(), profile)
+		assert.Equal(t, "alice", profile.Username)
+		assert.Nil(t, profile.PinnedTweet)
+		assert.Equal(t, "tweet 2 not found", profile.PinnedTweetError)
+	})
+}
@@ -0,0 +1,60 @@
+package twitter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	minPollOptions = 2
+	maxPollOptions = 4
+	// minPollDuration and maxPollDuration are Twitter's bounds in minutes (5 minutes to 7 days)
+	minPollDuration = 5
+	maxPollDuration = 7 * 24 * 60
+	// defaultPollDuration is used when a poll doesn't set a duration (1 day)
+	defaultPollDuration = 24 * 60
+)
+
+// Poll is a poll attached to a new tweet
+type Poll struct {
+	Options         []string `json:"options"`
+	DurationMinutes int      `json:"duration_minutes,omitempty"`
+}
+
+// Validate checks the poll against Twitter's limits
+func (p *Poll) Validate() error {
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return fmt.Errorf("poll must have between %d and %d options, got %d", minPollOptions, maxPollOptions, len(p.Options))
+	}
+	for i, option := range p.Options {
+		if option == "" {
+			return fmt.Errorf("poll option %d is empty", i+1)
+		}
+	}
+	if p.DurationMinutes < minPollDuration || p.DurationMinutes > maxPollDuration {
+		return fmt.Errorf("poll duration must be between %d and %d minutes, got %d", minPollDuration, maxPollDuration, p.DurationMinutes)
+	}
+	return nil
+}
+
+// parsePollArgument reads the poll tool argument, which arrives either as
+// decoded JSON from an MCP client or as a *Poll from the AgentManager
+func parsePollArgument(value interface{}) (*Poll, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid poll: %v", err)
+	}
+
+	var poll Poll
+	if err := json.Unmarshal(data, &poll); err != nil {
+		return nil, fmt.Errorf("invalid poll: %v", err)
+	}
+	if poll.DurationMinutes == 0 {
+		poll.DurationMinutes = defaultPollDuration
+	}
+
+	if err := poll.Validate(); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
@@ -0,0 +1,89 @@
+package twitter
+
+import (
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// UserProfile is the JSON shape of a user profile. twitter-scraper's Profile
+// has no JSON tags, so it is mapped field by field to keep the keys stable
+// whatever the library renames.
+type UserProfile struct {
+	UserID               string     `json:"user_id"`
+	Username             string     `json:"username"`
+	Name                 string     `json:"name"`
+	Biography            string     `json:"bio"`
+	AvatarURL            string     `json:"avatar_url"`
+	BannerURL            string     `json:"banner_url"`
+	Birthday             string     `json:"birthday"`
+	Location             string     `json:"location"`
+	URL                  string     `json:"url"`
+	Website              string     `json:"website"`
+	Joined               *time.Time `json:"joined"`
+	TweetsCount          int        `json:"tweets"`
+	LikesCount           int        `json:"likes"`
+	MediaCount           int        `json:"media"`
+	FollowersCount       int        `json:"followers"`
+	FollowingCount       int        `json:"following"`
+	FriendsCount         int        `json:"friends"`
+	NormalFollowersCount int        `json:"normal_followers"`
+	FastFollowersCount   int        `json:"fast_followers"`
+	ListedCount          int        `json:"listed"`
+	IsVerified           bool       `json:"verified"`
+	IsPrivate            bool       `json:"private"`
+	IsBlueVerified       bool       `json:"blue_verified"`
+	CanHighlightTweets   bool       `json:"can_highlight_tweets"`
+	HasGraduatedAccess   bool       `json:"has_graduated_access"`
+	Sensitive            bool       `json:"sensitive"`
+	ProfileImageShape    string     `json:"profile_image_shape"`
+	// ViewerFollows and FollowsViewer are the relationship between the user
+	// and the account that fetched the profile
+	ViewerFollows  bool     `json:"viewer_follows"`
+	FollowsViewer  bool     `json:"follows_viewer"`
+	PinnedTweetIDs []string `json:"pinned_tweet_ids"`
+	// PinnedTweet is the first pinned tweet, when requested with
+	// include_pinned; PinnedTweetError says why it couldn't be fetched
+	PinnedTweet      *twitterscraper.Tweet `json:"pinned_tweet"`
+	PinnedTweetError string                `json:"pinned_tweet_error,omitempty"`
+}
+
+// newUserProfile converts a scraper profile into a UserProfile
+func newUserProfile(profile *twitterscraper.Profile) UserProfile {
+	pinnedTweetIDs := profile.PinnedTweetIDs
+	if pinnedTweetIDs == nil {
+		pinnedTweetIDs = []string{}
+	}
+	return UserProfile{
+		UserID:               profile.UserID,
+		Username:             profile.Username,
+		Name:                 profile.Name,
+		Biography:            profile.Biography,
+		AvatarURL:            profile.Avatar,
+		BannerURL:            profile.Banner,
+		Birthday:             profile.Birthday,
+		Location:             profile.Location,
+		URL:                  profile.URL,
+		Website:              profile.Website,
+		Joined:               profile.Joined,
+		TweetsCount:          profile.TweetsCount,
+		LikesCount:           profile.LikesCount,
+		MediaCount:           profile.MediaCount,
+		FollowersCount:       profile.FollowersCount,
+		FollowingCount:       profile.FollowingCount,
+		FriendsCount:         profile.FriendsCount,
+		NormalFollowersCount: profile.NormalFollowersCount,
+		FastFollowersCount:   profile.FastFollowersCount,
+		ListedCount:          profile.ListedCount,
+		IsVerified:           profile.IsVerified,
+		IsPrivate:            profile.IsPrivate,
+		IsBlueVerified:       profile.IsBlueVerified,
+		CanHighlightTweets:   profile.CanHighlightTweets,
+		HasGraduatedAccess:   profile.HasGraduatedAccess,
+		Sensitive:            profile.Sensitive,
+		ProfileImageShape:    profile.ProfileImageShape,
+		ViewerFollows:        profile.Following,
+		FollowsViewer:        profile.FollowedBy,
+		PinnedTweetIDs:       pinnedTweetIDs,
+	}
+}
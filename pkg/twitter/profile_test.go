@@ -0,0 +1,51 @@
+package twitter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserProfileRoundTrip(t *testing.T) {
+	joined := time.Date(2010, 6, 1, 0, 0, 0, 0, time.UTC)
+	scraped := &twitterscraper.Profile{
+		Avatar: "https://pbs.twimg.com/a.jpg", Banner: "https://pbs.twimg.com/b.jpg",
+		Biography: "bio", Birthday: "1990-01-01", Location: "Berlin", Name: "Alice",
+		URL: "https://twitter.com/alice", UserID: "42", Username: "alice", Website: "https://alice.dev",
+		Joined: &joined, FollowersCount: 1, FollowingCount: 2, FriendsCount: 3, LikesCount: 4,
+		ListedCount: 5, TweetsCount: 6, MediaCount: 7, FastFollowersCount: 8, NormalFollowersCount: 9,
+		IsPrivate: true, IsVerified: true, IsBlueVerified: true, Sensitive: true, Following: true,
+		FollowedBy: true, HasGraduatedAccess: true, CanHighlightTweets: true,
+		ProfileImageShape: "Circle", PinnedTweetIDs: []string{"1"},
+	}
+	profile := newUserProfile(scraped)
+
+	// Every field of the scraper profile is carried over
+	value := reflect.ValueOf(profile)
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Name
+		if name == "PinnedTweet" || name == "PinnedTweetError" {
+			continue
+		}
+		assert.False(t, value.Field(i).IsZero(), "%s isn't set", name)
+	}
+
+	data, err := json.Marshal(profile)
+	assert.NoError(t, err)
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "Berlin", fields["location"])
+	assert.Equal(t, "https://alice.dev", fields["website"])
+	assert.Equal(t, "https://twitter.com/alice", fields["url"])
+	assert.Equal(t, "bio", fields["bio"])
+	assert.Equal(t, float64(1), fields["followers"])
+	assert.Contains(t, fields, "pinned_tweet")
+
+	var decoded UserProfile
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, profile, decoded)
+}
@@ -0,0 +1,177 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// quoteTweetsPageSize is the number of quote tweets requested per page
+const quoteTweetsPageSize = 20
+
+// QuoteTweetsPage is a page of the tweets quoting a tweet
+type QuoteTweetsPage struct {
+	Tweets []SimplifiedTweet `json:"tweets"`
+	// NextCursor fetches the next page; empty once no more quotes are found
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// quoteTweetsQuery is the search query matching the tweets that quote tweetID
+func quoteTweetsQuery(tweetID string) string {
+	return "quoted_tweet_id:" + tweetID
+}
+
+// handleGetQuoteTweets gets a page of the tweets quoting a tweet. Twitter
+// has no endpoint listing quotes, so they are found with the search operator
+// quoted_tweet_id. Search only covers what Twitter indexes for it, so quotes
+// by protected, suspended or filtered accounts and some older quotes are
+// missing; the result is a sample rather than a complete list.
+func (a *Agent) handleGetQuoteTweets(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "tweet_id parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	if !validTweetID(tweetID) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("invalid tweet_id %q, expected a tweet ID", tweetID),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	cursor, _ := request.Params.Arguments["cursor"].(string)
+
+	// Quotes come from search, so they count against its limit
+	if err := a.limiter.waitForEndpoint(ctx, "search_tweets"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweets, next, err := a.scraper.FetchSearchTweets(ctx, quoteTweetsQuery(tweetID), quoteTweetsPageSize, cursor)
+	a.limiter.doneEndpoint("search_tweets")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting quote tweets: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	page := QuoteTweetsPage{Tweets: make([]SimplifiedTweet, 0, len(tweets))}
+	for _, tweet := range tweets {
+		if tweet.ID == tweetID {
+			continue
+		}
+		page.Tweets = append(page.Tweets, newSimplifiedTweet(tweet))
+	}
+	// Search keeps returning a cursor past the last result
+	if len(tweets) > 0 {
+		page.NextCursor = next
+	}
+
+	jsonData, err := json.Marshal(page)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// GetQuoteTweets gets a page of the tweets quoting tweetID using the next
+// available agent, starting at cursor or at the first page when it is empty.
+// The quotes are found by search, so they may be incomplete.
+func (am *AgentManager) GetQuoteTweets(ctx context.Context, tweetID string, cursor string) (*QuoteTweetsPage, string, error) {
+	logger := am.requestLogger(ctx)
+	if !validTweetID(tweetID) {
+		return nil, "", fmt.Errorf("%w: invalid tweet ID %q", ErrInvalidTweetRequest, tweetID)
+	}
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting quotes of tweet %s using agent %s", tweetID, agentUsername)
+
+	result, err := agent.handleGetQuoteTweets(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_quotes",
+			Arguments: map[string]interface{}{
+				"tweet_id": tweetID,
+				"cursor":   cursor,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting quotes of tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for quotes of tweet %s: %s", tweetID, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var page QuoteTweetsPage
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &page); err != nil {
+		logger.Error("Error unmarshaling quotes of tweet %s: %v", tweetID, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved %d quotes of tweet %s", len(page.Tweets), tweetID)
+	return &page, agentUsername, nil
+}
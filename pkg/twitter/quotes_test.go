@@ -0,0 +1,58 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// quoteScraper answers searches with two quotes of tweet 100 on the first
+// page, and the quoted tweet itself, and nothing after
+type quoteScraper struct {
+	mockScraper
+	query string
+}
+
+func (s *quoteScraper) FetchSearchTweets(ctx context.Context, query string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	s.query = query
+	if cursor != "" {
+		return nil, "end", nil
+	}
+	return []*twitterscraper.Tweet{
+		{ID: "102", Text: "so true", Username: "bob", QuotedStatusID: "100"},
+		{ID: "100", Text: "original", Username: "alice"},
+		{ID: "101", Text: "nope", Username: "carol", QuotedStatusID: "100"},
+	}, "page2", nil
+}
+
+func TestGetQuoteTweets(t *testing.T) {
+	scraper := &quoteScraper{mockScraper: mockScraper{isLoggedIn: true}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	_, _, err := manager.GetQuoteTweets(context.Background(), "not-an-id", "")
+	assert.ErrorIs(t, err, ErrInvalidTweetRequest)
+
+	page, _, err := manager.GetQuoteTweets(context.Background(), "100", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "quoted_tweet_id:100", scraper.query)
+	assert.Equal(t, "page2", page.NextCursor)
+	var ids []string
+	for _, tweet := range page.Tweets {
+		ids = append(ids, tweet.ID)
+	}
+	assert.Equal(t, []string{"102", "101"}, ids)
+
+	// The last page has no cursor
+	agent.limiter.lastCallTime = time.Time{}
+	page, _, err = manager.GetQuoteTweets(context.Background(), "100", "page2")
+	assert.NoError(t, err)
+	assert.Empty(t, page.Tweets)
+	assert.Empty(t, page.NextCursor)
+}
@@ -0,0 +1,154 @@
+package twitter
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitedEndpoints are the endpoints the rate limiter counts calls under
+var RateLimitedEndpoints = []string{
+	"get_user_tweets", "get_media_tweets", "get_profile", "get_tweet", "search_tweets",
+	"create_tweet", "like_tweet", "unlike_tweet", "retweet", "follow_user", "unfollow_user",
+	"get_followers", "get_following", "get_tweet_replies", "get_relationship", "get_trends",
+	"get_notifications", "get_list_tweets", "get_list_members",
+}
+
+// isRateLimitedEndpoint reports whether endpoint is one of RateLimitedEndpoints
+func isRateLimitedEndpoint(endpoint string) bool {
+	for _, e := range RateLimitedEndpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// EndpointWindow is the state of an agent's call window for an endpoint.
+// WindowStart and ResetAt are unset while no window is open, either because
+// the endpoint wasn't called yet or because its last window is over.
+type EndpointWindow struct {
+	MaxCalls    int        `json:"max_calls"`
+	Calls       int        `json:"calls"`
+	Remaining   int        `json:"remaining"`
+	WindowStart *time.Time `json:"window_start,omitempty"`
+	ResetAt     *time.Time `json:"reset_at,omitempty"`
+	// InFlight is how many calls hold a concurrency slot, out of
+	// MaxConcurrent; both are 0 when the endpoint isn't capped
+	InFlight      int `json:"in_flight"`
+	MaxConcurrent int `json:"max_concurrent"`
+}
+
+// GlobalSpacing is the state of the least time an agent leaves between two
+// calls, over all endpoints
+type GlobalSpacing struct {
+	MinIntervalMs int64     `json:"min_interval_ms"`
+	LastCallAt    time.Time `json:"last_call_at"`
+	// NextCallAt is the earliest time the next call can be made
+	NextCallAt time.Time `json:"next_call_at"`
+}
+
+// limiterSnapshot is the state of a rate limiter at one point in time
+type limiterSnapshot struct {
+	global    GlobalSpacing
+	endpoints map[string]EndpointWindow
+}
+
+// snapshot returns the state of the global spacing and of the windows of
+// every endpoint that was called or is capped
+func (r *rateLimiter) snapshot() limiterSnapshot {
+	now := time.Now()
+
+	r.mu.Lock()
+	lastCall := r.lastCallTime
+	limits := make(map[string]*endpointLimit, len(r.endpointCalls))
+	for endpoint, limit := range r.endpointCalls {
+		limits[endpoint] = limit
+	}
+	r.mu.Unlock()
+
+	snapshot := limiterSnapshot{
+		global: GlobalSpacing{
+			MinIntervalMs: globalCallInterval.Milliseconds(),
+			LastCallAt:    lastCall,
+			NextCallAt:    lastCall.Add(globalCallInterval),
+		},
+		endpoints: make(map[string]EndpointWindow),
+	}
+	if snapshot.global.NextCallAt.Before(now) {
+		snapshot.global.NextCallAt = now
+	}
+
+	for endpoint, limit := range limits {
+		limit.mu.Lock()
+		window := EndpointWindow{MaxCalls: limit.maxCalls, Remaining: limit.maxCalls}
+		// An elapsed window is reset by the next call
+		if now.Sub(limit.windowStart) <= limit.windowLength {
+			start, reset := limit.windowStart, limit.windowStart.Add(limit.windowLength)
+			window.Calls = limit.calls
+			window.Remaining = max(limit.maxCalls-limit.calls, 0)
+			window.WindowStart, window.ResetAt = &start, &reset
+		}
+		limit.mu.Unlock()
+		snapshot.endpoints[endpoint] = window
+	}
+
+	r.concurrencyMu.Lock()
+	for endpoint, sem := range r.inFlight {
+		window, ok := snapshot.endpoints[endpoint]
+		if !ok {
+			window = newEndpointWindow()
+		}
+		window.InFlight, window.MaxConcurrent = len(sem), cap(sem)
+		snapshot.endpoints[endpoint] = window
+	}
+	r.concurrencyMu.Unlock()
+
+	return snapshot
+}
+
+// newEndpointWindow returns the state of an endpoint that wasn't called yet
+func newEndpointWindow() EndpointWindow {
+	return EndpointWindow{MaxCalls: endpointMaxCalls, Remaining: endpointMaxCalls}
+}
+
+// AgentEndpointWindow is an agent's rate limit state for one endpoint
+type AgentEndpointWindow struct {
+	Agent string `json:"agent"`
+	EndpointWindow
+	Global GlobalSpacing `json:"global"`
+}
+
+// EndpointRateLimits is the rate limit state of one endpoint for every agent
+type EndpointRateLimits struct {
+	Endpoint string                `json:"endpoint"`
+	Agents   []AgentEndpointWindow `json:"agents"`
+}
+
+// EndpointRateLimits returns the call window of endpoint and the global
+// spacing of every agent, for finding out why calls to it are slow. An
+// unknown endpoint is an error wrapping ErrNotFound.
+func (am *AgentManager) EndpointRateLimits(endpoint string) (EndpointRateLimits, error) {
+	if !isRateLimitedEndpoint(endpoint) {
+		return EndpointRateLimits{}, fmt.Errorf("%w: unknown endpoint %s", ErrNotFound, endpoint)
+	}
+
+	am.mutex.RLock()
+	agents := make([]*Agent, len(am.agents))
+	copy(agents, am.agents)
+	am.mutex.RUnlock()
+
+	limits := EndpointRateLimits{Endpoint: endpoint, Agents: make([]AgentEndpointWindow, 0, len(agents))}
+	for _, agent := range agents {
+		snapshot := agent.limiter.snapshot()
+		window, ok := snapshot.endpoints[endpoint]
+		if !ok {
+			window = newEndpointWindow()
+		}
+		limits.Agents = append(limits.Agents, AgentEndpointWindow{
+			Agent:          agent.username,
+			EndpointWindow: window,
+			Global:         snapshot.global,
+		})
+	}
+	return limits, nil
+}
@@ -7,10 +7,26 @@ import (
 	"time"
 )
 
+// globalCallInterval is the least time between two calls of an agent, over
+// all endpoints
+const globalCallInterval = 1500 * time.Millisecond
+
+const (
+	// endpointWindowLength is the window an endpoint's calls are counted in
+	endpointWindowLength = 15 * time.Minute
+	// endpointMaxCalls is how many calls to an endpoint a window allows
+	endpointMaxCalls = 100
+)
+
 type rateLimiter struct {
 	mu            sync.Mutex
 	lastCallTime  time.Time
 	endpointCalls map[string]*endpointLimit
+
+	concurrencyMu sync.Mutex
+	// inFlight holds a semaphore for each endpoint whose concurrent calls
+	// are capped, with a slot per call allowed at once
+	inFlight map[string]chan struct{}
 }
 
 type endpointLimit struct {
@@ -42,8 +58,8 @@ func (r *rateLimiter) waitForGlobalLimit() error {
 	defer r.mu.Unlock()
 
 	elapsed := time.Since(r.lastCallTime)
-	if elapsed < 1500*time.Millisecond {
-		waitTime := 1500*time.Millisecond - elapsed
+	if elapsed < globalCallInterval {
+		waitTime := globalCallInterval - elapsed
 		time.Sleep(waitTime)
 	}
 	r.lastCallTime = time.Now()
@@ -55,8 +71,8 @@ func (r *rateLimiter) checkEndpointLimit(endpoint string) (bool, time.Duration)
 	limit, exists := r.endpointCalls[endpoint]
 	if !exists {
 		limit = &endpointLimit{
-			windowLength: 15 * time.Minute,
-			maxCalls:     100,
+			windowLength: endpointWindowLength,
+			maxCalls:     endpointMaxCalls,
 			windowStart:  time.Now(),
 		}
 		r.endpointCalls[endpoint] = limit
@@ -84,7 +100,60 @@ func (r *rateLimiter) checkEndpointLimit(endpoint string) (bool, time.Duration)
 	return true, 0
 }
 
+// setConcurrency caps how many calls to each endpoint of limits run at once.
+// Endpoints missing from limits, or with a limit of 0, aren't capped.
+func (r *rateLimiter) setConcurrency(limits map[string]int) {
+	inFlight := make(map[string]chan struct{})
+	for endpoint, limit := range limits {
+		if limit > 0 {
+			inFlight[endpoint] = make(chan struct{}, limit)
+		}
+	}
+	r.concurrencyMu.Lock()
+	r.inFlight = inFlight
+	r.concurrencyMu.Unlock()
+}
+
+// semaphore returns the concurrency semaphore of endpoint, nil when uncapped
+func (r *rateLimiter) semaphore(endpoint string) chan struct{} {
+	r.concurrencyMu.Lock()
+	defer r.concurrencyMu.Unlock()
+	return r.inFlight[endpoint]
+}
+
+// waitForEndpoint waits until a call to endpoint is allowed: a concurrency
+// slot is free, if the endpoint is capped, and the call fits the endpoint's
+// window and the global spacing. Every call it allows must be ended with
+// doneEndpoint once the request to Twitter is over.
 func (r *rateLimiter) waitForEndpoint(ctx context.Context, endpoint string) error {
+	if sem := r.semaphore(endpoint); sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := r.waitForCallLimit(ctx, endpoint); err != nil {
+		r.doneEndpoint(endpoint)
+		return err
+	}
+	return nil
+}
+
+// doneEndpoint ends a call to endpoint allowed by waitForEndpoint, freeing
+// its concurrency slot for the next waiting call
+func (r *rateLimiter) doneEndpoint(endpoint string) {
+	if sem := r.semaphore(endpoint); sem != nil {
+		select {
+		case <-sem:
+		default:
+		}
+	}
+}
+
+// waitForCallLimit waits until a call to endpoint fits its window and the
+// global spacing
+func (r *rateLimiter) waitForCallLimit(ctx context.Context, endpoint string) error {
 	for {
 		select {
 		case <-ctx.Done():
@@ -0,0 +1,103 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointConcurrency(t *testing.T) {
+	limiter := newRateLimiter()
+	limiter.setConcurrency(map[string]int{"search_tweets": 1})
+	// Skip the spacing between calls, it isn't what is tested here
+	skipSpacing := func() {
+		limiter.mu.Lock()
+		limiter.lastCallTime = time.Time{}
+		limiter.mu.Unlock()
+	}
+
+	skipSpacing()
+	assert.NoError(t, limiter.waitForEndpoint(context.Background(), "search_tweets"))
+
+	// A second call over the cap blocks until its context is done
+	skipSpacing()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.waitForEndpoint(ctx, "search_tweets"), context.DeadlineExceeded)
+
+	// Uncapped endpoints aren't held up
+	skipSpacing()
+	assert.NoError(t, limiter.waitForEndpoint(context.Background(), "get_profile"))
+	limiter.doneEndpoint("get_profile")
+
+	// A blocked call goes ahead once the running one is done
+	skipSpacing()
+	waited := make(chan error, 1)
+	go func() { waited <- limiter.waitForEndpoint(context.Background(), "search_tweets") }()
+	select {
+	case err := <-waited:
+		t.Fatalf("call over the cap didn't block: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	limiter.doneEndpoint("search_tweets")
+	select {
+	case err := <-waited:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("call wasn't let through after doneEndpoint")
+	}
+	limiter.doneEndpoint("search_tweets")
+
+	// Releasing more than was acquired doesn't raise the cap
+	limiter.doneEndpoint("search_tweets")
+	skipSpacing()
+	assert.NoError(t, limiter.waitForEndpoint(context.Background(), "search_tweets"))
+	skipSpacing()
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.waitForEndpoint(ctx, "search_tweets"), context.DeadlineExceeded)
+}
+
+func TestRateLimiterSnapshot(t *testing.T) {
+	limiter := newRateLimiter()
+	limiter.setConcurrency(map[string]int{"search_tweets": 2})
+	limiter.lastCallTime = time.Time{}
+	assert.NoError(t, limiter.waitForEndpoint(context.Background(), "get_tweet"))
+	limiter.doneEndpoint("get_tweet")
+
+	snapshot := limiter.snapshot()
+	assert.Equal(t, int64(1500), snapshot.global.MinIntervalMs)
+	assert.Equal(t, snapshot.global.LastCallAt.Add(globalCallInterval), snapshot.global.NextCallAt)
+
+	window := snapshot.endpoints["get_tweet"]
+	assert.Equal(t, 1, window.Calls)
+	assert.Equal(t, 99, window.Remaining)
+	if assert.NotNil(t, window.WindowStart) && assert.NotNil(t, window.ResetAt) {
+		assert.Equal(t, 15*time.Minute, window.ResetAt.Sub(*window.WindowStart))
+	}
+
+	// Capped endpoints show up before they are called
+	assert.Equal(t, EndpointWindow{MaxCalls: 100, Remaining: 100, MaxConcurrent: 2}, snapshot.endpoints["search_tweets"])
+	_, ok := snapshot.endpoints["get_profile"]
+	assert.False(t, ok)
+}
+
+func TestEndpointRateLimits(t *testing.T) {
+	agent := newMockAgent()
+	am := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	limits, err := am.EndpointRateLimits("get_profile")
+	assert.NoError(t, err)
+	assert.Equal(t, "get_profile", limits.Endpoint)
+	if assert.Len(t, limits.Agents, 1) {
+		assert.Equal(t, agent.username, limits.Agents[0].Agent)
+		assert.Equal(t, 100, limits.Agents[0].Remaining)
+		assert.Nil(t, limits.Agents[0].WindowStart)
+	}
+
+	_, err = am.EndpointRateLimits("get_everything")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
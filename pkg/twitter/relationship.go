@@ -0,0 +1,59 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnknownSourceAccount is returned when a relationship is asked for from a
+// user that isn't one of the manager's logged-in accounts
+var ErrUnknownSourceAccount = errors.New("source must be the user ID of a logged-in account")
+
+// Relationship describes how a target user relates to a source user.
+// Blocked and Muted are only set when the scraper can tell; twitter-scraper
+// doesn't expose block or mute state, so they are currently always omitted.
+type Relationship struct {
+	SourceUserID string `json:"source_user_id,omitempty"`
+	TargetUserID string `json:"target_user_id"`
+	// Following is set when the source follows the target
+	Following bool `json:"following"`
+	// FollowedBy is set when the target follows the source
+	FollowedBy bool `json:"followed_by"`
+	Blocked    bool `json:"blocked,omitempty"`
+	Muted      bool `json:"muted,omitempty"`
+}
+
+// agentForUserID returns the logged-in agent whose account has the given
+// user ID. Account profiles are cached, so this rarely calls Twitter.
+func (am *AgentManager) agentForUserID(ctx context.Context, userID string) (*Agent, error) {
+	am.mutex.RLock()
+	agents := make([]*Agent, len(am.agents))
+	copy(agents, am.agents)
+	am.mutex.RUnlock()
+
+	// Accounts that can't be checked are reported as skipped
+	var skipped []string
+	for i, agent := range agents {
+		if !agent.IsLoggedIn() {
+			skipped = append(skipped, agent.username)
+			continue
+		}
+		profile, err := am.accountProfile(ctx, agent)
+		if err != nil {
+			am.requestLogger(ctx).Error("Failed to get profile for account %s: %v", agent.username, err)
+			skipped = append(skipped, agent.username)
+			continue
+		}
+		if profile.userID == userID {
+			am.recordSelection(ctx, AgentSelection{
+				Strategy: SelectionByUserID,
+				Agent:    agent.username,
+				Index:    i,
+				PoolSize: len(agents),
+				Skipped:  skipped,
+			})
+			return agent, nil
+		}
+	}
+	return nil, ErrUnknownSourceAccount
+}
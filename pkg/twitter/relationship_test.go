@@ -0,0 +1,63 @@
+package twitter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// relationshipScraper is logged in as userID and follows every target
+type relationshipScraper struct {
+	mockScraper
+	userID string
+}
+
+func (s *relationshipScraper) GetProfile(ctx context.Context, username string) (*twitterscraper.Profile, error) {
+	return &twitterscraper.Profile{UserID: s.userID, Username: username}, nil
+}
+
+func (s *relationshipScraper) GetRelationship(ctx context.Context, sourceUserID, targetUserID string) (bool, bool, bool, bool, error) {
+	return true, false, false, false, nil
+}
+
+func TestGetRelationship(t *testing.T) {
+	newAgent := func(username, userID string) *Agent {
+		agent := newMockAgent()
+		agent.username = username
+		agent.scraper = &relationshipScraper{mockScraper: mockScraper{isLoggedIn: true}, userID: userID}
+		return agent
+	}
+	manager := &AgentManager{
+		agents:       []*Agent{newAgent("a", "100"), newAgent("b", "200")},
+		logger:       logging.Default(),
+		profileCache: map[string]cachedAccountProfile{},
+	}
+
+	result, agentUsername, err := manager.GetRelationship(context.Background(), "200", "300")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", agentUsername)
+	assert.Equal(t, map[string]interface{}{
+		"source_user_id": "200",
+		"target_user_id": "300",
+		"following":      true,
+		"followed_by":    false,
+	}, result)
+
+	_, _, err = manager.GetRelationship(context.Background(), "999", "300")
+	assert.ErrorIs(t, err, ErrUnknownSourceAccount)
+}
+
+func TestLoggedInUserID(t *testing.T) {
+	scraper := newScraperWrapper()
+	assert.Empty(t, scraper.loggedInUserID())
+
+	scraper.SetCookies([]*http.Cookie{{Name: "twid", Value: `"u=1234"`}})
+	assert.Equal(t, "1234", scraper.loggedInUserID())
+
+	scraper.SetCookies([]*http.Cookie{{Name: "twid", Value: "u%3D5678"}})
+	assert.Equal(t, "5678", scraper.loggedInUserID())
+}
@@ -0,0 +1,160 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccountsReload is the outcome of ReloadAccounts
+type AccountsReload struct {
+	Added   []string `json:"added"`   // Accounts that logged in and joined the rotation
+	Removed []string `json:"removed"` // Accounts no longer configured, dropped from the rotation
+	Failed  []string `json:"failed"`  // Accounts not in the rotation that couldn't log in
+	// Agents is the login status of every configured account after the reload
+	Agents []AgentStartupStatus `json:"agents"`
+}
+
+// ReloadAccounts brings the rotation in line with the accounts currently in
+// accounts.json and XGO_ACCOUNTS_JSON, without a restart. Configured accounts
+// without an agent, new ones or ones that couldn't log in before, are logged
+// in like at startup; agents of accounts no longer configured are dropped.
+// Agents of accounts still configured are kept as they are. The rotation is
+// left unchanged when it would end up empty. Once ctx is done no more
+// accounts are logged in; those already logged in still join the rotation.
+func (am *AgentManager) ReloadAccounts(ctx context.Context) (*AccountsReload, error) {
+	am.reloadMutex.Lock()
+	defer am.reloadMutex.Unlock()
+
+	accounts, err := am.authManager.LoadAllAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("%w: reload would remove every account", ErrNoAccounts)
+	}
+
+	configured := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		configured[strings.ToLower(account.Username)] = true
+	}
+
+	am.mutex.RLock()
+	current := am.agents
+	am.mutex.RUnlock()
+
+	result := &AccountsReload{Added: []string{}, Removed: []string{}, Failed: []string{}}
+	inRotation := make(map[string]bool, len(current))
+	kept := make([]*Agent, 0, len(current))
+	for _, agent := range current {
+		inRotation[strings.ToLower(agent.username)] = true
+		if configured[strings.ToLower(agent.username)] {
+			kept = append(kept, agent)
+		} else {
+			result.Removed = append(result.Removed, agent.username)
+		}
+	}
+
+	var added []*Agent
+	var statuses []AgentStartupStatus
+	throttle := am.newLoginThrottle(ctx)
+	for _, account := range accounts {
+		if inRotation[strings.ToLower(account.Username)] {
+			continue
+		}
+		if ctx.Err() != nil {
+			am.logger.Warning("Reload cancelled, not logging in the remaining accounts")
+			break
+		}
+		agent, err := am.startAccountAgent(account, throttle)
+		if err != nil {
+			if ctx.Err() != nil {
+				am.logger.Warning("Reload cancelled while logging in account %s: %v", account.Username, err)
+				break
+			}
+			var loginErr *LoginError
+			if !errors.As(err, &loginErr) {
+				loginErr = &LoginError{Username: account.Username, Status: AgentStatusFailed, Err: err}
+			}
+			am.logger.Error("Failed to login account %s (%s), skipping it: %v", account.Username, loginErr.Status, loginErr.Err)
+			statuses = append(statuses, AgentStartupStatus{Username: account.Username, Status: loginErr.Status, Error: loginErr.Err.Error()})
+			result.Failed = append(result.Failed, account.Username)
+			continue
+		}
+		added = append(added, agent)
+		statuses = append(statuses, AgentStartupStatus{Username: account.Username, Status: AgentStatusActive})
+		result.Added = append(result.Added, account.Username)
+	}
+
+	agents := append(kept, added...)
+	if len(agents) == 0 {
+		am.logger.Error("None of the %d configured accounts could log in, keeping the current agents", len(accounts))
+		return nil, fmt.Errorf("%w: none of the %d accounts could log in", ErrNoAccounts, len(accounts))
+	}
+
+	// Agents added by Relogin meanwhile are kept
+	am.mutex.Lock()
+	agents = append(agents, am.agents[len(current):]...)
+	am.agents = agents
+	am.mutex.Unlock()
+
+	for _, status := range statuses {
+		am.setLoginStatus(status)
+	}
+	am.forgetUnconfiguredAccounts(configured)
+	for _, username := range result.Removed {
+		am.logger.Info("Removed account no longer configured: %s", username)
+	}
+	for _, username := range result.Added {
+		am.logger.Info("Added account: %s", username)
+	}
+
+	// The cached login state may describe the old rotation
+	am.loginMutex.Lock()
+	am.loginCheckedAt = time.Time{}
+	am.loginMutex.Unlock()
+
+	result.Agents = am.StartupStatuses()
+	return result, nil
+}
+
+// forgetUnconfiguredAccounts drops the login statuses, cached profiles and
+// saved cookie fingerprints of accounts not in configured, keyed by lower
+// case username
+func (am *AgentManager) forgetUnconfiguredAccounts(configured map[string]bool) {
+	am.mutex.Lock()
+	statuses := make([]AgentStartupStatus, 0, len(am.startupStatuses))
+	for _, status := range am.startupStatuses {
+		if configured[strings.ToLower(status.Username)] {
+			statuses = append(statuses, status)
+		}
+	}
+	am.startupStatuses = statuses
+	am.mutex.Unlock()
+
+	am.profileMutex.Lock()
+	for username := range am.profileCache {
+		if !configured[strings.ToLower(username)] {
+			delete(am.profileCache, username)
+		}
+	}
+	am.profileMutex.Unlock()
+
+	am.loginMutex.Lock()
+	for username := range am.loginStatuses {
+		if !configured[strings.ToLower(username)] {
+			delete(am.loginStatuses, username)
+		}
+	}
+	am.loginMutex.Unlock()
+
+	am.cookieMutex.Lock()
+	for username := range am.savedCookies {
+		if !configured[strings.ToLower(username)] {
+			delete(am.savedCookies, username)
+		}
+	}
+	am.cookieMutex.Unlock()
+}
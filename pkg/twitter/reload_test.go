@@ -0,0 +1,114 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadAccountsRemovesUnconfigured(t *testing.T) {
+	dir := t.TempDir()
+	accountsPath := filepath.Join(dir, "accounts.json")
+	assert.NoError(t, os.WriteFile(accountsPath, []byte(`[{"username": "Alice", "password": "secret"}]`), 0o600))
+
+	alice := newMockAgent()
+	alice.username = "alice"
+	bob := newMockAgent()
+	bob.username = "bob"
+	manager := &AgentManager{
+		agents:      []*Agent{alice, bob},
+		authManager: auth.NewAccountManager(dir),
+		logger:      logging.Default(),
+		profileCache: map[string]cachedAccountProfile{
+			"alice": {},
+			"bob":   {},
+		},
+		startupStatuses: []AgentStartupStatus{
+			{Username: "alice", Status: AgentStatusActive},
+			{Username: "bob", Status: AgentStatusActive},
+		},
+	}
+	before := manager.agents
+
+	result, err := manager.ReloadAccounts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, result.Added)
+	assert.Equal(t, []string{"bob"}, result.Removed)
+	assert.Equal(t, []string{}, result.Failed)
+	assert.Equal(t, []AgentStartupStatus{{Username: "alice", Status: AgentStatusActive}}, result.Agents)
+	assert.Equal(t, []*Agent{alice}, manager.agents)
+	assert.Equal(t, []*Agent{alice, bob}, before, "the old rotation must not be modified")
+	assert.Contains(t, manager.profileCache, "alice")
+	assert.NotContains(t, manager.profileCache, "bob")
+
+	// Removing every account keeps the current rotation
+	assert.NoError(t, os.WriteFile(accountsPath, []byte(`[]`), 0o600))
+	_, err = manager.ReloadAccounts(context.Background())
+	assert.ErrorIs(t, err, ErrNoAccounts)
+	assert.Equal(t, []*Agent{alice}, manager.agents)
+}
+
+func TestReloadAccountsAddsConfigured(t *testing.T) {
+	dir := t.TempDir()
+	accountsPath := filepath.Join(dir, "accounts.json")
+	assert.NoError(t, os.WriteFile(accountsPath, []byte(`[
+		{"username": "alice", "password": "secret"},
+		{"username": "carol", "password": "secret"},
+		{"username": "dave", "password": "secret"}
+	]`), 0o600))
+
+	alice := newMockAgent()
+	alice.username = "alice"
+	// New agents are created for carol, who logs in, and dave, who doesn't
+	scrapers := []*loginScraper{{}, {loginErr: errors.New("auth error: LoginAcid")}}
+	manager := &AgentManager{
+		agents:       []*Agent{alice},
+		authManager:  auth.NewAccountManager(dir),
+		logger:       logging.Default(),
+		profileCache: map[string]cachedAccountProfile{},
+		startupStatuses: []AgentStartupStatus{
+			{Username: "alice", Status: AgentStatusActive},
+		},
+		newScraper: func() Scraper {
+			scraper := scrapers[0]
+			scrapers = scrapers[1:]
+			return scraper
+		},
+	}
+
+	result, err := manager.ReloadAccounts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carol"}, result.Added)
+	assert.Equal(t, []string{}, result.Removed)
+	assert.Equal(t, []string{"dave"}, result.Failed)
+	assert.Equal(t, []AgentStartupStatus{
+		{Username: "alice", Status: AgentStatusActive},
+		{Username: "carol", Status: AgentStatusActive},
+		{Username: "dave", Status: AgentStatusLocked, Error: "auth error: LoginAcid"},
+	}, result.Agents)
+	if assert.Len(t, manager.agents, 2) {
+		assert.Equal(t, alice, manager.agents[0])
+		assert.Equal(t, "carol", manager.agents[1].username)
+		assert.True(t, manager.agents[1].IsLoggedIn())
+	}
+	// The new account's cookies are saved for the next start
+	cookies, err := manager.authManager.LoadCookies("carol")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", cookies[0].Value)
+
+	// A cancelled reload logs no account in
+	manager.agents = []*Agent{alice}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = manager.ReloadAccounts(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, result.Added)
+	assert.Equal(t, []string{}, result.Failed)
+	assert.Equal(t, []*Agent{alice}, manager.agents)
+}
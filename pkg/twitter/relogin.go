@@ -0,0 +1,115 @@
+package twitter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asabya/x-go/pkg/twitter/auth"
+)
+
+// LoginError is returned by Relogin when Twitter refuses the login
+type LoginError struct {
+	Username string
+	Status   string // One of the AgentStatus values
+	Err      error
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("login of account %s failed (%s): %v", e.Username, e.Status, e.Err)
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// Relogin logs the named account in again with its stored credentials and
+// saves the new session cookies, e.g. after its cookies expired at runtime.
+// An account that couldn't log in at startup joins the rotation once it
+// succeeds. The outcome is reported by StartupStatuses.
+func (am *AgentManager) Relogin(username string) error {
+	accounts, err := am.authManager.LoadAllAccounts()
+	if err != nil {
+		return fmt.Errorf("failed to load accounts: %w", err)
+	}
+	var account *auth.Account
+	for i := range accounts {
+		if strings.EqualFold(accounts[i].Username, username) {
+			account = &accounts[i]
+			break
+		}
+	}
+	if account == nil {
+		am.logger.Warning("No account found with username: %s", username)
+		return ErrInvalidAgentIndex
+	}
+	username = account.Username
+
+	// An account skipped at startup gets a new agent
+	agent := am.agentByUsername(username)
+	inRotation := agent != nil
+	if !inRotation {
+		if agent, err = am.newAccountAgent(*account); err != nil {
+			return err
+		}
+	}
+
+	am.logger.Info("Logging in account again: %s", username)
+	if err := agent.Login(username, account.Password); err != nil {
+		status := classifyLoginError(err)
+		am.logger.Error("Failed to login account %s again (%s): %v", username, status, err)
+		am.setLoginStatus(AgentStartupStatus{Username: username, Status: status, Error: err.Error()})
+		return &LoginError{Username: username, Status: status, Err: err}
+	}
+	am.logger.Info("Successfully logged in account again: %s", username)
+
+	cookies := agent.GetCookies()
+	if err := am.authManager.SaveCookies(username, cookies); err != nil {
+		am.logger.Error("Failed to save cookies for account %s: %v", username, err)
+	} else {
+		am.markCookiesSaved(username, cookies)
+	}
+
+	am.mutex.Lock()
+	if !inRotation {
+		am.agents = append(am.agents, agent)
+	}
+	am.mutex.Unlock()
+	am.setLoginStatus(AgentStartupStatus{Username: username, Status: AgentStatusActive})
+
+	// The cached login state and profile may describe the old session
+	am.loginMutex.Lock()
+	am.loginCheckedAt = time.Time{}
+	delete(am.loginStatuses, username)
+	am.loginMutex.Unlock()
+	am.profileMutex.Lock()
+	delete(am.profileCache, username)
+	am.profileMutex.Unlock()
+
+	return nil
+}
+
+// agentByUsername returns the agent in the rotation with the given username, or nil
+func (am *AgentManager) agentByUsername(username string) *Agent {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+	for _, agent := range am.agents {
+		if agent.username == username {
+			return agent
+		}
+	}
+	return nil
+}
+
+// setLoginStatus replaces the reported login status of status.Username
+func (am *AgentManager) setLoginStatus(status AgentStartupStatus) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	for i := range am.startupStatuses {
+		if am.startupStatuses[i].Username == status.Username {
+			am.startupStatuses[i] = status
+			return
+		}
+	}
+	am.startupStatuses = append(am.startupStatuses, status)
+}
@@ -0,0 +1,65 @@
+package twitter
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/asabya/x-go/pkg/twitter/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginScraper logs in with loginErr as the outcome
+type loginScraper struct {
+	mockScraper
+	loginErr error
+}
+
+func (s *loginScraper) Login(credentials ...string) error {
+	if s.loginErr != nil {
+		return s.loginErr
+	}
+	s.isLoggedIn = true
+	return nil
+}
+
+func (s *loginScraper) GetCookies() []*http.Cookie {
+	return []*http.Cookie{{Name: "auth_token", Value: "new"}}
+}
+
+func TestRelogin(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "accounts.json"), []byte(`[{"username": "alice", "password": "secret"}]`), 0o600))
+
+	scraper := &loginScraper{}
+	agent := newMockAgent()
+	agent.username = "alice"
+	agent.scraper = scraper
+	manager := &AgentManager{
+		agents:          []*Agent{agent},
+		authManager:     auth.NewAccountManager(dir),
+		logger:          logging.Default(),
+		profileCache:    map[string]cachedAccountProfile{},
+		startupStatuses: []AgentStartupStatus{{Username: "alice", Status: AgentStatusActive}},
+	}
+
+	scraper.loginErr = errors.New("auth error: LoginAcid")
+	err := manager.Relogin("alice")
+	var loginErr *LoginError
+	assert.ErrorAs(t, err, &loginErr)
+	assert.Equal(t, AgentStatusLocked, loginErr.Status)
+	assert.Equal(t, []AgentStartupStatus{{Username: "alice", Status: AgentStatusLocked, Error: "auth error: LoginAcid"}}, manager.StartupStatuses())
+
+	scraper.loginErr = nil
+	assert.NoError(t, manager.Relogin("ALICE"))
+	assert.True(t, agent.IsLoggedIn())
+	assert.Equal(t, []AgentStartupStatus{{Username: "alice", Status: AgentStatusActive}}, manager.StartupStatuses())
+	cookies, err := manager.authManager.LoadCookies("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", cookies[0].Value)
+
+	assert.ErrorIs(t, manager.Relogin("bob"), ErrInvalidAgentIndex)
+}
@@ -0,0 +1,46 @@
+package twitter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/asabya/x-go/pkg/logging"
+)
+
+// NoRequestID is returned by RequestIDFromContext when ctx carries no request ID
+const NoRequestID = "-"
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random 16 character hex request ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return NoRequestID
+	}
+	return hex.EncodeToString(b)
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the given request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or NoRequestID
+func RequestIDFromContext(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok && requestID != "" {
+		return requestID
+	}
+	return NoRequestID
+}
+
+// requestLogger returns the manager's logger, tagging every line with the
+// request ID of ctx when it has one
+func (am *AgentManager) requestLogger(ctx context.Context) logging.Logger {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == NoRequestID {
+		return am.logger
+	}
+	return logging.WithPrefix(am.logger, "[request_id="+requestID+"] ")
+}
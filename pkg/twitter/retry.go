@@ -0,0 +1,298 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"syscall"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// RetryConfig controls how transient Twitter errors are retried
+type RetryConfig struct {
+	MaxAttempts int           // Total attempts per call, including the first; 1 disables retries
+	BaseDelay   time.Duration // Backoff before the second attempt, doubled for every attempt after it
+	MaxDelay    time.Duration // Upper bound of the backoff between attempts
+	RetryWrites bool          // Also retry write operations, which may then be applied twice
+}
+
+// DefaultRetryConfig is used by the AgentManager unless WithRetryConfig is given
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// statusCodePattern extracts the HTTP status from twitter-scraper errors,
+// which are formatted as "response status 502 Bad Gateway: ..."
+var statusCodePattern = regexp.MustCompile(`response status (\d{3})`)
+
+// isTransientError reports whether err is worth retrying: timeouts, dropped
+// connections, rate limiting and 5xx responses
+func isTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	if match := statusCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		return status == 429 || status >= 500
+	}
+
+	return false
+}
+
+// backoff returns the delay before the given retry (1 for the first retry):
+// exponential growth from BaseDelay capped at MaxDelay, with the upper half
+// randomised so agents retrying together spread out
+func (c RetryConfig) backoff(retry int) time.Duration {
+	delay := c.BaseDelay
+	for i := 1; i < retry && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// do calls fn until it succeeds, returns a non-transient error, the attempts
+// run out or ctx is done
+func (c RetryConfig) do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= c.MaxAttempts || !isTransientError(err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryScraper decorates a Scraper, retrying read calls that fail with a
+// transient error. Write calls are passed through unless RetryWrites is set.
+// The streaming GetTweets and SearchTweets calls are never retried since
+// results may already have been delivered.
+type retryScraper struct {
+	Scraper
+	config RetryConfig
+}
+
+func newRetryScraper(scraper Scraper, config RetryConfig) *retryScraper {
+	return &retryScraper{Scraper: scraper, config: config}
+}
+
+func (s *retryScraper) GetProfile(ctx context.Context, username string) (*twitterscraper.Profile, error) {
+	var profile *twitterscraper.Profile
+	err := s.config.do(ctx, func() (err error) {
+		profile, err = s.Scraper.GetProfile(ctx, username)
+		return err
+	})
+	return profile, err
+}
+
+func (s *retryScraper) GetRelationship(ctx context.Context, sourceUserID, targetUserID string) (following, followedBy, blocked, muted bool, err error) {
+	err = s.config.do(ctx, func() (err error) {
+		following, followedBy, blocked, muted, err = s.Scraper.GetRelationship(ctx, sourceUserID, targetUserID)
+		return err
+	})
+	return following, followedBy, blocked, muted, err
+}
+
+func (s *retryScraper) GetTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
+	var tweet *twitterscraper.Tweet
+	err := s.config.do(ctx, func() (err error) {
+		tweet, err = s.Scraper.GetTweet(ctx, id)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *retryScraper) TweetVisibility(ctx context.Context, id string) (string, error) {
+	var visibility string
+	err := s.config.do(ctx, func() (err error) {
+		visibility, err = s.Scraper.TweetVisibility(ctx, id)
+		return err
+	})
+	return visibility, err
+}
+
+func (s *retryScraper) GetTweetReplies(id string, cursor string) ([]*twitterscraper.Tweet, []*twitterscraper.ThreadCursor, error) {
+	var tweets []*twitterscraper.Tweet
+	var cursors []*twitterscraper.ThreadCursor
+	err := s.config.do(context.Background(), func() (err error) {
+		tweets, cursors, err = s.Scraper.GetTweetReplies(id, cursor)
+		return err
+	})
+	return tweets, cursors, err
+}
+
+func (s *retryScraper) FetchSearchTweets(ctx context.Context, query string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	var tweets []*twitterscraper.Tweet
+	var next string
+	err := s.config.do(ctx, func() (err error) {
+		tweets, next, err = s.Scraper.FetchSearchTweets(ctx, query, maxTweetsNbr, cursor)
+		return err
+	})
+	return tweets, next, err
+}
+
+func (s *retryScraper) FetchTweetsAndReplies(ctx context.Context, username string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	var tweets []*twitterscraper.Tweet
+	var next string
+	err := s.config.do(ctx, func() (err error) {
+		tweets, next, err = s.Scraper.FetchTweetsAndReplies(ctx, username, maxTweetsNbr, cursor)
+		return err
+	})
+	return tweets, next, err
+}
+
+func (s *retryScraper) FetchFollowers(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	var profiles []*twitterscraper.Profile
+	var next string
+	err := s.config.do(ctx, func() (err error) {
+		profiles, next, err = s.Scraper.FetchFollowers(ctx, username, maxUsersNbr, cursor)
+		return err
+	})
+	return profiles, next, err
+}
+
+func (s *retryScraper) FetchFollowing(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	var profiles []*twitterscraper.Profile
+	var next string
+	err := s.config.do(ctx, func() (err error) {
+		profiles, next, err = s.Scraper.FetchFollowing(ctx, username, maxUsersNbr, cursor)
+		return err
+	})
+	return profiles, next, err
+}
+
+func (s *retryScraper) GetNotifications(ctx context.Context, cursor string) ([]Notification, string, error) {
+	var notifications []Notification
+	var next string
+	err := s.config.do(ctx, func() (err error) {
+		notifications, next, err = s.Scraper.GetNotifications(ctx, cursor)
+		return err
+	})
+	return notifications, next, err
+}
+
+func (s *retryScraper) GetTrends(ctx context.Context) ([]string, error) {
+	var trends []string
+	err := s.config.do(ctx, func() (err error) {
+		trends, err = s.Scraper.GetTrends(ctx)
+		return err
+	})
+	return trends, err
+}
+
+func (s *retryScraper) GetListTweets(ctx context.Context, listID string, maxTweets int) ([]*twitterscraper.Tweet, error) {
+	var tweets []*twitterscraper.Tweet
+	err := s.config.do(ctx, func() (err error) {
+		tweets, err = s.Scraper.GetListTweets(ctx, listID, maxTweets)
+		return err
+	})
+	return tweets, err
+}
+
+func (s *retryScraper) GetListMembers(ctx context.Context, listID string, cursor string) ([]*twitterscraper.Profile, string, error) {
+	var profiles []*twitterscraper.Profile
+	var next string
+	err := s.config.do(ctx, func() (err error) {
+		profiles, next, err = s.Scraper.GetListMembers(ctx, listID, cursor)
+		return err
+	})
+	return profiles, next, err
+}
+
+// doWrite retries a write operation only when RetryWrites is set
+func (s *retryScraper) doWrite(ctx context.Context, fn func() error) error {
+	if !s.config.RetryWrites {
+		return fn()
+	}
+	return s.config.do(ctx, fn)
+}
+
+func (s *retryScraper) Tweet(ctx context.Context, text string) (*twitterscraper.Tweet, error) {
+	var tweet *twitterscraper.Tweet
+	err := s.doWrite(ctx, func() (err error) {
+		tweet, err = s.Scraper.Tweet(ctx, text)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *retryScraper) ReplyTweet(ctx context.Context, text string, inReplyToID string) (*twitterscraper.Tweet, error) {
+	var tweet *twitterscraper.Tweet
+	err := s.doWrite(ctx, func() (err error) {
+		tweet, err = s.Scraper.ReplyTweet(ctx, text, inReplyToID)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *retryScraper) QuoteTweet(ctx context.Context, text string, quotedID string, mediaIDs []string) (*twitterscraper.Tweet, error) {
+	var tweet *twitterscraper.Tweet
+	err := s.doWrite(ctx, func() (err error) {
+		tweet, err = s.Scraper.QuoteTweet(ctx, text, quotedID, mediaIDs)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *retryScraper) SetMediaAltText(ctx context.Context, mediaID string, altText string) error {
+	return s.doWrite(ctx, func() error { return s.Scraper.SetMediaAltText(ctx, mediaID, altText) })
+}
+
+func (s *retryScraper) TweetWithPoll(ctx context.Context, text string, poll Poll) (*twitterscraper.Tweet, error) {
+	var tweet *twitterscraper.Tweet
+	err := s.doWrite(ctx, func() (err error) {
+		tweet, err = s.Scraper.TweetWithPoll(ctx, text, poll)
+		return err
+	})
+	return tweet, err
+}
+
+func (s *retryScraper) LikeTweet(ctx context.Context, id string) error {
+	return s.doWrite(ctx, func() error { return s.Scraper.LikeTweet(ctx, id) })
+}
+
+func (s *retryScraper) UnlikeTweet(ctx context.Context, id string) error {
+	return s.doWrite(ctx, func() error { return s.Scraper.UnlikeTweet(ctx, id) })
+}
+
+func (s *retryScraper) CreateRetweet(ctx context.Context, id string) error {
+	return s.doWrite(ctx, func() error { return s.Scraper.CreateRetweet(ctx, id) })
+}
+
+func (s *retryScraper) Follow(ctx context.Context, id string) error {
+	return s.doWrite(ctx, func() error { return s.Scraper.Follow(ctx, id) })
+}
+
+func (s *retryScraper) Unfollow(ctx context.Context, id string) error {
+	return s.doWrite(ctx, func() error { return s.Scraper.Unfollow(ctx, id) })
+}
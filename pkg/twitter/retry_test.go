@@ -0,0 +1,121 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyScraper fails the first failures calls of GetProfile and Follow with err
+type flakyScraper struct {
+	mockScraper
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyScraper) GetProfile(ctx context.Context, username string) (*twitterscraper.Profile, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &twitterscraper.Profile{Username: username}, nil
+}
+
+func (f *flakyScraper) Follow(ctx context.Context, id string) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+var testRetryConfig = RetryConfig{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+func TestRetryScraper(t *testing.T) {
+	badGateway := errors.New("response status 502 Bad Gateway: ")
+
+	t.Run("read succeeds on third attempt", func(t *testing.T) {
+		flaky := &flakyScraper{failures: 2, err: badGateway}
+		scraper := newRetryScraper(flaky, testRetryConfig)
+
+		profile, err := scraper.GetProfile(context.Background(), "testuser")
+		assert.NoError(t, err)
+		assert.Equal(t, "testuser", profile.Username)
+		assert.Equal(t, 3, flaky.calls)
+	})
+
+	t.Run("read gives up after max attempts", func(t *testing.T) {
+		flaky := &flakyScraper{failures: 5, err: badGateway}
+		scraper := newRetryScraper(flaky, testRetryConfig)
+
+		_, err := scraper.GetProfile(context.Background(), "testuser")
+		assert.Equal(t, badGateway, err)
+		assert.Equal(t, 3, flaky.calls)
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		notFound := errors.New("response status 404 Not Found: ")
+		flaky := &flakyScraper{failures: 2, err: notFound}
+		scraper := newRetryScraper(flaky, testRetryConfig)
+
+		_, err := scraper.GetProfile(context.Background(), "testuser")
+		assert.Equal(t, notFound, err)
+		assert.Equal(t, 1, flaky.calls)
+	})
+
+	t.Run("cancelled context stops retrying", func(t *testing.T) {
+		flaky := &flakyScraper{failures: 2, err: badGateway}
+		config := testRetryConfig
+		config.BaseDelay = time.Hour
+		config.MaxDelay = time.Hour
+		scraper := newRetryScraper(flaky, config)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := scraper.GetProfile(ctx, "testuser")
+		assert.Equal(t, badGateway, err)
+		assert.Equal(t, 1, flaky.calls)
+	})
+
+	t.Run("writes are not retried by default", func(t *testing.T) {
+		flaky := &flakyScraper{failures: 1, err: badGateway}
+		scraper := newRetryScraper(flaky, testRetryConfig)
+
+		assert.Equal(t, badGateway, scraper.Follow(context.Background(), "123"))
+		assert.Equal(t, 1, flaky.calls)
+	})
+
+	t.Run("writes are retried when enabled", func(t *testing.T) {
+		flaky := &flakyScraper{failures: 1, err: badGateway}
+		config := testRetryConfig
+		config.RetryWrites = true
+		scraper := newRetryScraper(flaky, config)
+
+		assert.NoError(t, scraper.Follow(context.Background(), "123"))
+		assert.Equal(t, 2, flaky.calls)
+	})
+}
+
+func TestRetryConfigBackoff(t *testing.T) {
+	config := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for retry, max := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		5: time.Second,
+	} {
+		delay := config.backoff(retry)
+		assert.GreaterOrEqual(t, delay, max/2)
+		assert.LessOrEqual(t, delay, max)
+	}
+}
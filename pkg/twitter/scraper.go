@@ -1,8 +1,16 @@
 package twitter
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	twitterscraper "github.com/imperatrona/twitter-scraper"
 )
@@ -10,19 +18,33 @@ import (
 // scraperWrapper wraps the twitter-scraper to match our interface
 type scraperWrapper struct {
 	*twitterscraper.Scraper
+
+	cookieMutex sync.Mutex
+	// authCookieExpiries is when each auth cookie expires, by name; cookies
+	// without a known expiry are missing
+	authCookieExpiries map[string]time.Time
 }
 
 func newScraperWrapper() *scraperWrapper {
-	return &scraperWrapper{
+	s := &scraperWrapper{
 		Scraper: twitterscraper.New(),
 	}
+	s.watchCookieJar()
+	return s
 }
 
+// IsLoggedIn checks the session with Twitter, unless its auth cookies are
+// known to have expired
 func (s *scraperWrapper) IsLoggedIn() bool {
+	if s.authCookiesExpired() {
+		return false
+	}
+	defer scraperCalls.start()()
 	return s.Scraper.IsLoggedIn()
 }
 
 func (s *scraperWrapper) GetProfile(ctx context.Context, username string) (*twitterscraper.Profile, error) {
+	defer scraperCalls.start()()
 	profile, err := s.Scraper.GetProfile(username)
 	if err != nil {
 		return nil, err
@@ -31,10 +53,15 @@ func (s *scraperWrapper) GetProfile(ctx context.Context, username string) (*twit
 }
 
 func (s *scraperWrapper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
-	return s.Scraper.GetTweets(ctx, username, maxTweetsNb)
+	return scraperCalls.stream(ctx, s.Scraper.GetTweets(ctx, username, maxTweetsNb))
+}
+
+func (s *scraperWrapper) GetMediaTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return scraperCalls.stream(ctx, s.Scraper.GetMediaTweets(ctx, username, maxTweetsNb))
 }
 
 func (s *scraperWrapper) GetTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
+	defer scraperCalls.start()()
 	tweet, err := s.Scraper.GetTweet(id)
 	if err != nil {
 		return nil, err
@@ -43,10 +70,11 @@ func (s *scraperWrapper) GetTweet(ctx context.Context, id string) (*twitterscrap
 }
 
 func (s *scraperWrapper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
-	return s.Scraper.SearchTweets(ctx, query, maxTweetsNb)
+	return scraperCalls.stream(ctx, s.Scraper.SearchTweets(ctx, query, maxTweetsNb))
 }
 
 func (s *scraperWrapper) Tweet(ctx context.Context, text string) (*twitterscraper.Tweet, error) {
+	defer scraperCalls.start()()
 	tweet := twitterscraper.NewTweet{
 		Text: text,
 	}
@@ -57,23 +85,430 @@ func (s *scraperWrapper) Tweet(ctx context.Context, text string) (*twitterscrape
 	return result, nil
 }
 
+const (
+	// createTweetURL is the GraphQL endpoint twitter-scraper uses for CreateTweet
+	createTweetURL = "https://twitter.com/i/api/graphql/oB-5XsHNAbjvARJEc8CZFw/CreateTweet"
+	// createCardURL is the endpoint the web client uses to create poll cards
+	createCardURL = "https://caps.twitter.com/v2/cards/create.json"
+	// tweetResultURL is the GraphQL endpoint twitter-scraper uses to look up a tweet logged out
+	tweetResultURL = "https://twitter.com/i/api/graphql/xBtHv5-Xsk268T5ng_OGNg/TweetResultByRestId"
+	// notificationsURL is the endpoint the web client reads the notifications timeline from
+	notificationsURL = "https://twitter.com/i/api/2/notifications/all.json"
+	// listTweetsURL is the GraphQL endpoint the web client reads a list's latest tweets from
+	listTweetsURL = "https://twitter.com/i/api/graphql/whF0_KH1fCkdLLoyNPMoEw/ListLatestTweetsTimeline"
+	// listMembersURL is the GraphQL endpoint the web client reads a list's members from
+	listMembersURL = "https://twitter.com/i/api/graphql/BQp2IEYkgxuSxqbTAr1e1g/ListMembers"
+	// mediaMetadataURL is the endpoint that sets the alt text of uploaded media
+	mediaMetadataURL = "https://api.twitter.com/1.1/media/metadata/create.json"
+)
+
+// ReplyTweet posts text as a reply to inReplyToID. twitter-scraper's
+// CreateTweet can't reply, so this sends the same GraphQL request with the
+// reply variables added, through the scraper's authenticated client.
+func (s *scraperWrapper) ReplyTweet(ctx context.Context, text string, inReplyToID string) (*twitterscraper.Tweet, error) {
+	defer scraperCalls.start()()
+	variables := newTweetVariables(text)
+	variables["reply"] = map[string]interface{}{
+		"in_reply_to_tweet_id":   inReplyToID,
+		"exclude_reply_user_ids": []string{},
+	}
+
+	tweet, err := s.postCreateTweet(ctx, variables)
+	if err != nil {
+		return nil, err
+	}
+	tweet.InReplyToStatusID = inReplyToID
+	tweet.IsReply = true
+	return tweet, nil
+}
+
+// QuoteTweet posts text quoting quotedID, with the uploaded media mediaIDs
+// attached. Like the web client it attaches the quoted tweet's URL to the
+// CreateTweet request, which twitter-scraper's CreateTweet has no field for.
+func (s *scraperWrapper) QuoteTweet(ctx context.Context, text string, quotedID string, mediaIDs []string) (*twitterscraper.Tweet, error) {
+	defer scraperCalls.start()()
+	variables := newTweetVariables(text)
+	variables["attachment_url"] = "https://twitter.com/i/status/" + quotedID
+	if len(mediaIDs) > 0 {
+		entities := make([]map[string]interface{}, 0, len(mediaIDs))
+		for _, id := range mediaIDs {
+			entities = append(entities, map[string]interface{}{
+				"media_id":     id,
+				"tagged_users": []string{},
+			})
+		}
+		variables["media"] = map[string]interface{}{
+			"media_entities":     entities,
+			"possibly_sensitive": false,
+		}
+	}
+
+	tweet, err := s.postCreateTweet(ctx, variables)
+	if err != nil {
+		return nil, err
+	}
+	tweet.QuotedStatusID = quotedID
+	tweet.IsQuoted = true
+	return tweet, nil
+}
+
+// SetMediaAltText sets the alt text of the uploaded media mediaID, which has
+// to happen before the media is attached to a tweet. twitter-scraper can
+// only upload media, so this sends the media metadata request itself.
+func (s *scraperWrapper) SetMediaAltText(ctx context.Context, mediaID string, altText string) error {
+	defer scraperCalls.start()()
+	body, err := json.Marshal(map[string]interface{}{
+		"media_id": mediaID,
+		"alt_text": map[string]string{"text": altText},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", mediaMetadataURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	return s.Scraper.RequestAPI(req, nil)
+}
+
+// TweetWithPoll posts text with a poll attached. twitter-scraper has no poll
+// support, so like the web client this first creates a poll card and then
+// references it from the CreateTweet request.
+func (s *scraperWrapper) TweetWithPoll(ctx context.Context, text string, poll Poll) (*twitterscraper.Tweet, error) {
+	defer scraperCalls.start()()
+	cardData := map[string]interface{}{
+		"twitter:card":                  fmt.Sprintf("poll%dchoice_text_only", len(poll.Options)),
+		"twitter:api:api:endpoint":      "1",
+		"twitter:long:duration_minutes": poll.DurationMinutes,
+	}
+	for i, option := range poll.Options {
+		cardData[fmt.Sprintf("twitter:string:choice%d_label", i+1)] = option
+	}
+
+	cardJSON, err := json.Marshal(cardData)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{"card_data": {string(cardJSON)}}
+	req, err := http.NewRequestWithContext(ctx, "POST", createCardURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+
+	var card struct {
+		CardURI string `json:"card_uri"`
+	}
+	if err := s.Scraper.RequestAPI(req, &card); err != nil {
+		return nil, fmt.Errorf("error creating poll: %w", err)
+	}
+	if card.CardURI == "" {
+		return nil, errors.New("poll wasn't created")
+	}
+
+	variables := newTweetVariables(text)
+	variables["card_uri"] = card.CardURI
+	return s.postCreateTweet(ctx, variables)
+}
+
+// newTweetVariables returns the GraphQL variables for a plain text tweet
+func newTweetVariables(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"dark_request": false,
+		"media": map[string]interface{}{
+			"media_entities":     []map[string]interface{}{},
+			"possibly_sensitive": false,
+		},
+		"semantic_annotation_ids": []string{},
+		"tweet_text":              text,
+	}
+}
+
+// postCreateTweet sends a CreateTweet GraphQL request with variables and
+// returns the ID and text of the created tweet
+func (s *scraperWrapper) postCreateTweet(ctx context.Context, variables map[string]interface{}) (*twitterscraper.Tweet, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"features":  createTweetFeatures,
+		"variables": variables,
+		"queryId":   "oB-5XsHNAbjvARJEc8CZFw",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", createTweetURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/json")
+
+	var response struct {
+		Data struct {
+			CreateTweet struct {
+				TweetResults struct {
+					Result struct {
+						RestID string `json:"rest_id"`
+						Legacy struct {
+							FullText string `json:"full_text"`
+						} `json:"legacy"`
+					} `json:"result"`
+				} `json:"tweet_results"`
+			} `json:"create_tweet"`
+		} `json:"data"`
+	}
+	if err := s.Scraper.RequestAPI(req, &response); err != nil {
+		return nil, err
+	}
+
+	result := response.Data.CreateTweet.TweetResults.Result
+	if result.RestID == "" {
+		return nil, errors.New("tweet wasn't posted")
+	}
+
+	return &twitterscraper.Tweet{
+		ID:   result.RestID,
+		Text: result.Legacy.FullText,
+	}, nil
+}
+
+// createTweetFeatures are the GraphQL feature flags sent with CreateTweet,
+// matching the ones twitter-scraper sends
+var createTweetFeatures = map[string]interface{}{
+	"communities_web_enable_tweet_community_results_fetch":                    true,
+	"c9s_tweet_anatomy_moderator_badge_enabled":                               true,
+	"tweetypie_unmention_optimization_enabled":                                true,
+	"responsive_web_edit_tweet_api_enabled":                                   true,
+	"graphql_is_translatable_rweb_tweet_is_translatable_enabled":              true,
+	"view_counts_everywhere_api_enabled":                                      true,
+	"longform_notetweets_consumption_enabled":                                 true,
+	"responsive_web_twitter_article_tweet_consumption_enabled":                true,
+	"tweet_awards_web_tipping_enabled":                                        false,
+	"creator_subscriptions_quote_tweet_preview_enabled":                       false,
+	"longform_notetweets_rich_text_read_enabled":                              true,
+	"longform_notetweets_inline_media_enabled":                                true,
+	"articles_preview_enabled":                                                true,
+	"rweb_video_timestamps_enabled":                                           true,
+	"rweb_tipjar_consumption_enabled":                                         true,
+	"responsive_web_graphql_exclude_directive_enabled":                        true,
+	"verified_phone_label_enabled":                                            false,
+	"freedom_of_speech_not_reach_fetch_enabled":                               true,
+	"standardized_nudges_misinfo":                                             true,
+	"tweet_with_visibility_results_prefer_gql_limited_actions_policy_enabled": true,
+	"responsive_web_graphql_skip_user_profile_image_extensions_enabled":       false,
+	"responsive_web_graphql_timeline_navigation_enabled":                      true,
+	"responsive_web_enhance_cards_enabled":                                    false,
+}
+
+// TweetVisibility looks tweet id up with TweetResultByRestId and reports
+// only its result type, without parsing the tweet, its author or media
+func (s *scraperWrapper) TweetVisibility(ctx context.Context, id string) (string, error) {
+	defer scraperCalls.start()()
+	query := url.Values{}
+	query.Set("variables", mapToJSONString(map[string]interface{}{
+		"tweetId":                id,
+		"withCommunity":          false,
+		"includePromotedContent": false,
+		"withVoice":              false,
+	}))
+	query.Set("features", mapToJSONString(createTweetFeatures))
+	req, err := http.NewRequestWithContext(ctx, "GET", tweetResultURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Data struct {
+			TweetResult struct {
+				Result *struct {
+					Typename string `json:"__typename"`
+					Reason   string `json:"reason"`
+				} `json:"result"`
+			} `json:"tweetResult"`
+		} `json:"data"`
+	}
+	if err := s.Scraper.RequestAPI(req, &response); err != nil {
+		return "", err
+	}
+
+	// Deleted tweets have no result; tweets of protected accounts are
+	// TweetUnavailable with the reason Protected, and tweets of suspended
+	// or deactivated accounts a TweetTombstone or another reason
+	result := response.Data.TweetResult.Result
+	switch {
+	case result == nil:
+		return TweetNotFound, nil
+	case result.Typename == "TweetUnavailable" && result.Reason == "Protected":
+		return TweetProtected, nil
+	case result.Typename == "TweetUnavailable", result.Typename == "TweetTombstone":
+		return TweetNotFound, nil
+	}
+	return TweetAvailable, nil
+}
+
+// GetNotifications gets a page of the logged-in account's notifications
+// timeline, starting at cursor or at the newest when it is empty, and the
+// cursor of the next page. twitter-scraper has no notifications support, so
+// this reads the timeline the web client uses.
+func (s *scraperWrapper) GetNotifications(ctx context.Context, cursor string) ([]Notification, string, error) {
+	defer scraperCalls.start()()
+	query := url.Values{}
+	query.Set("count", "40")
+	query.Set("include_profile_interstitial_type", "1")
+	query.Set("tweet_mode", "extended")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", notificationsURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var response notificationsResponse
+	if err := s.Scraper.RequestAPI(req, &response); err != nil {
+		return nil, "", err
+	}
+	notifications, next := response.parse()
+	return notifications, next, nil
+}
+
+// GetListTweets gets up to maxTweets of the latest tweets of list listID,
+// newest first. twitter-scraper has no list support, so this reads the
+// timeline the web client uses, a page at a time.
+func (s *scraperWrapper) GetListTweets(ctx context.Context, listID string, maxTweets int) ([]*twitterscraper.Tweet, error) {
+	defer scraperCalls.start()()
+	var tweets []*twitterscraper.Tweet
+	var cursor string
+	for len(tweets) < maxTweets {
+		variables := map[string]interface{}{
+			"listId": listID,
+			"count":  listTweetsPageSize,
+		}
+		if cursor != "" {
+			variables["cursor"] = cursor
+		}
+		var response listTweetsResponse
+		if err := s.getGraphQL(ctx, listTweetsURL, variables, &response); err != nil {
+			return nil, err
+		}
+		page, next := response.Data.List.TweetsTimeline.Timeline.parseTweets()
+		tweets = append(tweets, page...)
+		// An empty page or a repeated cursor is the end of the list
+		if len(page) == 0 || next == "" || next == cursor {
+			break
+		}
+		cursor = next
+	}
+	if len(tweets) > maxTweets {
+		tweets = tweets[:maxTweets]
+	}
+	return tweets, nil
+}
+
+// GetListMembers gets a page of the members of list listID, starting at
+// cursor or at the first page when it is empty, and the cursor of the next
+// page, which is empty on the last page
+func (s *scraperWrapper) GetListMembers(ctx context.Context, listID string, cursor string) ([]*twitterscraper.Profile, string, error) {
+	defer scraperCalls.start()()
+	variables := map[string]interface{}{
+		"listId": listID,
+		"count":  listMembersPageSize,
+	}
+	if cursor != "" {
+		variables["cursor"] = cursor
+	}
+	var response listMembersResponse
+	if err := s.getGraphQL(ctx, listMembersURL, variables, &response); err != nil {
+		return nil, "", err
+	}
+	members, next := response.Data.List.MembersTimeline.Timeline.parseUsers()
+	return members, next, nil
+}
+
+// getGraphQL sends a GraphQL query with the tweet features and decodes the
+// response into target
+func (s *scraperWrapper) getGraphQL(ctx context.Context, endpoint string, variables map[string]interface{}, target interface{}) error {
+	query := url.Values{}
+	query.Set("variables", mapToJSONString(variables))
+	query.Set("features", mapToJSONString(createTweetFeatures))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return s.Scraper.RequestAPI(req, target)
+}
+
+// mapToJSONString encodes GraphQL variables or features for a query string
+func mapToJSONString(data map[string]interface{}) string {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
 func (s *scraperWrapper) Follow(ctx context.Context, id string) error {
+	defer scraperCalls.start()()
 	return s.Scraper.Follow(id)
 }
 
 func (s *scraperWrapper) Unfollow(ctx context.Context, id string) error {
+	defer scraperCalls.start()()
 	return s.Scraper.Unfollow(id)
 }
 
+// GetRelationship reports how the target user relates to the source user.
+// twitter-scraper only exposes the relationship flags of a profile as seen by
+// the logged-in account, so sourceUserID must be that account's ID, or empty
+// to mean it. Block and mute state isn't exposed at all, so blocked and muted
+// are always false.
+func (s *scraperWrapper) GetRelationship(ctx context.Context, sourceUserID, targetUserID string) (following, followedBy, blocked, muted bool, err error) {
+	defer scraperCalls.start()()
+	if sourceUserID != "" {
+		if ownID := s.loggedInUserID(); ownID != "" && ownID != sourceUserID {
+			return false, false, false, false, fmt.Errorf("relationships can only be looked up from the logged-in account %s, not %s", ownID, sourceUserID)
+		}
+	}
+
+	profile, err := s.Scraper.GetProfileByID(targetUserID)
+	if err != nil {
+		return false, false, false, false, err
+	}
+	// Following and FollowedBy are from the viewer's side: Following means the
+	// viewer follows the target, FollowedBy that the target follows the viewer
+	return profile.Following, profile.FollowedBy, false, false, nil
+}
+
+// loggedInUserID returns the user ID of the logged-in account from its twid
+// cookie, or an empty string when the cookie isn't set
+func (s *scraperWrapper) loggedInUserID() string {
+	for _, cookie := range s.Scraper.GetCookies() {
+		if cookie.Name != "twid" {
+			continue
+		}
+		value, err := url.QueryUnescape(strings.Trim(cookie.Value, `"`))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimPrefix(value, "u=")
+	}
+	return ""
+}
+
 func (s *scraperWrapper) LikeTweet(ctx context.Context, id string) error {
+	defer scraperCalls.start()()
 	return s.Scraper.LikeTweet(id)
 }
 
 func (s *scraperWrapper) UnlikeTweet(ctx context.Context, id string) error {
+	defer scraperCalls.start()()
 	return s.Scraper.UnlikeTweet(id)
 }
 
 func (s *scraperWrapper) CreateRetweet(ctx context.Context, id string) error {
+	defer scraperCalls.start()()
 	_, err := s.Scraper.CreateRetweet(id)
 	return err
 }
@@ -84,6 +519,52 @@ func (s *scraperWrapper) CreateScheduledTweet(ctx context.Context, text string,
 	return nil
 }
 
-func (s *scraperWrapper) GetCookies() []*http.Cookie {
-	return s.Scraper.GetCookies()
+func (s *scraperWrapper) GetTrends(ctx context.Context) ([]string, error) {
+	defer scraperCalls.start()()
+	return s.Scraper.GetTrends()
+}
+
+func (s *scraperWrapper) GetTweetReplies(id string, cursor string) ([]*twitterscraper.Tweet, []*twitterscraper.ThreadCursor, error) {
+	defer scraperCalls.start()()
+	return s.Scraper.GetTweetReplies(id, cursor)
+}
+
+// FetchSearchTweets fetches a page of search results, checking ctx before
+// sending the request like FetchFollowers.
+func (s *scraperWrapper) FetchSearchTweets(ctx context.Context, query string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	defer scraperCalls.start()()
+	return s.Scraper.FetchSearchTweets(query, maxTweetsNbr, cursor)
+}
+
+// FetchTweetsAndReplies fetches a page of a user's tweets and replies,
+// checking ctx before sending the request like FetchFollowers.
+func (s *scraperWrapper) FetchTweetsAndReplies(ctx context.Context, username string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	defer scraperCalls.start()()
+	return s.Scraper.FetchTweetsAndReplies(username, maxTweetsNbr, cursor)
+}
+
+// FetchFollowers fetches a page of the followers of username. The scraper
+// can't cancel a request in flight, so ctx is checked before sending it.
+func (s *scraperWrapper) FetchFollowers(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	defer scraperCalls.start()()
+	return s.Scraper.FetchFollowers(username, maxUsersNbr, cursor)
+}
+
+// FetchFollowing fetches a page of the users username follows, checking ctx
+// before sending the request like FetchFollowers.
+func (s *scraperWrapper) FetchFollowing(ctx context.Context, username string, maxUsersNbr int, cursor string) ([]*twitterscraper.Profile, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	defer scraperCalls.start()()
+	return s.Scraper.FetchFollowing(username, maxUsersNbr, cursor)
 }
@@ -0,0 +1,49 @@
+package twitter
+
+import (
+	"fmt"
+	"time"
+)
+
+// searchDateLayout is the date format of Twitter's since: and until: operators
+const searchDateLayout = "2006-01-02"
+
+// SearchOptions holds the optional filters of a live tweet search
+type SearchOptions struct {
+	Since string // Only tweets from this date (YYYY-MM-DD) on
+	Until string // Only tweets before this date (YYYY-MM-DD)
+	// IncludeHTML adds the tweet text rendered as HTML, with links, mentions
+	// and hashtags as anchors, to each result
+	IncludeHTML bool
+}
+
+// Validate checks that the dates are well formed and Since isn't after Until
+func (o SearchOptions) Validate() error {
+	var since, until time.Time
+	var err error
+	if o.Since != "" {
+		if since, err = time.Parse(searchDateLayout, o.Since); err != nil {
+			return fmt.Errorf("invalid since date %q, expected YYYY-MM-DD", o.Since)
+		}
+	}
+	if o.Until != "" {
+		if until, err = time.Parse(searchDateLayout, o.Until); err != nil {
+			return fmt.Errorf("invalid until date %q, expected YYYY-MM-DD", o.Until)
+		}
+	}
+	if o.Since != "" && o.Until != "" && since.After(until) {
+		return fmt.Errorf("since date %s is after until date %s", o.Since, o.Until)
+	}
+	return nil
+}
+
+// apply appends the since: and until: operators for the set dates to query
+func (o SearchOptions) apply(query string) string {
+	if o.Since != "" {
+		query += " since:" + o.Since
+	}
+	if o.Until != "" {
+		query += " until:" + o.Until
+	}
+	return query
+}
@@ -0,0 +1,139 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     SearchOptions
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "no dates",
+			expected: "golang",
+		},
+		{
+			name:     "since and until",
+			opts:     SearchOptions{Since: "2024-01-01", Until: "2024-02-01"},
+			expected: "golang since:2024-01-01 until:2024-02-01",
+		},
+		{
+			name:     "until only",
+			opts:     SearchOptions{Until: "2024-02-01"},
+			expected: "golang until:2024-02-01",
+		},
+		{
+			name:    "invalid since",
+			opts:    SearchOptions{Since: "01/02/2024"},
+			wantErr: `invalid since date "01/02/2024", expected YYYY-MM-DD`,
+		},
+		{
+			name:    "invalid until",
+			opts:    SearchOptions{Until: "2024-13-01"},
+			wantErr: `invalid until date "2024-13-01", expected YYYY-MM-DD`,
+		},
+		{
+			name:    "since after until",
+			opts:    SearchOptions{Since: "2024-03-01", Until: "2024-02-01"},
+			wantErr: "since date 2024-03-01 is after until date 2024-02-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tt.opts.apply("golang"))
+		})
+	}
+}
+
+// htmlScraper finds, times and looks up a single tweet carrying HTML
+type htmlScraper struct {
+	mockScraper
+}
+
+func (s *htmlScraper) tweet() twitterscraper.Tweet {
+	return twitterscraper.Tweet{ID: "1", Text: "hi @bob", HTML: `hi <a href="https://twitter.com/bob">@bob</a>`}
+}
+
+func (s *htmlScraper) SearchTweets(ctx context.Context, query string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	ch := make(chan *twitterscraper.TweetResult, 1)
+	ch <- &twitterscraper.TweetResult{Tweet: s.tweet()}
+	close(ch)
+	return ch
+}
+
+func (s *htmlScraper) GetTweets(ctx context.Context, username string, maxTweetsNb int) <-chan *twitterscraper.TweetResult {
+	return s.SearchTweets(ctx, username, maxTweetsNb)
+}
+
+func (s *htmlScraper) GetTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
+	tweet := s.tweet()
+	return &tweet, nil
+}
+
+func TestSearchTweetsIncludeHTML(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &htmlScraper{mockScraper{isLoggedIn: true}}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	for _, include := range []bool{false, true} {
+		agent.limiter.lastCallTime = time.Time{}
+		result, _, _, err := manager.SearchTweets(context.Background(), "bob", 10, SearchOptions{IncludeHTML: include})
+		assert.NoError(t, err)
+		data, err := json.Marshal(result)
+		assert.NoError(t, err)
+		var tweets []map[string]interface{}
+		assert.NoError(t, json.Unmarshal(data, &tweets))
+		if !assert.Len(t, tweets, 1) {
+			return
+		}
+		html, ok := tweets[0]["html"]
+		assert.Equal(t, include, ok)
+		if include {
+			assert.Equal(t, `hi <a href="https://twitter.com/bob">@bob</a>`, html)
+		}
+	}
+}
+
+func TestTweetToolsIncludeHTML(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &htmlScraper{mockScraper{isLoggedIn: true}}
+
+	call := func(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) string {
+		agent.limiter.lastCallTime = time.Time{}
+		var request mcp.CallToolRequest
+		request.Params.Arguments = args
+		result, err := handler(context.Background(), request)
+		assert.NoError(t, err)
+		assert.False(t, result.IsError)
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+
+	for _, include := range []bool{false, true} {
+		tweets := call(agent.handleGetUserTweets, map[string]interface{}{"username": "alice", "include_html": include})
+		tweet := call(agent.handleGetTweet, map[string]interface{}{"tweet_id": "1", "include_html": include})
+		for _, text := range []string{tweets, tweet} {
+			// The field is left out rather than sent empty
+			assert.Equal(t, include, strings.Contains(text, `"HTML"`), text)
+			assert.Contains(t, text, `"Text":"hi @bob"`)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Strategies an agent can be selected by
+const (
+	// SelectionRoundRobin picks the next agent in rotation
+	SelectionRoundRobin = "round_robin"
+	// SelectionByUsername picks the agent named by the request
+	SelectionByUsername = "username"
+	// SelectionByUserID picks the agent whose account has the requested user ID
+	SelectionByUserID = "user_id"
+)
+
+// AgentSelection describes how the agent serving a request was chosen
+type AgentSelection struct {
+	Strategy string
+	Agent    string
+	// Index is the agent's position in the manager's pool and PoolSize the
+	// number of agents in it
+	Index    int
+	PoolSize int
+	// Skipped lists agents passed over before Agent was chosen
+	Skipped []string
+}
+
+// String formats the selection for the X-Agent-Selection header, e.g.
+// "strategy=round_robin; agent=alice; index=2/3; skipped=bob"
+func (s AgentSelection) String() string {
+	parts := []string{
+		"strategy=" + s.Strategy,
+		"agent=" + s.Agent,
+		fmt.Sprintf("index=%d/%d", s.Index, s.PoolSize),
+	}
+	if len(s.Skipped) > 0 {
+		parts = append(parts, "skipped="+strings.Join(s.Skipped, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// SelectionRecorder collects the agent selections made while serving a request
+type SelectionRecorder struct {
+	mu         sync.Mutex
+	selections []AgentSelection
+}
+
+// Selections returns the selections recorded so far, in order
+func (r *SelectionRecorder) Selections() []AgentSelection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AgentSelection(nil), r.selections...)
+}
+
+func (r *SelectionRecorder) record(selection AgentSelection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.selections = append(r.selections, selection)
+}
+
+type selectionRecorderKey struct{}
+
+// ContextWithSelectionRecorder returns a copy of ctx in which the agent
+// manager records its agent selections to recorder
+func ContextWithSelectionRecorder(ctx context.Context, recorder *SelectionRecorder) context.Context {
+	return context.WithValue(ctx, selectionRecorderKey{}, recorder)
+}
+
+// recordSelection logs selection and records it to ctx's selection recorder, if any
+func (am *AgentManager) recordSelection(ctx context.Context, selection AgentSelection) {
+	am.requestLogger(ctx).Debug("Selected agent: %s", selection)
+	if recorder, ok := ctx.Value(selectionRecorderKey{}).(*SelectionRecorder); ok && recorder != nil {
+		recorder.record(selection)
+	}
+}
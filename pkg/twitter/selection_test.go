@@ -0,0 +1,45 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentSelection(t *testing.T) {
+	newAgent := func(username string) *Agent {
+		agent := newMockAgent()
+		agent.username = username
+		return agent
+	}
+	manager := &AgentManager{
+		agents: []*Agent{newAgent("a"), newAgent("b")},
+		logger: logging.Default(),
+	}
+
+	recorder := &SelectionRecorder{}
+	ctx := ContextWithSelectionRecorder(context.Background(), recorder)
+
+	_, username, err := manager.resolveAgent(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", username)
+	_, _, err = manager.resolveAgent(ctx, "A")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []AgentSelection{
+		{Strategy: SelectionRoundRobin, Agent: "b", Index: 1, PoolSize: 2},
+		{Strategy: SelectionByUsername, Agent: "a", Index: 0, PoolSize: 2},
+	}, recorder.Selections())
+
+	// Selections aren't recorded without a recorder
+	_, _, err = manager.resolveAgent(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Selections(), 2)
+}
+
+func TestAgentSelectionString(t *testing.T) {
+	selection := AgentSelection{Strategy: SelectionByUserID, Agent: "c", Index: 2, PoolSize: 3, Skipped: []string{"a", "b"}}
+	assert.Equal(t, "strategy=user_id; agent=c; index=2/3; skipped=a,b", selection.String())
+}
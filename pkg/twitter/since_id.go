@@ -0,0 +1,110 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// validTweetID reports whether id looks like a tweet ID, a decimal number
+func validTweetID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// compareTweetIDs compares the tweet IDs a and b numerically, returning -1,
+// 0 or 1. Tweet IDs grow over time, so a newer tweet has the larger ID.
+func compareTweetIDs(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// GetNewUserTweets gets the tweets a user posted after the tweet sinceID,
+// using the next available agent. The timeline is read newest first, page by
+// page, and reading stops at the first tweet that isn't newer than sinceID,
+// so tweets already seen aren't fetched again; at most maxResults tweets are
+// returned. It also returns the newest tweet ID seen, sinceID when there are
+// no new tweets, to poll with next. When reading stopped on an error before
+// reaching sinceID, the tweets read are returned with the reason in partial,
+// and sinceID is returned as the newest ID: the tweets between the ones read
+// and sinceID weren't fetched, and polling past them would lose them.
+func (am *AgentManager) GetNewUserTweets(ctx context.Context, username, sinceID string) (data interface{}, newestID string, partial []string, agentUsername string, err error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername = selection.Agent
+	logger.Debug("Getting tweets after %s for user %s using agent %s", sinceID, username, agentUsername)
+
+	limit := am.maxResults
+	if limit <= 0 {
+		limit = DefaultMaxResults
+	}
+	result, err := agent.handleGetUserTweets(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_user_tweets",
+			Arguments: map[string]interface{}{
+				"username":     username,
+				"limit":        float64(limit),
+				"since_id":     sinceID,
+				"include_html": true,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting tweets for user %s: %v", username, err)
+		return nil, "", nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for user %s: %s", username, errMsg)
+		return nil, "", nil, agentUsername, toolError(errMsg)
+	}
+
+	var tweets []map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &tweets); err != nil {
+		logger.Error("Error unmarshaling response for user %s: %v", username, err)
+		return nil, "", nil, agentUsername, err
+	}
+
+	newestID = sinceID
+	newTweets := make([]interface{}, 0, len(tweets))
+	for _, tweet := range tweets {
+		if id, ok := tweet["ID"].(string); ok && compareTweetIDs(id, newestID) > 0 {
+			newestID = id
+		}
+		newTweets = append(newTweets, tweet)
+	}
+
+	partial = partialResults(result)
+	if len(partial) > 0 {
+		logger.Warning("Tweets after %s for user %s are incomplete, not advancing past them: %s", sinceID, username, partial[0])
+		newestID = sinceID
+	}
+
+	logger.Debug("Successfully retrieved %d tweets after %s for user %s", len(newTweets), sinceID, username)
+	return newTweets, newestID, partial, agentUsername, nil
+}
@@ -0,0 +1,173 @@
+package twitter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxTweetLength is the maximum weighted length of a tweet
+	maxTweetLength = 280
+	// tweetURLLength is the weight of any URL, since Twitter wraps them in t.co links
+	tweetURLLength = 23
+)
+
+// urlPattern matches the URLs Twitter shortens to t.co links
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// tweetLength counts the weighted length of text following Twitter's rules:
+// URLs count as 23 characters, Latin and common punctuation as 1, and
+// everything else (CJK, emoji, ...) as 2. Emoji modifiers and ZWJ sequences
+// are counted once for the whole emoji.
+func tweetLength(text string) int {
+	length := len(urlPattern.FindAllStringIndex(text, -1)) * tweetURLLength
+	rest := urlPattern.ReplaceAllString(text, "")
+
+	joined := false
+	for len(rest) > 0 {
+		r, size := utf8.DecodeRuneInString(rest)
+		rest = rest[size:]
+
+		switch {
+		case r == 0x200D:
+			// Zero width joiner glues the next emoji onto the previous one
+			joined = true
+			continue
+		case r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF):
+			// Variation selectors and skin tone modifiers don't count on their own
+			continue
+		case joined:
+			joined = false
+			continue
+		}
+
+		length += runeWeight(r)
+	}
+
+	return length
+}
+
+// runeWeight returns the weight of a single character per twitter-text's ranges
+func runeWeight(r rune) int {
+	switch {
+	case r <= 4351,
+		r >= 8192 && r <= 8205,
+		r >= 8208 && r <= 8223,
+		r >= 8242 && r <= 8247:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// splitThread splits text into tweets that each fit in maxTweetLength
+// including an "(n/m)" marker. Breaks prefer sentence ends, then whitespace,
+// so URLs are never cut; only a single word too long for one tweet is split.
+// Text that already fits is returned unchanged and without a marker.
+func splitThread(text string) []string {
+	text = strings.TrimSpace(text)
+	if tweetLength(text) <= maxTweetLength {
+		return []string{text}
+	}
+
+	sentences := splitSentences(text)
+
+	// The marker width depends on the number of parts, so retry with wider
+	// markers until the part count fits the assumed number of digits
+	for digits := 1; ; digits++ {
+		markerLength := len(" (/)") + 2*digits
+		parts := packThread(sentences, maxTweetLength-markerLength)
+		if len(strconv.Itoa(len(parts))) > digits {
+			continue
+		}
+
+		for i := range parts {
+			parts[i] = fmt.Sprintf("%s (%d/%d)", parts[i], i+1, len(parts))
+		}
+		return parts
+	}
+}
+
+// splitSentences groups the words of text into sentences, ending a sentence
+// at any word that ends with '.', '!' or '?'
+func splitSentences(text string) [][]string {
+	var sentences [][]string
+	var current []string
+	for _, word := range strings.Fields(text) {
+		current = append(current, word)
+		if strings.ContainsAny(word[len(word)-1:], ".!?") {
+			sentences = append(sentences, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		sentences = append(sentences, current)
+	}
+	return sentences
+}
+
+// packThread fills parts of at most budget weighted characters, starting a
+// new part at a sentence boundary whenever the next sentence fits in one
+func packThread(sentences [][]string, budget int) []string {
+	var parts []string
+	current := ""
+
+	join := func(a, b string) string {
+		if a == "" {
+			return b
+		}
+		return a + " " + b
+	}
+	flush := func() {
+		if current != "" {
+			parts = append(parts, current)
+			current = ""
+		}
+	}
+
+	for _, sentence := range sentences {
+		joined := strings.Join(sentence, " ")
+		if tweetLength(join(current, joined)) <= budget {
+			current = join(current, joined)
+			continue
+		}
+		if tweetLength(joined) <= budget {
+			flush()
+			current = joined
+			continue
+		}
+
+		// The sentence needs more than one part, so break it between words
+		for _, word := range sentence {
+			if tweetLength(join(current, word)) <= budget {
+				current = join(current, word)
+				continue
+			}
+			flush()
+			for tweetLength(word) > budget {
+				head, tail := splitWord(word, budget)
+				parts = append(parts, head)
+				word = tail
+			}
+			current = word
+		}
+	}
+	flush()
+
+	return parts
+}
+
+// splitWord cuts word after the longest prefix that fits in budget
+func splitWord(word string, budget int) (string, string) {
+	end := 0
+	for i, r := range word {
+		if tweetLength(word[:i+utf8.RuneLen(r)]) > budget {
+			break
+		}
+		end = i + utf8.RuneLen(r)
+	}
+	return word[:end], word[end:]
+}
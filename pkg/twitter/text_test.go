@@ -0,0 +1,151 @@
+package twitter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTweetLength(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{
+			name: "empty",
+			text: "",
+			want: 0,
+		},
+		{
+			name: "plain ascii",
+			text: "Hello, world!",
+			want: 13,
+		},
+		{
+			name: "accented latin",
+			text: "café",
+			want: 4,
+		},
+		{
+			name: "url counts as 23",
+			text: "https://example.com/a/very/long/path/that/goes/on/and/on",
+			want: 23,
+		},
+		{
+			name: "url with surrounding text",
+			text: "read this http://x.co now",
+			want: len("read this ") + 23 + len(" now"),
+		},
+		{
+			name: "multiple urls",
+			text: "https://a.com https://b.com",
+			want: 23 + 1 + 23,
+		},
+		{
+			name: "cjk counts as 2",
+			text: "你好世界",
+			want: 8,
+		},
+		{
+			name: "japanese mixed with ascii",
+			text: "hi こんにちは",
+			want: 3 + 10,
+		},
+		{
+			name: "single emoji",
+			text: "😀",
+			want: 2,
+		},
+		{
+			name: "emoji with skin tone",
+			text: "👍🏽",
+			want: 2,
+		},
+		{
+			name: "zwj family emoji",
+			text: "👨‍👩‍👧",
+			want: 2,
+		},
+		{
+			name: "emoji with variation selector",
+			text: "❤️",
+			want: 2,
+		},
+		{
+			name: "limit of ascii",
+			text: strings.Repeat("a", 280),
+			want: 280,
+		},
+		{
+			name: "limit of cjk",
+			text: strings.Repeat("字", 140),
+			want: 280,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tweetLength(tt.text))
+		})
+	}
+}
+
+func TestSplitThread(t *testing.T) {
+	// stripMarker removes the trailing " (n/m)" marker from a part
+	stripMarker := func(part string) string {
+		return part[:strings.LastIndex(part, " (")]
+	}
+
+	t.Run("short text is not split", func(t *testing.T) {
+		parts := splitThread("Just one tweet.")
+		assert.Equal(t, []string{"Just one tweet."}, parts)
+	})
+
+	t.Run("long text keeps every word and fits each part", func(t *testing.T) {
+		text := strings.Repeat("This sentence is about forty characters. ", 20)
+		parts := splitThread(text)
+		assert.Greater(t, len(parts), 1)
+
+		var words []string
+		for i, part := range parts {
+			assert.LessOrEqual(t, tweetLength(part), maxTweetLength)
+			assert.True(t, strings.HasSuffix(part, fmt.Sprintf("(%d/%d)", i+1, len(parts))), part)
+			words = append(words, strings.Fields(stripMarker(part))...)
+		}
+		assert.Equal(t, strings.Fields(text), words)
+	})
+
+	t.Run("breaks at sentence ends", func(t *testing.T) {
+		text := strings.Repeat("a", 200) + ". " + strings.Repeat("b", 200) + "."
+		parts := splitThread(text)
+		assert.Equal(t, []string{
+			strings.Repeat("a", 200) + ". (1/2)",
+			strings.Repeat("b", 200) + ". (2/2)",
+		}, parts)
+	})
+
+	t.Run("urls are never split", func(t *testing.T) {
+		url := "https://example.com/" + strings.Repeat("path/", 60)
+		text := strings.Repeat("word ", 50) + url + " " + strings.Repeat("word ", 50)
+		parts := splitThread(text)
+		found := false
+		for _, part := range parts {
+			assert.LessOrEqual(t, tweetLength(part), maxTweetLength)
+			if strings.Contains(part, url) {
+				found = true
+			}
+		}
+		assert.True(t, found, "url should appear intact in one part")
+	})
+
+	t.Run("overlong word is cut", func(t *testing.T) {
+		parts := splitThread(strings.Repeat("x", 600))
+		assert.Len(t, parts, 3)
+		for _, part := range parts {
+			assert.LessOrEqual(t, tweetLength(part), maxTweetLength)
+		}
+	})
+}
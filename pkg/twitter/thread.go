@@ -0,0 +1,132 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+const (
+	// defaultThreadDepth is the reply depth used when none is requested
+	defaultThreadDepth = 3
+	// maxThreadDepth caps how deep a thread is walked
+	maxThreadDepth = 10
+	// maxThreadTweets caps the total number of tweets collected for one thread
+	maxThreadTweets = 200
+	// maxReplyPages caps how many reply pages are followed for a single tweet
+	maxReplyPages = 5
+)
+
+// ThreadNode is a tweet together with the replies made to it
+type ThreadNode struct {
+	Tweet    SimplifiedTweet `json:"tweet"`
+	Children []*ThreadNode   `json:"children"`
+}
+
+// buildThread walks the replies below tweet up to maxDepth levels deep.
+// fetched counts the tweets collected so far and is shared across the
+// recursion so the whole tree stays within maxThreadTweets.
+func (a *Agent) buildThread(ctx context.Context, tweet *twitterscraper.Tweet, depth, maxDepth int, fetched *int) (*ThreadNode, error) {
+	node := &ThreadNode{
+		Tweet:    newSimplifiedTweet(tweet),
+		Children: make([]*ThreadNode, 0),
+	}
+	*fetched++
+
+	if depth >= maxDepth || tweet.Replies == 0 || *fetched >= maxThreadTweets {
+		return node, nil
+	}
+
+	replies, err := a.fetchDirectReplies(ctx, tweet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reply := range replies {
+		if *fetched >= maxThreadTweets {
+			break
+		}
+		child, err := a.buildThread(ctx, reply, depth+1, maxDepth, fetched)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+
+	return node, nil
+}
+
+// fetchDirectReplies returns the tweets replying directly to tweetID,
+// following the conversation cursors until they run out or maxReplyPages
+// is reached. Cursors already followed are skipped to avoid loops.
+func (a *Agent) fetchDirectReplies(ctx context.Context, tweetID string) ([]*twitterscraper.Tweet, error) {
+	var replies []*twitterscraper.Tweet
+	seenTweets := make(map[string]bool)
+	seenCursors := make(map[string]bool)
+	cursor := ""
+
+	for page := 0; page < maxReplyPages; page++ {
+		if err := a.limiter.waitForEndpoint(ctx, "get_tweet_replies"); err != nil {
+			return nil, err
+		}
+
+		tweets, cursors, err := a.scraper.GetTweetReplies(tweetID, cursor)
+		a.limiter.doneEndpoint("get_tweet_replies")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tweet := range tweets {
+			if tweet.InReplyToStatusID != tweetID || seenTweets[tweet.ID] {
+				continue
+			}
+			seenTweets[tweet.ID] = true
+			replies = append(replies, tweet)
+		}
+
+		// Only follow cursors that page through this tweet's own replies
+		cursor = ""
+		for _, c := range cursors {
+			if c.ThreadID != "" && c.ThreadID != tweetID {
+				continue
+			}
+			if !seenCursors[c.Cursor] {
+				cursor = c.Cursor
+				break
+			}
+		}
+		if cursor == "" {
+			break
+		}
+		seenCursors[cursor] = true
+	}
+
+	return replies, nil
+}
+
+// createThread posts parts as a self-thread, each part replying to the one
+// before it, and returns the IDs of the tweets created. When a part fails the
+// IDs posted so far are returned with the error so callers can report them.
+func (a *Agent) createThread(ctx context.Context, parts []string) ([]string, error) {
+	ids := make([]string, 0, len(parts))
+	for i, part := range parts {
+		if err := a.limiter.waitForEndpoint(ctx, "create_tweet"); err != nil {
+			return ids, err
+		}
+
+		var tweet *twitterscraper.Tweet
+		var err error
+		if i == 0 {
+			tweet, err = a.scraper.Tweet(ctx, part)
+		} else {
+			tweet, err = a.scraper.ReplyTweet(ctx, part, ids[i-1])
+		}
+		a.limiter.doneEndpoint("create_tweet")
+		if err != nil {
+			return ids, fmt.Errorf("error creating tweet %d of %d: %w", i+1, len(parts), err)
+		}
+		ids = append(ids, tweet.ID)
+	}
+	return ids, nil
+}
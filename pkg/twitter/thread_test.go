@@ -0,0 +1,117 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// replyPage is one page of replies served by threadScraper
+type replyPage struct {
+	tweets  []*twitterscraper.Tweet
+	cursors []*twitterscraper.ThreadCursor
+}
+
+// threadScraper serves reply pages keyed by tweet ID and cursor and records
+// each request as "id/cursor"
+type threadScraper struct {
+	mockScraper
+	pages    map[string]replyPage
+	requests []string
+}
+
+func (s *threadScraper) GetTweetReplies(id string, cursor string) ([]*twitterscraper.Tweet, []*twitterscraper.ThreadCursor, error) {
+	key := id + "/" + cursor
+	s.requests = append(s.requests, key)
+	page := s.pages[key]
+	return page.tweets, page.cursors, nil
+}
+
+func newThreadAgent(scraper *threadScraper) *Agent {
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	return agent
+}
+
+func TestBuildThreadDepthCap(t *testing.T) {
+	scraper := &threadScraper{pages: map[string]replyPage{
+		"1/": {tweets: []*twitterscraper.Tweet{
+			{ID: "2", InReplyToStatusID: "1", Replies: 1},
+			{ID: "3", InReplyToStatusID: "1", Replies: 4},
+		}},
+	}}
+
+	fetched := 0
+	root := &twitterscraper.Tweet{ID: "1", Replies: 2}
+	node, err := newThreadAgent(scraper).buildThread(context.Background(), root, 0, 1, &fetched)
+	assert.NoError(t, err)
+
+	// The replies sit at the last level, so theirs are not fetched
+	assert.Equal(t, []string{"1/"}, scraper.requests)
+	assert.Equal(t, 3, fetched)
+	if assert.Len(t, node.Children, 2) {
+		assert.Equal(t, "2", node.Children[0].Tweet.ID)
+		assert.Empty(t, node.Children[0].Children)
+		assert.Equal(t, "3", node.Children[1].Tweet.ID)
+		assert.Empty(t, node.Children[1].Children)
+	}
+}
+
+func TestBuildThreadTweetCap(t *testing.T) {
+	var replies []*twitterscraper.Tweet
+	for i := 0; i < maxThreadTweets+50; i++ {
+		// Replies without replies of their own aren't fetched
+		replies = append(replies, &twitterscraper.Tweet{ID: fmt.Sprintf("r%d", i), InReplyToStatusID: "1"})
+	}
+	scraper := &threadScraper{pages: map[string]replyPage{"1/": {tweets: replies}}}
+
+	fetched := 0
+	root := &twitterscraper.Tweet{ID: "1", Replies: len(replies)}
+	node, err := newThreadAgent(scraper).buildThread(context.Background(), root, 0, maxThreadDepth, &fetched)
+	assert.NoError(t, err)
+
+	// The root counts towards the cap
+	assert.Equal(t, maxThreadTweets, fetched)
+	assert.Len(t, node.Children, maxThreadTweets-1)
+	assert.Equal(t, []string{"1/"}, scraper.requests)
+}
+
+func TestFetchDirectRepliesCursors(t *testing.T) {
+	scraper := &threadScraper{pages: map[string]replyPage{
+		"1/": {
+			tweets: []*twitterscraper.Tweet{
+				{ID: "2", InReplyToStatusID: "1"},
+				// A reply to another tweet in the conversation is skipped
+				{ID: "9", InReplyToStatusID: "2"},
+			},
+			cursors: []*twitterscraper.ThreadCursor{
+				// A cursor for another thread is not followed
+				{ThreadID: "2", Cursor: "other"},
+				{ThreadID: "1", Cursor: "next"},
+			},
+		},
+		// The second page repeats a reply and hands back the cursor already followed
+		"1/next": {
+			tweets: []*twitterscraper.Tweet{
+				{ID: "2", InReplyToStatusID: "1"},
+				{ID: "3", InReplyToStatusID: "1"},
+			},
+			cursors: []*twitterscraper.ThreadCursor{{ThreadID: "1", Cursor: "next"}},
+		},
+	}}
+
+	replies, err := newThreadAgent(scraper).fetchDirectReplies(context.Background(), "1")
+	assert.NoError(t, err)
+
+	var ids []string
+	for _, reply := range replies {
+		ids = append(ids, reply.ID)
+	}
+	assert.Equal(t, []string{"2", "3"}, ids)
+	assert.Equal(t, []string{"1/", "1/next"}, scraper.requests)
+}
@@ -0,0 +1,78 @@
+package twitter
+
+import (
+	"strings"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ToolNames lists every tool GetTools can return, whether or not the agent
+// is logged in or has GetMoni set up
+var ToolNames = []string{
+	"get_user_tweets", "get_media_tweets", "get_profile", "get_tweet", "get_tweet_stats",
+	"get_followers", "get_tweet_replies", "get_tweet_thread", "get_conversation_context",
+	"get_tweets", "get_trends",
+	"search_tweets", "search_tweets_advanced", "create_tweet", "reply_tweet", "quote_tweet",
+	"like_tweet", "unlike_tweet", "retweet", "get_relationship", "get_notifications", "get_quotes",
+	"get_user_replies", "get_list_tweets", "get_list_members",
+	"get_smart_followers",
+}
+
+// ToolFilter restricts the tools an MCP server registers, e.g. to run it
+// read-only with a logged-in account. When Allow isn't empty only the tools
+// it names are registered; the tools Deny names never are.
+type ToolFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// ParseToolList splits a comma-separated list of tool names, as given in an
+// environment variable
+func ParseToolList(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Unknown returns the names in f that aren't in ToolNames, which are
+// probably misspelled
+func (f ToolFilter) Unknown() []string {
+	var unknown []string
+	for _, name := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if !containsString(ToolNames, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// Apply returns the tools f lets through, in their order
+func (f ToolFilter) Apply(tools []server.ServerTool) []server.ServerTool {
+	filtered := make([]server.ServerTool, 0, len(tools))
+	for _, tool := range tools {
+		if f.allows(tool.Tool.Name) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
+func (f ToolFilter) allows(name string) bool {
+	if len(f.Allow) > 0 && !containsString(f.Allow, name) {
+		return false
+	}
+	return !containsString(f.Deny, name)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
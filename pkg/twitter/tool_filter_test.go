@@ -0,0 +1,55 @@
+package twitter
+
+import (
+	"testing"
+
+	"github.com/asabya/x-go/pkg/getmoni"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func toolNames(tools []server.ServerTool) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Tool.Name)
+	}
+	return names
+}
+
+func TestToolFilter(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper.(*mockScraper).isLoggedIn = true
+	tools := agent.GetTools()
+
+	// A denylisted tool isn't registered
+	filtered := ToolFilter{Deny: []string{"create_tweet", "retweet"}}.Apply(tools)
+	assert.Len(t, filtered, len(tools)-2)
+	assert.NotContains(t, toolNames(filtered), "create_tweet")
+	assert.NotContains(t, toolNames(filtered), "retweet")
+	assert.Contains(t, toolNames(filtered), "get_profile")
+
+	// Only allowlisted tools are, in their order, unless also denylisted
+	filtered = ToolFilter{Allow: []string{"get_tweet", "get_profile", "like_tweet"}, Deny: []string{"like_tweet"}}.Apply(tools)
+	assert.Equal(t, []string{"get_profile", "get_tweet"}, toolNames(filtered))
+
+	assert.Len(t, ToolFilter{}.Apply(tools), len(tools))
+}
+
+func TestToolFilterUnknown(t *testing.T) {
+	filter := ToolFilter{Allow: []string{"get_tweet", "get_tweeet"}, Deny: []string{"post_tweet"}}
+	assert.Equal(t, []string{"get_tweeet", "post_tweet"}, filter.Unknown())
+	assert.Empty(t, ToolFilter{Deny: []string{"create_tweet"}}.Unknown())
+}
+
+// ToolNames must know every tool, or valid names would be reported unknown
+func TestToolNamesComplete(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper.(*mockScraper).isLoggedIn = true
+	agent.SetGetMoni(getmoni.NewGetMoni(""))
+	assert.ElementsMatch(t, ToolNames, toolNames(agent.GetTools()))
+}
+
+func TestParseToolList(t *testing.T) {
+	assert.Equal(t, []string{"create_tweet", "retweet"}, ParseToolList(" create_tweet, ,retweet "))
+	assert.Empty(t, ParseToolList(""))
+}
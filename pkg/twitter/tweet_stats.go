@@ -0,0 +1,132 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// TweetStats are the current engagement counts of a tweet
+type TweetStats struct {
+	TweetID  string `json:"tweet_id"`
+	Likes    int    `json:"likes"`
+	Retweets int    `json:"retweets"`
+	Replies  int    `json:"replies"`
+	Views    int    `json:"views"`
+}
+
+// handleGetTweetStats fetches a tweet and returns only its engagement
+// counts. The tweet is always fetched from Twitter, never from the tweet
+// store, since the point is the current numbers.
+func (a *Agent) handleGetTweetStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tweetID, ok := request.Params.Arguments["tweet_id"].(string)
+	if !ok || tweetID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "tweet_id parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Wait for rate limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_tweet"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweet, err := a.scraper.GetTweet(ctx, tweetID)
+	a.limiter.doneEndpoint("get_tweet")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting tweet: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	jsonData, err := json.Marshal(TweetStats{
+		TweetID:  tweet.ID,
+		Likes:    tweet.Likes,
+		Retweets: tweet.Retweets,
+		Replies:  tweet.Replies,
+		Views:    tweet.Views,
+	})
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// GetTweetStats gets the current likes, retweets, replies and views of a
+// tweet using the next available agent, without the rest of the tweet
+func (am *AgentManager) GetTweetStats(ctx context.Context, tweetID string) (likes, retweets, replies, views int, err error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	logger.Debug("Getting stats of tweet %s using agent %s", tweetID, selection.Agent)
+
+	result, err := agent.handleGetTweetStats(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_tweet_stats",
+			Arguments: map[string]interface{}{
+				"tweet_id": tweetID,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting stats of tweet %s: %v", tweetID, err)
+		return 0, 0, 0, 0, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for stats of tweet %s: %s", tweetID, errMsg)
+		return 0, 0, 0, 0, toolError(errMsg)
+	}
+
+	var stats TweetStats
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &stats); err != nil {
+		logger.Error("Error unmarshaling stats of tweet %s: %v", tweetID, err)
+		return 0, 0, 0, 0, err
+	}
+
+	logger.Debug("Successfully retrieved stats of tweet %s", tweetID)
+	return stats.Likes, stats.Retweets, stats.Replies, stats.Views, nil
+}
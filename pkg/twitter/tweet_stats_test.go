@@ -0,0 +1,51 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// statsScraper returns a tweet with fixed counts, or err when set
+type statsScraper struct {
+	mockScraper
+	err error
+}
+
+func (s *statsScraper) GetTweet(ctx context.Context, id string) (*twitterscraper.Tweet, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &twitterscraper.Tweet{ID: id, Text: "hello", Likes: 10, Retweets: 3, Replies: 2, Views: 500}, nil
+}
+
+func TestGetTweetStats(t *testing.T) {
+	scraper := &statsScraper{}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	likes, retweets, replies, views, err := manager.GetTweetStats(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 3, 2, 500}, []int{likes, retweets, replies, views})
+
+	// The tool returns only the counts
+	agent.limiter.lastCallTime = time.Time{}
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]interface{}{"tweet_id": "1"}
+	result, err := agent.handleGetTweetStats(context.Background(), request)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"tweet_id": "1", "likes": 10, "retweets": 3, "replies": 2, "views": 500}`, result.Content[0].(*mcp.TextContent).Text)
+
+	agent.limiter.lastCallTime = time.Time{}
+	scraper.err = errors.New("tweet not found")
+	_, _, _, _, err = manager.GetTweetStats(context.Background(), "2")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
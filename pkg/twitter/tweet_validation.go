@@ -0,0 +1,100 @@
+package twitter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TweetIssue is a reason a tweet can't be posted
+type TweetIssue struct {
+	Field   string `json:"field"` // Request field at fault: text, quote_of, media, schedule_time or poll
+	Message string `json:"message"`
+}
+
+// TweetValidation reports whether a tweet can be posted, without posting it
+type TweetValidation struct {
+	Valid     bool `json:"valid"`
+	Length    int  `json:"length"` // Weighted character count of the text
+	MaxLength int  `json:"max_length"`
+	// Parts are the tweets an auto thread splits the text into, when it
+	// takes more than one
+	Parts  []string     `json:"parts,omitempty"`
+	Errors []TweetIssue `json:"errors"`
+}
+
+// ValidateTweet runs the checks PostTweet runs before posting req and
+// reports every problem found along with the weighted length of the text.
+// Nothing is sent to Twitter.
+func ValidateTweet(req PostTweetRequest) *TweetValidation {
+	validation := &TweetValidation{
+		Length:    tweetLength(req.Text),
+		MaxLength: maxTweetLength,
+		Errors:    req.issues(time.Now()),
+	}
+	validation.Valid = len(validation.Errors) == 0
+	if req.AutoThread && req.ReplyTo == "" && req.QuoteOf == "" {
+		if parts := splitThread(req.Text); len(parts) > 1 {
+			validation.Parts = parts
+		}
+	}
+	return validation
+}
+
+// issues returns every reason req can't be posted at now, in the order
+// validate reports them
+func (req PostTweetRequest) issues(now time.Time) []TweetIssue {
+	issues := make([]TweetIssue, 0)
+	add := func(field, format string, args ...interface{}) {
+		issues = append(issues, TweetIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(req.Text) == "" {
+		// Media can be posted without text
+		if len(req.Media) == 0 {
+			add("text", "text is required unless media is attached")
+		}
+	} else if length := tweetLength(req.Text); length > maxTweetLength && !req.AutoThread {
+		add("text", "tweet exceeds %d characters (counted %d)", maxTweetLength, length)
+	}
+
+	if req.ReplyTo != "" && req.QuoteOf != "" {
+		add("quote_of", "reply_to and quote_of can't both be set")
+	}
+	if len(req.Media) > 0 {
+		if req.QuoteOf == "" {
+			add("media", "media can only be used with quote_of")
+		}
+		if err := validateMedia(req.Media); err != nil {
+			add("media", "%v", err)
+		}
+	}
+	if (req.ReplyTo != "" || req.QuoteOf != "") && (req.ScheduleTime != "" || req.AutoThread || req.Poll != nil) {
+		add("quote_of", "schedule_time, auto_thread and poll can't be used with reply_to or quote_of")
+	}
+
+	if req.ScheduleTime != "" {
+		scheduled, err := time.Parse(time.RFC3339, req.ScheduleTime)
+		switch {
+		case err != nil:
+			add("schedule_time", "invalid schedule time format %q, expected ISO 8601 such as 2024-05-01T12:00:00Z", req.ScheduleTime)
+		case !scheduled.After(now):
+			add("schedule_time", "schedule time %s is not in the future", req.ScheduleTime)
+		}
+	}
+
+	if req.Poll != nil {
+		poll := *req.Poll
+		if poll.DurationMinutes == 0 {
+			poll.DurationMinutes = defaultPollDuration
+		}
+		if err := poll.Validate(); err != nil {
+			add("poll", "%v", err)
+		}
+		if req.AutoThread && len(splitThread(req.Text)) > 1 {
+			add("poll", "a poll can't be attached to an auto thread")
+		}
+	}
+
+	return issues
+}
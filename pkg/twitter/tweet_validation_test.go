@@ -0,0 +1,116 @@
+package twitter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTweet(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	long := strings.Repeat("word ", 60)
+
+	tests := []struct {
+		name     string
+		req      PostTweetRequest
+		length   int
+		fields   []string
+		numParts int
+	}{
+		{
+			name:   "valid",
+			req:    PostTweetRequest{Text: "hello https://example.com"},
+			length: 29,
+		},
+		{
+			name:   "empty text",
+			req:    PostTweetRequest{Text: "  "},
+			length: 2,
+			fields: []string{"text"},
+		},
+		{
+			name:   "too long",
+			req:    PostTweetRequest{Text: long},
+			length: 300,
+			fields: []string{"text"},
+		},
+		{
+			name:     "auto thread",
+			req:      PostTweetRequest{Text: long, CreateTweetOptions: CreateTweetOptions{AutoThread: true}},
+			length:   300,
+			numParts: 2,
+		},
+		{
+			name:     "poll on auto thread",
+			req:      PostTweetRequest{Text: long, CreateTweetOptions: CreateTweetOptions{AutoThread: true, Poll: &Poll{Options: []string{"a", "b"}}}},
+			length:   300,
+			fields:   []string{"poll"},
+			numParts: 2,
+		},
+		{
+			name:   "invalid poll",
+			req:    PostTweetRequest{Text: "poll", CreateTweetOptions: CreateTweetOptions{Poll: &Poll{Options: []string{"only"}}}},
+			length: 4,
+			fields: []string{"poll"},
+		},
+		{
+			name:   "scheduled",
+			req:    PostTweetRequest{Text: "later", CreateTweetOptions: CreateTweetOptions{ScheduleTime: future}},
+			length: 5,
+		},
+		{
+			name:   "invalid schedule time",
+			req:    PostTweetRequest{Text: "later", CreateTweetOptions: CreateTweetOptions{ScheduleTime: "tomorrow"}},
+			length: 5,
+			fields: []string{"schedule_time"},
+		},
+		{
+			name:   "past schedule time",
+			req:    PostTweetRequest{Text: "later", CreateTweetOptions: CreateTweetOptions{ScheduleTime: "2020-01-01T00:00:00Z"}},
+			length: 5,
+			fields: []string{"schedule_time"},
+		},
+		{
+			name:   "every problem is reported",
+			req:    PostTweetRequest{Text: "", ReplyTo: "1", QuoteOf: "2", Media: []MediaAttachment{{ID: "1"}, {ID: "1"}}, CreateTweetOptions: CreateTweetOptions{ScheduleTime: "tomorrow"}},
+			fields: []string{"quote_of", "media", "quote_of", "schedule_time"},
+		},
+		{
+			name: "media without text",
+			req:  PostTweetRequest{QuoteOf: "2", Media: []MediaAttachment{{ID: "1"}}},
+		},
+		{
+			name:   "media without a quote",
+			req:    PostTweetRequest{Text: "look", Media: []MediaAttachment{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "5"}}},
+			length: 4,
+			fields: []string{"media", "media"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation := ValidateTweet(tt.req)
+			assert.Equal(t, tt.length, validation.Length)
+			assert.Equal(t, maxTweetLength, validation.MaxLength)
+			assert.Equal(t, len(tt.fields) == 0, validation.Valid)
+			assert.Len(t, validation.Parts, tt.numParts)
+
+			var fields []string
+			for _, issue := range validation.Errors {
+				fields = append(fields, issue.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+
+			// PostTweet rejects exactly the tweets reported invalid
+			err := tt.req.validate()
+			if validation.Valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidTweetRequest)
+				assert.ErrorContains(t, err, validation.Errors[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// userIDTTL is how long the user ID a username was resolved to is cached.
+// Handles can change owner, so it is kept short.
+const userIDTTL = 10 * time.Minute
+
+// cachedUserID is the user ID a username resolved to
+type cachedUserID struct {
+	userID     string
+	resolvedAt time.Time
+}
+
+// isUserID reports whether s is a numeric user ID
+func isUserID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveUserID returns the user ID for idOrUsername, which is either a
+// numeric user ID, returned as is, or a username that is looked up with the
+// next available agent. Handles can be all digits too, so "@" forces a
+// lookup. Lookups are cached for userIDTTL. A username that doesn't resolve
+// is an error wrapping ErrNotFound.
+func (am *AgentManager) ResolveUserID(ctx context.Context, idOrUsername string) (string, error) {
+	idOrUsername = strings.TrimSpace(idOrUsername)
+	if isUserID(idOrUsername) {
+		return idOrUsername, nil
+	}
+	username, err := NormalizeUsername(idOrUsername)
+	if err != nil {
+		return "", err
+	}
+
+	am.userIDMutex.Lock()
+	cached, ok := am.userIDCache[username]
+	am.userIDMutex.Unlock()
+	if ok && time.Since(cached.resolvedAt) < userIDTTL {
+		return cached.userID, nil
+	}
+
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	logger.Debug("Resolving user ID of %s using agent %s", username, selection.Agent)
+
+	if err := agent.limiter.waitForEndpoint(ctx, "get_profile"); err != nil {
+		return "", toolError(fmt.Sprintf("rate limit error: %v", err))
+	}
+	profile, err := agent.scraper.GetProfile(ctx, username)
+	agent.limiter.doneEndpoint("get_profile")
+	if err != nil {
+		logger.Error("Error resolving user ID of %s: %v", username, err)
+		return "", toolError(fmt.Sprintf("error resolving user %s to a user ID: %v", username, err))
+	}
+	if profile == nil || profile.UserID == "" {
+		return "", fmt.Errorf("%w: user %s has no user ID", ErrNotFound, username)
+	}
+
+	am.userIDMutex.Lock()
+	if am.userIDCache == nil {
+		am.userIDCache = make(map[string]cachedUserID)
+	}
+	am.userIDCache[username] = cachedUserID{userID: profile.UserID, resolvedAt: time.Now()}
+	am.userIDMutex.Unlock()
+
+	logger.Debug("Resolved user %s to user ID %s", username, profile.UserID)
+	return profile.UserID, nil
+}
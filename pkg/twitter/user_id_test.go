@@ -0,0 +1,72 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+// userIDScraper knows the user IDs of a few users and counts lookups
+type userIDScraper struct {
+	mockScraper
+	ids     map[string]string
+	lookups int
+}
+
+func (s *userIDScraper) GetProfile(ctx context.Context, username string) (*twitterscraper.Profile, error) {
+	s.lookups++
+	id, ok := s.ids[username]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return &twitterscraper.Profile{UserID: id, Username: username}, nil
+}
+
+func TestResolveUserID(t *testing.T) {
+	scraper := &userIDScraper{ids: map[string]string{"alice": "42", "1234": "77"}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+	ctx := context.Background()
+
+	// IDs are used as is
+	id, err := manager.ResolveUserID(ctx, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", id)
+	assert.Equal(t, 0, scraper.lookups)
+
+	// Usernames are looked up once
+	id, err = manager.ResolveUserID(ctx, "@Alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", id)
+	id, err = manager.ResolveUserID(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", id)
+	assert.Equal(t, 1, scraper.lookups)
+
+	// "@" makes an all-digit handle a username
+	agent.limiter.lastCallTime = time.Time{}
+	id, err = manager.ResolveUserID(ctx, "@1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "77", id)
+
+	// Stale entries are looked up again
+	manager.userIDCache["alice"] = cachedUserID{userID: "42", resolvedAt: time.Now().Add(-userIDTTL)}
+	agent.limiter.lastCallTime = time.Time{}
+	_, err = manager.ResolveUserID(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, scraper.lookups)
+
+	agent.limiter.lastCallTime = time.Time{}
+	_, err = manager.ResolveUserID(ctx, "nobody")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = manager.ResolveUserID(ctx, "not a user")
+	assert.ErrorIs(t, err, ErrInvalidUsername)
+}
@@ -0,0 +1,189 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// userRepliesPageSize is the number of timeline entries requested per page.
+// The timeline mixes in tweets, retweets and the tweets replied to, so a
+// page holds fewer replies.
+const userRepliesPageSize = 40
+
+// UserReply is a reply posted by a user
+type UserReply struct {
+	SimplifiedTweet
+	InReplyTo      string `json:"in_reply_to"` // ID of the tweet replied to
+	ConversationID string `json:"conversation_id,omitempty"`
+}
+
+// UserRepliesPage is a page of the replies posted by a user, newest first
+type UserRepliesPage struct {
+	Username string      `json:"username"`
+	Replies  []UserReply `json:"replies"`
+	// NextCursor fetches the next page; empty once the timeline is exhausted.
+	// A page may hold no replies while the next ones still do.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// handleGetUserReplies gets a page of the replies a user posted. They are
+// read from the user's tweets and replies timeline, which also holds their
+// tweets and retweets and the tweets they replied to; only the user's own
+// replies are kept.
+func (a *Agent) handleGetUserReplies(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !a.scraper.IsLoggedIn() {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "This tool requires login. Please provide Twitter cookies to use this tool.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	username, ok := request.Params.Arguments["username"].(string)
+	if !ok || username == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: "username parameter is required",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	username, err := NormalizeUsername(username)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: err.Error(),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	cursor, _ := request.Params.Arguments["cursor"].(string)
+
+	// The replies come from the user's timeline, so they count against its limit
+	if err := a.limiter.waitForEndpoint(ctx, "get_user_tweets"); err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("rate limit error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	tweets, next, err := a.scraper.FetchTweetsAndReplies(ctx, username, userRepliesPageSize, cursor)
+	a.limiter.doneEndpoint("get_user_tweets")
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error getting replies: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	page := UserRepliesPage{Username: username, Replies: make([]UserReply, 0)}
+	for _, tweet := range tweets {
+		if !tweet.IsReply || tweet.IsRetweet || !strings.EqualFold(tweet.Username, username) {
+			continue
+		}
+		page.Replies = append(page.Replies, UserReply{
+			SimplifiedTweet: newSimplifiedTweet(tweet),
+			InReplyTo:       tweet.InReplyToStatusID,
+			ConversationID:  tweet.ConversationID,
+		})
+	}
+	// The timeline keeps returning a cursor past its end
+	if len(tweets) > 0 {
+		page.NextCursor = next
+	}
+
+	jsonData, err := json.Marshal(page)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("error marshaling results: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// GetUserReplies gets a page of the replies username posted using the next
+// available agent, starting at cursor or at the newest replies when it is
+// empty. An invalid username is an error wrapping ErrInvalidUsername.
+func (am *AgentManager) GetUserReplies(ctx context.Context, username string, cursor string) (*UserRepliesPage, string, error) {
+	logger := am.requestLogger(ctx)
+	username, err := NormalizeUsername(username)
+	if err != nil {
+		return nil, "", err
+	}
+	agent, selection := am.getNextAgent(ctx)
+	agentUsername := selection.Agent
+	logger.Debug("Getting replies of user %s using agent %s", username, agentUsername)
+
+	result, err := agent.handleGetUserReplies(ctx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+			Meta      *struct {
+				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+			} `json:"_meta,omitempty"`
+		}{
+			Name: "get_user_replies",
+			Arguments: map[string]interface{}{
+				"username": username,
+				"cursor":   cursor,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error getting replies of user %s: %v", username, err)
+		return nil, agentUsername, err
+	}
+	if result.IsError {
+		errMsg := result.Content[0].(*mcp.TextContent).Text
+		logger.Error("Error in response for replies of user %s: %s", username, errMsg)
+		return nil, agentUsername, toolError(errMsg)
+	}
+
+	var page UserRepliesPage
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &page); err != nil {
+		logger.Error("Error unmarshaling replies of user %s: %v", username, err)
+		return nil, agentUsername, err
+	}
+
+	logger.Debug("Successfully retrieved %d replies of user %s", len(page.Replies), username)
+	return &page, agentUsername, nil
+}
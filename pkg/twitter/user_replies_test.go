@@ -0,0 +1,60 @@
+package twitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+type repliesScraper struct {
+	mockScraper
+	username string
+	cursor   string
+}
+
+func (s *repliesScraper) FetchTweetsAndReplies(ctx context.Context, username string, maxTweetsNbr int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	s.username, s.cursor = username, cursor
+	if cursor == "end" {
+		return nil, "past-end", nil
+	}
+	return []*twitterscraper.Tweet{
+		{ID: "1", Username: "alice", Text: "a tweet"},
+		{ID: "2", Username: "Alice", Text: "a reply", IsReply: true, InReplyToStatusID: "9", ConversationID: "8"},
+		{ID: "9", Username: "bob", Text: "the tweet replied to", IsReply: true, InReplyToStatusID: "8"},
+		{ID: "3", Username: "alice", Text: "a retweeted reply", IsReply: true, IsRetweet: true, InReplyToStatusID: "7"},
+	}, "next", nil
+}
+
+func TestGetUserReplies(t *testing.T) {
+	scraper := &repliesScraper{mockScraper: mockScraper{isLoggedIn: true}}
+	agent := newMockAgent()
+	agent.scraper = scraper
+	agent.limiter.lastCallTime = time.Time{}
+	am := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	page, _, err := am.GetUserReplies(context.Background(), "@Alice", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", scraper.username)
+	assert.Equal(t, "alice", page.Username)
+	if assert.Len(t, page.Replies, 1) {
+		assert.Equal(t, "2", page.Replies[0].ID)
+		assert.Equal(t, "9", page.Replies[0].InReplyTo)
+		assert.Equal(t, "8", page.Replies[0].ConversationID)
+	}
+	assert.Equal(t, "next", page.NextCursor)
+
+	// The cursor returned past the end of the timeline isn't passed on
+	agent.limiter.lastCallTime = time.Time{}
+	page, _, err = am.GetUserReplies(context.Background(), "alice", "end")
+	assert.NoError(t, err)
+	assert.Equal(t, "end", scraper.cursor)
+	assert.Empty(t, page.Replies)
+	assert.Empty(t, page.NextCursor)
+
+	_, _, err = am.GetUserReplies(context.Background(), "not a user", "")
+	assert.ErrorIs(t, err, ErrInvalidUsername)
+}
@@ -0,0 +1,31 @@
+package twitter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxUsernameLength is the longest handle Twitter allows
+const maxUsernameLength = 15
+
+// ErrInvalidUsername is returned by NormalizeUsername for strings that can't
+// be a Twitter handle
+var ErrInvalidUsername = errors.New("invalid username")
+
+// NormalizeUsername returns the canonical form of a Twitter handle as stored
+// in the database: without surrounding spaces or a leading "@", in lower case.
+// Twitter matches handles case-insensitively, so "@Foo" and "foo" are the same
+// user. Handles are 1 to 15 letters, digits and underscores.
+func NormalizeUsername(s string) (string, error) {
+	username := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "@"))
+	if username == "" || len(username) > maxUsernameLength {
+		return "", fmt.Errorf("%w %q: must be 1 to %d characters", ErrInvalidUsername, s, maxUsernameLength)
+	}
+	for _, c := range username {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return "", fmt.Errorf("%w %q: only letters, digits and underscores are allowed", ErrInvalidUsername, s)
+		}
+	}
+	return username, nil
+}
@@ -0,0 +1,52 @@
+package twitter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "foo", expected: "foo"},
+		{input: "@Foo", expected: "foo"},
+		{input: " Jack_Dorsey1 ", expected: "jack_dorsey1"},
+		{input: "a", expected: "a"},
+		{input: "abcdefghijklmno", expected: "abcdefghijklmno"},
+		{input: "", wantErr: true},
+		{input: "@", wantErr: true},
+		{input: "@@foo", wantErr: true},
+		{input: "abcdefghijklmnop", wantErr: true},
+		{input: "foo bar", wantErr: true},
+		{input: "foo-bar", wantErr: true},
+		{input: "fóo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			username, err := NormalizeUsername(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidUsername)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, username)
+		})
+	}
+}
+
+// The users and smart_users tables only have UNIQUE(username), which relies
+// on every spelling of a handle being stored as the same normalized string
+func TestNormalizeUsernameUniqueKey(t *testing.T) {
+	stored := make(map[string]bool)
+	for _, spelling := range []string{"Foo", "@foo", "FOO", " @fOo", "foo"} {
+		username, err := NormalizeUsername(spelling)
+		assert.NoError(t, err)
+		stored[username] = true
+	}
+	assert.Equal(t, map[string]bool{"foo": true}, stored)
+}
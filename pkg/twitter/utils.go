@@ -1,6 +1,113 @@
 package twitter
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
 // BoolPtr returns a pointer to the given bool value
 func BoolPtr(b bool) *bool {
 	return &b
 }
+
+// validateHeaderValue rejects values that can't be sent as an HTTP header,
+// such as ones containing line breaks or other control characters
+func validateHeaderValue(value string) error {
+	for _, r := range value {
+		if (r < 0x20 && r != '\t') || r == 0x7f {
+			return fmt.Errorf("header value contains control character %q", r)
+		}
+	}
+	return nil
+}
+
+// DefaultMaxResults caps the number of tweets a single search or timeline
+// request may ask for, so a huge limit can't exhaust memory
+const DefaultMaxResults = 1000
+
+// ClampLimit caps limit at max, returning the capped limit and, when it had
+// to cap, a warning to include in the response. A max of 0 disables the cap.
+func ClampLimit(limit, max int) (int, string) {
+	if max > 0 && limit > max {
+		return max, fmt.Sprintf("limit %d exceeds the maximum of %d, returning at most %d results", limit, max, max)
+	}
+	return limit, ""
+}
+
+// withWarnings appends each non-empty warning, e.g. from ClampLimit, to
+// result as a further text item, leaving the JSON payload in the first item
+// untouched
+func withWarnings(result *mcp.CallToolResult, warnings ...string) *mcp.CallToolResult {
+	for _, warning := range warnings {
+		if warning != "" {
+			result.Content = append(result.Content, &mcp.TextContent{
+				Type: "text",
+				Text: "warning: " + warning,
+			})
+		}
+	}
+	return result
+}
+
+// partialResultsWarning describes a tweet stream that failed with err after
+// count tweets had been read, which are returned nonetheless
+func partialResultsWarning(count int, err error) string {
+	return fmt.Sprintf("%s, only %d tweets were read before an error: %v", partialResultsPrefix, count, err)
+}
+
+// partialResultsPrefix starts every partialResultsWarning
+const partialResultsPrefix = "partial results"
+
+// partialResults returns the partial results warnings withWarnings added to
+// result, without their "warning: " prefix. Other warnings are left out.
+func partialResults(result *mcp.CallToolResult) []string {
+	var warnings []string
+	for _, content := range result.Content[1:] {
+		text, ok := content.(*mcp.TextContent)
+		if !ok {
+			continue
+		}
+		if warning := strings.TrimPrefix(text.Text, "warning: "); strings.HasPrefix(warning, partialResultsPrefix) {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
+// errTimelineEnd is returned by drainTweets callbacks to stop reading a
+// timeline that has gone past the requested window
+var errTimelineEnd = errors.New("end of timeline window")
+
+// drainTweets passes each result read from tweets to yield until the channel
+// closes, yield returns an error or ctx is done, returning that error or
+// ctx.Err(). On early return the rest of the channel is drained in the
+// background, so the scraper's goroutine isn't left blocked on a send.
+func drainTweets(ctx context.Context, tweets <-chan *twitterscraper.TweetResult, yield func(*twitterscraper.TweetResult) error) (err error) {
+	defer func() {
+		if err != nil {
+			go func() {
+				for range tweets {
+				}
+			}()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case tweet, ok := <-tweets:
+			if !ok {
+				return nil
+			}
+			if err := yield(tweet); err != nil {
+				return err
+			}
+		}
+	}
+}
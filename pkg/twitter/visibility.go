@@ -0,0 +1,47 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+)
+
+// Visibilities of a tweet reported by the scraper's TweetVisibility
+const (
+	TweetAvailable = "available" // The tweet exists and is public
+	TweetProtected = "protected" // The tweet exists but its author's tweets are protected
+	TweetNotFound  = "not_found" // The tweet was deleted or its author is gone
+)
+
+// TweetExists checks whether a tweet still exists and is public without
+// fetching it: a single lookup whose result type alone is read. A missing
+// tweet isn't an error; exists is false then. A tweet of a protected account
+// exists but is protected.
+func (am *AgentManager) TweetExists(ctx context.Context, tweetID string) (exists bool, protected bool, err error) {
+	logger := am.requestLogger(ctx)
+	agent, selection := am.getNextAgent(ctx)
+	logger.Debug("Checking existence of tweet %s using agent %s", tweetID, selection.Agent)
+
+	visibility, err := agent.tweetVisibility(ctx, tweetID)
+	if err != nil {
+		logger.Error("Error checking tweet %s: %v", tweetID, err)
+		return false, false, err
+	}
+	return visibility != TweetNotFound, visibility == TweetProtected, nil
+}
+
+// tweetVisibility looks up whether tweet id is available, protected or gone
+func (a *Agent) tweetVisibility(ctx context.Context, id string) (string, error) {
+	if err := a.limiter.waitForEndpoint(ctx, "get_tweet"); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", toolError(fmt.Sprintf("rate limit error: %v", err))
+	}
+
+	visibility, err := a.scraper.TweetVisibility(ctx, id)
+	a.limiter.doneEndpoint("get_tweet")
+	if err != nil {
+		return "", toolError(fmt.Sprintf("error checking tweet %s: %v", id, err))
+	}
+	return visibility, nil
+}
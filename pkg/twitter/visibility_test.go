@@ -0,0 +1,55 @@
+package twitter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asabya/x-go/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// visibilityScraper reports fixed visibilities of tweets by ID
+type visibilityScraper struct {
+	mockScraper
+	visibilities map[string]string
+}
+
+func (s *visibilityScraper) TweetVisibility(ctx context.Context, id string) (string, error) {
+	if visibility, ok := s.visibilities[id]; ok {
+		return visibility, nil
+	}
+	return "", errors.New("response status 503 Service Unavailable")
+}
+
+func TestTweetExists(t *testing.T) {
+	agent := newMockAgent()
+	agent.scraper = &visibilityScraper{visibilities: map[string]string{
+		"1": TweetAvailable,
+		"2": TweetProtected,
+		"3": TweetNotFound,
+	}}
+	manager := &AgentManager{agents: []*Agent{agent}, logger: logging.Default()}
+
+	tests := []struct {
+		id        string
+		exists    bool
+		protected bool
+	}{
+		{"1", true, false},
+		{"2", true, true},
+		{"3", false, false},
+	}
+	for _, tt := range tests {
+		agent.limiter.lastCallTime = time.Time{}
+		exists, protected, err := manager.TweetExists(context.Background(), tt.id)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.exists, exists, tt.id)
+		assert.Equal(t, tt.protected, protected, tt.id)
+	}
+
+	agent.limiter.lastCallTime = time.Time{}
+	_, _, err := manager.TweetExists(context.Background(), "4")
+	assert.ErrorIs(t, err, ErrUpstream)
+}